@@ -0,0 +1,519 @@
+// Copyright ©, 2023-present, Lightspark Group, Inc. - All Rights Reserved
+
+// Package bolt11 decodes BOLT11 Lightning invoices locally, without calling the Lightspark API.
+//
+// Decode validates the bech32 checksum, the human-readable part and the signature of the invoice, and
+// recovers the public key of the payee when the invoice doesn't include it:
+//
+//	invoice, err := bolt11.Decode(encodedInvoice)
+//	if err != nil {
+//		return err
+//	}
+//	if invoice.IsExpired(time.Now()) {
+//		...
+//	}
+//	data := invoice.ToInvoiceData()
+package bolt11
+
+import (
+	"bytes"
+	"crypto/sha256"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"math"
+	"strconv"
+	"strings"
+	"time"
+	"unicode/utf8"
+
+	"github.com/btcsuite/btcd/btcec/v2"
+	"github.com/btcsuite/btcd/btcec/v2/ecdsa"
+	"github.com/btcsuite/btcd/btcutil/bech32"
+	"github.com/lightsparkdev/go-sdk/objects"
+)
+
+const (
+	// DefaultExpiry is the expiry of invoices without an `x` field.
+	DefaultExpiry = time.Hour
+	// DefaultMinFinalCltvExpiry is the min_final_cltv_expiry of invoices without a `c` field.
+	DefaultMinFinalCltvExpiry = 18
+
+	timestampLength = 7
+	signatureLength = 104
+	hashLength      = 52
+	pubKeyLength    = 53
+	routeHopLength  = 51
+
+	// maxExpirySeconds is the largest expiry that fits in a time.Duration.
+	maxExpirySeconds = math.MaxInt64 / uint64(time.Second)
+)
+
+const (
+	fieldPaymentHash        = 1
+	fieldRouteHint          = 3
+	fieldFeatures           = 5
+	fieldExpiry             = 6
+	fieldFallbackAddress    = 9
+	fieldDescription        = 13
+	fieldPaymentSecret      = 16
+	fieldPayeePubKey        = 19
+	fieldDescriptionHash    = 23
+	fieldMinFinalCltvExpiry = 24
+	fieldPaymentMetadata    = 27
+)
+
+var (
+	// ErrInvalidSignature is returned when the signature doesn't match the payee public key of the invoice,
+	// or when no public key can be recovered from it.
+	ErrInvalidSignature = errors.New("bolt11: invalid signature")
+	// ErrUnknownNetwork is returned when the human-readable part of the invoice doesn't start with a known
+	// currency prefix.
+	ErrUnknownNetwork = errors.New("bolt11: unknown network")
+)
+
+// networkPrefixes maps the currency prefixes of BOLT11 to networks. Longer prefixes come first, since
+// `bc` is a prefix of `bcrt` and `tb` of `tbs`.
+var networkPrefixes = []struct {
+	prefix  string
+	network objects.BitcoinNetwork
+}{
+	{"bcrt", objects.BitcoinNetworkRegtest},
+	{"bc", objects.BitcoinNetworkMainnet},
+	{"tbs", objects.BitcoinNetworkSignet},
+	{"tb", objects.BitcoinNetworkTestnet},
+}
+
+// RouteHintHop is a hop of a private route to the payee.
+type RouteHintHop struct {
+	PubKey                    []byte
+	ShortChannelId            uint64
+	FeeBaseMsat               uint32
+	FeeProportionalMillionths uint32
+	CltvExpiryDelta           uint16
+}
+
+// FallbackAddress is an on-chain address to use when the payment cannot be made over Lightning.
+type FallbackAddress struct {
+	// Version is the witness version, or 17 for P2PKH and 18 for P2SH addresses.
+	Version byte
+	Program []byte
+}
+
+// FeatureVector lists the feature bits set in an invoice, in increasing order.
+type FeatureVector []int
+
+// IsSet reports whether the given bit is set.
+func (f FeatureVector) IsSet(bit int) bool {
+	for _, b := range f {
+		if b == bit {
+			return true
+		}
+	}
+	return false
+}
+
+// Supports reports whether a feature is set, either as required (even bit) or optional (odd bit).
+func (f FeatureVector) Supports(bit int) bool {
+	return f.IsSet(bit) || f.IsSet(bit^1)
+}
+
+// Invoice is a decoded BOLT11 invoice.
+type Invoice struct {
+	// Encoded is the invoice as it was given to Decode, in lowercase.
+	Encoded string
+	Network objects.BitcoinNetwork
+	// AmountMsats is nil for invoices without an amount.
+	AmountMsats     *int64
+	Timestamp       time.Time
+	PaymentHash     [32]byte
+	PaymentSecret   *[32]byte
+	Description     *string
+	DescriptionHash *[32]byte
+	// PayeePubKey is the compressed public key of the payee, taken from the `n` field or recovered from
+	// the signature.
+	PayeePubKey        []byte
+	Expiry             time.Duration
+	MinFinalCltvExpiry uint64
+	FallbackAddresses  []FallbackAddress
+	RouteHints         [][]RouteHintHop
+	Features           FeatureVector
+	PaymentMetadata    []byte
+	// Signature is the compact signature of the invoice, followed by its recovery id.
+	Signature [65]byte
+}
+
+// ExpiresAt returns the time after which the invoice cannot be paid anymore.
+func (i *Invoice) ExpiresAt() time.Time {
+	return i.Timestamp.Add(i.Expiry)
+}
+
+// IsExpired reports whether the invoice has expired at the given time.
+func (i *Invoice) IsExpired(now time.Time) bool {
+	return !now.Before(i.ExpiresAt())
+}
+
+// ToInvoiceData converts the invoice to the shape returned by LightsparkClient.DecodePaymentRequest. The
+// destination is a GraphNode with only its public key and network set, since its Lightspark id is not
+// known offline.
+func (i *Invoice) ToInvoiceData() objects.InvoiceData {
+	var amountMsats int64
+	if i.AmountMsats != nil {
+		amountMsats = *i.AmountMsats
+	}
+	publicKey := hex.EncodeToString(i.PayeePubKey)
+	return objects.InvoiceData{
+		EncodedPaymentRequest: i.Encoded,
+		BitcoinNetwork:        i.Network,
+		PaymentHash:           hex.EncodeToString(i.PaymentHash[:]),
+		Amount: objects.CurrencyAmount{
+			OriginalValue:                 amountMsats,
+			OriginalUnit:                  objects.CurrencyUnitMillisatoshi,
+			PreferredCurrencyUnit:         objects.CurrencyUnitSatoshi,
+			PreferredCurrencyValueRounded: amountMsats / 1000,
+			PreferredCurrencyValueApprox:  float64(amountMsats) / 1000,
+		},
+		CreatedAt: i.Timestamp,
+		ExpiresAt: i.ExpiresAt(),
+		Memo:      i.Description,
+		Destination: objects.GraphNode{
+			BitcoinNetwork: i.Network,
+			DisplayName:    publicKey,
+			PublicKey:      &publicKey,
+			Typename:       "GraphNode",
+		},
+		Typename: "InvoiceData",
+	}
+}
+
+// Decode parses and validates a BOLT11 invoice. A `lightning:` URI prefix is accepted.
+func Decode(encoded string) (*Invoice, error) {
+	if strings.HasPrefix(strings.ToLower(encoded), "lightning:") {
+		encoded = encoded[len("lightning:"):]
+	}
+	if strings.ToLower(encoded) != encoded && strings.ToUpper(encoded) != encoded {
+		return nil, errors.New("bolt11: mixed case invoice")
+	}
+	encoded = strings.ToLower(encoded)
+
+	hrp, data, err := bech32.DecodeNoLimit(encoded)
+	if err != nil {
+		return nil, fmt.Errorf("bolt11: %w", err)
+	}
+	if len(data) < timestampLength+signatureLength {
+		return nil, errors.New("bolt11: invoice too short")
+	}
+
+	invoice := &Invoice{
+		Encoded:            encoded,
+		Expiry:             DefaultExpiry,
+		MinFinalCltvExpiry: DefaultMinFinalCltvExpiry,
+	}
+	if err := invoice.parseHumanReadablePart(hrp); err != nil {
+		return nil, err
+	}
+
+	signedData := data[:len(data)-signatureLength]
+	timestamp := readUint(signedData[:timestampLength])
+	invoice.Timestamp = time.Unix(int64(timestamp), 0).UTC()
+	if err := invoice.parseTaggedFields(signedData[timestampLength:]); err != nil {
+		return nil, err
+	}
+	if err := invoice.verifySignature(hrp, signedData, data[len(data)-signatureLength:]); err != nil {
+		return nil, err
+	}
+	return invoice, nil
+}
+
+func (i *Invoice) parseHumanReadablePart(hrp string) error {
+	if !strings.HasPrefix(hrp, "ln") {
+		return errors.New("bolt11: human-readable part must start with ln")
+	}
+	rest := hrp[2:]
+	found := false
+	for _, candidate := range networkPrefixes {
+		if strings.HasPrefix(rest, candidate.prefix) {
+			i.Network = candidate.network
+			rest = rest[len(candidate.prefix):]
+			found = true
+			break
+		}
+	}
+	if !found {
+		return ErrUnknownNetwork
+	}
+	if rest == "" {
+		return nil
+	}
+
+	amountMsats, err := parseAmount(rest)
+	if err != nil {
+		return err
+	}
+	i.AmountMsats = &amountMsats
+	return nil
+}
+
+// parseAmount converts the amount of the human-readable part, in bitcoin with an optional multiplier, to
+// millisatoshis.
+func parseAmount(amount string) (int64, error) {
+	msatsPerUnit := int64(100_000_000_000)
+	digits := amount[:len(amount)-1]
+	switch multiplier := amount[len(amount)-1]; {
+	case multiplier == 'm':
+		msatsPerUnit = 100_000_000
+	case multiplier == 'u':
+		msatsPerUnit = 100_000
+	case multiplier == 'n':
+		msatsPerUnit = 100
+	case multiplier == 'p':
+		msatsPerUnit = 0
+	case multiplier >= '0' && multiplier <= '9':
+		digits = amount
+	default:
+		return 0, fmt.Errorf("bolt11: invalid amount multiplier %q", multiplier)
+	}
+	if digits == "" || digits[0] == '0' {
+		return 0, fmt.Errorf("bolt11: invalid amount %q", amount)
+	}
+	value, err := strconv.ParseInt(digits, 10, 64)
+	if err != nil {
+		return 0, fmt.Errorf("bolt11: invalid amount %q", amount)
+	}
+
+	// A pico-bitcoin is a tenth of a millisatoshi.
+	if msatsPerUnit == 0 {
+		if value%10 != 0 {
+			return 0, fmt.Errorf("bolt11: amount %q is not a whole number of millisatoshis", amount)
+		}
+		return value / 10, nil
+	}
+	if value > math.MaxInt64/msatsPerUnit {
+		return 0, fmt.Errorf("bolt11: amount %q is too large", amount)
+	}
+	return value * msatsPerUnit, nil
+}
+
+func (i *Invoice) parseTaggedFields(data []byte) error {
+	hasPaymentHash := false
+	for len(data) > 0 {
+		if len(data) < 3 {
+			return errors.New("bolt11: truncated tagged field")
+		}
+		fieldType := data[0]
+		length := int(readUint(data[1:3]))
+		if len(data) < 3+length {
+			return errors.New("bolt11: truncated tagged field")
+		}
+		value := data[3 : 3+length]
+		data = data[3+length:]
+
+		// Fields with an unexpected length are skipped, as required by BOLT11.
+		switch fieldType {
+		case fieldPaymentHash:
+			if length != hashLength || hasPaymentHash {
+				continue
+			}
+			hash, err := readHash(value)
+			if err != nil {
+				return err
+			}
+			i.PaymentHash = hash
+			hasPaymentHash = true
+		case fieldPaymentSecret:
+			if length != hashLength || i.PaymentSecret != nil {
+				continue
+			}
+			secret, err := readHash(value)
+			if err != nil {
+				return err
+			}
+			i.PaymentSecret = &secret
+		case fieldDescription:
+			bytes, err := bech32.ConvertBits(value, 5, 8, false)
+			if err != nil {
+				return fmt.Errorf("bolt11: invalid description: %w", err)
+			}
+			if !utf8.Valid(bytes) {
+				return errors.New("bolt11: description is not valid UTF-8")
+			}
+			description := string(bytes)
+			i.Description = &description
+		case fieldDescriptionHash:
+			if length != hashLength {
+				continue
+			}
+			hash, err := readHash(value)
+			if err != nil {
+				return err
+			}
+			i.DescriptionHash = &hash
+		case fieldPayeePubKey:
+			if length != pubKeyLength {
+				continue
+			}
+			pubKey, err := bech32.ConvertBits(value, 5, 8, false)
+			if err != nil {
+				return fmt.Errorf("bolt11: invalid payee public key: %w", err)
+			}
+			if _, err := btcec.ParsePubKey(pubKey); err != nil {
+				return fmt.Errorf("bolt11: invalid payee public key: %w", err)
+			}
+			i.PayeePubKey = pubKey
+		case fieldExpiry:
+			expiry := readUint(value)
+			if length > 12 || expiry > maxExpirySeconds {
+				return errors.New("bolt11: expiry is too large")
+			}
+			i.Expiry = time.Duration(expiry) * time.Second
+		case fieldMinFinalCltvExpiry:
+			if length > 12 {
+				return errors.New("bolt11: min_final_cltv_expiry is too large")
+			}
+			i.MinFinalCltvExpiry = readUint(value)
+		case fieldFallbackAddress:
+			if length == 0 {
+				continue
+			}
+			program, err := bech32.ConvertBits(value[1:], 5, 8, false)
+			if err != nil {
+				return fmt.Errorf("bolt11: invalid fallback address: %w", err)
+			}
+			i.FallbackAddresses = append(i.FallbackAddresses, FallbackAddress{Version: value[0], Program: program})
+		case fieldRouteHint:
+			route, err := parseRouteHint(value)
+			if err != nil {
+				return err
+			}
+			i.RouteHints = append(i.RouteHints, route)
+		case fieldFeatures:
+			i.Features = parseFeatures(value)
+		case fieldPaymentMetadata:
+			metadata, err := bech32.ConvertBits(value, 5, 8, false)
+			if err != nil {
+				return fmt.Errorf("bolt11: invalid payment metadata: %w", err)
+			}
+			i.PaymentMetadata = metadata
+		}
+	}
+
+	if !hasPaymentHash {
+		return errors.New("bolt11: missing payment hash")
+	}
+	if i.Description == nil && i.DescriptionHash == nil {
+		return errors.New("bolt11: missing description or description hash")
+	}
+	return nil
+}
+
+func parseRouteHint(value []byte) ([]RouteHintHop, error) {
+	bytes, err := bech32.ConvertBits(value, 5, 8, false)
+	if err != nil {
+		return nil, fmt.Errorf("bolt11: invalid route hint: %w", err)
+	}
+	if len(bytes) == 0 || len(bytes)%routeHopLength != 0 {
+		return nil, errors.New("bolt11: invalid route hint length")
+	}
+
+	var route []RouteHintHop
+	for ; len(bytes) > 0; bytes = bytes[routeHopLength:] {
+		hop := bytes[:routeHopLength]
+		if _, err := btcec.ParsePubKey(hop[:33]); err != nil {
+			return nil, fmt.Errorf("bolt11: invalid route hint public key: %w", err)
+		}
+		route = append(route, RouteHintHop{
+			PubKey:                    append([]byte(nil), hop[:33]...),
+			ShortChannelId:            readBigEndian(hop[33:41]),
+			FeeBaseMsat:               uint32(readBigEndian(hop[41:45])),
+			FeeProportionalMillionths: uint32(readBigEndian(hop[45:49])),
+			CltvExpiryDelta:           uint16(readBigEndian(hop[49:51])),
+		})
+	}
+	return route, nil
+}
+
+// parseFeatures reads a feature bit vector. Bit 0 is the least significant bit of the last group.
+func parseFeatures(value []byte) FeatureVector {
+	var features FeatureVector
+	for bit := 0; bit < len(value)*5; bit++ {
+		group := value[len(value)-1-bit/5]
+		if group&(1<<(bit%5)) != 0 {
+			features = append(features, bit)
+		}
+	}
+	return features
+}
+
+// verifySignature checks the signature over the human-readable part and the data part, and recovers the
+// payee public key when the invoice doesn't include it.
+func (i *Invoice) verifySignature(hrp string, signedData []byte, signatureData []byte) error {
+	signature, err := bech32.ConvertBits(signatureData, 5, 8, false)
+	if err != nil || len(signature) != 65 {
+		return ErrInvalidSignature
+	}
+	copy(i.Signature[:], signature)
+	recoveryId := signature[64]
+	if recoveryId > 3 {
+		return ErrInvalidSignature
+	}
+
+	message, err := bech32.ConvertBits(signedData, 5, 8, true)
+	if err != nil {
+		return err
+	}
+	hash := sha256.Sum256(append([]byte(hrp), message...))
+
+	// High-S signatures are accepted, as required by BOLT11, by recovering the key from their low-S form
+	// with the same recovery id.
+	var s btcec.ModNScalar
+	if s.SetByteSlice(signature[32:64]) {
+		return ErrInvalidSignature
+	}
+	if s.IsOverHalfOrder() {
+		s.Negate()
+	}
+	compact := make([]byte, 65)
+	compact[0] = 27 + 4 + recoveryId
+	copy(compact[1:33], signature[:32])
+	s.PutBytesUnchecked(compact[33:])
+	pubKey, _, err := ecdsa.RecoverCompact(compact, hash[:])
+	if err != nil {
+		return ErrInvalidSignature
+	}
+	recovered := pubKey.SerializeCompressed()
+	if i.PayeePubKey != nil {
+		if !bytes.Equal(recovered, i.PayeePubKey) {
+			return ErrInvalidSignature
+		}
+		return nil
+	}
+	i.PayeePubKey = recovered
+	return nil
+}
+
+func readHash(value []byte) ([32]byte, error) {
+	var hash [32]byte
+	bytes, err := bech32.ConvertBits(value, 5, 8, false)
+	if err != nil || len(bytes) != 32 {
+		return hash, errors.New("bolt11: invalid hash")
+	}
+	copy(hash[:], bytes)
+	return hash, nil
+}
+
+// readUint reads a big endian integer made of 5 bit groups.
+func readUint(groups []byte) uint64 {
+	var value uint64
+	for _, group := range groups {
+		value = value<<5 | uint64(group)
+	}
+	return value
+}
+
+func readBigEndian(bytes []byte) uint64 {
+	var value uint64
+	for _, b := range bytes {
+		value = value<<8 | uint64(b)
+	}
+	return value
+}
@@ -0,0 +1,422 @@
+// Copyright ©, 2023-present, Lightspark Group, Inc. - All Rights Reserved
+package bolt11_test
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/btcsuite/btcd/btcec/v2"
+	"github.com/btcsuite/btcd/btcec/v2/ecdsa"
+	"github.com/btcsuite/btcd/btcutil/bech32"
+	"github.com/lightsparkdev/go-sdk/bolt11"
+	"github.com/lightsparkdev/go-sdk/objects"
+	"github.com/stretchr/testify/require"
+)
+
+// regtestInvoice was created by the Lightspark API. See objects/test/serialization_test.go for the data
+// returned by DecodePaymentRequest.
+const regtestInvoice = "lnbcrt34170n1pj5vdn4pp56jhw0672v566u4rvl333v8hwwuvavvu9gx4a2mqag4pkrvm0hwkqhp5xaz278y6cejcvpqnndl4wfq3slgthjduwlfksg778aevn23v2pdscqzpgxqyz5vqsp5ee5jezfvjqvvz7hfwta3ekk8hs6dq36szkgp40qh7twa8upquxlq9qyyssqjg2slc95falxf2t67y0wu2w43qwfcvfflwl8tn4ppqw9tumwqxk36qkfct9p2w8c3yy2ld7c6nacy4ssv2gl6qyqfpmhl4jmarnjf8cpvjlxek"
+
+func TestDecodeLightsparkInvoice(t *testing.T) {
+	invoice, err := bolt11.Decode(regtestInvoice)
+	require.NoError(t, err)
+
+	require.Equal(t, objects.BitcoinNetworkRegtest, invoice.Network)
+	require.Equal(t, int64(3_417_000), *invoice.AmountMsats)
+	require.Equal(t, time.Date(2023, 11, 4, 12, 17, 57, 0, time.UTC), invoice.Timestamp)
+	require.Equal(t, 24*time.Hour, invoice.Expiry)
+	require.Equal(t, "d4aee7ebca6535ae546cfc63161eee7719d6338541abd56c1d454361b36fbbac",
+		hex.EncodeToString(invoice.PaymentHash[:]))
+	require.Equal(t, "02253935a5703a6f0429081e08d2defce0faa15f4d75305302284751d53a4e0608",
+		hex.EncodeToString(invoice.PayeePubKey))
+	require.NotNil(t, invoice.PaymentSecret)
+	require.NotNil(t, invoice.DescriptionHash)
+	require.Nil(t, invoice.Description)
+	require.True(t, invoice.Features.Supports(14), "payment secret feature")
+	require.True(t, invoice.IsExpired(time.Now()))
+
+	data := invoice.ToInvoiceData()
+	require.Equal(t, regtestInvoice, data.EncodedPaymentRequest)
+	require.Equal(t, objects.BitcoinNetworkRegtest, data.BitcoinNetwork)
+	require.Equal(t, hex.EncodeToString(invoice.PaymentHash[:]), data.PaymentHash)
+	require.Equal(t, int64(3_417_000), data.Amount.OriginalValue)
+	require.Equal(t, time.Date(2023, 11, 5, 12, 17, 57, 0, time.UTC), data.ExpiresAt)
+	require.Equal(t, "02253935a5703a6f0429081e08d2defce0faa15f4d75305302284751d53a4e0608",
+		*data.Destination.(objects.GraphNode).PublicKey)
+}
+
+func TestDecodeRejectsInvalidInvoices(t *testing.T) {
+	_, err := bolt11.Decode(regtestInvoice[:len(regtestInvoice)-1] + "q")
+	require.Error(t, err, "bad checksum")
+
+	_, err = bolt11.Decode(strings.ToUpper(regtestInvoice[:20]) + regtestInvoice[20:])
+	require.Error(t, err, "mixed case")
+
+	_, err = bolt11.Decode("lightning:" + strings.ToUpper(regtestInvoice))
+	require.NoError(t, err)
+}
+
+type field struct {
+	fieldType byte
+	data      []byte
+}
+
+func bytesField(fieldType byte, value []byte) field {
+	groups, err := bech32.ConvertBits(value, 8, 5, true)
+	if err != nil {
+		panic(err)
+	}
+	return field{fieldType, groups}
+}
+
+func uintGroups(value uint64) []byte {
+	var groups []byte
+	for ; value > 0; value >>= 5 {
+		groups = append([]byte{byte(value & 31)}, groups...)
+	}
+	return groups
+}
+
+// encode creates a BOLT11 invoice signed with key.
+func encode(t *testing.T, hrp string, timestamp time.Time, fields []field, key *btcec.PrivateKey) string {
+	data := uintGroups(uint64(timestamp.Unix()))
+	for len(data) < 7 {
+		data = append([]byte{0}, data...)
+	}
+	for _, f := range fields {
+		data = append(data, f.fieldType, byte(len(f.data)>>5), byte(len(f.data)&31))
+		data = append(data, f.data...)
+	}
+
+	message, err := bech32.ConvertBits(data, 5, 8, true)
+	require.NoError(t, err)
+	hash := sha256.Sum256(append([]byte(hrp), message...))
+	compact, err := ecdsa.SignCompact(key, hash[:], true)
+	require.NoError(t, err)
+	signature := append(compact[1:], compact[0]-27-4)
+	signatureGroups, err := bech32.ConvertBits(signature, 8, 5, true)
+	require.NoError(t, err)
+
+	encoded, err := bech32.Encode(hrp, append(data, signatureGroups...))
+	require.NoError(t, err)
+	return encoded
+}
+
+func TestDecodeFields(t *testing.T) {
+	key, err := btcec.NewPrivateKey()
+	require.NoError(t, err)
+	hopKey, err := btcec.NewPrivateKey()
+	require.NoError(t, err)
+	paymentHash := sha256.Sum256([]byte("preimage"))
+	timestamp := time.Date(2024, 5, 1, 0, 0, 0, 0, time.UTC)
+
+	hop := append(hopKey.PubKey().SerializeCompressed(),
+		0, 0, 0, 1, 0, 0, 0, 2, // short channel id
+		0, 0, 3, 232, // fee base msat
+		0, 0, 0, 100, // fee proportional millionths
+		0, 40, // cltv expiry delta
+	)
+	encoded := encode(t, "lntb25u", timestamp, []field{
+		bytesField(1, paymentHash[:]),
+		bytesField(13, []byte("coffee ☕")),
+		bytesField(19, key.PubKey().SerializeCompressed()),
+		{6, uintGroups(600)},
+		{24, uintGroups(144)},
+		bytesField(3, hop),
+		{5, []byte{1, 0, 0}}, // bit 10
+		bytesField(27, []byte{0xca, 0xfe}),
+	}, key)
+
+	invoice, err := bolt11.Decode(encoded)
+	require.NoError(t, err)
+	require.Equal(t, objects.BitcoinNetworkTestnet, invoice.Network)
+	require.Equal(t, int64(2_500_000), *invoice.AmountMsats)
+	require.Equal(t, timestamp, invoice.Timestamp)
+	require.Equal(t, paymentHash, invoice.PaymentHash)
+	require.Equal(t, "coffee ☕", *invoice.Description)
+	require.Equal(t, key.PubKey().SerializeCompressed(), invoice.PayeePubKey)
+	require.Equal(t, 10*time.Minute, invoice.Expiry)
+	require.Equal(t, uint64(144), invoice.MinFinalCltvExpiry)
+	require.Equal(t, bolt11.FeatureVector{10}, invoice.Features)
+	require.Equal(t, []byte{0xca, 0xfe}, invoice.PaymentMetadata)
+	require.Equal(t, [][]bolt11.RouteHintHop{{{
+		PubKey:                    hopKey.PubKey().SerializeCompressed(),
+		ShortChannelId:            1<<32 | 2,
+		FeeBaseMsat:               1000,
+		FeeProportionalMillionths: 100,
+		CltvExpiryDelta:           40,
+	}}}, invoice.RouteHints)
+	require.Equal(t, timestamp.Add(10*time.Minute), invoice.ToInvoiceData().ExpiresAt)
+
+	otherKey, err := btcec.NewPrivateKey()
+	require.NoError(t, err)
+	forged := encode(t, "lntb25u", timestamp, []field{
+		bytesField(1, paymentHash[:]),
+		bytesField(13, []byte("coffee")),
+		bytesField(19, key.PubKey().SerializeCompressed()),
+	}, otherKey)
+	_, err = bolt11.Decode(forged)
+	require.ErrorIs(t, err, bolt11.ErrInvalidSignature)
+}
+
+func TestDecodeAmounts(t *testing.T) {
+	key, err := btcec.NewPrivateKey()
+	require.NoError(t, err)
+	paymentHash := sha256.Sum256([]byte("preimage"))
+	fields := []field{bytesField(1, paymentHash[:]), bytesField(13, []byte("test"))}
+
+	amounts := map[string]int64{
+		"lnbc1":       100_000_000_000,
+		"lnbc2m":      200_000_000,
+		"lnbcrt25u":   2_500_000,
+		"lntbs1500n":  150_000,
+		"lnbc10p":     1,
+		"lnbcrt12340": 1_234_000_000_000_000,
+	}
+	for hrp, expected := range amounts {
+		invoice, err := bolt11.Decode(encode(t, hrp, time.Now(), fields, key))
+		require.NoError(t, err, hrp)
+		require.Equal(t, expected, *invoice.AmountMsats, hrp)
+	}
+
+	invoice, err := bolt11.Decode(encode(t, "lntbs", time.Now(), fields, key))
+	require.NoError(t, err)
+	require.Nil(t, invoice.AmountMsats)
+	require.Equal(t, objects.BitcoinNetworkSignet, invoice.Network)
+	require.Equal(t, bolt11.DefaultExpiry, invoice.Expiry)
+
+	for _, hrp := range []string{"lnbc1p", "lnbc01u", "lnxy1u", "lnbc99999999999999999m", "lnbc2500x", "lnbc15p"} {
+		_, err := bolt11.Decode(encode(t, hrp, time.Now(), fields, key))
+		require.Error(t, err, hrp)
+	}
+}
+
+func TestDecodeRejectsOverflowingExpiry(t *testing.T) {
+	key, err := btcec.NewPrivateKey()
+	require.NoError(t, err)
+	paymentHash := sha256.Sum256([]byte("preimage"))
+	encoded := encode(t, "lnbc1u", time.Now(), []field{
+		bytesField(1, paymentHash[:]),
+		bytesField(13, []byte("test")),
+		{6, uintGroups(1 << 59)},
+	}, key)
+	_, err = bolt11.Decode(encoded)
+	require.ErrorContains(t, err, "expiry is too large")
+}
+
+// The test vectors of BOLT11, signed with the key of specVectorPubKey.
+const (
+	specVectorPubKey            = "03e7156ae33b0a208d0744199163177e909e80176e55d97a2f221ede0f934dd9ad"
+	specVectorPaymentHash       = "0001020304050607080900010203040506070809000102030405060708090102"
+	specVectorHashedDescription = "3925b6f67e2c340036ed12093dd44e0368df1b6ea26c53dbe4811f58fd5db8c1"
+)
+
+var specVectorTimestamp = time.Unix(1496314658, 0).UTC()
+
+func TestDecodeSpecVectors(t *testing.T) {
+	vectors := []struct {
+		name        string
+		encoded     string
+		network     objects.BitcoinNetwork
+		amountMsats int64
+		description string
+		expiry      time.Duration
+		fallback    string
+		routeHints  int
+		features    bolt11.FeatureVector
+	}{
+		{
+			name:        "donation without amount",
+			encoded:     "lnbc1pvjluezsp5zyg3zyg3zyg3zyg3zyg3zyg3zyg3zyg3zyg3zyg3zyg3zyg3zygspp5qqqsyqcyq5rqwzqfqqqsyqcyq5rqwzqfqqqsyqcyq5rqwzqfqypqdpl2pkx2ctnv5sxxmmwwd5kgetjypeh2ursdae8g6twvus8g6rfwvs8qun0dfjkxaq9qrsgq357wnc5r2ueh7ck6q93dj32dlqnls087fxdwk8qakdyafkq3yap9us6v52vjjsrvywa6rt52cm9r9zqt8r2t7mlcwspyetp5h2tztugp9lfyql",
+			network:     objects.BitcoinNetworkMainnet,
+			description: "Please consider supporting this project",
+			expiry:      time.Hour,
+			features:    bolt11.FeatureVector{8, 14},
+		},
+		{
+			name:        "coffee with expiry",
+			encoded:     "lnbc2500u1pvjluezsp5zyg3zyg3zyg3zyg3zyg3zyg3zyg3zyg3zyg3zyg3zyg3zyg3zygspp5qqqsyqcyq5rqwzqfqqqsyqcyq5rqwzqfqqqsyqcyq5rqwzqfqypqdq5xysxxatsyp3k7enxv4jsxqzpu9qrsgquk0rl77nj30yxdy8j9vdx85fkpmdla2087ne0xh8nhedh8w27kyke0lp53ut353s06fv3qfegext0eh0ymjpf39tuven09sam30g4vgpfna3rh",
+			network:     objects.BitcoinNetworkMainnet,
+			amountMsats: 250_000_000,
+			description: "1 cup coffee",
+			expiry:      time.Minute,
+			features:    bolt11.FeatureVector{8, 14},
+		},
+		{
+			name:        "utf-8 description",
+			encoded:     "lnbc2500u1pvjluezsp5zyg3zyg3zyg3zyg3zyg3zyg3zyg3zyg3zyg3zyg3zyg3zyg3zygspp5qqqsyqcyq5rqwzqfqqqsyqcyq5rqwzqfqqqsyqcyq5rqwzqfqypqdpquwpc4curk03c9wlrswe78q4eyqc7d8d0xqzpu9qrsgqhtjpauu9ur7fw2thcl4y9vfvh4m9wlfyz2gem29g5ghe2aak2pm3ps8fdhtceqsaagty2vph7utlgj48u0ged6a337aewvraedendscp573dxr",
+			network:     objects.BitcoinNetworkMainnet,
+			amountMsats: 250_000_000,
+			description: "ナンセンス 1杯",
+			expiry:      time.Minute,
+			features:    bolt11.FeatureVector{8, 14},
+		},
+		{
+			name:        "hashed description",
+			encoded:     "lnbc20m1pvjluezsp5zyg3zyg3zyg3zyg3zyg3zyg3zyg3zyg3zyg3zyg3zyg3zyg3zygspp5qqqsyqcyq5rqwzqfqqqsyqcyq5rqwzqfqqqsyqcyq5rqwzqfqypqhp58yjmdan79s6qqdhdzgynm4zwqd5d7xmw5fk98klysy043l2ahrqs9qrsgq7ea976txfraylvgzuxs8kgcw23ezlrszfnh8r6qtfpr6cxga50aj6txm9rxrydzd06dfeawfk6swupvz4erwnyutnjq7x39ymw6j38gp7ynn44",
+			network:     objects.BitcoinNetworkMainnet,
+			amountMsats: 2_000_000_000,
+			expiry:      time.Hour,
+			features:    bolt11.FeatureVector{8, 14},
+		},
+		{
+			name:        "testnet P2PKH fallback",
+			encoded:     "lntb20m1pvjluezsp5zyg3zyg3zyg3zyg3zyg3zyg3zyg3zyg3zyg3zyg3zyg3zyg3zygshp58yjmdan79s6qqdhdzgynm4zwqd5d7xmw5fk98klysy043l2ahrqspp5qqqsyqcyq5rqwzqfqqqsyqcyq5rqwzqfqqqsyqcyq5rqwzqfqypqfpp3x9et2e20v6pu37c5d9vax37wxq72un989qrsgqdj545axuxtnfemtpwkc45hx9d2ft7x04mt8q7y6t0k2dge9e7h8kpy9p34ytyslj3yu569aalz2xdk8xkd7ltxqld94u8h2esmsmacgpghe9k8",
+			network:     objects.BitcoinNetworkTestnet,
+			amountMsats: 2_000_000_000,
+			expiry:      time.Hour,
+			fallback:    "113172b5654f6683c8fb146959d347ce303cae4ca7",
+			features:    bolt11.FeatureVector{8, 14},
+		},
+		{
+			name:        "P2PKH fallback and route hints",
+			encoded:     "lnbc20m1pvjluezsp5zyg3zyg3zyg3zyg3zyg3zyg3zyg3zyg3zyg3zyg3zyg3zyg3zygspp5qqqsyqcyq5rqwzqfqqqsyqcyq5rqwzqfqqqsyqcyq5rqwzqfqypqhp58yjmdan79s6qqdhdzgynm4zwqd5d7xmw5fk98klysy043l2ahrqsfpp3qjmp7lwpagxun9pygexvgpjdc4jdj85fr9yq20q82gphp2nflc7jtzrcazrra7wwgzxqc8u7754cdlpfrmccae92qgzqvzq2ps8pqqqqqqpqqqqq9qqqvpeuqafqxu92d8lr6fvg0r5gv0heeeqgcrqlnm6jhphu9y00rrhy4grqszsvpcgpy9qqqqqqgqqqqq7qqzq9qrsgqdfjcdk6w3ak5pca9hwfwfh63zrrz06wwfya0ydlzpgzxkn5xagsqz7x9j4jwe7yj7vaf2k9lqsdk45kts2fd0fkr28am0u4w95tt2nsq76cqw0",
+			network:     objects.BitcoinNetworkMainnet,
+			amountMsats: 2_000_000_000,
+			expiry:      time.Hour,
+			fallback:    "1104b61f7dc1ea0dc99424464cc4064dc564d91e89",
+			routeHints:  1,
+			features:    bolt11.FeatureVector{8, 14},
+		},
+		{
+			name:        "P2SH fallback",
+			encoded:     "lnbc20m1pvjluezsp5zyg3zyg3zyg3zyg3zyg3zyg3zyg3zyg3zyg3zyg3zyg3zyg3zygshp58yjmdan79s6qqdhdzgynm4zwqd5d7xmw5fk98klysy043l2ahrqspp5qqqsyqcyq5rqwzqfqqqsyqcyq5rqwzqfqqqsyqcyq5rqwzqfqypqfppj3a24vwu6r8ejrss3axul8rxldph2q7z99qrsgqz6qsgww34xlatfj6e3sngrwfy3ytkt29d2qttr8qz2mnedfqysuqypgqex4haa2h8fx3wnypranf3pdwyluftwe680jjcfp438u82xqphf75ym",
+			network:     objects.BitcoinNetworkMainnet,
+			amountMsats: 2_000_000_000,
+			expiry:      time.Hour,
+			fallback:    "128f55563b9a19f321c211e9b9f38cdf686ea07845",
+			features:    bolt11.FeatureVector{8, 14},
+		},
+		{
+			name:        "P2WPKH fallback",
+			encoded:     "lnbc20m1pvjluezsp5zyg3zyg3zyg3zyg3zyg3zyg3zyg3zyg3zyg3zyg3zyg3zyg3zygshp58yjmdan79s6qqdhdzgynm4zwqd5d7xmw5fk98klysy043l2ahrqspp5qqqsyqcyq5rqwzqfqqqsyqcyq5rqwzqfqqqsyqcyq5rqwzqfqypqfppqw508d6qejxtdg4y5r3zarvary0c5xw7k9qrsgqt29a0wturnys2hhxpner2e3plp6jyj8qx7548zr2z7ptgjjc7hljm98xhjym0dg52sdrvqamxdezkmqg4gdrvwwnf0kv2jdfnl4xatsqmrnsse",
+			network:     objects.BitcoinNetworkMainnet,
+			amountMsats: 2_000_000_000,
+			expiry:      time.Hour,
+			fallback:    "00751e76e8199196d454941c45d1b3a323f1433bd6",
+			features:    bolt11.FeatureVector{8, 14},
+		},
+		{
+			name:        "P2WSH fallback",
+			encoded:     "lnbc20m1pvjluezsp5zyg3zyg3zyg3zyg3zyg3zyg3zyg3zyg3zyg3zyg3zyg3zyg3zygshp58yjmdan79s6qqdhdzgynm4zwqd5d7xmw5fk98klysy043l2ahrqspp5qqqsyqcyq5rqwzqfqqqsyqcyq5rqwzqfqqqsyqcyq5rqwzqfqypqfp4qrp33g0q5c5txsp9arysrx4k6zdkfs4nce4xj0gdcccefvpysxf3q9qrsgq9vlvyj8cqvq6ggvpwd53jncp9nwc47xlrsnenq2zp70fq83qlgesn4u3uyf4tesfkkwwfg3qs54qe426hp3tz7z6sweqdjg05axsrjqp9yrrwc",
+			network:     objects.BitcoinNetworkMainnet,
+			amountMsats: 2_000_000_000,
+			expiry:      time.Hour,
+			fallback:    "001863143c14c5166804bd19203356da136c985678cd4d27a1b8c6329604903262",
+			features:    bolt11.FeatureVector{8, 14},
+		},
+		{
+			name:        "unknown feature bits",
+			encoded:     "lnbc25m1pvjluezpp5qqqsyqcyq5rqwzqfqqqsyqcyq5rqwzqfqqqsyqcyq5rqwzqfqypqdq5vdhkven9v5sxyetpdeessp5zyg3zyg3zyg3zyg3zyg3zyg3zyg3zyg3zyg3zyg3zyg3zyg3zygs9q5sqqqqqqqqqqqqqqqqsgq2a25dxl5hrntdtn6zvydt7d66hyzsyhqs4wdynavys42xgl6sgx9c4g7me86a27t07mdtfry458rtjr0v92cnmswpsjscgt2vcse3sgpz3uapa",
+			network:     objects.BitcoinNetworkMainnet,
+			amountMsats: 2_500_000_000,
+			description: "coffee beans",
+			expiry:      time.Hour,
+			features:    bolt11.FeatureVector{8, 14, 99},
+		},
+		{
+			name:        "uppercase",
+			encoded:     "LNBC25M1PVJLUEZPP5QQQSYQCYQ5RQWZQFQQQSYQCYQ5RQWZQFQQQSYQCYQ5RQWZQFQYPQDQ5VDHKVEN9V5SXYETPDEESSP5ZYG3ZYG3ZYG3ZYG3ZYG3ZYG3ZYG3ZYG3ZYG3ZYG3ZYG3ZYG3ZYGS9Q5SQQQQQQQQQQQQQQQQSGQ2A25DXL5HRNTDTN6ZVYDT7D66HYZSYHQS4WDYNAVYS42XGL6SGX9C4G7ME86A27T07MDTFRY458RTJR0V92CNMSWPSJSCGT2VCSE3SGPZ3UAPA",
+			network:     objects.BitcoinNetworkMainnet,
+			amountMsats: 2_500_000_000,
+			description: "coffee beans",
+			expiry:      time.Hour,
+			features:    bolt11.FeatureVector{8, 14, 99},
+		},
+		{
+			name:        "high-S signature",
+			encoded:     "lnbc1pvjluezsp5zyg3zyg3zyg3zyg3zyg3zyg3zyg3zyg3zyg3zyg3zyg3zyg3zygspp5qqqsyqcyq5rqwzqfqqqsyqcyq5rqwzqfqqqsyqcyq5rqwzqfqypqdpl2pkx2ctnv5sxxmmwwd5kgetjypeh2ursdae8g6twvus8g6rfwvs8qun0dfjkxaq9qrsgq357wnc5r2ueh7ck6q93dj32dlqnls087fxdwk8qakdyafkq3yap2r09nt4ndd0unm3z9u5t48y6ucv4r5sg7lk98c77ctvjczkspk5qprc90gx",
+			network:     objects.BitcoinNetworkMainnet,
+			description: "Please consider supporting this project",
+			expiry:      time.Hour,
+			features:    bolt11.FeatureVector{8, 14},
+		},
+	}
+	for _, vector := range vectors {
+		t.Run(vector.name, func(t *testing.T) {
+			invoice, err := bolt11.Decode(vector.encoded)
+			require.NoError(t, err)
+			require.Equal(t, vector.network, invoice.Network)
+			if vector.amountMsats == 0 {
+				require.Nil(t, invoice.AmountMsats)
+			} else {
+				require.Equal(t, vector.amountMsats, *invoice.AmountMsats)
+			}
+			require.Equal(t, specVectorTimestamp, invoice.Timestamp)
+			require.Equal(t, specVectorPaymentHash, hex.EncodeToString(invoice.PaymentHash[:]))
+			require.Equal(t, specVectorPubKey, hex.EncodeToString(invoice.PayeePubKey))
+			if vector.description == "" {
+				require.Nil(t, invoice.Description)
+				require.Equal(t, specVectorHashedDescription, hex.EncodeToString(invoice.DescriptionHash[:]))
+			} else {
+				require.Equal(t, vector.description, *invoice.Description)
+			}
+			require.Equal(t, vector.expiry, invoice.Expiry)
+			if vector.fallback == "" {
+				require.Empty(t, invoice.FallbackAddresses)
+			} else {
+				require.Len(t, invoice.FallbackAddresses, 1)
+				fallback := invoice.FallbackAddresses[0]
+				require.Equal(t, vector.fallback, hex.EncodeToString(append([]byte{fallback.Version}, fallback.Program...)))
+			}
+			require.Len(t, invoice.RouteHints, vector.routeHints)
+			require.Equal(t, vector.features, invoice.Features)
+		})
+	}
+}
+
+func TestDecodeSpecPicoAmountAndMetadata(t *testing.T) {
+	invoice, err := bolt11.Decode("lnbc9678785340p1pwmna7lpp5gc3xfm08u9qy06djf8dfflhugl6p7lgza6dsjxq454gxhj9t7a0sd8dgfkx7cmtwd68yetpd5s9xar0wfjn5gpc8qhrsdfq24f5ggrxdaezqsnvda3kkum5wfjkzmfqf3jkgem9wgsyuctwdus9xgrcyqcjcgpzgfskx6eqf9hzqnteypzxz7fzypfhg6trddjhygrcyqezcgpzfysywmm5ypxxjemgw3hxjmn8yptk7untd9hxwg3q2d6xjcmtv4ezq7pqxgsxzmnyyqcjqmt0wfjjq6t5v4khxsp5zyg3zyg3zyg3zyg3zyg3zyg3zyg3zyg3zyg3zyg3zyg3zyg3zygsxqyjw5qcqp2rzjq0gxwkzc8w6323m55m4jyxcjwmy7stt9hwkwe2qxmy8zpsgg7jcuwz87fcqqeuqqqyqqqqlgqqqqn3qq9q9qrsgqrvgkpnmps664wgkp43l22qsgdw4ve24aca4nymnxddlnp8vh9v2sdxlu5ywdxefsfvm0fq3sesf08uf6q9a2ke0hc9j6z6wlxg5z5kqpu2v9wz")
+	require.NoError(t, err)
+	require.Equal(t, int64(967_878_534), *invoice.AmountMsats)
+	require.Equal(t, time.Unix(1572468703, 0).UTC(), invoice.Timestamp)
+	require.Equal(t, 7*24*time.Hour, invoice.Expiry)
+	require.Equal(t, uint64(10), invoice.MinFinalCltvExpiry)
+	require.Len(t, invoice.RouteHints, 1)
+	require.Equal(t, specVectorPubKey, hex.EncodeToString(invoice.PayeePubKey))
+
+	invoice, err = bolt11.Decode("lnbc10m1pvjluezpp5qqqsyqcyq5rqwzqfqqqsyqcyq5rqwzqfqqqsyqcyq5rqwzqfqypqdp9wpshjmt9de6zqmt9w3skgct5vysxjmnnd9jx2mq8q8a04uqsp5zyg3zyg3zyg3zyg3zyg3zyg3zyg3zyg3zyg3zyg3zyg3zyg3zygs9q2gqqqqqqsgq7hf8he7ecf7n4ffphs6awl9t6676rrclv9ckg3d3ncn7fct63p6s365duk5wrk202cfy3aj5xnnp5gs3vrdvruverwwq7yzhkf5a3xqpd05wjc")
+	require.NoError(t, err)
+	require.Equal(t, "payment metadata inside", *invoice.Description)
+	require.Equal(t, []byte{0x01, 0xfa, 0xfa, 0xf0}, invoice.PaymentMetadata)
+	require.Equal(t, bolt11.FeatureVector{8, 14, 48}, invoice.Features)
+}
+
+func TestDecodeSpecInvalidVectors(t *testing.T) {
+	vectors := map[string]struct {
+		encoded string
+		err     string
+	}{
+		"invalid checksum": {
+			"lnbc2500u1pvjluezpp5qqqsyqcyq5rqwzqfqqqsyqcyq5rqwzqfqqqsyqcyq5rqwzqfqypqdpquwpc4curk03c9wlrswe78q4eyqc7d8d0xqzpuyk0sg5g70me25alkluzd2x62aysf2pyy8edtjeevuv4p2d5p76r4zkmneet7uvyakky2zr4cusd45tftc9c5fh0nnqpnl2jfll544esqchsrnt",
+			"invalid checksum",
+		},
+		"missing separator": {
+			"pvjluezpp5qqqsyqcyq5rqwzqfqqqsyqcyq5rqwzqfqqqsyqcyq5rqwzqfqypqdpquwpc4curk03c9wlrswe78q4eyqc7d8d0xqzpuyk0sg5g70me25alkluzd2x62aysf2pyy8edtjeevuv4p2d5p76r4zkmneet7uvyakky2zr4cusd45tftc9c5fh0nnqpnl2jfll544esqchsrny",
+			"separator",
+		},
+		"mixed case": {
+			"LNBC2500u1pvjluezpp5qqqsyqcyq5rqwzqfqqqsyqcyq5rqwzqfqqqsyqcyq5rqwzqfqypqdpquwpc4curk03c9wlrswe78q4eyqc7d8d0xqzpuyk0sg5g70me25alkluzd2x62aysf2pyy8edtjeevuv4p2d5p76r4zkmneet7uvyakky2zr4cusd45tftc9c5fh0nnqpnl2jfll544esqchsrny",
+			"mixed case",
+		},
+		"too short": {
+			"lnbc1pvjluezpp5qqqsyqcyq5rqwzqfqqqsyqcyq5rqwzqfqqqsyqcyq5rqwzqfqypqdpl2pkx2ctnv5sxxmmwwd5kgetjypeh2ursdae8g6na6hlh",
+			"too short",
+		},
+		"invalid multiplier": {
+			"lnbc2500x1pvjluezpp5qqqsyqcyq5rqwzqfqqqsyqcyq5rqwzqfqqqsyqcyq5rqwzqfqypqdq5xysxxatsyp3k7enxv4jsxqzpusp5zyg3zyg3zyg3zyg3zyg3zyg3zyg3zyg3zyg3zyg3zyg3zyg3zygs9qrsgqrrzc4cvfue4zp3hggxp47ag7xnrlr8vgcmkjxk3j5jqethnumgkpqp23z9jclu3v0a7e0aruz366e9wqdykw6dxhdzcjjhldxq0w6wgqcnu43j",
+			"invalid amount multiplier",
+		},
+		"sub-millisatoshi amount": {
+			"lnbc2500000001p1pvjluezpp5qqqsyqcyq5rqwzqfqqqsyqcyq5rqwzqfqqqsyqcyq5rqwzqfqypqdq5xysxxatsyp3k7enxv4jsxqzpusp5zyg3zyg3zyg3zyg3zyg3zyg3zyg3zyg3zyg3zyg3zyg3zyg3zygs9qrsgq0lzc236j96a95uv0m3umg28gclm5lqxtqqwk32uuk4k6673k6n5kfvx3d2h8s295fad45fdhmusm8sjudfhlf6dcsxmfvkeywmjdkxcp99202x",
+			"not a whole number of millisatoshis",
+		},
+	}
+	for name, vector := range vectors {
+		_, err := bolt11.Decode(vector.encoded)
+		require.ErrorContains(t, err, vector.err, name)
+	}
+}
@@ -0,0 +1,513 @@
+// Copyright ©, 2023-present, Lightspark Group, Inc. - All Rights Reserved
+
+// Package bolt12 encodes, decodes and validates BOLT12 offers locally, so that the `lno...` strings
+// returned by CreateOffer can be inspected before calling PayOffer:
+//
+//	offer, err := bolt12.DecodeOffer(encodedOffer)
+//	if err != nil {
+//		return err
+//	}
+//	if err := offer.CheckPayment(objects.BitcoinNetworkMainnet, &amountMsats, time.Now()); err != nil {
+//		return err
+//	}
+//	payment, err := client.PayOffer(nodeId, encodedOffer, 60, 1000, &amountMsats, nil)
+//
+// Offers are not signed. The merkle root signatures of invoice requests and invoices can be checked with
+// VerifyMessage.
+package bolt12
+
+import (
+	"bytes"
+	"errors"
+	"fmt"
+	"strings"
+	"time"
+	"unicode/utf8"
+
+	"github.com/btcsuite/btcd/btcec/v2"
+	"github.com/btcsuite/btcd/btcutil/bech32"
+	"github.com/btcsuite/btcd/chaincfg"
+	"github.com/lightsparkdev/go-sdk/objects"
+)
+
+// OfferPrefix is the human-readable part of encoded offers.
+const OfferPrefix = "lno"
+
+const charset = "qpzry9x8gf2tvdw0s3jn54khce6mua7l"
+
+const (
+	typeOfferChains         = 2
+	typeOfferMetadata       = 4
+	typeOfferCurrency       = 6
+	typeOfferAmount         = 8
+	typeOfferDescription    = 10
+	typeOfferFeatures       = 12
+	typeOfferAbsoluteExpiry = 14
+	typeOfferPaths          = 16
+	typeOfferIssuer         = 18
+	typeOfferQuantityMax    = 20
+	typeOfferIssuerId       = 22
+)
+
+var (
+	// ErrOfferExpired is returned by CheckPayment when the absolute expiry of the offer has passed.
+	ErrOfferExpired = errors.New("bolt12: offer expired")
+	// ErrInvalidAmount is returned by CheckPayment when the amount doesn't satisfy the offer.
+	ErrInvalidAmount = errors.New("bolt12: invalid amount for offer")
+	// ErrUnsupportedNetwork is returned by CheckPayment when the offer is for another chain.
+	ErrUnsupportedNetwork = errors.New("bolt12: offer is not valid on this network")
+)
+
+var chainHashes = map[objects.BitcoinNetwork][32]byte{
+	objects.BitcoinNetworkMainnet: *chaincfg.MainNetParams.GenesisHash,
+	objects.BitcoinNetworkTestnet: *chaincfg.TestNet3Params.GenesisHash,
+	objects.BitcoinNetworkSignet:  *chaincfg.SigNetParams.GenesisHash,
+	objects.BitcoinNetworkRegtest: *chaincfg.RegressionNetParams.GenesisHash,
+}
+
+// ChainHash returns the chain hash used by BOLT12 for a network.
+func ChainHash(network objects.BitcoinNetwork) ([32]byte, bool) {
+	hash, ok := chainHashes[network]
+	return hash, ok
+}
+
+// ShortChannelIdDir identifies the first node of a blinded path by a channel and a direction, instead of
+// a public key.
+type ShortChannelIdDir struct {
+	// Direction is 0 for the node with the lesser public key and 1 for the other one.
+	Direction      byte
+	ShortChannelId uint64
+}
+
+// BlindedHop is a hop of a blinded path.
+type BlindedHop struct {
+	BlindedNodeId          []byte
+	EncryptedRecipientData []byte
+}
+
+// BlindedPath is a route to the offer issuer that hides the nodes after the introduction node.
+type BlindedPath struct {
+	// FirstNodeId is the public key of the introduction node. It is nil when FirstScidDir is set.
+	FirstNodeId  []byte
+	FirstScidDir *ShortChannelIdDir
+	FirstPathKey []byte
+	Hops         []BlindedHop
+}
+
+// Offer is a decoded BOLT12 offer.
+type Offer struct {
+	// Chains are the chain hashes the offer is valid for. Only bitcoin mainnet is allowed when empty.
+	Chains   [][32]byte
+	Metadata []byte
+	// Currency is the ISO 4217 code of Amount. Amount is in millisatoshis when nil.
+	Currency *string
+	// Amount is the minimum amount per item, nil when the payer chooses the amount.
+	Amount         *uint64
+	Description    *string
+	Features       []byte
+	AbsoluteExpiry *time.Time
+	Paths          []BlindedPath
+	Issuer         *string
+	// QuantityMax is set when the offer can be paid for several items. 0 means no limit.
+	QuantityMax *uint64
+	IssuerId    []byte
+	// Unknown holds the odd records this package doesn't know about, so that Encode preserves them.
+	Unknown []Record
+}
+
+// DecodeOffer decodes and validates an `lno...` string, following the reader requirements of BOLT12.
+func DecodeOffer(encoded string) (*Offer, error) {
+	data, err := decodeString(encoded, OfferPrefix)
+	if err != nil {
+		return nil, err
+	}
+	records, err := ParseRecords(data)
+	if err != nil {
+		return nil, err
+	}
+	return offerFromRecords(records)
+}
+
+// DecodeLightsparkOffer decodes the encoded offer of an objects.Offer.
+func DecodeLightsparkOffer(offer objects.Offer) (*Offer, error) {
+	return DecodeOffer(offer.EncodedOffer)
+}
+
+func offerFromRecords(records []Record) (*Offer, error) {
+	offer := &Offer{}
+	for _, record := range records {
+		if !isOfferType(record.Type) {
+			return nil, fmt.Errorf("bolt12: TLV type %d is not allowed in offers", record.Type)
+		}
+		var err error
+		value := record.Value
+		switch record.Type {
+		case typeOfferChains:
+			if len(value) == 0 || len(value)%32 != 0 {
+				return nil, errors.New("bolt12: invalid offer_chains")
+			}
+			for ; len(value) > 0; value = value[32:] {
+				offer.Chains = append(offer.Chains, [32]byte(value[:32]))
+			}
+		case typeOfferMetadata:
+			offer.Metadata = value
+		case typeOfferCurrency:
+			currency, err := readString(value, "offer_currency")
+			if err != nil {
+				return nil, err
+			}
+			if len(currency) != 3 || strings.ToUpper(currency) != currency {
+				return nil, errors.New("bolt12: invalid offer_currency")
+			}
+			offer.Currency = &currency
+		case typeOfferAmount:
+			amount, err := readTu64(value)
+			if err != nil {
+				return nil, err
+			}
+			offer.Amount = &amount
+		case typeOfferDescription:
+			offer.Description, err = readOptionalString(value, "offer_description")
+		case typeOfferFeatures:
+			offer.Features = value
+		case typeOfferAbsoluteExpiry:
+			seconds, err := readTu64(value)
+			if err != nil {
+				return nil, err
+			}
+			expiry := time.Unix(int64(seconds), 0).UTC()
+			offer.AbsoluteExpiry = &expiry
+		case typeOfferPaths:
+			offer.Paths, err = readBlindedPaths(value)
+		case typeOfferIssuer:
+			offer.Issuer, err = readOptionalString(value, "offer_issuer")
+		case typeOfferQuantityMax:
+			quantity, err := readTu64(value)
+			if err != nil {
+				return nil, err
+			}
+			offer.QuantityMax = &quantity
+		case typeOfferIssuerId:
+			if _, err := btcec.ParsePubKey(value); err != nil {
+				return nil, fmt.Errorf("bolt12: invalid offer_issuer_id: %w", err)
+			}
+			offer.IssuerId = value
+		default:
+			if record.Type%2 == 0 {
+				return nil, fmt.Errorf("bolt12: unknown even TLV type %d", record.Type)
+			}
+			offer.Unknown = append(offer.Unknown, record)
+		}
+		if err != nil {
+			return nil, err
+		}
+	}
+	return offer, offer.validate()
+}
+
+func (o *Offer) validate() error {
+	if o.Amount != nil {
+		if *o.Amount == 0 {
+			return errors.New("bolt12: offer_amount must not be 0")
+		}
+		if o.Description == nil {
+			return errors.New("bolt12: offer_description is required when offer_amount is set")
+		}
+	} else if o.Currency != nil {
+		return errors.New("bolt12: offer_currency is set without offer_amount")
+	}
+	if o.IssuerId == nil && len(o.Paths) == 0 {
+		return errors.New("bolt12: offer has neither offer_issuer_id nor offer_paths")
+	}
+	return nil
+}
+
+// SupportsNetwork reports whether the offer can be paid on the given network.
+func (o *Offer) SupportsNetwork(network objects.BitcoinNetwork) bool {
+	hash, ok := ChainHash(network)
+	if !ok {
+		return false
+	}
+	if len(o.Chains) == 0 {
+		return network == objects.BitcoinNetworkMainnet
+	}
+	for _, chain := range o.Chains {
+		if chain == hash {
+			return true
+		}
+	}
+	return false
+}
+
+// IsExpired reports whether the offer has expired at the given time.
+func (o *Offer) IsExpired(now time.Time) bool {
+	return o.AbsoluteExpiry != nil && !now.Before(*o.AbsoluteExpiry)
+}
+
+// AmountMsats returns the amount of the offer in millisatoshis. ok is false when the offer has no amount
+// or when its amount is in a fiat currency.
+func (o *Offer) AmountMsats() (amount int64, ok bool) {
+	if o.Amount == nil || o.Currency != nil {
+		return 0, false
+	}
+	return int64(*o.Amount), true
+}
+
+// CheckPayment checks the rules of the offer for a single-item payment of amountMsats on network, the way
+// the amount would be passed to PayOffer. amountMsats may be nil when the offer has an amount. Offers
+// priced in a fiat currency cannot be converted offline, so their amount is not checked.
+func (o *Offer) CheckPayment(network objects.BitcoinNetwork, amountMsats *int64, now time.Time) error {
+	if !o.SupportsNetwork(network) {
+		return ErrUnsupportedNetwork
+	}
+	if o.IsExpired(now) {
+		return ErrOfferExpired
+	}
+	if o.Amount == nil {
+		if amountMsats == nil || *amountMsats <= 0 {
+			return fmt.Errorf("%w: the offer has no amount, so one must be given", ErrInvalidAmount)
+		}
+		return nil
+	}
+	minimum, ok := o.AmountMsats()
+	if ok && amountMsats != nil && *amountMsats < minimum {
+		return fmt.Errorf("%w: %d msats is below the offer amount of %d msats", ErrInvalidAmount, *amountMsats, minimum)
+	}
+	return nil
+}
+
+// CheckQuantity checks a number of items against offer_quantity_max.
+func (o *Offer) CheckQuantity(quantity uint64) error {
+	if o.QuantityMax == nil {
+		if quantity != 1 {
+			return errors.New("bolt12: the offer is for a single item")
+		}
+		return nil
+	}
+	if quantity == 0 || (*o.QuantityMax != 0 && quantity > *o.QuantityMax) {
+		return fmt.Errorf("bolt12: quantity %d is not allowed by the offer", quantity)
+	}
+	return nil
+}
+
+// Records returns the TLV records of the offer, sorted by type.
+func (o *Offer) Records() []Record {
+	var records []Record
+	add := func(recordType uint64, value []byte) {
+		records = append(records, Record{Type: recordType, Value: value})
+	}
+	if len(o.Chains) > 0 {
+		var chains []byte
+		for _, chain := range o.Chains {
+			chains = append(chains, chain[:]...)
+		}
+		add(typeOfferChains, chains)
+	}
+	if o.Metadata != nil {
+		add(typeOfferMetadata, o.Metadata)
+	}
+	if o.Currency != nil {
+		add(typeOfferCurrency, []byte(*o.Currency))
+	}
+	if o.Amount != nil {
+		add(typeOfferAmount, encodeTu64(*o.Amount))
+	}
+	if o.Description != nil {
+		add(typeOfferDescription, []byte(*o.Description))
+	}
+	if o.Features != nil {
+		add(typeOfferFeatures, o.Features)
+	}
+	if o.AbsoluteExpiry != nil {
+		add(typeOfferAbsoluteExpiry, encodeTu64(uint64(o.AbsoluteExpiry.Unix())))
+	}
+	if len(o.Paths) > 0 {
+		add(typeOfferPaths, encodeBlindedPaths(o.Paths))
+	}
+	if o.Issuer != nil {
+		add(typeOfferIssuer, []byte(*o.Issuer))
+	}
+	if o.QuantityMax != nil {
+		add(typeOfferQuantityMax, encodeTu64(*o.QuantityMax))
+	}
+	if o.IssuerId != nil {
+		add(typeOfferIssuerId, o.IssuerId)
+	}
+
+	for _, unknown := range o.Unknown {
+		index := len(records)
+		for index > 0 && records[index-1].Type > unknown.Type {
+			index--
+		}
+		records = append(records[:index], append([]Record{unknown}, records[index:]...)...)
+	}
+	return records
+}
+
+// Encode validates the offer and returns its `lno...` string.
+func (o *Offer) Encode() (string, error) {
+	if err := o.validate(); err != nil {
+		return "", err
+	}
+	return encodeString(OfferPrefix, EncodeRecords(o.Records()))
+}
+
+// isOfferType reports whether a TLV type is in the ranges allowed in offers.
+func isOfferType(recordType uint64) bool {
+	return (recordType >= 1 && recordType <= 79) || (recordType >= 1_000_000_000 && recordType <= 1_999_999_999)
+}
+
+func readBlindedPaths(value []byte) ([]BlindedPath, error) {
+	var paths []BlindedPath
+	for len(value) > 0 {
+		var path BlindedPath
+		switch {
+		case len(value) >= 9 && value[0] <= 1:
+			path.FirstScidDir = &ShortChannelIdDir{Direction: value[0], ShortChannelId: readUint64(value[1:9])}
+			value = value[9:]
+		case len(value) >= 33:
+			if _, err := btcec.ParsePubKey(value[:33]); err != nil {
+				return nil, fmt.Errorf("bolt12: invalid blinded path first_node_id: %w", err)
+			}
+			path.FirstNodeId = value[:33]
+			value = value[33:]
+		default:
+			return nil, errors.New("bolt12: truncated blinded path")
+		}
+		if len(value) < 34 {
+			return nil, errors.New("bolt12: truncated blinded path")
+		}
+		path.FirstPathKey = value[:33]
+		numHops := int(value[33])
+		value = value[34:]
+		if numHops == 0 {
+			return nil, errors.New("bolt12: blinded path without hops")
+		}
+		for i := 0; i < numHops; i++ {
+			if len(value) < 35 {
+				return nil, errors.New("bolt12: truncated blinded hop")
+			}
+			length := int(value[33])<<8 | int(value[34])
+			if len(value) < 35+length {
+				return nil, errors.New("bolt12: truncated blinded hop")
+			}
+			path.Hops = append(path.Hops, BlindedHop{
+				BlindedNodeId:          value[:33],
+				EncryptedRecipientData: value[35 : 35+length],
+			})
+			value = value[35+length:]
+		}
+		paths = append(paths, path)
+	}
+	if len(paths) == 0 {
+		return nil, errors.New("bolt12: empty offer_paths")
+	}
+	return paths, nil
+}
+
+func encodeBlindedPaths(paths []BlindedPath) []byte {
+	var buffer bytes.Buffer
+	for _, path := range paths {
+		if path.FirstScidDir != nil {
+			buffer.WriteByte(path.FirstScidDir.Direction)
+			buffer.Write(encodeUint64(path.FirstScidDir.ShortChannelId))
+		} else {
+			buffer.Write(path.FirstNodeId)
+		}
+		buffer.Write(path.FirstPathKey)
+		buffer.WriteByte(byte(len(path.Hops)))
+		for _, hop := range path.Hops {
+			buffer.Write(hop.BlindedNodeId)
+			buffer.WriteByte(byte(len(hop.EncryptedRecipientData) >> 8))
+			buffer.WriteByte(byte(len(hop.EncryptedRecipientData)))
+			buffer.Write(hop.EncryptedRecipientData)
+		}
+	}
+	return buffer.Bytes()
+}
+
+func readString(value []byte, name string) (string, error) {
+	if !utf8.Valid(value) {
+		return "", fmt.Errorf("bolt12: %s is not valid UTF-8", name)
+	}
+	return string(value), nil
+}
+
+func readOptionalString(value []byte, name string) (*string, error) {
+	result, err := readString(value, name)
+	if err != nil {
+		return nil, err
+	}
+	return &result, nil
+}
+
+func readUint64(value []byte) uint64 {
+	var result uint64
+	for _, b := range value {
+		result = result<<8 | uint64(b)
+	}
+	return result
+}
+
+func encodeUint64(value uint64) []byte {
+	result := make([]byte, 8)
+	for i := 7; i >= 0; i-- {
+		result[i] = byte(value)
+		value >>= 8
+	}
+	return result
+}
+
+// decodeString decodes a BOLT12 string: bech32 without a checksum, where `+` followed by optional
+// whitespace can split long strings.
+func decodeString(encoded string, prefix string) ([]byte, error) {
+	if strings.ToLower(encoded) != encoded && strings.ToUpper(encoded) != encoded {
+		return nil, errors.New("bolt12: mixed case string")
+	}
+	encoded = strings.ToLower(encoded)
+	if strings.Contains(encoded, "+") {
+		parts := strings.Split(encoded, "+")
+		for i, part := range parts {
+			if i > 0 {
+				part = strings.TrimLeft(part, " \t\r\n")
+			}
+			if part == "" {
+				return nil, errors.New("bolt12: invalid use of +")
+			}
+			parts[i] = part
+		}
+		encoded = strings.Join(parts, "")
+	}
+
+	separator := strings.LastIndexByte(encoded, '1')
+	if separator < 0 || encoded[:separator] != prefix {
+		return nil, fmt.Errorf("bolt12: expected a string starting with %s1", prefix)
+	}
+	groups := make([]byte, 0, len(encoded)-separator-1)
+	for _, c := range encoded[separator+1:] {
+		index := strings.IndexRune(charset, c)
+		if index < 0 {
+			return nil, fmt.Errorf("bolt12: invalid character %q", c)
+		}
+		groups = append(groups, byte(index))
+	}
+	data, err := bech32.ConvertBits(groups, 5, 8, false)
+	if err != nil {
+		return nil, fmt.Errorf("bolt12: %w", err)
+	}
+	return data, nil
+}
+
+func encodeString(prefix string, data []byte) (string, error) {
+	groups, err := bech32.ConvertBits(data, 8, 5, true)
+	if err != nil {
+		return "", err
+	}
+	var builder strings.Builder
+	builder.WriteString(prefix)
+	builder.WriteByte('1')
+	for _, group := range groups {
+		builder.WriteByte(charset[group])
+	}
+	return builder.String(), nil
+}
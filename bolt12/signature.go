@@ -0,0 +1,125 @@
+// Copyright ©, 2023-present, Lightspark Group, Inc. - All Rights Reserved
+package bolt12
+
+import (
+	"bytes"
+	"crypto/sha256"
+	"errors"
+
+	"github.com/btcsuite/btcd/btcec/v2"
+	"github.com/btcsuite/btcd/btcec/v2/schnorr"
+)
+
+// Messages signed with a merkle root signature. Offers are not signed.
+const (
+	MessageInvoiceRequest = "invoice_request"
+	MessageInvoice        = "invoice"
+)
+
+const (
+	signatureFieldType = 240
+	// Types 240 to 1000 are signature fields, excluded from the merkle tree.
+	signatureRangeEnd = 1000
+)
+
+// ErrInvalidSignature is returned when a BOLT12 signature doesn't match the message and public key.
+var ErrInvalidSignature = errors.New("bolt12: invalid signature")
+
+// MerkleRoot computes the merkle root of a TLV stream, as defined by BOLT12. Signature records are
+// ignored.
+func MerkleRoot(records []Record) [32]byte {
+	var signed []Record
+	for _, record := range records {
+		if record.Type < signatureFieldType || record.Type > signatureRangeEnd {
+			signed = append(signed, record)
+		}
+	}
+	if len(signed) == 0 {
+		return [32]byte{}
+	}
+
+	nonceTag := append([]byte("LnNonce"), signed[0].encode()...)
+	leaves := make([][32]byte, 0, 2*len(signed))
+	for _, record := range signed {
+		leaves = append(leaves,
+			taggedHash([]byte("LnLeaf"), record.encode()),
+			taggedHash(nonceTag, encodeBigSize(record.Type)),
+		)
+	}
+
+	// The tree is built in place. When the number of leaves is not a power of 2, the lowest-order
+	// leaves are the deepest.
+	for step := 2; step/2 < len(leaves); step *= 2 {
+		for i := 0; i+step/2 < len(leaves); i += step {
+			leaves[i] = branchHash(leaves[i], leaves[i+step/2])
+		}
+	}
+	return leaves[0]
+}
+
+// SignatureHash returns the message signed by the `signature` field of a BOLT12 message, for example
+// MessageInvoice.
+func SignatureHash(messageName string, records []Record) [32]byte {
+	root := MerkleRoot(records)
+	return taggedHash([]byte("lightning"+messageName+"signature"), root[:])
+}
+
+// Sign signs a BOLT12 message and returns the BIP340 signature to store in its `signature` field.
+func Sign(messageName string, records []Record, privateKey *btcec.PrivateKey) ([]byte, error) {
+	hash := SignatureHash(messageName, records)
+	signature, err := schnorr.Sign(privateKey, hash[:])
+	if err != nil {
+		return nil, err
+	}
+	return signature.Serialize(), nil
+}
+
+// VerifySignature checks the BIP340 signature of a BOLT12 message. publicKey may be a 33 byte compressed
+// key or a 32 byte x-only key.
+func VerifySignature(messageName string, records []Record, signature []byte, publicKey []byte) error {
+	if len(publicKey) == 33 {
+		publicKey = publicKey[1:]
+	}
+	key, err := schnorr.ParsePubKey(publicKey)
+	if err != nil {
+		return err
+	}
+	parsed, err := schnorr.ParseSignature(signature)
+	if err != nil {
+		return ErrInvalidSignature
+	}
+	hash := SignatureHash(messageName, records)
+	if !parsed.Verify(hash[:], key) {
+		return ErrInvalidSignature
+	}
+	return nil
+}
+
+// VerifyMessage checks the `signature` record of a TLV stream against publicKey.
+func VerifyMessage(messageName string, records []Record, publicKey []byte) error {
+	for _, record := range records {
+		if record.Type == signatureFieldType {
+			return VerifySignature(messageName, records, record.Value, publicKey)
+		}
+	}
+	return errors.New("bolt12: missing signature")
+}
+
+func taggedHash(tag []byte, message []byte) [32]byte {
+	tagHash := sha256.Sum256(tag)
+	hash := sha256.New()
+	hash.Write(tagHash[:])
+	hash.Write(tagHash[:])
+	hash.Write(message)
+	var result [32]byte
+	copy(result[:], hash.Sum(nil))
+	return result
+}
+
+// branchHash hashes two nodes of the merkle tree, the lesser one first.
+func branchHash(a [32]byte, b [32]byte) [32]byte {
+	if bytes.Compare(a[:], b[:]) > 0 {
+		a, b = b, a
+	}
+	return taggedHash([]byte("LnBranch"), append(a[:], b[:]...))
+}
@@ -0,0 +1,196 @@
+// Copyright ©, 2023-present, Lightspark Group, Inc. - All Rights Reserved
+package bolt12_test
+
+import (
+	"crypto/sha256"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/btcsuite/btcd/btcec/v2"
+	"github.com/lightsparkdev/go-sdk/bolt12"
+	"github.com/lightsparkdev/go-sdk/objects"
+	"github.com/stretchr/testify/require"
+)
+
+func newPubKey(t *testing.T) []byte {
+	key, err := btcec.NewPrivateKey()
+	require.NoError(t, err)
+	return key.PubKey().SerializeCompressed()
+}
+
+func ptr[T any](value T) *T {
+	return &value
+}
+
+func TestOfferRoundTrip(t *testing.T) {
+	regtest, _ := bolt12.ChainHash(objects.BitcoinNetworkRegtest)
+	expiry := time.Date(2030, 1, 1, 0, 0, 0, 0, time.UTC)
+	offer := &bolt12.Offer{
+		Chains:         [][32]byte{regtest},
+		Amount:         ptr(uint64(50_000)),
+		Description:    ptr("coffee"),
+		AbsoluteExpiry: &expiry,
+		Issuer:         ptr("Lightspark"),
+		QuantityMax:    ptr(uint64(5)),
+		IssuerId:       newPubKey(t),
+		Paths: []bolt12.BlindedPath{
+			{
+				FirstNodeId:  newPubKey(t),
+				FirstPathKey: newPubKey(t),
+				Hops: []bolt12.BlindedHop{
+					{BlindedNodeId: newPubKey(t), EncryptedRecipientData: []byte{1, 2, 3}},
+					{BlindedNodeId: newPubKey(t), EncryptedRecipientData: []byte{}},
+				},
+			},
+			{
+				FirstScidDir: &bolt12.ShortChannelIdDir{Direction: 1, ShortChannelId: 1 << 40},
+				FirstPathKey: newPubKey(t),
+				Hops:         []bolt12.BlindedHop{{BlindedNodeId: newPubKey(t), EncryptedRecipientData: []byte{4}}},
+			},
+		},
+		Unknown: []bolt12.Record{{Type: 1_000_000_001, Value: []byte("custom")}},
+	}
+
+	encoded, err := offer.Encode()
+	require.NoError(t, err)
+	require.True(t, strings.HasPrefix(encoded, "lno1"))
+
+	decoded, err := bolt12.DecodeOffer(encoded)
+	require.NoError(t, err)
+	require.Equal(t, offer.Chains, decoded.Chains)
+	require.Equal(t, *offer.Amount, *decoded.Amount)
+	require.Equal(t, "coffee", *decoded.Description)
+	require.Equal(t, expiry, *decoded.AbsoluteExpiry)
+	require.Equal(t, "Lightspark", *decoded.Issuer)
+	require.Equal(t, uint64(5), *decoded.QuantityMax)
+	require.Equal(t, offer.IssuerId, decoded.IssuerId)
+	require.Equal(t, offer.Paths[0].Hops[0], decoded.Paths[0].Hops[0])
+	require.Equal(t, *offer.Paths[1].FirstScidDir, *decoded.Paths[1].FirstScidDir)
+	require.Equal(t, offer.Unknown, decoded.Unknown)
+
+	reencoded, err := decoded.Encode()
+	require.NoError(t, err)
+	require.Equal(t, encoded, reencoded)
+
+	// Long offers may be split with + and whitespace.
+	split := encoded[:20] + "+\n  " + encoded[20:]
+	_, err = bolt12.DecodeOffer(strings.ToUpper(split))
+	require.NoError(t, err)
+}
+
+func TestOfferValidation(t *testing.T) {
+	issuerId := newPubKey(t)
+	invalid := map[string]*bolt12.Offer{
+		"amount without description": {Amount: ptr(uint64(1)), IssuerId: issuerId},
+		"zero amount":                {Amount: ptr(uint64(0)), Description: ptr("x"), IssuerId: issuerId},
+		"currency without amount":    {Currency: ptr("USD"), Description: ptr("x"), IssuerId: issuerId},
+		"no issuer id nor paths":     {Description: ptr("x")},
+	}
+	for name, offer := range invalid {
+		_, err := offer.Encode()
+		require.Error(t, err, name)
+	}
+
+	valid, err := (&bolt12.Offer{Description: ptr("x"), IssuerId: issuerId}).Encode()
+	require.NoError(t, err)
+	_, err = bolt12.DecodeOffer("lni1" + valid[4:])
+	require.Error(t, err, "wrong prefix")
+	_, err = bolt12.DecodeOffer(valid + "q")
+	require.Error(t, err, "truncated record")
+	_, err = bolt12.DecodeOffer(valid[:10] + "+" + valid[10:] + "+")
+	require.Error(t, err, "trailing +")
+
+	unknownEven := &bolt12.Offer{Description: ptr("x"), IssuerId: issuerId, Unknown: []bolt12.Record{{Type: 30}}}
+	encoded, err := unknownEven.Encode()
+	require.NoError(t, err)
+	_, err = bolt12.DecodeOffer(encoded)
+	require.ErrorContains(t, err, "unknown even TLV type 30")
+
+	signed := &bolt12.Offer{Description: ptr("x"), IssuerId: issuerId, Unknown: []bolt12.Record{{Type: 241}}}
+	encoded, err = signed.Encode()
+	require.NoError(t, err)
+	_, err = bolt12.DecodeOffer(encoded)
+	require.ErrorContains(t, err, "not allowed in offers")
+}
+
+func TestCheckPayment(t *testing.T) {
+	now := time.Now()
+	expiry := now.Add(time.Hour)
+	offer := &bolt12.Offer{
+		Amount:         ptr(uint64(10_000)),
+		Description:    ptr("x"),
+		AbsoluteExpiry: &expiry,
+		IssuerId:       newPubKey(t),
+	}
+
+	require.NoError(t, offer.CheckPayment(objects.BitcoinNetworkMainnet, nil, now))
+	require.NoError(t, offer.CheckPayment(objects.BitcoinNetworkMainnet, ptr(int64(12_000)), now))
+	require.ErrorIs(t, offer.CheckPayment(objects.BitcoinNetworkMainnet, ptr(int64(9_999)), now), bolt12.ErrInvalidAmount)
+	require.ErrorIs(t, offer.CheckPayment(objects.BitcoinNetworkRegtest, nil, now), bolt12.ErrUnsupportedNetwork)
+	require.ErrorIs(t, offer.CheckPayment(objects.BitcoinNetworkMainnet, nil, expiry), bolt12.ErrOfferExpired)
+
+	offer.Amount = nil
+	require.ErrorIs(t, offer.CheckPayment(objects.BitcoinNetworkMainnet, nil, now), bolt12.ErrInvalidAmount)
+	require.NoError(t, offer.CheckPayment(objects.BitcoinNetworkMainnet, ptr(int64(1)), now))
+
+	require.NoError(t, offer.CheckQuantity(1))
+	require.Error(t, offer.CheckQuantity(2))
+	offer.QuantityMax = ptr(uint64(0))
+	require.NoError(t, offer.CheckQuantity(1000))
+	require.Error(t, offer.CheckQuantity(0))
+}
+
+func taggedHash(tag string, message []byte) [32]byte {
+	tagHash := sha256.Sum256([]byte(tag))
+	return sha256.Sum256(append(append(tagHash[:], tagHash[:]...), message...))
+}
+
+func branch(a [32]byte, b [32]byte) [32]byte {
+	if string(a[:]) > string(b[:]) {
+		a, b = b, a
+	}
+	return taggedHash("LnBranch", append(a[:], b[:]...))
+}
+
+func TestMerkleRootAndSignature(t *testing.T) {
+	records := []bolt12.Record{
+		{Type: 10, Value: []byte("a")},
+		{Type: 22, Value: newPubKey(t)},
+		{Type: 88, Value: []byte{1}},
+	}
+	stream := bolt12.EncodeRecords(records)
+	parsed, err := bolt12.ParseRecords(stream)
+	require.NoError(t, err)
+	require.Equal(t, records, parsed)
+
+	// Three records give six leaves: the last pair is joined with the first four at the root.
+	encoded := make([][]byte, len(records))
+	for i, record := range records {
+		encoded[i] = bolt12.EncodeRecords([]bolt12.Record{record})
+	}
+	nonceTag := "LnNonce" + string(encoded[0])
+	pairs := make([][32]byte, len(records))
+	for i, record := range records {
+		pairs[i] = branch(taggedHash("LnLeaf", encoded[i]), taggedHash(nonceTag, []byte{byte(record.Type)}))
+	}
+	expected := branch(branch(pairs[0], pairs[1]), pairs[2])
+	require.Equal(t, expected, bolt12.MerkleRoot(records))
+
+	key, err := btcec.NewPrivateKey()
+	require.NoError(t, err)
+	signature, err := bolt12.Sign(bolt12.MessageInvoice, records, key)
+	require.NoError(t, err)
+	signedRecords := append(append([]bolt12.Record(nil), records...), bolt12.Record{Type: 240, Value: signature})
+	require.Equal(t, bolt12.MerkleRoot(records), bolt12.MerkleRoot(signedRecords))
+	require.NoError(t, bolt12.VerifyMessage(bolt12.MessageInvoice, signedRecords, key.PubKey().SerializeCompressed()))
+	require.ErrorIs(t, bolt12.VerifyMessage(bolt12.MessageInvoiceRequest, signedRecords, key.PubKey().SerializeCompressed()),
+		bolt12.ErrInvalidSignature)
+
+	signedRecords[0].Value = []byte("b")
+	require.ErrorIs(t, bolt12.VerifyMessage(bolt12.MessageInvoice, signedRecords, key.PubKey().SerializeCompressed()),
+		bolt12.ErrInvalidSignature)
+
+	_, err = bolt12.ParseRecords(bolt12.EncodeRecords([]bolt12.Record{records[1], records[0]}))
+	require.Error(t, err, "unsorted records")
+}
@@ -0,0 +1,119 @@
+// Copyright ©, 2023-present, Lightspark Group, Inc. - All Rights Reserved
+package bolt12
+
+import (
+	"bytes"
+	"encoding/binary"
+	"errors"
+	"fmt"
+)
+
+// Record is a TLV record of a BOLT12 message.
+type Record struct {
+	Type  uint64
+	Value []byte
+}
+
+// encode returns the serialized record, as hashed into the merkle tree of signed messages.
+func (r Record) encode() []byte {
+	var buffer bytes.Buffer
+	buffer.Write(encodeBigSize(r.Type))
+	buffer.Write(encodeBigSize(uint64(len(r.Value))))
+	buffer.Write(r.Value)
+	return buffer.Bytes()
+}
+
+// ParseRecords parses a TLV stream. Types must be strictly increasing.
+func ParseRecords(stream []byte) ([]Record, error) {
+	var records []Record
+	for len(stream) > 0 {
+		recordType, n, err := readBigSize(stream)
+		if err != nil {
+			return nil, err
+		}
+		stream = stream[n:]
+		length, n, err := readBigSize(stream)
+		if err != nil {
+			return nil, err
+		}
+		stream = stream[n:]
+		if uint64(len(stream)) < length {
+			return nil, errors.New("bolt12: truncated TLV record")
+		}
+		if len(records) > 0 && recordType <= records[len(records)-1].Type {
+			return nil, fmt.Errorf("bolt12: TLV type %d is not in increasing order", recordType)
+		}
+		records = append(records, Record{Type: recordType, Value: stream[:length]})
+		stream = stream[length:]
+	}
+	return records, nil
+}
+
+// EncodeRecords serializes records, which must be sorted by type.
+func EncodeRecords(records []Record) []byte {
+	var buffer bytes.Buffer
+	for _, record := range records {
+		buffer.Write(record.encode())
+	}
+	return buffer.Bytes()
+}
+
+func encodeBigSize(value uint64) []byte {
+	switch {
+	case value < 0xfd:
+		return []byte{byte(value)}
+	case value <= 0xffff:
+		return binary.BigEndian.AppendUint16([]byte{0xfd}, uint16(value))
+	case value <= 0xffffffff:
+		return binary.BigEndian.AppendUint32([]byte{0xfe}, uint32(value))
+	}
+	return binary.BigEndian.AppendUint64([]byte{0xff}, value)
+}
+
+func readBigSize(data []byte) (uint64, int, error) {
+	if len(data) == 0 {
+		return 0, 0, errors.New("bolt12: truncated bigsize")
+	}
+	var value uint64
+	var n int
+	switch data[0] {
+	case 0xfd:
+		n = 3
+	case 0xfe:
+		n = 5
+	case 0xff:
+		n = 9
+	default:
+		return uint64(data[0]), 1, nil
+	}
+	if len(data) < n {
+		return 0, 0, errors.New("bolt12: truncated bigsize")
+	}
+	for _, b := range data[1:n] {
+		value = value<<8 | uint64(b)
+	}
+	minimum := map[int]uint64{3: 0xfd, 5: 0x10000, 9: 0x100000000}[n]
+	if value < minimum {
+		return 0, 0, errors.New("bolt12: non-minimal bigsize")
+	}
+	return value, n, nil
+}
+
+func encodeTu64(value uint64) []byte {
+	encoded := binary.BigEndian.AppendUint64(nil, value)
+	return bytes.TrimLeft(encoded, "\x00")
+}
+
+func readTu64(value []byte) (uint64, error) {
+	if len(value) > 8 {
+		return 0, errors.New("bolt12: tu64 is too long")
+	}
+	if len(value) > 0 && value[0] == 0 {
+		return 0, errors.New("bolt12: non-minimal tu64")
+	}
+	var result uint64
+	for _, b := range value {
+		result = result<<8 | uint64(b)
+	}
+	return result, nil
+}
@@ -0,0 +1,282 @@
+// Copyright ©, 2023-present, Lightspark Group, Inc. - All Rights Reserved
+package main
+
+import (
+	"bufio"
+	"crypto/rand"
+	"encoding/hex"
+	"errors"
+	"flag"
+	"fmt"
+	"log"
+	"os"
+	"strings"
+
+	"github.com/lightsparkdev/go-sdk/crypto"
+	"github.com/lightsparkdev/go-sdk/objects"
+)
+
+/**
+ * Manages the encrypted keystore holding the master seed of a remote signing node. Passphrases are
+ * read from the KEYSTORE_PASSPHRASE and NEW_KEYSTORE_PASSPHRASE environment variables, or from the
+ * files given with -passphrase-file and -new-passphrase-file.
+ *
+ * MASTER_SEED_HEX=... go run ./cmd/keystore create -keystore seed.json -network REGTEST
+ * MNEMONIC="..." MNEMONIC_PASSPHRASE=... go run ./cmd/keystore create -keystore seed.json -network REGTEST
+ * go run ./cmd/keystore mnemonic
+ * go run ./cmd/keystore create -keystore seed.json -network MAINNET -kdf argon2id
+ * go run ./cmd/keystore unlock -keystore seed.json
+ * go run ./cmd/keystore change-passphrase -keystore seed.json
+ * go run ./cmd/keystore export -keystore seed.json
+ * go run ./cmd/keystore split -keystore seed.json -group-threshold 2 -groups 1of1,2of3,3of5 > shares.txt
+ * go run ./cmd/keystore combine -keystore restored.json -network MAINNET < shares.txt
+ */
+
+const usage = `usage: keystore <command> [flags]
+
+commands:
+  mnemonic           print a new 24 words BIP 39 mnemonic to back up and create a keystore from
+  create             encrypt MASTER_SEED_HEX, the seed of MNEMONIC and MNEMONIC_PASSPHRASE, or a new
+                     random seed, into a new keystore
+  unlock             check the passphrase and print the network and fingerprint of a keystore
+  change-passphrase  encrypt a keystore under NEW_KEYSTORE_PASSPHRASE
+  export             print the master seed of a keystore in hex
+  split              split the master seed of a keystore into Shamir shares, one per line
+  combine            recover a master seed from Shamir shares read from standard input, one per
+                     line, into a new keystore
+`
+
+func main() {
+	log.SetFlags(0)
+	if len(os.Args) < 2 {
+		fmt.Fprint(os.Stderr, usage)
+		os.Exit(2)
+	}
+	command := os.Args[1]
+	if command == "mnemonic" {
+		mnemonic, err := crypto.GenerateMnemonic(24)
+		if err != nil {
+			log.Fatal(err)
+		}
+		fmt.Println(strings.Join(mnemonic, " "))
+		return
+	}
+	flags := flag.NewFlagSet(command, flag.ExitOnError)
+	path := flags.String("keystore", "keystore.json", "path of the keystore")
+	passphraseFile := flags.String("passphrase-file", "", "file containing the passphrase, instead of KEYSTORE_PASSPHRASE")
+	var networkName, kdf, newPassphraseFile *string
+	var groupThreshold *int
+	var groups *string
+	switch command {
+	case "create", "combine":
+		networkName = flags.String("network", "", "bitcoin network of the node: MAINNET, TESTNET or REGTEST")
+		kdf = flags.String("kdf", crypto.KDF_SCRYPT, "key derivation function: scrypt or argon2id")
+	case "split":
+		groupThreshold = flags.Int("group-threshold", 1, "number of groups needed to recover the seed")
+		groups = flags.String("groups", "2of3", "comma separated groups, as the number of shares needed of the shares of the group")
+	case "change-passphrase":
+		kdf = flags.String("kdf", "", "new key derivation function, scrypt or argon2id; unchanged by default")
+		newPassphraseFile = flags.String("new-passphrase-file", "", "file containing the new passphrase, instead of NEW_KEYSTORE_PASSPHRASE")
+	case "unlock", "export":
+	default:
+		fmt.Fprint(os.Stderr, usage)
+		os.Exit(2)
+	}
+	flags.Parse(os.Args[2:])
+
+	passphrase, err := readPassphrase("KEYSTORE_PASSPHRASE", *passphraseFile)
+	if err != nil {
+		log.Fatal(err)
+	}
+
+	switch command {
+	case "create":
+		create(*path, passphrase, *networkName, *kdf)
+	case "split":
+		split(*path, passphrase, *groupThreshold, *groups)
+	case "combine":
+		seed, err := combine(os.Stdin)
+		if err != nil {
+			log.Fatal(err)
+		}
+		writeKeystore(*path, seed, passphrase, *networkName, *kdf)
+	case "unlock":
+		keystore := unlock(*path, passphrase)
+		fmt.Printf("network: %s\nfingerprint: %s\nkdf: %s\n", keystore.Network.StringValue(), keystore.Fingerprint, keystore.Kdf.Name)
+	case "change-passphrase":
+		newPassphrase, err := readPassphrase("NEW_KEYSTORE_PASSPHRASE", *newPassphraseFile)
+		if err != nil {
+			log.Fatal(err)
+		}
+		keystore, err := crypto.ReadKeystore(*path)
+		if err != nil {
+			log.Fatal(err)
+		}
+		var opts []crypto.KeystoreOption
+		if *kdf != "" {
+			opt, err := kdfOption(*kdf)
+			if err != nil {
+				log.Fatal(err)
+			}
+			opts = append(opts, opt)
+		}
+		if err := keystore.ChangePassphrase(passphrase, newPassphrase, opts...); err != nil {
+			log.Fatal(err)
+		}
+		if err := keystore.Write(*path); err != nil {
+			log.Fatal(err)
+		}
+		fmt.Println("passphrase changed")
+	case "export":
+		keystore, err := crypto.ReadKeystore(*path)
+		if err != nil {
+			log.Fatal(err)
+		}
+		seed, err := keystore.Unlock(passphrase)
+		if err != nil {
+			log.Fatal(err)
+		}
+		fmt.Fprintln(os.Stderr, "Anyone with this seed can spend the funds of the node.")
+		fmt.Println(hex.EncodeToString(seed))
+	}
+}
+
+func create(path string, passphrase string, networkName string, kdf string) {
+	var seed []byte
+	var err error
+	if phrase := os.Getenv("MNEMONIC"); phrase != "" {
+		seed, err = crypto.MnemonicToSeed(crypto.ParseMnemonic(phrase), os.Getenv("MNEMONIC_PASSPHRASE"))
+		if err != nil {
+			log.Fatalf("Invalid MNEMONIC: %s", err)
+		}
+	} else if seedHex := os.Getenv("MASTER_SEED_HEX"); seedHex != "" {
+		seed, err = hex.DecodeString(seedHex)
+		if err != nil {
+			log.Fatalf("Invalid MASTER_SEED_HEX: %s", err)
+		}
+	} else {
+		seed = make([]byte, 32)
+		if _, err := rand.Read(seed); err != nil {
+			log.Fatal(err)
+		}
+	}
+	writeKeystore(path, seed, passphrase, networkName, kdf)
+}
+
+func writeKeystore(path string, seed []byte, passphrase string, networkName string, kdf string) {
+	if _, err := os.Stat(path); !errors.Is(err, os.ErrNotExist) {
+		log.Fatalf("%s already exists", path)
+	}
+	var network objects.BitcoinNetwork
+	network.UnmarshalJSON([]byte(`"` + strings.ToUpper(networkName) + `"`))
+	if network == objects.BitcoinNetworkUndefined {
+		log.Fatalf("Invalid network %q", networkName)
+	}
+	opt, err := kdfOption(kdf)
+	if err != nil {
+		log.Fatal(err)
+	}
+
+	keystore, err := crypto.NewKeystore(seed, network, passphrase, opt)
+	if err != nil {
+		log.Fatal(err)
+	}
+	if err := keystore.Write(path); err != nil {
+		log.Fatal(err)
+	}
+	fmt.Printf("created %s\nfingerprint: %s\n", path, keystore.Fingerprint)
+}
+
+func split(path string, passphrase string, groupThreshold int, groupsSpec string) {
+	var groups []crypto.ShamirGroup
+	for _, spec := range strings.Split(groupsSpec, ",") {
+		var group crypto.ShamirGroup
+		if _, err := fmt.Sscanf(spec, "%dof%d", &group.Threshold, &group.Count); err != nil {
+			log.Fatalf("Invalid group %q, expected for example 2of3", spec)
+		}
+		groups = append(groups, group)
+	}
+
+	keystore, err := crypto.ReadKeystore(path)
+	if err != nil {
+		log.Fatal(err)
+	}
+	seed, err := keystore.Unlock(passphrase)
+	if err != nil {
+		log.Fatal(err)
+	}
+	shares, err := crypto.SplitSecret(seed, groupThreshold, groups)
+	clear(seed)
+	if err != nil {
+		log.Fatal(err)
+	}
+
+	fmt.Fprintf(os.Stderr, "network: %s\nfingerprint: %s\n", keystore.Network.StringValue(), keystore.Fingerprint)
+	fmt.Fprintf(os.Stderr, "Store every share separately. %d of the groups recover the seed.\n", groupThreshold)
+	for i, group := range shares {
+		for j, share := range group {
+			fmt.Fprintf(os.Stderr, "group %d of %d, share %d of %d, %d needed:\n", i+1, len(shares), j+1, len(group),
+				share.MemberThreshold)
+			fmt.Println(strings.Join(share.Mnemonic(), " "))
+		}
+	}
+}
+
+func combine(file *os.File) ([]byte, error) {
+	var shares []crypto.ShamirShare
+	scanner := bufio.NewScanner(file)
+	for scanner.Scan() {
+		words := crypto.ParseMnemonic(scanner.Text())
+		if len(words) == 0 {
+			continue
+		}
+		share, err := crypto.ParseShamirShare(words)
+		if err != nil {
+			return nil, fmt.Errorf("share %d: %w", len(shares)+1, err)
+		}
+		shares = append(shares, share)
+	}
+	if err := scanner.Err(); err != nil {
+		return nil, err
+	}
+	return crypto.CombineShares(shares)
+}
+
+func unlock(path string, passphrase string) *crypto.Keystore {
+	keystore, err := crypto.ReadKeystore(path)
+	if err != nil {
+		log.Fatal(err)
+	}
+	seed, err := keystore.Unlock(passphrase)
+	if err != nil {
+		log.Fatal(err)
+	}
+	clear(seed)
+	return keystore
+}
+
+func kdfOption(name string) (crypto.KeystoreOption, error) {
+	switch name {
+	case crypto.KDF_SCRYPT:
+		return crypto.WithScrypt(1<<17, 8, 1), nil
+	case crypto.KDF_ARGON2ID:
+		return crypto.WithArgon2id(3, 256*1024, 4), nil
+	default:
+		return nil, fmt.Errorf("invalid kdf %q", name)
+	}
+}
+
+func readPassphrase(env string, file string) (string, error) {
+	if file != "" {
+		data, err := os.ReadFile(file)
+		if err != nil {
+			return "", err
+		}
+		return strings.TrimRight(string(data), "\r\n"), nil
+	}
+	passphrase := os.Getenv(env)
+	if passphrase == "" {
+		return "", fmt.Errorf("%s is not set", env)
+	}
+	return passphrase, nil
+}
@@ -0,0 +1,139 @@
+// Copyright ©, 2023-present, Lightspark Group, Inc. - All Rights Reserved
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"flag"
+	"fmt"
+	"io"
+	"log"
+	"os"
+	"strings"
+	"time"
+
+	"github.com/lightsparkdev/go-sdk/objects"
+	"github.com/lightsparkdev/go-sdk/webhooks/webhookstest"
+)
+
+/**
+ * Sends realistic, signed webhook events to a local webhook server, such as
+ * examples/remote-signing-server, without a Lightspark account. The secret is read from the
+ * WEBHOOK_SECRET environment variable unless -secret is given.
+ *
+ * go run ./cmd/webhook-simulator -url http://127.0.0.1:8080/ln/webhooks -event REMOTE_SIGNING -sub-event ECDH
+ * go run ./cmd/webhook-simulator -url http://127.0.0.1:8080/ln/webhooks -all
+ * go run ./cmd/webhook-simulator -url http://127.0.0.1:8080/ln/webhooks -file captured_event.json
+ * go run ./cmd/webhook-simulator -event PAYMENT_FINISHED -print
+ */
+
+func main() {
+	url := flag.String("url", "http://127.0.0.1:8080/ln/webhooks", "URL of the webhook server")
+	secret := flag.String("secret", os.Getenv("WEBHOOK_SECRET"), "webhook secret used to sign the events")
+	eventTypeName := flag.String("event", "REMOTE_SIGNING", "type of the event to send")
+	subEventTypeName := flag.String("sub-event", "GET_PER_COMMITMENT_POINT", "sub event type of REMOTE_SIGNING events")
+	networkName := flag.String("network", "REGTEST", "bitcoin network of REMOTE_SIGNING events")
+	all := flag.Bool("all", false, "send one event of every event type and remote signing sub event type")
+	file := flag.String("file", "", "send the JSON body of this file as is instead of a template")
+	printOnly := flag.Bool("print", false, "print the events instead of sending them")
+	timeout := flag.Duration("timeout", 30*time.Second, "timeout of each request")
+	flag.Parse()
+
+	var network objects.BitcoinNetwork
+	if err := unmarshalEnum(*networkName, &network); err != nil || network == objects.BitcoinNetworkUndefined {
+		log.Fatalf("Invalid network %s", *networkName)
+	}
+
+	simulator := webhookstest.NewSimulator(*url, *secret)
+	if *file != "" {
+		body, err := os.ReadFile(*file)
+		if err != nil {
+			log.Fatalf("Couldn't read %s: %s", *file, err)
+		}
+		if *printOnly {
+			fmt.Println(string(body))
+			return
+		}
+		send(*file, *timeout, func(ctx context.Context) (io.ReadCloser, int, error) {
+			response, err := simulator.SendRaw(ctx, body)
+			if err != nil {
+				return nil, 0, err
+			}
+			return response.Body, response.StatusCode, nil
+		})
+		return
+	}
+
+	var events []*webhookstest.Event
+	if *all {
+		for _, eventType := range webhookstest.EventTypes {
+			if eventType != objects.WebhookEventTypeRemoteSigning {
+				events = append(events, webhookstest.NewEvent(eventType))
+			}
+		}
+		for _, subEventType := range webhookstest.RemoteSigningSubEventTypes {
+			events = append(events, webhookstest.NewRemoteSigningEvent(subEventType, network))
+		}
+	} else {
+		var eventType objects.WebhookEventType
+		if err := unmarshalEnum(*eventTypeName, &eventType); err != nil || eventType == objects.WebhookEventTypeUndefined {
+			log.Fatalf("Invalid event type %s", *eventTypeName)
+		}
+		if eventType == objects.WebhookEventTypeRemoteSigning {
+			var subEventType objects.RemoteSigningSubEventType
+			if err := unmarshalEnum(*subEventTypeName, &subEventType); err != nil ||
+				subEventType == objects.RemoteSigningSubEventTypeUndefined {
+				log.Fatalf("Invalid sub event type %s", *subEventTypeName)
+			}
+			events = append(events, webhookstest.NewRemoteSigningEvent(subEventType, network))
+		} else {
+			events = append(events, webhookstest.NewEvent(eventType))
+		}
+	}
+
+	for _, event := range events {
+		if *printOnly {
+			body, err := json.MarshalIndent(event, "", "  ")
+			if err != nil {
+				log.Fatalf("Couldn't encode event: %s", err)
+			}
+			fmt.Println(string(body))
+			continue
+		}
+		send(describe(event), *timeout, func(ctx context.Context) (io.ReadCloser, int, error) {
+			response, err := simulator.Send(ctx, event)
+			if err != nil {
+				return nil, 0, err
+			}
+			return response.Body, response.StatusCode, nil
+		})
+	}
+}
+
+func send(name string, timeout time.Duration, do func(ctx context.Context) (io.ReadCloser, int, error)) {
+	ctx, cancel := context.WithTimeout(context.Background(), timeout)
+	defer cancel()
+	body, status, err := do(ctx)
+	if err != nil {
+		log.Printf("%s: ERROR: %s", name, err)
+		return
+	}
+	defer body.Close()
+	responseBody, _ := io.ReadAll(body)
+	log.Printf("%s: %d %s", name, status, strings.TrimSpace(string(responseBody)))
+}
+
+func describe(event *webhookstest.Event) string {
+	if event.EventType == objects.WebhookEventTypeRemoteSigning {
+		return fmt.Sprintf("%s %v", event.EventType.StringValue(), event.Data["sub_event_type"])
+	}
+	return event.EventType.StringValue()
+}
+
+func unmarshalEnum(name string, enum json.Unmarshaler) error {
+	value, err := json.Marshal(strings.ToUpper(name))
+	if err != nil {
+		return err
+	}
+	return enum.UnmarshalJSON(value)
+}
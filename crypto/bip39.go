@@ -0,0 +1,122 @@
+// Copyright ©, 2023-present, Lightspark Group, Inc. - All Rights Reserved
+package crypto
+
+import (
+	"crypto/rand"
+	"crypto/sha256"
+	"crypto/sha512"
+	_ "embed"
+	"errors"
+	"fmt"
+	"math/big"
+	"strings"
+
+	"golang.org/x/crypto/pbkdf2"
+	"golang.org/x/text/unicode/norm"
+)
+
+// bip39English is the English wordlist of BIP 39.
+//
+//go:embed bip39_english.txt
+var bip39English string
+
+var (
+	bip39Words   = strings.Fields(bip39English)
+	bip39Indexes = func() map[string]int {
+		indexes := make(map[string]int, len(bip39Words))
+		for i, word := range bip39Words {
+			indexes[word] = i
+		}
+		return indexes
+	}()
+)
+
+var (
+	ErrInvalidMnemonicLength   = errors.New("mnemonic must have 12, 15, 18, 21 or 24 words")
+	ErrInvalidMnemonicWord     = errors.New("mnemonic word is not in the BIP 39 wordlist")
+	ErrInvalidMnemonicChecksum = errors.New("invalid mnemonic checksum")
+)
+
+// GenerateMnemonic generates a new BIP 39 mnemonic of wordCount words from random entropy. Use 24 words
+// for the master seed of a node.
+func GenerateMnemonic(wordCount int) ([]string, error) {
+	if wordCount < 12 || wordCount > 24 || wordCount%3 != 0 {
+		return nil, ErrInvalidMnemonicLength
+	}
+	entropy := make([]byte, wordCount*4/3)
+	if _, err := rand.Read(entropy); err != nil {
+		return nil, err
+	}
+	return EntropyToMnemonic(entropy)
+}
+
+// EntropyToMnemonic encodes 16 to 32 bytes of entropy, in multiples of 4 bytes, as a BIP 39 mnemonic.
+func EntropyToMnemonic(entropy []byte) ([]string, error) {
+	if len(entropy) < 16 || len(entropy) > 32 || len(entropy)%4 != 0 {
+		return nil, fmt.Errorf("invalid entropy length %d", len(entropy))
+	}
+	checksumBits := uint(len(entropy) / 4)
+	hash := sha256.Sum256(entropy)
+	bits := new(big.Int).SetBytes(entropy)
+	bits.Lsh(bits, checksumBits)
+	bits.Or(bits, big.NewInt(int64(hash[0]>>(8-checksumBits))))
+
+	mnemonic := make([]string, (uint(len(entropy))*8+checksumBits)/11)
+	index := new(big.Int)
+	mask := big.NewInt(2047)
+	for i := len(mnemonic) - 1; i >= 0; i-- {
+		index.And(bits, mask)
+		mnemonic[i] = bip39Words[index.Int64()]
+		bits.Rsh(bits, 11)
+	}
+	return mnemonic, nil
+}
+
+// MnemonicToEntropy decodes a BIP 39 mnemonic, checking its words and checksum.
+func MnemonicToEntropy(mnemonic []string) ([]byte, error) {
+	if len(mnemonic) < 12 || len(mnemonic) > 24 || len(mnemonic)%3 != 0 {
+		return nil, ErrInvalidMnemonicLength
+	}
+	bits := new(big.Int)
+	for _, word := range mnemonic {
+		index, ok := bip39Indexes[word]
+		if !ok {
+			return nil, fmt.Errorf("%w: %q", ErrInvalidMnemonicWord, word)
+		}
+		bits.Lsh(bits, 11)
+		bits.Or(bits, big.NewInt(int64(index)))
+	}
+
+	checksumBits := uint(len(mnemonic) / 3)
+	checksum := new(big.Int).And(bits, big.NewInt(1<<checksumBits-1)).Int64()
+	bits.Rsh(bits, checksumBits)
+	entropy := make([]byte, len(mnemonic)*4/3)
+	bits.FillBytes(entropy)
+	hash := sha256.Sum256(entropy)
+	if int64(hash[0]>>(8-checksumBits)) != checksum {
+		return nil, ErrInvalidMnemonicChecksum
+	}
+	return entropy, nil
+}
+
+// ValidateMnemonic checks the length, words and checksum of a BIP 39 mnemonic.
+func ValidateMnemonic(mnemonic []string) error {
+	_, err := MnemonicToEntropy(mnemonic)
+	return err
+}
+
+// ParseMnemonic splits a space separated mnemonic phrase into its words.
+func ParseMnemonic(phrase string) []string {
+	return strings.Fields(strings.ToLower(norm.NFKD.String(phrase)))
+}
+
+// MnemonicToSeed validates a BIP 39 mnemonic and derives its 64 bytes seed with an optional passphrase.
+// The seed can be used as the master seed of a remote signing node.
+func MnemonicToSeed(mnemonic []string, passphrase string) ([]byte, error) {
+	if err := ValidateMnemonic(mnemonic); err != nil {
+		return nil, err
+	}
+	password := norm.NFKD.String(strings.Join(mnemonic, " "))
+	salt := norm.NFKD.String("mnemonic" + passphrase)
+	return pbkdf2.Key([]byte(password), []byte(salt), 2048, 64, sha512.New), nil
+}
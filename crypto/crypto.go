@@ -12,7 +12,7 @@ import (
 
 	"golang.org/x/crypto/pbkdf2"
 
-	lightspark_crypto "github.com/lightsparkdev/lightspark-crypto-uniffi/lightspark-crypto-go"
+	"github.com/lightsparkdev/go-sdk/crypto/lightsparkcrypto"
 )
 
 const KEY_LEN = 32
@@ -82,22 +82,22 @@ func Sha256HexString(str string) string {
 	return hex.EncodeToString(hash[:])
 }
 
-func DerivePublicKey(seedHexString string, network lightspark_crypto.BitcoinNetwork, derivationPath string) (string, error) {
+func DerivePublicKey(seedHexString string, network lightsparkcrypto.BitcoinNetwork, derivationPath string) (string, error) {
 	seedBytes, err := hex.DecodeString(seedHexString)
 	if err != nil {
 		return "", err
 	}
 
-	return lightspark_crypto.DerivePublicKey(seedBytes, network, derivationPath)
+	return lightsparkcrypto.DerivePublicKey(seedBytes, network, derivationPath)
 }
 
-func ECDH(seedBytes []byte, network lightspark_crypto.BitcoinNetwork, otherPubKey string) (string, error) {
+func ECDH(seedBytes []byte, network lightsparkcrypto.BitcoinNetwork, otherPubKey string) (string, error) {
 	otherPubKeyBytes, err := hex.DecodeString(otherPubKey)
 	if err != nil {
 		return "", err
 	}
 
-	secretBytes, err := lightspark_crypto.Ecdh(seedBytes, network, otherPubKeyBytes)
+	secretBytes, err := lightsparkcrypto.Ecdh(seedBytes, network, otherPubKeyBytes)
 	if err != nil {
 		return "", err
 	}
@@ -0,0 +1,290 @@
+// Copyright ©, 2023-present, Lightspark Group, Inc. - All Rights Reserved
+package crypto
+
+import (
+	"crypto/aes"
+	"crypto/cipher"
+	"crypto/rand"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+
+	"github.com/btcsuite/btcd/btcutil"
+	"github.com/btcsuite/btcd/btcutil/hdkeychain"
+	"github.com/btcsuite/btcd/chaincfg"
+	"github.com/lightsparkdev/go-sdk/objects"
+	"golang.org/x/crypto/argon2"
+	"golang.org/x/crypto/scrypt"
+)
+
+const (
+	KEYSTORE_VERSION = 1
+	KEYSTORE_CIPHER  = "aes-256-gcm"
+	KDF_SCRYPT       = "scrypt"
+	KDF_ARGON2ID     = "argon2id"
+)
+
+// Bounds of the key derivation parameters, checked before deriving a key so that a tampered keystore can't
+// exhaust memory or CPU before its authentication fails.
+const (
+	maxKdfMemoryBytes = 1 << 30
+	maxScryptP        = 16
+	maxArgon2Time     = 16
+)
+
+// ErrWrongPassphrase is returned when a keystore is unlocked with the wrong passphrase.
+var ErrWrongPassphrase = errors.New("wrong keystore passphrase")
+
+// KeystoreKdf holds the parameters of the key derivation function deriving the encryption key of a
+// Keystore from its passphrase. N, R and P are used by scrypt; Time, Memory (in KiB) and Threads by
+// argon2id.
+type KeystoreKdf struct {
+	Name    string `json:"name"`
+	Salt    []byte `json:"salt"`
+	N       int    `json:"n,omitempty"`
+	R       int    `json:"r,omitempty"`
+	P       int    `json:"p,omitempty"`
+	Time    uint32 `json:"time,omitempty"`
+	Memory  uint32 `json:"memory,omitempty"`
+	Threads uint8  `json:"threads,omitempty"`
+}
+
+// Keystore is a master seed encrypted at rest with AES-256-GCM, under a key derived from a passphrase
+// with scrypt or argon2id. The network and the fingerprint of the seed are stored in clear and
+// authenticated by the encryption, so they can be checked without the passphrase.
+type Keystore struct {
+	Version int                    `json:"version"`
+	Network objects.BitcoinNetwork `json:"network"`
+	// Fingerprint is the BIP 32 fingerprint of the master key of the seed, in hex.
+	Fingerprint string      `json:"fingerprint"`
+	Kdf         KeystoreKdf `json:"kdf"`
+	Cipher      string      `json:"cipher"`
+	Nonce       []byte      `json:"nonce"`
+	Ciphertext  []byte      `json:"ciphertext"`
+}
+
+// KeystoreOption configures the key derivation function of a Keystore.
+type KeystoreOption func(*KeystoreKdf)
+
+// WithScrypt derives the encryption key with scrypt. This is the default, with N=2^17, r=8 and p=1.
+func WithScrypt(n int, r int, p int) KeystoreOption {
+	return func(kdf *KeystoreKdf) {
+		*kdf = KeystoreKdf{Name: KDF_SCRYPT, N: n, R: r, P: p}
+	}
+}
+
+// WithArgon2id derives the encryption key with argon2id, using memory KiB of memory.
+func WithArgon2id(time uint32, memory uint32, threads uint8) KeystoreOption {
+	return func(kdf *KeystoreKdf) {
+		*kdf = KeystoreKdf{Name: KDF_ARGON2ID, Time: time, Memory: memory, Threads: threads}
+	}
+}
+
+// SeedFingerprint returns the BIP 32 fingerprint of the master key of a seed, in hex.
+func SeedFingerprint(seed []byte) (string, error) {
+	masterKey, err := hdkeychain.NewMaster(seed, &chaincfg.MainNetParams)
+	if err != nil {
+		return "", err
+	}
+	publicKey, err := masterKey.ECPubKey()
+	if err != nil {
+		return "", err
+	}
+	return hex.EncodeToString(btcutil.Hash160(publicKey.SerializeCompressed())[:4]), nil
+}
+
+// NewKeystore encrypts a master seed of a node on network with a passphrase.
+func NewKeystore(seed []byte, network objects.BitcoinNetwork, passphrase string, opts ...KeystoreOption) (*Keystore, error) {
+	if network == objects.BitcoinNetworkUndefined {
+		return nil, errors.New("invalid network")
+	}
+	fingerprint, err := SeedFingerprint(seed)
+	if err != nil {
+		return nil, err
+	}
+	keystore := &Keystore{
+		Version:     KEYSTORE_VERSION,
+		Network:     network,
+		Fingerprint: fingerprint,
+		Cipher:      KEYSTORE_CIPHER,
+	}
+	if err := keystore.encrypt(seed, passphrase, opts); err != nil {
+		return nil, err
+	}
+	return keystore, nil
+}
+
+// ParseKeystore parses a JSON keystore.
+func ParseKeystore(data []byte) (*Keystore, error) {
+	var keystore Keystore
+	if err := json.Unmarshal(data, &keystore); err != nil {
+		return nil, err
+	}
+	if keystore.Version != KEYSTORE_VERSION {
+		return nil, fmt.Errorf("unsupported keystore version %d", keystore.Version)
+	}
+	if keystore.Cipher != KEYSTORE_CIPHER {
+		return nil, fmt.Errorf("unsupported keystore cipher %s", keystore.Cipher)
+	}
+	if keystore.Network == objects.BitcoinNetworkUndefined {
+		return nil, errors.New("invalid keystore network")
+	}
+	return &keystore, nil
+}
+
+// ReadKeystore reads the keystore at path.
+func ReadKeystore(path string) (*Keystore, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, err
+	}
+	keystore, err := ParseKeystore(data)
+	if err != nil {
+		return nil, fmt.Errorf("invalid keystore %s: %w", path, err)
+	}
+	return keystore, nil
+}
+
+// Write writes the keystore to path, replacing the file atomically. The file is only readable by its
+// owner.
+func (k *Keystore) Write(path string) error {
+	data, err := json.MarshalIndent(k, "", "  ")
+	if err != nil {
+		return err
+	}
+	temp, err := os.CreateTemp(filepath.Dir(path), filepath.Base(path)+".*.tmp")
+	if err != nil {
+		return err
+	}
+	defer os.Remove(temp.Name())
+	if err := temp.Chmod(0o600); err != nil {
+		temp.Close()
+		return err
+	}
+	if _, err := temp.Write(data); err != nil {
+		temp.Close()
+		return err
+	}
+	if err := temp.Sync(); err != nil {
+		temp.Close()
+		return err
+	}
+	if err := temp.Close(); err != nil {
+		return err
+	}
+	return os.Rename(temp.Name(), path)
+}
+
+// Unlock decrypts the seed of the keystore. It returns ErrWrongPassphrase when the passphrase is wrong
+// or the keystore was tampered with.
+func (k *Keystore) Unlock(passphrase string) ([]byte, error) {
+	gcm, err := k.cipher(passphrase)
+	if err != nil {
+		return nil, err
+	}
+	aad, err := k.additionalData()
+	if err != nil {
+		return nil, err
+	}
+	if len(k.Nonce) != gcm.NonceSize() {
+		return nil, ErrWrongPassphrase
+	}
+	seed, err := gcm.Open(nil, k.Nonce, k.Ciphertext, aad)
+	if err != nil {
+		return nil, ErrWrongPassphrase
+	}
+	fingerprint, err := SeedFingerprint(seed)
+	if err != nil || fingerprint != k.Fingerprint {
+		clear(seed)
+		return nil, errors.New("keystore fingerprint doesn't match its seed")
+	}
+	return seed, nil
+}
+
+// ChangePassphrase encrypts the seed again under a new passphrase, with a new salt. The key derivation
+// function is kept unless options are given.
+func (k *Keystore) ChangePassphrase(oldPassphrase string, newPassphrase string, opts ...KeystoreOption) error {
+	seed, err := k.Unlock(oldPassphrase)
+	if err != nil {
+		return err
+	}
+	defer clear(seed)
+	if len(opts) == 0 {
+		kdf := k.Kdf
+		opts = []KeystoreOption{func(k *KeystoreKdf) { *k = kdf }}
+	}
+	return k.encrypt(seed, newPassphrase, opts)
+}
+
+func (k *Keystore) encrypt(seed []byte, passphrase string, opts []KeystoreOption) error {
+	kdf := KeystoreKdf{Name: KDF_SCRYPT, N: 1 << 17, R: 8, P: 1}
+	for _, opt := range opts {
+		opt(&kdf)
+	}
+	kdf.Salt = make([]byte, 16)
+	if _, err := rand.Read(kdf.Salt); err != nil {
+		return err
+	}
+	updated := *k
+	updated.Kdf = kdf
+	gcm, err := updated.cipher(passphrase)
+	if err != nil {
+		return err
+	}
+	updated.Nonce = make([]byte, gcm.NonceSize())
+	if _, err := rand.Read(updated.Nonce); err != nil {
+		return err
+	}
+	aad, err := updated.additionalData()
+	if err != nil {
+		return err
+	}
+	updated.Ciphertext = gcm.Seal(nil, updated.Nonce, seed, aad)
+	*k = updated
+	return nil
+}
+
+func (k *Keystore) cipher(passphrase string) (cipher.AEAD, error) {
+	var key []byte
+	var err error
+	switch k.Kdf.Name {
+	case KDF_SCRYPT:
+		n, r, p := k.Kdf.N, k.Kdf.R, k.Kdf.P
+		// scrypt uses 128*N*r bytes of memory.
+		if n <= 1 || n&(n-1) != 0 || r <= 0 || p <= 0 || p > maxScryptP || n > maxKdfMemoryBytes/128/r {
+			return nil, errors.New("invalid scrypt parameters")
+		}
+		key, err = scrypt.Key([]byte(passphrase), k.Kdf.Salt, k.Kdf.N, k.Kdf.R, k.Kdf.P, KEY_LEN)
+		if err != nil {
+			return nil, err
+		}
+	case KDF_ARGON2ID:
+		if k.Kdf.Time == 0 || k.Kdf.Time > maxArgon2Time || k.Kdf.Memory == 0 || k.Kdf.Memory > maxKdfMemoryBytes/1024 ||
+			k.Kdf.Threads == 0 {
+			return nil, errors.New("invalid argon2id parameters")
+		}
+		key = argon2.IDKey([]byte(passphrase), k.Kdf.Salt, k.Kdf.Time, k.Kdf.Memory, k.Kdf.Threads, KEY_LEN)
+	default:
+		return nil, fmt.Errorf("unsupported keystore kdf %s", k.Kdf.Name)
+	}
+	defer clear(key)
+	block, err := aes.NewCipher(key)
+	if err != nil {
+		return nil, err
+	}
+	return cipher.NewGCM(block)
+}
+
+// additionalData authenticates the clear fields of the keystore.
+func (k *Keystore) additionalData() ([]byte, error) {
+	return json.Marshal(struct {
+		Version     int                    `json:"version"`
+		Network     objects.BitcoinNetwork `json:"network"`
+		Fingerprint string                 `json:"fingerprint"`
+		Kdf         KeystoreKdf            `json:"kdf"`
+		Cipher      string                 `json:"cipher"`
+	}{k.Version, k.Network, k.Fingerprint, k.Kdf, k.Cipher})
+}
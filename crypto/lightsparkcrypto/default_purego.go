@@ -0,0 +1,17 @@
+// Copyright ©, 2023-present, Lightspark Group, Inc. - All Rights Reserved
+
+//go:build !cgo || lightspark_purego
+
+package lightsparkcrypto
+
+// BitcoinNetwork is the network keys are serialized for.
+type BitcoinNetwork uint
+
+const (
+	Mainnet BitcoinNetwork = 1
+	Testnet BitcoinNetwork = 2
+	Regtest BitcoinNetwork = 3
+)
+
+// Default is the Backend of the package functions.
+var Default = PureGo
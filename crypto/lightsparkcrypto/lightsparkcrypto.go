@@ -0,0 +1,105 @@
+// Copyright ©, 2023-present, Lightspark Group, Inc. - All Rights Reserved
+
+// Package lightsparkcrypto provides the key operations of lightspark-crypto: ECDSA signing, ECDH, BIP 32
+// key derivation, per-commitment secrets and payment preimages.
+//
+// Two backends implement them. Uniffi binds the lightspark-crypto Rust library with cgo, and PureGo
+// implements the same operations in Go on top of btcec. The package functions use Default, which is
+// Uniffi unless the module is built with CGO_ENABLED=0 or the lightspark_purego build tag:
+//
+//	CGO_ENABLED=0 go build ./...
+//	go build -tags lightspark_purego ./...
+package lightsparkcrypto
+
+// SignedInvoice is a recoverable signature of an invoice hash.
+type SignedInvoice struct {
+	RecoveryId int32
+	Signature  []byte
+}
+
+// Backend implements the key operations of lightspark-crypto. Keys are derived from a master seed, with
+// the node key at m/0.
+type Backend interface {
+	// Ecdh returns the SHA256 of the shared point of the node key and a public key.
+	Ecdh(seedBytes []byte, network BitcoinNetwork, otherPubKey []byte) ([]byte, error)
+	// DerivePublicKey returns the extended public key at derivationPath, serialized for network.
+	DerivePublicKey(seedBytes []byte, network BitcoinNetwork, derivationPath string) (string, error)
+	// DerivePrivateKey returns the private key at derivationPath in hex.
+	DerivePrivateKey(seedBytes []byte, network BitcoinNetwork, derivationPath string) (string, error)
+	// DeriveKeyAndSign signs a message with the key at derivationPath, multiplied by multTweak and then
+	// added to addTweak when they are not empty. The message is hashed with SHA256 unless isRaw is set.
+	// The signature is compact.
+	DeriveKeyAndSign(seedBytes []byte, network BitcoinNetwork, message []byte, derivationPath string, isRaw bool, addTweak *[]byte, multTweak *[]byte) ([]byte, error)
+	// SignInvoiceHash signs the hash of an invoice with the node key.
+	SignInvoiceHash(seedBytes []byte, network BitcoinNetwork, unsignedInvoice []byte) (*SignedInvoice, error)
+	// GetPerCommitmentPoint returns the per-commitment point of a commitment of the channel at
+	// derivationPath.
+	GetPerCommitmentPoint(seedBytes []byte, network BitcoinNetwork, derivationPath string, perCommitmentPointIdx uint64) ([]byte, error)
+	// ReleasePerCommitmentSecret returns the per-commitment secret of a commitment of the channel at
+	// derivationPath.
+	ReleasePerCommitmentSecret(seedBytes []byte, network BitcoinNetwork, derivationPath string, perCommitmentPointIdx uint64) ([]byte, error)
+	// GeneratePreimageNonce returns a new random nonce.
+	GeneratePreimageNonce(seedBytes []byte) ([]byte, error)
+	// GeneratePreimage returns the payment preimage of a nonce.
+	GeneratePreimage(seedBytes []byte, nonce []byte) ([]byte, error)
+	// GeneratePreimageHash returns the payment hash of the preimage of a nonce.
+	GeneratePreimageHash(seedBytes []byte, nonce []byte) ([]byte, error)
+	// SignEcdsa returns the DER signature of the SHA256 of a message.
+	SignEcdsa(message []byte, privateKey []byte) ([]byte, error)
+	// VerifyEcdsa verifies a signature returned by SignEcdsa.
+	VerifyEcdsa(message []byte, signature []byte, publicKey []byte) (bool, error)
+	// GenerateMultiSigAddress returns the P2WSH address of the 2-of-2 multisig of two public keys.
+	GenerateMultiSigAddress(network BitcoinNetwork, publicKey1 []byte, publicKey2 []byte) (string, error)
+}
+
+func Ecdh(seedBytes []byte, network BitcoinNetwork, otherPubKey []byte) ([]byte, error) {
+	return Default.Ecdh(seedBytes, network, otherPubKey)
+}
+
+func DerivePublicKey(seedBytes []byte, network BitcoinNetwork, derivationPath string) (string, error) {
+	return Default.DerivePublicKey(seedBytes, network, derivationPath)
+}
+
+func DerivePrivateKey(seedBytes []byte, network BitcoinNetwork, derivationPath string) (string, error) {
+	return Default.DerivePrivateKey(seedBytes, network, derivationPath)
+}
+
+func DeriveKeyAndSign(seedBytes []byte, network BitcoinNetwork, message []byte, derivationPath string, isRaw bool, addTweak *[]byte, multTweak *[]byte) ([]byte, error) {
+	return Default.DeriveKeyAndSign(seedBytes, network, message, derivationPath, isRaw, addTweak, multTweak)
+}
+
+func SignInvoiceHash(seedBytes []byte, network BitcoinNetwork, unsignedInvoice []byte) (*SignedInvoice, error) {
+	return Default.SignInvoiceHash(seedBytes, network, unsignedInvoice)
+}
+
+func GetPerCommitmentPoint(seedBytes []byte, network BitcoinNetwork, derivationPath string, perCommitmentPointIdx uint64) ([]byte, error) {
+	return Default.GetPerCommitmentPoint(seedBytes, network, derivationPath, perCommitmentPointIdx)
+}
+
+func ReleasePerCommitmentSecret(seedBytes []byte, network BitcoinNetwork, derivationPath string, perCommitmentPointIdx uint64) ([]byte, error) {
+	return Default.ReleasePerCommitmentSecret(seedBytes, network, derivationPath, perCommitmentPointIdx)
+}
+
+func GeneratePreimageNonce(seedBytes []byte) ([]byte, error) {
+	return Default.GeneratePreimageNonce(seedBytes)
+}
+
+func GeneratePreimage(seedBytes []byte, nonce []byte) ([]byte, error) {
+	return Default.GeneratePreimage(seedBytes, nonce)
+}
+
+func GeneratePreimageHash(seedBytes []byte, nonce []byte) ([]byte, error) {
+	return Default.GeneratePreimageHash(seedBytes, nonce)
+}
+
+func SignEcdsa(message []byte, privateKey []byte) ([]byte, error) {
+	return Default.SignEcdsa(message, privateKey)
+}
+
+func VerifyEcdsa(message []byte, signature []byte, publicKey []byte) (bool, error) {
+	return Default.VerifyEcdsa(message, signature, publicKey)
+}
+
+func GenerateMultiSigAddress(network BitcoinNetwork, publicKey1 []byte, publicKey2 []byte) (string, error) {
+	return Default.GenerateMultiSigAddress(network, publicKey1, publicKey2)
+}
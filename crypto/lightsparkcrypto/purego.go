@@ -0,0 +1,332 @@
+// Copyright ©, 2023-present, Lightspark Group, Inc. - All Rights Reserved
+package lightsparkcrypto
+
+import (
+	"bytes"
+	"crypto/hmac"
+	"crypto/rand"
+	"crypto/sha256"
+	"crypto/sha512"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"strconv"
+	"strings"
+
+	"github.com/btcsuite/btcd/btcec/v2"
+	"github.com/btcsuite/btcd/btcec/v2/ecdsa"
+	"github.com/btcsuite/btcd/btcutil"
+	"github.com/btcsuite/btcd/btcutil/hdkeychain"
+	"github.com/btcsuite/btcd/chaincfg"
+	"github.com/btcsuite/btcd/txscript"
+)
+
+// PureGo is the Backend implemented in Go, without cgo.
+var PureGo Backend = pureGo{}
+
+const (
+	nodeKeyPath         = "m/0"
+	preimageBaseKeyPath = "m/4'"
+	// commitmentIndexBits is the number of bits of the per-commitment index used to build the
+	// per-commitment secret, as specified in BOLT 3.
+	commitmentIndexBits = 48
+)
+
+type pureGo struct{}
+
+func (pureGo) Ecdh(seedBytes []byte, network BitcoinNetwork, otherPubKey []byte) ([]byte, error) {
+	publicKey, err := btcec.ParsePubKey(otherPubKey)
+	if err != nil {
+		return nil, err
+	}
+	key, err := derivePrivateKey(seedBytes, network, nodeKeyPath)
+	if err != nil {
+		return nil, err
+	}
+	defer key.Zero()
+
+	var point btcec.JacobianPoint
+	publicKey.AsJacobian(&point)
+	btcec.ScalarMultNonConst(&key.Key, &point, &point)
+	point.ToAffine()
+	sharedSecret := sha256.Sum256(btcec.NewPublicKey(&point.X, &point.Y).SerializeCompressed())
+	return sharedSecret[:], nil
+}
+
+func (pureGo) DerivePublicKey(seedBytes []byte, network BitcoinNetwork, derivationPath string) (string, error) {
+	extendedKey, err := deriveExtendedKey(seedBytes, network, derivationPath)
+	if err != nil {
+		return "", err
+	}
+	defer extendedKey.Zero()
+	publicKey, err := extendedKey.Neuter()
+	if err != nil {
+		return "", err
+	}
+	return publicKey.String(), nil
+}
+
+func (pureGo) DerivePrivateKey(seedBytes []byte, network BitcoinNetwork, derivationPath string) (string, error) {
+	key, err := derivePrivateKey(seedBytes, network, derivationPath)
+	if err != nil {
+		return "", err
+	}
+	defer key.Zero()
+	return hex.EncodeToString(key.Serialize()), nil
+}
+
+func (pureGo) DeriveKeyAndSign(seedBytes []byte, network BitcoinNetwork, message []byte, derivationPath string, isRaw bool, addTweak *[]byte, multTweak *[]byte) ([]byte, error) {
+	key, err := derivePrivateKey(seedBytes, network, derivationPath)
+	if err != nil {
+		return nil, err
+	}
+	defer key.Zero()
+
+	var tweaked btcec.ModNScalar
+	tweaked.Set(&key.Key)
+	defer tweaked.Zero()
+	if multTweak != nil && len(*multTweak) > 0 {
+		tweak, err := parseTweak(*multTweak)
+		if err != nil {
+			return nil, err
+		}
+		if tweak.IsZero() {
+			return nil, errors.New("invalid tweak")
+		}
+		tweaked.Mul(&tweak)
+	}
+	if addTweak != nil && len(*addTweak) > 0 {
+		tweak, err := parseTweak(*addTweak)
+		if err != nil {
+			return nil, err
+		}
+		tweaked.Add(&tweak)
+	}
+	if tweaked.IsZero() {
+		return nil, errors.New("invalid tweak")
+	}
+
+	if !isRaw {
+		hash := sha256.Sum256(message)
+		message = hash[:]
+	}
+	signature, err := signCompact(btcec.PrivKeyFromScalar(&tweaked), message)
+	if err != nil {
+		return nil, err
+	}
+	return signature[1:], nil
+}
+
+func (pureGo) SignInvoiceHash(seedBytes []byte, network BitcoinNetwork, unsignedInvoice []byte) (*SignedInvoice, error) {
+	key, err := derivePrivateKey(seedBytes, network, nodeKeyPath)
+	if err != nil {
+		return nil, err
+	}
+	defer key.Zero()
+	signature, err := signCompact(key, unsignedInvoice)
+	if err != nil {
+		return nil, err
+	}
+	// The header byte of a compact signature is 27 + 4 for compressed keys + the recovery ID.
+	return &SignedInvoice{RecoveryId: int32(signature[0] - 31), Signature: signature[1:]}, nil
+}
+
+func (p pureGo) GetPerCommitmentPoint(seedBytes []byte, network BitcoinNetwork, derivationPath string, perCommitmentPointIdx uint64) ([]byte, error) {
+	secret, err := p.ReleasePerCommitmentSecret(seedBytes, network, derivationPath, perCommitmentPointIdx)
+	if err != nil {
+		return nil, err
+	}
+	defer clear(secret)
+	key, publicKey := btcec.PrivKeyFromBytes(secret)
+	defer key.Zero()
+	return publicKey.SerializeCompressed(), nil
+}
+
+func (pureGo) ReleasePerCommitmentSecret(seedBytes []byte, network BitcoinNetwork, derivationPath string, perCommitmentPointIdx uint64) ([]byte, error) {
+	key, err := derivePrivateKey(seedBytes, network, derivationPath)
+	if err != nil {
+		return nil, err
+	}
+	defer key.Zero()
+
+	keyHash := sha256.Sum256(key.Serialize())
+	commitmentSeed := sha256.Sum256(append(keyHash[:], "commitment seed"...))
+	// Build the per-commitment secret as specified in BOLT 3, flipping the bits of the index from the
+	// most significant one and hashing after every flip.
+	secret := commitmentSeed
+	for bit := commitmentIndexBits - 1; bit >= 0; bit-- {
+		if perCommitmentPointIdx&(1<<uint(bit)) != 0 {
+			secret[bit/8] ^= 1 << uint(bit%8)
+			secret = sha256.Sum256(secret[:])
+		}
+	}
+	return secret[:], nil
+}
+
+func (pureGo) GeneratePreimageNonce(seedBytes []byte) ([]byte, error) {
+	nonce := make([]byte, 32)
+	if _, err := rand.Read(nonce); err != nil {
+		return nil, err
+	}
+	return nonce, nil
+}
+
+func (pureGo) GeneratePreimage(seedBytes []byte, nonce []byte) ([]byte, error) {
+	return generatePreimage(seedBytes, nonce)
+}
+
+func (pureGo) GeneratePreimageHash(seedBytes []byte, nonce []byte) ([]byte, error) {
+	preimage, err := generatePreimage(seedBytes, nonce)
+	if err != nil {
+		return nil, err
+	}
+	paymentHash := sha256.Sum256(preimage)
+	return paymentHash[:], nil
+}
+
+func (pureGo) SignEcdsa(message []byte, privateKey []byte) ([]byte, error) {
+	key, err := parsePrivateKey(privateKey)
+	if err != nil {
+		return nil, err
+	}
+	defer key.Zero()
+	hash := sha256.Sum256(message)
+	return ecdsa.Sign(key, hash[:]).Serialize(), nil
+}
+
+func (pureGo) VerifyEcdsa(message []byte, signature []byte, publicKey []byte) (bool, error) {
+	key, err := btcec.ParsePubKey(publicKey)
+	if err != nil {
+		return false, err
+	}
+	parsed, err := ecdsa.ParseDERSignature(signature)
+	if err != nil {
+		return false, err
+	}
+	// Like libsecp256k1, only accept signatures with a low S, which serialize back to themselves.
+	if !bytes.Equal(parsed.Serialize(), signature) {
+		return false, nil
+	}
+	hash := sha256.Sum256(message)
+	return parsed.Verify(hash[:], key), nil
+}
+
+func (pureGo) GenerateMultiSigAddress(network BitcoinNetwork, publicKey1 []byte, publicKey2 []byte) (string, error) {
+	var keys [][]byte
+	for _, publicKey := range [][]byte{publicKey1, publicKey2} {
+		key, err := btcec.ParsePubKey(publicKey)
+		if err != nil {
+			return "", err
+		}
+		keys = append(keys, key.SerializeCompressed())
+	}
+	// Sort the keys so that the address doesn't depend on their order, as in BIP 67.
+	if bytes.Compare(keys[0], keys[1]) > 0 {
+		keys[0], keys[1] = keys[1], keys[0]
+	}
+	script, err := txscript.NewScriptBuilder().
+		AddOp(txscript.OP_2).
+		AddData(keys[0]).
+		AddData(keys[1]).
+		AddOp(txscript.OP_2).
+		AddOp(txscript.OP_CHECKMULTISIG).
+		Script()
+	if err != nil {
+		return "", err
+	}
+	scriptHash := sha256.Sum256(script)
+	address, err := btcutil.NewAddressWitnessScriptHash(scriptHash[:], chainParams(network))
+	if err != nil {
+		return "", err
+	}
+	return address.EncodeAddress(), nil
+}
+
+func chainParams(network BitcoinNetwork) *chaincfg.Params {
+	switch network {
+	case Testnet:
+		return &chaincfg.TestNet3Params
+	case Regtest:
+		return &chaincfg.RegressionNetParams
+	default:
+		return &chaincfg.MainNetParams
+	}
+}
+
+// deriveExtendedKey derives the BIP 32 extended key at a derivation path like m/0'/1h/2.
+func deriveExtendedKey(seedBytes []byte, network BitcoinNetwork, derivationPath string) (*hdkeychain.ExtendedKey, error) {
+	components := strings.Split(derivationPath, "/")
+	if components[0] != "m" {
+		return nil, fmt.Errorf("invalid derivation path %q", derivationPath)
+	}
+	key, err := hdkeychain.NewMaster(seedBytes, chainParams(network))
+	if err != nil {
+		return nil, err
+	}
+	for _, component := range components[1:] {
+		var hardened uint32
+		if trimmed, ok := strings.CutSuffix(component, "'"); ok {
+			component, hardened = trimmed, hdkeychain.HardenedKeyStart
+		} else if trimmed, ok := strings.CutSuffix(component, "h"); ok {
+			component, hardened = trimmed, hdkeychain.HardenedKeyStart
+		}
+		index, err := strconv.ParseUint(component, 10, 31)
+		if err != nil {
+			key.Zero()
+			return nil, fmt.Errorf("invalid derivation path %q", derivationPath)
+		}
+		child, err := key.Derive(uint32(index) + hardened)
+		key.Zero()
+		if err != nil {
+			return nil, err
+		}
+		key = child
+	}
+	return key, nil
+}
+
+func derivePrivateKey(seedBytes []byte, network BitcoinNetwork, derivationPath string) (*btcec.PrivateKey, error) {
+	extendedKey, err := deriveExtendedKey(seedBytes, network, derivationPath)
+	if err != nil {
+		return nil, err
+	}
+	defer extendedKey.Zero()
+	return extendedKey.ECPrivKey()
+}
+
+func parsePrivateKey(privateKey []byte) (*btcec.PrivateKey, error) {
+	var scalar btcec.ModNScalar
+	if len(privateKey) != 32 || scalar.SetByteSlice(privateKey) || scalar.IsZero() {
+		return nil, errors.New("invalid private key")
+	}
+	return btcec.PrivKeyFromScalar(&scalar), nil
+}
+
+func parseTweak(tweak []byte) (btcec.ModNScalar, error) {
+	var scalar btcec.ModNScalar
+	if len(tweak) != 32 || scalar.SetByteSlice(tweak) {
+		return scalar, errors.New("invalid tweak")
+	}
+	return scalar, nil
+}
+
+func signCompact(key *btcec.PrivateKey, hash []byte) ([]byte, error) {
+	if len(hash) != 32 {
+		return nil, errors.New("message was not 32 bytes")
+	}
+	return ecdsa.SignCompact(key, hash, true)
+}
+
+// generatePreimage returns the HMAC-SHA512 of the nonce, truncated to 32 bytes, keyed by the key at
+// m/4'.
+func generatePreimage(seedBytes []byte, nonce []byte) ([]byte, error) {
+	key, err := derivePrivateKey(seedBytes, Mainnet, preimageBaseKeyPath)
+	if err != nil {
+		return nil, err
+	}
+	defer key.Zero()
+	mac := hmac.New(sha512.New, key.Serialize())
+	mac.Write([]byte("invoice preimage"))
+	mac.Write(nonce)
+	return mac.Sum(nil)[:32], nil
+}
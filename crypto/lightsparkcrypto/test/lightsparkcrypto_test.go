@@ -0,0 +1,229 @@
+//go:build cgo
+
+package lightsparkcrypto_test
+
+import (
+	"bytes"
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/binary"
+	"encoding/hex"
+	"fmt"
+	"testing"
+
+	"github.com/btcsuite/btcd/btcec/v2"
+	"github.com/lightsparkdev/go-sdk/crypto/lightsparkcrypto"
+	lightspark_crypto "github.com/lightsparkdev/lightspark-crypto-uniffi/lightspark-crypto-go"
+	"github.com/stretchr/testify/require"
+)
+
+// The pure Go backend is checked against the lightspark-crypto library, whatever the default backend.
+
+var pureGo = lightsparkcrypto.PureGo
+
+var networks = []lightsparkcrypto.BitcoinNetwork{lightsparkcrypto.Mainnet, lightsparkcrypto.Testnet, lightsparkcrypto.Regtest}
+
+var derivationPaths = []string{"m", "m/0", "m/3/599143572/0", "m/0'/1h/2", "m/84'/1'/0'/1/7", "m/2147483647'"}
+
+func randomBytes(t *testing.T, n int) []byte {
+	b := make([]byte, n)
+	_, err := rand.Read(b)
+	require.NoError(t, err)
+	return b
+}
+
+func randomSeeds(t *testing.T) [][]byte {
+	seeds := [][]byte{bytes.Repeat([]byte{0}, 16)}
+	for _, size := range []int{16, 32, 64} {
+		seeds = append(seeds, randomBytes(t, size))
+	}
+	return seeds
+}
+
+func TestDeriveKeys(t *testing.T) {
+	for _, seed := range randomSeeds(t) {
+		for _, network := range networks {
+			for _, path := range derivationPaths {
+				expected, err := lightspark_crypto.DerivePrivateKey(seed, lightspark_crypto.BitcoinNetwork(network), path)
+				require.NoError(t, err)
+				privateKey, err := pureGo.DerivePrivateKey(seed, network, path)
+				require.NoError(t, err)
+				require.Equal(t, expected, privateKey, path)
+
+				expected, err = lightspark_crypto.DerivePublicKey(seed, lightspark_crypto.BitcoinNetwork(network), path)
+				require.NoError(t, err)
+				publicKey, err := pureGo.DerivePublicKey(seed, network, path)
+				require.NoError(t, err)
+				require.Equal(t, expected, publicKey, path)
+			}
+		}
+	}
+
+	seed := randomBytes(t, 32)
+	for _, path := range []string{"", "0/1", "m/x", "m/1/", "m/2147483648"} {
+		_, err := pureGo.DerivePrivateKey(seed, lightsparkcrypto.Regtest, path)
+		require.Error(t, err, path)
+	}
+}
+
+func TestEcdh(t *testing.T) {
+	for _, seed := range randomSeeds(t) {
+		peer, err := btcec.NewPrivateKey()
+		require.NoError(t, err)
+		for _, publicKey := range [][]byte{peer.PubKey().SerializeCompressed(), peer.PubKey().SerializeUncompressed()} {
+			expected, err := lightspark_crypto.Ecdh(seed, lightspark_crypto.Regtest, publicKey)
+			require.NoError(t, err)
+			sharedSecret, err := pureGo.Ecdh(seed, lightsparkcrypto.Regtest, publicKey)
+			require.NoError(t, err)
+			require.Equal(t, expected, sharedSecret)
+		}
+	}
+
+	_, err := pureGo.Ecdh(randomBytes(t, 32), lightsparkcrypto.Regtest, []byte{2, 1})
+	require.Error(t, err)
+}
+
+func TestDeriveKeyAndSign(t *testing.T) {
+	seed := randomBytes(t, 32)
+	message := sha256.Sum256([]byte("Hello Crypto World"))
+	tweaks := [][]byte{nil, {}, randomBytes(t, 32)}
+	for _, path := range derivationPaths {
+		for _, addTweak := range tweaks {
+			for _, mulTweak := range tweaks {
+				expected, err := lightspark_crypto.DeriveKeyAndSign(seed, lightspark_crypto.Regtest, message[:], path, true, &addTweak, &mulTweak)
+				require.NoError(t, err)
+				signature, err := pureGo.DeriveKeyAndSign(seed, lightsparkcrypto.Regtest, message[:], path, true, &addTweak, &mulTweak)
+				require.NoError(t, err)
+				require.Equal(t, expected, signature, path)
+			}
+		}
+
+		expected, err := lightspark_crypto.DeriveKeyAndSign(seed, lightspark_crypto.Regtest, []byte("unhashed"), path, false, nil, nil)
+		require.NoError(t, err)
+		signature, err := pureGo.DeriveKeyAndSign(seed, lightsparkcrypto.Regtest, []byte("unhashed"), path, false, nil, nil)
+		require.NoError(t, err)
+		require.Equal(t, expected, signature, path)
+	}
+
+	_, err := pureGo.DeriveKeyAndSign(seed, lightsparkcrypto.Regtest, message[:31], "m/0", true, nil, nil)
+	require.Error(t, err)
+	invalidTweak := bytes.Repeat([]byte{0xff}, 32)
+	_, err = pureGo.DeriveKeyAndSign(seed, lightsparkcrypto.Regtest, message[:], "m/0", true, &invalidTweak, nil)
+	require.Error(t, err)
+}
+
+func TestSignInvoiceHash(t *testing.T) {
+	for _, seed := range randomSeeds(t) {
+		hash := randomBytes(t, 32)
+		expected, err := lightspark_crypto.SignInvoiceHash(seed, lightspark_crypto.Mainnet, hash)
+		require.NoError(t, err)
+		signed, err := pureGo.SignInvoiceHash(seed, lightsparkcrypto.Mainnet, hash)
+		require.NoError(t, err)
+		require.Equal(t, expected.Signature, signed.Signature)
+		require.Equal(t, expected.RecoveryId, signed.RecoveryId)
+	}
+}
+
+func TestPerCommitmentSecrets(t *testing.T) {
+	seed := randomBytes(t, 32)
+	indexes := []uint64{0, 1, 2, 5, 1<<47 + 3, 1<<48 - 1}
+	for i := 0; i < 4; i++ {
+		indexes = append(indexes, binary.BigEndian.Uint64(randomBytes(t, 8))>>16)
+	}
+	for _, path := range derivationPaths {
+		for _, index := range indexes {
+			name := fmt.Sprintf("%s %d", path, index)
+			expected, err := lightspark_crypto.ReleasePerCommitmentSecret(seed, lightspark_crypto.Regtest, path, index)
+			require.NoError(t, err)
+			secret, err := pureGo.ReleasePerCommitmentSecret(seed, lightsparkcrypto.Regtest, path, index)
+			require.NoError(t, err)
+			require.Equal(t, expected, secret, name)
+
+			expected, err = lightspark_crypto.GetPerCommitmentPoint(seed, lightspark_crypto.Regtest, path, index)
+			require.NoError(t, err)
+			point, err := pureGo.GetPerCommitmentPoint(seed, lightsparkcrypto.Regtest, path, index)
+			require.NoError(t, err)
+			require.Equal(t, expected, point, name)
+		}
+	}
+}
+
+func TestPreimages(t *testing.T) {
+	for _, seed := range randomSeeds(t) {
+		nonce, err := pureGo.GeneratePreimageNonce(seed)
+		require.NoError(t, err)
+		require.Len(t, nonce, 32)
+
+		expected, err := lightspark_crypto.GeneratePreimage(seed, nonce)
+		require.NoError(t, err)
+		preimage, err := pureGo.GeneratePreimage(seed, nonce)
+		require.NoError(t, err)
+		require.Equal(t, expected, preimage)
+
+		expected, err = lightspark_crypto.GeneratePreimageHash(seed, nonce)
+		require.NoError(t, err)
+		paymentHash, err := pureGo.GeneratePreimageHash(seed, nonce)
+		require.NoError(t, err)
+		require.Equal(t, expected, paymentHash)
+		hash := sha256.Sum256(preimage)
+		require.Equal(t, hash[:], paymentHash)
+	}
+}
+
+func TestEcdsa(t *testing.T) {
+	for i := 0; i < 8; i++ {
+		key, err := btcec.NewPrivateKey()
+		require.NoError(t, err)
+		message := randomBytes(t, 100)
+
+		expected, err := lightspark_crypto.SignEcdsa(message, key.Serialize())
+		require.NoError(t, err)
+		signature, err := pureGo.SignEcdsa(message, key.Serialize())
+		require.NoError(t, err)
+		require.Equal(t, expected, signature)
+
+		for _, publicKey := range [][]byte{key.PubKey().SerializeCompressed(), key.PubKey().SerializeUncompressed()} {
+			valid, err := pureGo.VerifyEcdsa(message, signature, publicKey)
+			require.NoError(t, err)
+			require.True(t, valid)
+		}
+		valid, err := pureGo.VerifyEcdsa(append(message, 0), signature, key.PubKey().SerializeCompressed())
+		require.NoError(t, err)
+		require.False(t, valid)
+		_, err = pureGo.VerifyEcdsa(message, signature[:10], key.PubKey().SerializeCompressed())
+		require.Error(t, err)
+	}
+
+	_, err := pureGo.SignEcdsa([]byte("message"), make([]byte, 32))
+	require.Error(t, err)
+}
+
+func TestGenerateMultiSigAddress(t *testing.T) {
+	for _, network := range networks {
+		key1, err := btcec.NewPrivateKey()
+		require.NoError(t, err)
+		key2, err := btcec.NewPrivateKey()
+		require.NoError(t, err)
+		publicKey1, publicKey2 := key1.PubKey().SerializeCompressed(), key2.PubKey().SerializeCompressed()
+
+		expected, err := lightspark_crypto.GenerateMultiSigAddress(lightspark_crypto.BitcoinNetwork(network), publicKey1, publicKey2)
+		require.NoError(t, err)
+		address, err := pureGo.GenerateMultiSigAddress(network, publicKey1, publicKey2)
+		require.NoError(t, err)
+		require.Equal(t, expected, address)
+		address, err = pureGo.GenerateMultiSigAddress(network, publicKey2, publicKey1)
+		require.NoError(t, err)
+		require.Equal(t, expected, address)
+	}
+}
+
+func TestDefaultBackend(t *testing.T) {
+	// The package functions use the default backend, which must agree with lightspark-crypto too.
+	seed, err := hex.DecodeString("000102030405060708090a0b0c0d0e0f")
+	require.NoError(t, err)
+	expected, err := lightspark_crypto.DerivePrivateKey(seed, lightspark_crypto.Regtest, "m/5")
+	require.NoError(t, err)
+	privateKey, err := lightsparkcrypto.DerivePrivateKey(seed, lightsparkcrypto.Regtest, "m/5")
+	require.NoError(t, err)
+	require.Equal(t, expected, privateKey)
+}
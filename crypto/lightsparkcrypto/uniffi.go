@@ -0,0 +1,82 @@
+// Copyright ©, 2023-present, Lightspark Group, Inc. - All Rights Reserved
+
+//go:build cgo && !lightspark_purego
+
+package lightsparkcrypto
+
+import (
+	lightspark_crypto "github.com/lightsparkdev/lightspark-crypto-uniffi/lightspark-crypto-go"
+)
+
+// BitcoinNetwork is the network keys are serialized for.
+type BitcoinNetwork = lightspark_crypto.BitcoinNetwork
+
+const (
+	Mainnet = lightspark_crypto.Mainnet
+	Testnet = lightspark_crypto.Testnet
+	Regtest = lightspark_crypto.Regtest
+)
+
+// Uniffi is the Backend binding the lightspark-crypto Rust library.
+var Uniffi Backend = uniffi{}
+
+// Default is the Backend of the package functions.
+var Default = Uniffi
+
+type uniffi struct{}
+
+func (uniffi) Ecdh(seedBytes []byte, network BitcoinNetwork, otherPubKey []byte) ([]byte, error) {
+	return lightspark_crypto.Ecdh(seedBytes, network, otherPubKey)
+}
+
+func (uniffi) DerivePublicKey(seedBytes []byte, network BitcoinNetwork, derivationPath string) (string, error) {
+	return lightspark_crypto.DerivePublicKey(seedBytes, network, derivationPath)
+}
+
+func (uniffi) DerivePrivateKey(seedBytes []byte, network BitcoinNetwork, derivationPath string) (string, error) {
+	return lightspark_crypto.DerivePrivateKey(seedBytes, network, derivationPath)
+}
+
+func (uniffi) DeriveKeyAndSign(seedBytes []byte, network BitcoinNetwork, message []byte, derivationPath string, isRaw bool, addTweak *[]byte, multTweak *[]byte) ([]byte, error) {
+	return lightspark_crypto.DeriveKeyAndSign(seedBytes, network, message, derivationPath, isRaw, addTweak, multTweak)
+}
+
+func (uniffi) SignInvoiceHash(seedBytes []byte, network BitcoinNetwork, unsignedInvoice []byte) (*SignedInvoice, error) {
+	signed, err := lightspark_crypto.SignInvoiceHash(seedBytes, network, unsignedInvoice)
+	if err != nil {
+		return nil, err
+	}
+	return &SignedInvoice{RecoveryId: signed.RecoveryId, Signature: signed.Signature}, nil
+}
+
+func (uniffi) GetPerCommitmentPoint(seedBytes []byte, network BitcoinNetwork, derivationPath string, perCommitmentPointIdx uint64) ([]byte, error) {
+	return lightspark_crypto.GetPerCommitmentPoint(seedBytes, network, derivationPath, perCommitmentPointIdx)
+}
+
+func (uniffi) ReleasePerCommitmentSecret(seedBytes []byte, network BitcoinNetwork, derivationPath string, perCommitmentPointIdx uint64) ([]byte, error) {
+	return lightspark_crypto.ReleasePerCommitmentSecret(seedBytes, network, derivationPath, perCommitmentPointIdx)
+}
+
+func (uniffi) GeneratePreimageNonce(seedBytes []byte) ([]byte, error) {
+	return lightspark_crypto.GeneratePreimageNonce(seedBytes)
+}
+
+func (uniffi) GeneratePreimage(seedBytes []byte, nonce []byte) ([]byte, error) {
+	return lightspark_crypto.GeneratePreimage(seedBytes, nonce)
+}
+
+func (uniffi) GeneratePreimageHash(seedBytes []byte, nonce []byte) ([]byte, error) {
+	return lightspark_crypto.GeneratePreimageHash(seedBytes, nonce)
+}
+
+func (uniffi) SignEcdsa(message []byte, privateKey []byte) ([]byte, error) {
+	return lightspark_crypto.SignEcdsa(message, privateKey)
+}
+
+func (uniffi) VerifyEcdsa(message []byte, signature []byte, publicKey []byte) (bool, error) {
+	return lightspark_crypto.VerifyEcdsa(message, signature, publicKey)
+}
+
+func (uniffi) GenerateMultiSigAddress(network BitcoinNetwork, publicKey1 []byte, publicKey2 []byte) (string, error) {
+	return lightspark_crypto.GenerateMultiSigAddress(network, publicKey1, publicKey2)
+}
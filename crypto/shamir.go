@@ -0,0 +1,341 @@
+// Copyright ©, 2023-present, Lightspark Group, Inc. - All Rights Reserved
+package crypto
+
+import (
+	"bytes"
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"math/big"
+)
+
+// Shamir secret sharing of master seeds, modeled after SLIP 39: the secret is split into groups with a
+// group threshold, and the share of every group is split again into member shares with a member
+// threshold. The secret is recovered from the member thresholds of any group threshold groups.
+//
+// Shares are encoded as words of the BIP 39 wordlist, with a checksum detecting mistyped words. The
+// shares are not compatible with SLIP 39 wallets.
+
+const (
+	shamirShareVersion = 0
+	// shamirHeaderLen is the length of the share header: version, identifier, group index, group threshold,
+	// group count, member index, member threshold and value length.
+	shamirHeaderLen      = 9
+	shamirChecksumLen    = 4
+	shamirDigestLen      = 4
+	shamirMaxShareCount  = 16
+	shamirMaxSecretLen   = 64
+	shamirMinSecretLen   = 16
+	shamirWordBits       = 11
+	shamirWordIndexLimit = 1 << shamirWordBits
+)
+
+var (
+	ErrInvalidShareChecksum = errors.New("invalid share checksum")
+	ErrShareMismatch        = errors.New("shares are not from the same split")
+	ErrNotEnoughShares      = errors.New("not enough shares to recover the secret")
+	ErrInvalidSecretDigest  = errors.New("recovered secret doesn't match its digest")
+)
+
+// ShamirGroup is the member threshold and member count of a group.
+type ShamirGroup struct {
+	Threshold int
+	Count     int
+}
+
+// ShamirShare is the share of a member of a group.
+type ShamirShare struct {
+	// Identifier is random and common to every share of a split.
+	Identifier      uint16
+	GroupIndex      uint8
+	GroupThreshold  uint8
+	GroupCount      uint8
+	MemberIndex     uint8
+	MemberThreshold uint8
+	Value           []byte
+}
+
+// SplitSecret splits a secret of 16 to 64 bytes so that it can be recovered from the shares of
+// groupThreshold of the groups. The shares are returned by group.
+func SplitSecret(secret []byte, groupThreshold int, groups []ShamirGroup) ([][]ShamirShare, error) {
+	if len(secret) < shamirMinSecretLen || len(secret) > shamirMaxSecretLen {
+		return nil, fmt.Errorf("secret must have %d to %d bytes", shamirMinSecretLen, shamirMaxSecretLen)
+	}
+	if err := checkThreshold(groupThreshold, len(groups)); err != nil {
+		return nil, fmt.Errorf("groups: %w", err)
+	}
+	for i, group := range groups {
+		if err := checkThreshold(group.Threshold, group.Count); err != nil {
+			return nil, fmt.Errorf("group %d: %w", i+1, err)
+		}
+	}
+
+	var identifier [2]byte
+	if _, err := rand.Read(identifier[:]); err != nil {
+		return nil, err
+	}
+	digest := sha256.Sum256(secret)
+	value := append(append([]byte(nil), secret...), digest[:shamirDigestLen]...)
+	groupValues, err := splitValue(value, groupThreshold, len(groups))
+	if err != nil {
+		return nil, err
+	}
+
+	shares := make([][]ShamirShare, len(groups))
+	for i, group := range groups {
+		memberValues, err := splitValue(groupValues[i], group.Threshold, group.Count)
+		if err != nil {
+			return nil, err
+		}
+		for j, memberValue := range memberValues {
+			shares[i] = append(shares[i], ShamirShare{
+				Identifier:      binary.BigEndian.Uint16(identifier[:]),
+				GroupIndex:      uint8(i),
+				GroupThreshold:  uint8(groupThreshold),
+				GroupCount:      uint8(len(groups)),
+				MemberIndex:     uint8(j),
+				MemberThreshold: uint8(group.Threshold),
+				Value:           memberValue,
+			})
+		}
+	}
+	return shares, nil
+}
+
+// CombineShares recovers the secret of a split from its shares. Extra shares are ignored.
+func CombineShares(shares []ShamirShare) ([]byte, error) {
+	if len(shares) == 0 {
+		return nil, ErrNotEnoughShares
+	}
+	first := shares[0]
+	groups := map[uint8]map[uint8][]byte{}
+	memberThresholds := map[uint8]uint8{}
+	for _, share := range shares {
+		if err := share.check(); err != nil {
+			return nil, err
+		}
+		if share.Identifier != first.Identifier || share.GroupThreshold != first.GroupThreshold ||
+			share.GroupCount != first.GroupCount || len(share.Value) != len(first.Value) {
+			return nil, ErrShareMismatch
+		}
+		if threshold, ok := memberThresholds[share.GroupIndex]; ok && threshold != share.MemberThreshold {
+			return nil, ErrShareMismatch
+		}
+		memberThresholds[share.GroupIndex] = share.MemberThreshold
+		if groups[share.GroupIndex] == nil {
+			groups[share.GroupIndex] = map[uint8][]byte{}
+		}
+		if previous, ok := groups[share.GroupIndex][share.MemberIndex]; ok && !bytes.Equal(previous, share.Value) {
+			return nil, ErrShareMismatch
+		}
+		groups[share.GroupIndex][share.MemberIndex] = share.Value
+	}
+
+	groupValues := map[uint8][]byte{}
+	for groupIndex, members := range groups {
+		if len(members) < int(memberThresholds[groupIndex]) {
+			continue
+		}
+		groupValues[groupIndex] = combineValues(members, int(memberThresholds[groupIndex]))
+	}
+	if len(groupValues) < int(first.GroupThreshold) {
+		return nil, ErrNotEnoughShares
+	}
+	value := combineValues(groupValues, int(first.GroupThreshold))
+
+	secret, digest := value[:len(value)-shamirDigestLen], value[len(value)-shamirDigestLen:]
+	expected := sha256.Sum256(secret)
+	if !bytes.Equal(digest, expected[:shamirDigestLen]) {
+		return nil, ErrInvalidSecretDigest
+	}
+	return secret, nil
+}
+
+// Mnemonic encodes the share as words of the BIP 39 wordlist.
+func (s ShamirShare) Mnemonic() []string {
+	data := []byte{
+		shamirShareVersion,
+		byte(s.Identifier >> 8),
+		byte(s.Identifier),
+		s.GroupIndex,
+		s.GroupThreshold,
+		s.GroupCount,
+		s.MemberIndex,
+		s.MemberThreshold,
+		byte(len(s.Value)),
+	}
+	data = append(data, s.Value...)
+	checksum := sha256.Sum256(data)
+	data = append(data, checksum[:shamirChecksumLen]...)
+
+	wordCount := (len(data)*8 + shamirWordBits - 1) / shamirWordBits
+	bits := new(big.Int).SetBytes(data)
+	bits.Lsh(bits, uint(wordCount*shamirWordBits-len(data)*8))
+	words := make([]string, wordCount)
+	index := new(big.Int)
+	mask := big.NewInt(shamirWordIndexLimit - 1)
+	for i := wordCount - 1; i >= 0; i-- {
+		index.And(bits, mask)
+		words[i] = bip39Words[index.Int64()]
+		bits.Rsh(bits, shamirWordBits)
+	}
+	return words
+}
+
+// ParseShamirShare decodes a share encoded by ShamirShare.Mnemonic, checking its checksum.
+func ParseShamirShare(words []string) (ShamirShare, error) {
+	bits := new(big.Int)
+	for _, word := range words {
+		index, ok := bip39Indexes[word]
+		if !ok {
+			return ShamirShare{}, fmt.Errorf("%w: %q", ErrInvalidMnemonicWord, word)
+		}
+		bits.Lsh(bits, shamirWordBits)
+		bits.Or(bits, big.NewInt(int64(index)))
+	}
+	totalBits := len(words) * shamirWordBits
+	if totalBits < (shamirHeaderLen+shamirChecksumLen)*8 {
+		return ShamirShare{}, errors.New("share is too short")
+	}
+	// The header gives the length of the share, and the remaining bits are padding.
+	valueLen := int(new(big.Int).Rsh(bits, uint(totalBits-shamirHeaderLen*8)).Int64() & 0xff)
+	dataLen := shamirHeaderLen + valueLen + shamirChecksumLen
+	padding := totalBits - dataLen*8
+	if padding < 0 || padding >= shamirWordBits {
+		return ShamirShare{}, errors.New("invalid share length")
+	}
+	if new(big.Int).And(bits, big.NewInt(1<<padding-1)).Sign() != 0 {
+		return ShamirShare{}, ErrInvalidShareChecksum
+	}
+	data := make([]byte, dataLen)
+	bits.Rsh(bits, uint(padding)).FillBytes(data)
+
+	checksum := sha256.Sum256(data[:dataLen-shamirChecksumLen])
+	if !bytes.Equal(checksum[:shamirChecksumLen], data[dataLen-shamirChecksumLen:]) {
+		return ShamirShare{}, ErrInvalidShareChecksum
+	}
+	if data[0] != shamirShareVersion {
+		return ShamirShare{}, fmt.Errorf("unsupported share version %d", data[0])
+	}
+	share := ShamirShare{
+		Identifier:      binary.BigEndian.Uint16(data[1:3]),
+		GroupIndex:      data[3],
+		GroupThreshold:  data[4],
+		GroupCount:      data[5],
+		MemberIndex:     data[6],
+		MemberThreshold: data[7],
+		Value:           data[shamirHeaderLen : shamirHeaderLen+valueLen],
+	}
+	if err := share.check(); err != nil {
+		return ShamirShare{}, err
+	}
+	return share, nil
+}
+
+// check validates the metadata of a share and the length of its value.
+func (s ShamirShare) check() error {
+	if len(s.Value) < shamirMinSecretLen+shamirDigestLen || len(s.Value) > shamirMaxSecretLen+shamirDigestLen {
+		return errors.New("invalid share length")
+	}
+	if s.GroupIndex >= s.GroupCount || checkThreshold(int(s.GroupThreshold), int(s.GroupCount)) != nil ||
+		s.MemberThreshold == 0 || s.MemberThreshold > shamirMaxShareCount || s.MemberIndex >= shamirMaxShareCount {
+		return errors.New("invalid share metadata")
+	}
+	return nil
+}
+
+func checkThreshold(threshold int, count int) error {
+	if count < 1 || count > shamirMaxShareCount {
+		return fmt.Errorf("count must be between 1 and %d", shamirMaxShareCount)
+	}
+	if threshold < 1 || threshold > count {
+		return fmt.Errorf("threshold must be between 1 and %d", count)
+	}
+	return nil
+}
+
+// splitValue splits value into count shares with threshold using a random polynomial per byte over
+// GF(256). Share i is the evaluation of the polynomials at i+1.
+func splitValue(value []byte, threshold int, count int) ([][]byte, error) {
+	coefficients := make([]byte, (threshold-1)*len(value))
+	if _, err := rand.Read(coefficients); err != nil {
+		return nil, err
+	}
+	shares := make([][]byte, count)
+	for i := range shares {
+		x := byte(i + 1)
+		shares[i] = make([]byte, len(value))
+		for b := range value {
+			// Horner's method, from the highest degree coefficient.
+			var y byte
+			for d := threshold - 2; d >= 0; d-- {
+				y = gfMul(y, x) ^ coefficients[d*len(value)+b]
+			}
+			shares[i][b] = gfMul(y, x) ^ value[b]
+		}
+	}
+	return shares, nil
+}
+
+// combineValues interpolates the value at 0 from threshold of the shares, indexed by their index.
+func combineValues(shares map[uint8][]byte, threshold int) []byte {
+	var xs []byte
+	var ys [][]byte
+	for index, share := range shares {
+		if len(xs) == threshold {
+			break
+		}
+		xs = append(xs, index+1)
+		ys = append(ys, share)
+	}
+	value := make([]byte, len(ys[0]))
+	for i, xi := range xs {
+		// Lagrange basis polynomial of xi at 0. Subtraction is xor in GF(256).
+		basis := byte(1)
+		for j, xj := range xs {
+			if i != j {
+				basis = gfMul(basis, gfDiv(xj, xj^xi))
+			}
+		}
+		for b := range value {
+			value[b] ^= gfMul(basis, ys[i][b])
+		}
+	}
+	return value
+}
+
+// Arithmetic in GF(256) with the polynomial x^8 + x^4 + x^3 + x + 1, using 3 as generator.
+var gfExp, gfLog = func() ([510]byte, [256]byte) {
+	var exp [510]byte
+	var log [256]byte
+	x := byte(1)
+	for i := 0; i < 255; i++ {
+		exp[i] = x
+		exp[i+255] = x
+		log[x] = byte(i)
+		// Multiply by 3: x * 2 xor x.
+		high := x & 0x80
+		doubled := x << 1
+		if high != 0 {
+			doubled ^= 0x1b
+		}
+		x ^= doubled
+	}
+	return exp, log
+}()
+
+func gfMul(a byte, b byte) byte {
+	if a == 0 || b == 0 {
+		return 0
+	}
+	return gfExp[int(gfLog[a])+int(gfLog[b])]
+}
+
+func gfDiv(a byte, b byte) byte {
+	if a == 0 {
+		return 0
+	}
+	return gfExp[int(gfLog[a])+255-int(gfLog[b])]
+}
@@ -0,0 +1,95 @@
+package crypto_test
+
+import (
+	"encoding/hex"
+	"strings"
+	"testing"
+
+	"github.com/lightsparkdev/go-sdk/crypto"
+	"github.com/stretchr/testify/require"
+)
+
+// Test vectors of BIP 39, all using the passphrase "TREZOR".
+var bip39Vectors = []struct {
+	entropy  string
+	mnemonic string
+	seed     string
+}{
+	{
+		"00000000000000000000000000000000",
+		"abandon abandon abandon abandon abandon abandon abandon abandon abandon abandon abandon about",
+		"c55257c360c07c72029aebc1b53c05ed0362ada38ead3e3e9efa3708e53495531f09a6987599d18264c1e1c92f2cf141630c7a3c4ab7c81b2f001698e7463b04",
+	},
+	{
+		"7f7f7f7f7f7f7f7f7f7f7f7f7f7f7f7f",
+		"legal winner thank year wave sausage worth useful legal winner thank yellow",
+		"2e8905819b8723fe2c1d161860e5ee1830318dbf49a83bd451cfb8440c28bd6fa457fe1296106559a3c80937a1c1069be3a3a5bd381ee6260e8d9739fce1f607",
+	},
+	{
+		"80808080808080808080808080808080",
+		"letter advice cage absurd amount doctor acoustic avoid letter advice cage above",
+		"d71de856f81a8acc65e6fc851a38d4d7ec216fd0796d0a6827a3ad6ed5511a30fa280f12eb2e47ed2ac03b5c462a0358d18d69fe4f985ec81778c1b370b652a8",
+	},
+	{
+		"ffffffffffffffffffffffffffffffff",
+		"zoo zoo zoo zoo zoo zoo zoo zoo zoo zoo zoo wrong",
+		"ac27495480225222079d7be181583751e86f571027b0497b5b5d11218e0a8a13332572917f0f8e5a589620c6f15b11c61dee327651a14c34e18231052e48c069",
+	},
+	{
+		"000000000000000000000000000000000000000000000000",
+		"abandon abandon abandon abandon abandon abandon abandon abandon abandon abandon abandon abandon abandon abandon abandon abandon abandon agent",
+		"035895f2f481b1b0f01fcf8c289c794660b289981a78f8106447707fdd9666ca06da5a9a565181599b79f53b844d8a71dd9f439c52a3d7b3e8a79c906ac845fa",
+	},
+	{
+		"0000000000000000000000000000000000000000000000000000000000000000",
+		"abandon abandon abandon abandon abandon abandon abandon abandon abandon abandon abandon abandon abandon abandon abandon abandon abandon abandon abandon abandon abandon abandon abandon art",
+		"bda85446c68413707090a52022edd26a1c9462295029f2e60cd7c4f2bbd3097170af7a4d73245cafa9c3cca8d561a7c3de6f5d4a10be8ed2a5e608d68f92fcc8",
+	},
+	{
+		"ffffffffffffffffffffffffffffffffffffffffffffffffffffffffffffffff",
+		"zoo zoo zoo zoo zoo zoo zoo zoo zoo zoo zoo zoo zoo zoo zoo zoo zoo zoo zoo zoo zoo zoo zoo vote",
+		"dd48c104698c30cfe2b6142103248622fb7bb0ff692eebb00089b32d22484e1613912f0a5b694407be899ffd31ed3992c456cdf60f5d4564b8ba3f05a69890ad",
+	},
+	{
+		"9e885d952ad362caeb4efe34a8e91bd2",
+		"ozone drill grab fiber curtain grace pudding thank cruise elder eight picnic",
+		"274ddc525802f7c828d8ef7ddbcdc5304e87ac3535913611fbbfa986d0c9e5476c91689f9c8a54fd55bd38606aa6a8595ad213d4c9c9f9aca3fb217069a41028",
+	},
+}
+
+func TestBip39Vectors(t *testing.T) {
+	for _, vector := range bip39Vectors {
+		entropy, err := hex.DecodeString(vector.entropy)
+		require.NoError(t, err)
+
+		mnemonic, err := crypto.EntropyToMnemonic(entropy)
+		require.NoError(t, err)
+		require.Equal(t, vector.mnemonic, strings.Join(mnemonic, " "))
+
+		decoded, err := crypto.MnemonicToEntropy(crypto.ParseMnemonic(vector.mnemonic))
+		require.NoError(t, err)
+		require.Equal(t, entropy, decoded)
+
+		seed, err := crypto.MnemonicToSeed(mnemonic, "TREZOR")
+		require.NoError(t, err)
+		require.Equal(t, vector.seed, hex.EncodeToString(seed))
+	}
+}
+
+func TestInvalidMnemonic(t *testing.T) {
+	valid := crypto.ParseMnemonic(bip39Vectors[1].mnemonic)
+	require.NoError(t, crypto.ValidateMnemonic(valid))
+
+	require.ErrorIs(t, crypto.ValidateMnemonic(valid[:11]), crypto.ErrInvalidMnemonicLength)
+	invalidWord := append([]string{"legall"}, valid[1:]...)
+	require.ErrorIs(t, crypto.ValidateMnemonic(invalidWord), crypto.ErrInvalidMnemonicWord)
+	invalidChecksum := append(append([]string{}, valid[:11]...), "year")
+	require.ErrorIs(t, crypto.ValidateMnemonic(invalidChecksum), crypto.ErrInvalidMnemonicChecksum)
+	_, err := crypto.MnemonicToSeed(invalidChecksum, "")
+	require.Error(t, err)
+
+	_, err = crypto.GenerateMnemonic(13)
+	require.ErrorIs(t, err, crypto.ErrInvalidMnemonicLength)
+	_, err = crypto.EntropyToMnemonic(make([]byte, 15))
+	require.Error(t, err)
+}
@@ -0,0 +1,32 @@
+//go:build cgo
+
+package crypto_test
+
+import (
+	"testing"
+
+	"github.com/lightsparkdev/go-sdk/crypto"
+	lightspark_crypto "github.com/lightsparkdev/lightspark-crypto-uniffi/lightspark-crypto-go"
+	"github.com/stretchr/testify/require"
+)
+
+func TestBip39MatchesLightsparkCrypto(t *testing.T) {
+	// lightspark-crypto only generates 24 words mnemonics.
+	for i := 0; i < 8; i++ {
+		mnemonic, err := crypto.GenerateMnemonic(24)
+		require.NoError(t, err)
+		require.Len(t, mnemonic, 24)
+
+		entropy, err := crypto.MnemonicToEntropy(mnemonic)
+		require.NoError(t, err)
+		expected, err := lightspark_crypto.GetMnemonicSeedPhrase(entropy)
+		require.NoError(t, err)
+		require.Equal(t, expected, mnemonic)
+
+		expectedSeed, err := lightspark_crypto.MnemonicToSeed(mnemonic)
+		require.NoError(t, err)
+		seed, err := crypto.MnemonicToSeed(mnemonic, "")
+		require.NoError(t, err)
+		require.Equal(t, expectedSeed, seed)
+	}
+}
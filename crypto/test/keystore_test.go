@@ -0,0 +1,108 @@
+package crypto_test
+
+import (
+	"bytes"
+	"encoding/json"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/lightsparkdev/go-sdk/crypto"
+	"github.com/lightsparkdev/go-sdk/objects"
+	"github.com/stretchr/testify/require"
+)
+
+// Cheap parameters keep the tests fast.
+var testKdfs = map[string]crypto.KeystoreOption{
+	crypto.KDF_SCRYPT:   crypto.WithScrypt(1<<10, 8, 1),
+	crypto.KDF_ARGON2ID: crypto.WithArgon2id(1, 1024, 1),
+}
+
+func TestSeedFingerprint(t *testing.T) {
+	// Test vector 1 of BIP 32.
+	seed := []byte{0x00, 0x01, 0x02, 0x03, 0x04, 0x05, 0x06, 0x07, 0x08, 0x09, 0x0a, 0x0b, 0x0c, 0x0d, 0x0e, 0x0f}
+	fingerprint, err := crypto.SeedFingerprint(seed)
+	require.NoError(t, err)
+	require.Equal(t, "3442193e", fingerprint)
+}
+
+func TestKeystore(t *testing.T) {
+	seed := bytes.Repeat([]byte{3}, 32)
+	for name, kdf := range testKdfs {
+		t.Run(name, func(t *testing.T) {
+			keystore, err := crypto.NewKeystore(seed, objects.BitcoinNetworkRegtest, "passphrase", kdf)
+			require.NoError(t, err)
+			require.Equal(t, name, keystore.Kdf.Name)
+
+			path := filepath.Join(t.TempDir(), "keystore.json")
+			require.NoError(t, keystore.Write(path))
+			info, err := os.Stat(path)
+			require.NoError(t, err)
+			require.Equal(t, os.FileMode(0o600), info.Mode().Perm())
+
+			keystore, err = crypto.ReadKeystore(path)
+			require.NoError(t, err)
+			require.Equal(t, objects.BitcoinNetworkRegtest, keystore.Network)
+			unlocked, err := keystore.Unlock("passphrase")
+			require.NoError(t, err)
+			require.Equal(t, seed, unlocked)
+			_, err = keystore.Unlock("wrong")
+			require.ErrorIs(t, err, crypto.ErrWrongPassphrase)
+
+			require.ErrorIs(t, keystore.ChangePassphrase("wrong", "new passphrase"), crypto.ErrWrongPassphrase)
+			require.NoError(t, keystore.ChangePassphrase("passphrase", "new passphrase"))
+			require.Equal(t, name, keystore.Kdf.Name)
+			_, err = keystore.Unlock("passphrase")
+			require.ErrorIs(t, err, crypto.ErrWrongPassphrase)
+			unlocked, err = keystore.Unlock("new passphrase")
+			require.NoError(t, err)
+			require.Equal(t, seed, unlocked)
+		})
+	}
+}
+
+func TestKeystoreDetectsTampering(t *testing.T) {
+	keystore, err := crypto.NewKeystore(bytes.Repeat([]byte{3}, 32), objects.BitcoinNetworkRegtest, "passphrase",
+		testKdfs[crypto.KDF_SCRYPT])
+	require.NoError(t, err)
+	data, err := json.Marshal(keystore)
+	require.NoError(t, err)
+
+	tampered, err := crypto.ParseKeystore(data)
+	require.NoError(t, err)
+	tampered.Network = objects.BitcoinNetworkMainnet
+	_, err = tampered.Unlock("passphrase")
+	require.ErrorIs(t, err, crypto.ErrWrongPassphrase)
+
+	_, err = crypto.ParseKeystore(bytes.Replace(data, []byte(`"version":1`), []byte(`"version":2`), 1))
+	require.Error(t, err)
+	_, err = crypto.NewKeystore(bytes.Repeat([]byte{3}, 32), objects.BitcoinNetworkUndefined, "passphrase")
+	require.Error(t, err)
+
+	tampered, err = crypto.ParseKeystore(data)
+	require.NoError(t, err)
+	tampered.Nonce = tampered.Nonce[:4]
+	_, err = tampered.Unlock("passphrase")
+	require.ErrorIs(t, err, crypto.ErrWrongPassphrase)
+}
+
+func TestKeystoreBoundsKdfParameters(t *testing.T) {
+	seed := bytes.Repeat([]byte{3}, 32)
+	for _, kdf := range []crypto.KeystoreKdf{
+		{Name: crypto.KDF_SCRYPT, N: 1 << 30, R: 8, P: 1},
+		{Name: crypto.KDF_SCRYPT, N: 1000, R: 8, P: 1},
+		{Name: crypto.KDF_SCRYPT, N: 1 << 10, R: 1 << 20, P: 1},
+		{Name: crypto.KDF_SCRYPT, N: 1 << 10, R: 8, P: 1 << 20},
+		{Name: crypto.KDF_ARGON2ID, Time: 1, Memory: 1 << 31, Threads: 1},
+		{Name: crypto.KDF_ARGON2ID, Time: 1 << 30, Memory: 1024, Threads: 1},
+	} {
+		keystore, err := crypto.NewKeystore(seed, objects.BitcoinNetworkRegtest, "passphrase", testKdfs[kdf.Name])
+		require.NoError(t, err)
+		// A tampered keystore is refused before its key is derived.
+		kdf.Salt = keystore.Kdf.Salt
+		keystore.Kdf = kdf
+		_, err = keystore.Unlock("passphrase")
+		require.Error(t, err, "%+v", kdf)
+		require.NotErrorIs(t, err, crypto.ErrWrongPassphrase)
+	}
+}
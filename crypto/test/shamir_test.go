@@ -0,0 +1,122 @@
+package crypto_test
+
+import (
+	"bytes"
+	"testing"
+
+	"github.com/lightsparkdev/go-sdk/crypto"
+	"github.com/stretchr/testify/require"
+)
+
+func TestShamirSplitAndCombine(t *testing.T) {
+	for _, size := range []int{16, 32, 64} {
+		secret := bytes.Repeat([]byte{byte(size)}, size)
+		shares, err := crypto.SplitSecret(secret, 2, []crypto.ShamirGroup{
+			{Threshold: 1, Count: 1},
+			{Threshold: 2, Count: 3},
+			{Threshold: 3, Count: 5},
+		})
+		require.NoError(t, err)
+		require.Len(t, shares, 3)
+		require.Len(t, shares[2], 5)
+
+		combinations := [][]crypto.ShamirShare{
+			{shares[0][0], shares[1][0], shares[1][2]},
+			{shares[1][1], shares[1][2], shares[2][4], shares[2][0], shares[2][2]},
+			// Extra shares and incomplete groups are ignored.
+			{shares[0][0], shares[2][1], shares[2][2], shares[2][3], shares[2][4], shares[1][0]},
+		}
+		for _, combination := range combinations {
+			combined, err := crypto.CombineShares(combination)
+			require.NoError(t, err)
+			require.Equal(t, secret, combined)
+		}
+
+		_, err = crypto.CombineShares([]crypto.ShamirShare{shares[0][0], shares[1][0], shares[2][0], shares[2][1]})
+		require.ErrorIs(t, err, crypto.ErrNotEnoughShares)
+	}
+}
+
+func TestShamirShareMnemonic(t *testing.T) {
+	secret := bytes.Repeat([]byte{7}, 32)
+	shares, err := crypto.SplitSecret(secret, 1, []crypto.ShamirGroup{{Threshold: 2, Count: 3}})
+	require.NoError(t, err)
+
+	var parsed []crypto.ShamirShare
+	for _, share := range shares[0][1:] {
+		mnemonic := share.Mnemonic()
+		decoded, err := crypto.ParseShamirShare(mnemonic)
+		require.NoError(t, err)
+		require.Equal(t, share, decoded)
+		parsed = append(parsed, decoded)
+	}
+	combined, err := crypto.CombineShares(parsed)
+	require.NoError(t, err)
+	require.Equal(t, secret, combined)
+
+	mnemonic := shares[0][0].Mnemonic()
+	for i := range mnemonic {
+		mistyped := append([]string{}, mnemonic...)
+		if mistyped[i] == "abandon" {
+			mistyped[i] = "ability"
+		} else {
+			mistyped[i] = "abandon"
+		}
+		_, err := crypto.ParseShamirShare(mistyped)
+		require.Error(t, err)
+	}
+	_, err = crypto.ParseShamirShare(mnemonic[:len(mnemonic)-1])
+	require.Error(t, err)
+}
+
+func TestShamirRejectsMixedShares(t *testing.T) {
+	groups := []crypto.ShamirGroup{{Threshold: 2, Count: 3}}
+	first, err := crypto.SplitSecret(bytes.Repeat([]byte{1}, 16), 1, groups)
+	require.NoError(t, err)
+	second, err := crypto.SplitSecret(bytes.Repeat([]byte{2}, 16), 1, groups)
+	require.NoError(t, err)
+	// Identifiers are random, so make them collide to exercise the digest.
+	share := second[0][1]
+	share.Identifier = first[0][0].Identifier
+
+	_, err = crypto.CombineShares([]crypto.ShamirShare{first[0][0], second[0][1]})
+	if first[0][0].Identifier != second[0][1].Identifier {
+		require.ErrorIs(t, err, crypto.ErrShareMismatch)
+	}
+	_, err = crypto.CombineShares([]crypto.ShamirShare{first[0][0], share})
+	require.ErrorIs(t, err, crypto.ErrInvalidSecretDigest)
+}
+
+func TestShamirInvalidParameters(t *testing.T) {
+	secret := bytes.Repeat([]byte{1}, 32)
+	_, err := crypto.SplitSecret(secret[:15], 1, []crypto.ShamirGroup{{Threshold: 2, Count: 3}})
+	require.Error(t, err)
+	_, err = crypto.SplitSecret(secret, 2, []crypto.ShamirGroup{{Threshold: 2, Count: 3}})
+	require.Error(t, err)
+	_, err = crypto.SplitSecret(secret, 1, []crypto.ShamirGroup{{Threshold: 4, Count: 3}})
+	require.Error(t, err)
+	_, err = crypto.SplitSecret(secret, 1, []crypto.ShamirGroup{{Threshold: 2, Count: 17}})
+	require.Error(t, err)
+	_, err = crypto.CombineShares(nil)
+	require.ErrorIs(t, err, crypto.ErrNotEnoughShares)
+}
+
+func TestShamirRejectsInvalidShares(t *testing.T) {
+	shares, err := crypto.SplitSecret(bytes.Repeat([]byte{1}, 16), 1, []crypto.ShamirGroup{{Threshold: 1, Count: 1}})
+	require.NoError(t, err)
+	valid := shares[0][0]
+
+	short := valid
+	short.Value = short.Value[:2]
+	long := valid
+	long.Value = bytes.Repeat([]byte{1}, 69)
+	threshold := valid
+	threshold.MemberThreshold = 17
+	for _, share := range []crypto.ShamirShare{short, long, threshold} {
+		// The checksum of the mnemonic is valid.
+		_, err := crypto.ParseShamirShare(share.Mnemonic())
+		require.Error(t, err)
+		_, err = crypto.CombineShares([]crypto.ShamirShare{share})
+		require.Error(t, err)
+	}
+}
@@ -1,6 +1,6 @@
 module github.com/lightsparkdev/go-sdk/examples/uma-server
 
-go 1.22.0
+go 1.23.0
 
 toolchain go1.23.2
 
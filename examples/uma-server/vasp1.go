@@ -2,6 +2,7 @@ package main
 
 import (
 	"bytes"
+	"context"
 	"encoding/json"
 	stderrors "errors"
 	"fmt"
@@ -678,7 +679,7 @@ func (v *Vasp1) handleClientPaymentConfirm(context *gin.Context) {
 		})
 		return
 	}
-	payment, err = v.waitForPaymentCompletion(payment)
+	payment, err = v.waitForPaymentCompletion(context.Request.Context(), payment)
 	if err != nil {
 		context.Error(&errors.UmaError{
 			Reason:    "Failed while waiting for payment completion",
@@ -746,32 +747,17 @@ func (v *Vasp1) handleClientPaymentConfirm(context *gin.Context) {
 	})
 }
 
-func (v *Vasp1) waitForPaymentCompletion(payment *objects.OutgoingPayment) (*objects.OutgoingPayment, error) {
-	attemptLimit := 200
-	for payment.Status != objects.TransactionStatusSuccess && payment.Status != objects.TransactionStatusFailed {
-		if attemptLimit == 0 {
-			return nil, &errors.UmaError{
-				Reason:    "payment timed out",
-				ErrorCode: generated.InternalError,
-			}
-		}
-		attemptLimit--
-		time.Sleep(100 * time.Millisecond)
-
-		entity, err := v.client.GetEntity(payment.Id)
-		if err != nil {
-			return nil, err
-		}
-		castPayment, didCast := (*entity).(objects.OutgoingPayment)
-		if !didCast {
-			return nil, &errors.UmaError{
-				Reason:    "failed to cast payment to OutgoingPayment",
-				ErrorCode: generated.InternalError,
-			}
-		}
-		payment = &castPayment
-	}
-	return payment, nil
+func (v *Vasp1) waitForPaymentCompletion(ctx context.Context, payment *objects.OutgoingPayment) (*objects.OutgoingPayment, error) {
+	ctx, cancel := context.WithTimeout(ctx, 20*time.Second)
+	defer cancel()
+	completedPayment, err := v.client.WaitForOutgoingPayment(ctx, payment.Id,
+		services.WithPollInterval(100*time.Millisecond, time.Second))
+	var failure *services.PaymentFailedError
+	if stderrors.As(err, &failure) {
+		// Failed payments are reported through their status.
+		return completedPayment, nil
+	}
+	return completedPayment, err
 }
 
 func (v *Vasp1) handleNonUmaLnurlpResponse(
@@ -0,0 +1,5 @@
+// Copyright ©, 2023-present, Lightspark Group, Inc. - All Rights Reserved
+
+// Package zstd compresses the bodies of GraphQL requests and responses. It uses the zstd C library with
+// cgo, and a Go implementation when the module is built with CGO_ENABLED=0 or the lightspark_purego build tag.
+package zstd
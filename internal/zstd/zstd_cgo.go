@@ -0,0 +1,19 @@
+// Copyright ©, 2023-present, Lightspark Group, Inc. - All Rights Reserved
+
+//go:build cgo && !lightspark_purego
+
+package zstd
+
+import (
+	"github.com/DataDog/zstd"
+)
+
+// Compress appends the compressed src to dst.
+func Compress(dst []byte, src []byte) ([]byte, error) {
+	return zstd.Compress(dst, src)
+}
+
+// Decompress appends the decompressed src to dst.
+func Decompress(dst []byte, src []byte) ([]byte, error) {
+	return zstd.Decompress(dst, src)
+}
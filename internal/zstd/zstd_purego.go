@@ -0,0 +1,24 @@
+// Copyright ©, 2023-present, Lightspark Group, Inc. - All Rights Reserved
+
+//go:build !cgo || lightspark_purego
+
+package zstd
+
+import (
+	"github.com/klauspost/compress/zstd"
+)
+
+var (
+	encoder, _ = zstd.NewWriter(nil)
+	decoder, _ = zstd.NewReader(nil)
+)
+
+// Compress appends the compressed src to dst.
+func Compress(dst []byte, src []byte) ([]byte, error) {
+	return encoder.EncodeAll(src, dst), nil
+}
+
+// Decompress appends the decompressed src to dst.
+func Decompress(dst []byte, src []byte) ([]byte, error) {
+	return decoder.DecodeAll(src, dst)
+}
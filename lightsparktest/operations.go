@@ -0,0 +1,711 @@
+// Copyright ©, 2023-present, Lightspark Group, Inc. - All Rights Reserved
+package lightsparktest
+
+import (
+	"encoding/hex"
+	"fmt"
+	"strings"
+	"time"
+	"unicode"
+
+	"github.com/lightsparkdev/go-sdk/objects"
+	"github.com/lightsparkdev/go-sdk/types"
+)
+
+const (
+	defaultInvoiceExpirySecs = 86400
+	lightningFeeMsats        = 1000
+	withdrawalFeeSats        = 500
+	regtestWalletAddress     = "bcrt1qxyzfakefakefakefakefakefakefakefake0lightspark"
+)
+
+type operationContext struct {
+	name      string
+	variables map[string]interface{}
+	payload   []byte
+	signature string
+	webhooks  []pendingWebhook
+}
+
+type operation func(s *Server, ctx *operationContext) (map[string]interface{}, error)
+
+var operations = map[string]operation{
+	"BitcoinFeeEstimate":                (*Server).bitcoinFeeEstimate,
+	"CancelInvoice":                     (*Server).cancelInvoice,
+	"ClaimUmaInvitation":                (*Server).claimUmaInvitation,
+	"ClaimUmaInvitationWithIncentives":  (*Server).claimUmaInvitation,
+	"CreateApiToken":                    (*Server).createApiToken,
+	"CreateInvoice":                     (*Server).createInvoice,
+	"CreateLnurlInvoice":                (*Server).createInvoice,
+	"CreateNodeWalletAddress":           (*Server).createNodeWalletAddress,
+	"CreateTestModeInvoice":             (*Server).createTestModeInvoice,
+	"CreateTestModePayment":             (*Server).createTestModePayment,
+	"CreateUmaInvitation":               (*Server).createUmaInvitation,
+	"CreateUmaInvitationWithIncentives": (*Server).createUmaInvitation,
+	"CreateUmaInvoice":                  (*Server).createInvoice,
+	"DecodedPaymentRequest":             (*Server).decodedPaymentRequest,
+	"DeleteApiToken":                    (*Server).deleteApiToken,
+	"FetchUmaInvitation":                (*Server).fetchUmaInvitation,
+	"FundNode":                          (*Server).fundNode,
+	"GetCurrentAccount":                 (*Server).currentAccount,
+	"GetEntity":                         (*Server).getEntity,
+	"IncomingPaymentsForInvoice":        (*Server).incomingPaymentsForInvoice,
+	"InvoiceForPaymentHash":             (*Server).invoiceForPaymentHash,
+	"LightningFeeEstimateForInvoice":    (*Server).lightningFeeEstimateForInvoice,
+	"LightningFeeEstimateForNode":       (*Server).lightningFeeEstimateForNode,
+	"OutgoingPaymentForIdempotencyKey":  (*Server).outgoingPaymentForIdempotencyKey,
+	"OutgoingPaymentsForInvoice":        (*Server).outgoingPaymentsForInvoice,
+	"OutgoingPaymentsForPaymentHash":    (*Server).outgoingPaymentsForPaymentHash,
+	"PayInvoice":                        (*Server).payInvoice,
+	"PayUmaInvoice":                     (*Server).payInvoice,
+	"RequestWithdrawal":                 (*Server).requestWithdrawal,
+	"WithdrawalFeeEstimate":             (*Server).withdrawalFeeEstimate,
+}
+
+func (s *Server) execute(request graphqlRequest, payload []byte, signature string) (map[string]interface{}, []pendingWebhook, error) {
+	handler, ok := operations[request.OperationName]
+	if !ok {
+		return nil, nil, graphqlError{"UnsupportedOperation", request.OperationName + " is not supported by lightsparktest"}
+	}
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	ctx := &operationContext{name: request.OperationName, variables: request.Variables, payload: payload, signature: signature}
+	data, err := handler(s, ctx)
+	if err != nil {
+		return nil, nil, err
+	}
+	return data, ctx.webhooks, nil
+}
+
+func (s *Server) currentAccount(ctx *operationContext) (map[string]interface{}, error) {
+	name := "lightsparktest"
+	return map[string]interface{}{"current_account": objects.Account{
+		Id:       AccountId,
+		Name:     &name,
+		Typename: "Account",
+	}}, nil
+}
+
+func (s *Server) getEntity(ctx *operationContext) (map[string]interface{}, error) {
+	id, _ := ctx.variables["id"].(string)
+	var entity interface{}
+	if n, ok := s.nodes[id]; ok {
+		entity = s.nodeEntity(n)
+	} else if invoice, ok := s.invoices[id]; ok {
+		entity = invoice
+	} else if payment, ok := s.outgoingPayments[id]; ok {
+		entity = payment
+	} else if payment, ok := s.incomingPayments[id]; ok {
+		entity = payment
+	} else if request, ok := s.withdrawalRequests[id]; ok {
+		entity = request
+	} else if token, ok := s.apiTokens[id]; ok {
+		entity = token
+	}
+	return map[string]interface{}{"entity": entity}, nil
+}
+
+func (s *Server) createInvoice(ctx *operationContext) (map[string]interface{}, error) {
+	n, err := s.variableNode(ctx, "node_id")
+	if err != nil {
+		return nil, err
+	}
+	amountMsats, _ := int64Variable(ctx.variables, "amount_msats")
+	expirySecs, ok := int64Variable(ctx.variables, "expiry_secs")
+	if !ok {
+		expirySecs = defaultInvoiceExpirySecs
+	}
+	var paymentHash []byte
+	if hash, ok := ctx.variables["payment_hash"].(string); ok {
+		if paymentHash, err = hex.DecodeString(hash); err != nil || len(paymentHash) != 32 {
+			return nil, graphqlError{"InvalidInput", "invalid payment_hash"}
+		}
+	}
+	memo, _ := ctx.variables["memo"].(string)
+
+	invoice := s.newInvoice(n.entity.BitcoinNetwork, &n.entity, amountMsats, time.Duration(expirySecs)*time.Second, memo, paymentHash)
+	isUma := ctx.name == "CreateUmaInvoice"
+	invoice.IsUma = &isUma
+	return map[string]interface{}{responseKey(ctx.name): map[string]interface{}{"invoice": invoice}}, nil
+}
+
+func (s *Server) cancelInvoice(ctx *operationContext) (map[string]interface{}, error) {
+	id, _ := ctx.variables["invoice_id"].(string)
+	invoice, ok := s.invoices[id]
+	if !ok {
+		return nil, graphqlError{"NotFound", "invoice not found"}
+	}
+	invoice.Status = objects.PaymentRequestStatusClosed
+	invoice.UpdatedAt = time.Now().UTC()
+	return map[string]interface{}{"cancel_invoice": map[string]interface{}{"invoice": invoice}}, nil
+}
+
+func (s *Server) createTestModeInvoice(ctx *operationContext) (map[string]interface{}, error) {
+	n, err := s.variableNode(ctx, "local_node_id")
+	if err != nil {
+		return nil, err
+	}
+	amountMsats, _ := int64Variable(ctx.variables, "amount_msats")
+	memo, _ := ctx.variables["memo"].(string)
+	// Test mode invoices belong to a node outside of the account.
+	invoice := s.newInvoice(n.entity.BitcoinNetwork, nil, amountMsats, defaultInvoiceExpirySecs*time.Second, memo, nil)
+	return map[string]interface{}{"create_test_mode_invoice": map[string]interface{}{
+		"encoded_payment_request": invoice.Data.EncodedPaymentRequest,
+	}}, nil
+}
+
+func (s *Server) createTestModePayment(ctx *operationContext) (map[string]interface{}, error) {
+	n, err := s.variableNode(ctx, "local_node_id")
+	if err != nil {
+		return nil, err
+	}
+	invoice, err := s.variableInvoice(ctx, "encoded_invoice")
+	if err != nil {
+		return nil, err
+	}
+	if invoice.Data.Destination == nil || s.nodeIdOf(invoice) != n.entity.Id {
+		return nil, graphqlError{"InvalidInput", "the invoice was not created by this node"}
+	}
+	amountMsats, err := paymentAmount(ctx, invoice)
+	if err != nil {
+		return nil, err
+	}
+	payment := s.receivePayment(ctx, n, invoice, amountMsats, false)
+	return map[string]interface{}{"create_test_mode_payment": map[string]interface{}{"incoming_payment": payment}}, nil
+}
+
+func (s *Server) payInvoice(ctx *operationContext) (map[string]interface{}, error) {
+	n, err := s.variableNode(ctx, "node_id")
+	if err != nil {
+		return nil, err
+	}
+	if err := s.verifySignature(n, ctx.payload, ctx.signature); err != nil {
+		return nil, err
+	}
+	key := responseKey(ctx.name)
+
+	idempotencyKey, hasIdempotencyKey := ctx.variables["idempotency_key"].(string)
+	if hasIdempotencyKey {
+		for _, payment := range s.outgoingPayments {
+			if payment.IdempotencyKey != nil && *payment.IdempotencyKey == idempotencyKey {
+				return map[string]interface{}{key: map[string]interface{}{"payment": payment}}, nil
+			}
+		}
+	}
+
+	invoice, err := s.variableInvoice(ctx, "encoded_invoice")
+	if err != nil {
+		return nil, err
+	}
+	amountMsats, err := paymentAmount(ctx, invoice)
+	if err != nil {
+		return nil, err
+	}
+
+	now := time.Now().UTC()
+	var requestData objects.PaymentRequestData = invoice.Data
+	payment := &objects.OutgoingPayment{
+		Id:                 newId("OutgoingPayment"),
+		CreatedAt:          now,
+		UpdatedAt:          now,
+		ResolvedAt:         &now,
+		Amount:             millisatoshis(amountMsats),
+		Fees:               &objects.CurrencyAmount{OriginalUnit: objects.CurrencyUnitMillisatoshi},
+		IsUma:              ctx.name == "PayUmaInvoice",
+		PaymentRequestData: &requestData,
+		IsInternalPayment:  invoice.Data.Destination != nil,
+		Typename:           "OutgoingPayment",
+	}
+	payment.Origin.Id = n.entity.Id
+	if hasIdempotencyKey {
+		payment.IdempotencyKey = &idempotencyKey
+	}
+
+	var failureReason objects.PaymentFailureReason
+	switch {
+	case now.After(invoice.Data.ExpiresAt):
+		failureReason = objects.PaymentFailureReasonInvoiceExpired
+	case invoice.Status == objects.PaymentRequestStatusClosed:
+		failureReason = objects.PaymentFailureReasonInvoiceAlreadyPaid
+	case n.balance < amountMsats:
+		failureReason = objects.PaymentFailureReasonInsufficientBalance
+	case s.nodeIdOf(invoice) == n.entity.Id:
+		failureReason = objects.PaymentFailureReasonSelfPayment
+	}
+
+	if failureReason != objects.PaymentFailureReasonUndefined {
+		payment.Status = objects.TransactionStatusFailed
+		payment.FailureReason = &failureReason
+		payment.FailureMessage = &objects.RichText{Text: failureReason.StringValue()}
+	} else {
+		payment.Status = objects.TransactionStatusSuccess
+		n.balance -= amountMsats
+		preimage := hex.EncodeToString(randomBytes(32))
+		payment.PaymentPreimage = &preimage
+		if destination, ok := s.nodes[s.nodeIdOf(invoice)]; ok {
+			s.receivePayment(ctx, destination, invoice, amountMsats, true)
+		} else {
+			invoice.Status = objects.PaymentRequestStatusClosed
+		}
+	}
+
+	s.outgoingPayments[payment.Id] = payment
+	ctx.webhooks = append(ctx.webhooks, pendingWebhook{objects.WebhookEventTypePaymentFinished, payment.Id})
+	return map[string]interface{}{key: map[string]interface{}{"payment": payment}}, nil
+}
+
+func (s *Server) requestWithdrawal(ctx *operationContext) (map[string]interface{}, error) {
+	n, err := s.variableNode(ctx, "node_id")
+	if err != nil {
+		return nil, err
+	}
+	if err := s.verifySignature(n, ctx.payload, ctx.signature); err != nil {
+		return nil, err
+	}
+
+	idempotencyKey, hasIdempotencyKey := ctx.variables["idempotency_key"].(string)
+	if hasIdempotencyKey {
+		for _, request := range s.withdrawalRequests {
+			if request.IdempotencyKey != nil && *request.IdempotencyKey == idempotencyKey {
+				return map[string]interface{}{"request_withdrawal": map[string]interface{}{"request": request}}, nil
+			}
+		}
+	}
+
+	amountSats, _ := int64Variable(ctx.variables, "amount_sats")
+	amountMsats := amountSats * 1000
+	if amountSats == -1 {
+		amountMsats = n.balance
+	}
+	if amountMsats <= 0 {
+		return nil, graphqlError{"InvalidInput", "invalid amount_sats"}
+	}
+	if amountMsats <= withdrawalFeeSats*1000 {
+		return nil, graphqlError{"InvalidInput", "amount_sats doesn't cover the withdrawal fee"}
+	}
+	if amountMsats > n.balance {
+		return nil, graphqlError{"InsufficientBalance", "the node balance is too low for this withdrawal"}
+	}
+	address, _ := ctx.variables["bitcoin_address"].(string)
+	var mode objects.WithdrawalMode
+	if value, ok := ctx.variables["withdrawal_mode"].(string); ok {
+		mode.UnmarshalJSON([]byte(`"` + value + `"`))
+	}
+
+	n.balance -= amountMsats
+	now := time.Now().UTC()
+	fees := millisatoshis(withdrawalFeeSats * 1000)
+	withdrawn := millisatoshis(amountMsats - withdrawalFeeSats*1000)
+	request := &objects.WithdrawalRequest{
+		Id:              newId("WithdrawalRequest"),
+		CreatedAt:       now,
+		UpdatedAt:       now,
+		RequestedAmount: millisatoshis(amountMsats),
+		Amount:          millisatoshis(amountMsats),
+		AmountWithdrawn: &withdrawn,
+		TotalFees:       &fees,
+		BitcoinAddress:  address,
+		WithdrawalMode:  mode,
+		Status:          objects.WithdrawalRequestStatusSuccessful,
+		CompletedAt:     &now,
+		Typename:        "WithdrawalRequest",
+	}
+	if hasIdempotencyKey {
+		request.IdempotencyKey = &idempotencyKey
+	}
+	s.withdrawalRequests[request.Id] = request
+	ctx.webhooks = append(ctx.webhooks, pendingWebhook{objects.WebhookEventTypeWithdrawalFinished, request.Id})
+	return map[string]interface{}{"request_withdrawal": map[string]interface{}{"request": request}}, nil
+}
+
+func (s *Server) fundNode(ctx *operationContext) (map[string]interface{}, error) {
+	n, err := s.variableNode(ctx, "node_id")
+	if err != nil {
+		return nil, err
+	}
+	if n.entity.BitcoinNetwork != objects.BitcoinNetworkRegtest {
+		return nil, graphqlError{"InvalidInput", "only REGTEST nodes can be funded"}
+	}
+	amountSats, ok := int64Variable(ctx.variables, "amount_sats")
+	if !ok || amountSats <= 0 {
+		amountSats = 10_000_000
+	}
+	n.balance += amountSats * 1000
+	amount := objects.CurrencyAmount{OriginalValue: amountSats, OriginalUnit: objects.CurrencyUnitSatoshi}
+	return map[string]interface{}{"fund_node": map[string]interface{}{"amount": amount}}, nil
+}
+
+func (s *Server) createNodeWalletAddress(ctx *operationContext) (map[string]interface{}, error) {
+	if _, err := s.variableNode(ctx, "node_id"); err != nil {
+		return nil, err
+	}
+	return map[string]interface{}{"create_node_wallet_address": map[string]interface{}{
+		"wallet_address": regtestWalletAddress,
+	}}, nil
+}
+
+func (s *Server) decodedPaymentRequest(ctx *operationContext) (map[string]interface{}, error) {
+	invoice, err := s.variableInvoice(ctx, "encoded_payment_request")
+	if err != nil {
+		return nil, err
+	}
+	return map[string]interface{}{"decoded_payment_request": invoice.Data}, nil
+}
+
+func (s *Server) bitcoinFeeEstimate(ctx *operationContext) (map[string]interface{}, error) {
+	return map[string]interface{}{"bitcoin_fee_estimate": objects.FeeEstimate{
+		FeeFast: objects.CurrencyAmount{OriginalValue: 5, OriginalUnit: objects.CurrencyUnitSatoshi},
+		FeeMin:  objects.CurrencyAmount{OriginalValue: 1, OriginalUnit: objects.CurrencyUnitSatoshi},
+	}}, nil
+}
+
+func (s *Server) lightningFeeEstimateForInvoice(ctx *operationContext) (map[string]interface{}, error) {
+	if _, err := s.variableInvoice(ctx, "encoded_payment_request"); err != nil {
+		return nil, err
+	}
+	return map[string]interface{}{"lightning_fee_estimate_for_invoice": objects.LightningFeeEstimateOutput{
+		FeeEstimate: millisatoshis(lightningFeeMsats),
+	}}, nil
+}
+
+func (s *Server) lightningFeeEstimateForNode(ctx *operationContext) (map[string]interface{}, error) {
+	if _, err := s.variableNode(ctx, "node_id"); err != nil {
+		return nil, err
+	}
+	return map[string]interface{}{"lightning_fee_estimate_for_node": objects.LightningFeeEstimateOutput{
+		FeeEstimate: millisatoshis(lightningFeeMsats),
+	}}, nil
+}
+
+func (s *Server) withdrawalFeeEstimate(ctx *operationContext) (map[string]interface{}, error) {
+	if _, err := s.variableNode(ctx, "node_id"); err != nil {
+		return nil, err
+	}
+	return map[string]interface{}{"withdrawal_fee_estimate": objects.WithdrawalFeeEstimateOutput{
+		FeeEstimate: objects.CurrencyAmount{OriginalValue: withdrawalFeeSats, OriginalUnit: objects.CurrencyUnitSatoshi},
+	}}, nil
+}
+
+func (s *Server) outgoingPaymentsForInvoice(ctx *operationContext) (map[string]interface{}, error) {
+	encodedInvoice, _ := ctx.variables["encoded_invoice"].(string)
+	payments := []objects.OutgoingPayment{}
+	for _, payment := range s.outgoingPayments {
+		if payment.PaymentRequestData != nil &&
+			(*payment.PaymentRequestData).GetEncodedPaymentRequest() == encodedInvoice &&
+			matchesStatus(ctx.variables, payment.Status) {
+			payments = append(payments, *payment)
+		}
+	}
+	return map[string]interface{}{"outgoing_payments_for_invoice": objects.OutgoingPaymentsForInvoiceQueryOutput{
+		Payments: payments,
+	}}, nil
+}
+
+func (s *Server) outgoingPaymentsForPaymentHash(ctx *operationContext) (map[string]interface{}, error) {
+	paymentHash, _ := ctx.variables["payment_hash"].(string)
+	payments := []objects.OutgoingPayment{}
+	for _, payment := range s.outgoingPayments {
+		if data, ok := (*payment.PaymentRequestData).(objects.InvoiceData); ok &&
+			data.PaymentHash == paymentHash && matchesStatus(ctx.variables, payment.Status) {
+			payments = append(payments, *payment)
+		}
+	}
+	return map[string]interface{}{"outgoing_payments_for_payment_hash": map[string]interface{}{
+		"payments": payments,
+	}}, nil
+}
+
+func (s *Server) outgoingPaymentForIdempotencyKey(ctx *operationContext) (map[string]interface{}, error) {
+	idempotencyKey, _ := ctx.variables["idempotency_key"].(string)
+	var result *objects.OutgoingPayment
+	for _, payment := range s.outgoingPayments {
+		if payment.IdempotencyKey != nil && *payment.IdempotencyKey == idempotencyKey {
+			result = payment
+		}
+	}
+	return map[string]interface{}{"outgoing_payment_for_idempotency_key": map[string]interface{}{
+		"payment": result,
+	}}, nil
+}
+
+func (s *Server) incomingPaymentsForInvoice(ctx *operationContext) (map[string]interface{}, error) {
+	invoiceId, _ := ctx.variables["invoice_id"].(string)
+	payments := []objects.IncomingPayment{}
+	for _, payment := range s.incomingPayments {
+		if payment.PaymentRequest != nil && payment.PaymentRequest.Id == invoiceId &&
+			matchesStatus(ctx.variables, payment.Status) {
+			payments = append(payments, *payment)
+		}
+	}
+	return map[string]interface{}{"incoming_payments_for_invoice": objects.IncomingPaymentsForInvoiceQueryOutput{
+		Payments: payments,
+	}}, nil
+}
+
+func (s *Server) invoiceForPaymentHash(ctx *operationContext) (map[string]interface{}, error) {
+	paymentHash, _ := ctx.variables["payment_hash"].(string)
+	var result *objects.Invoice
+	for _, invoice := range s.invoices {
+		if invoice.Data.PaymentHash == paymentHash && invoice.Data.Destination != nil {
+			result = invoice
+		}
+	}
+	return map[string]interface{}{"invoice_for_payment_hash": map[string]interface{}{"invoice": result}}, nil
+}
+
+func (s *Server) createApiToken(ctx *operationContext) (map[string]interface{}, error) {
+	name, _ := ctx.variables["name"].(string)
+	var permissions []objects.Permission
+	if values, ok := ctx.variables["permissions"].([]interface{}); ok {
+		for _, value := range values {
+			var permission objects.Permission
+			if name, ok := value.(string); ok {
+				permission.UnmarshalJSON([]byte(`"` + name + `"`))
+			}
+			permissions = append(permissions, permission)
+		}
+	}
+	now := time.Now().UTC()
+	token := &objects.ApiToken{
+		Id:          newId("ApiToken"),
+		CreatedAt:   now,
+		UpdatedAt:   now,
+		ClientId:    hex.EncodeToString(randomBytes(16)),
+		Name:        name,
+		Permissions: permissions,
+		Typename:    "ApiToken",
+	}
+	s.apiTokens[token.Id] = token
+	return map[string]interface{}{"create_api_token": map[string]interface{}{
+		"api_token":     token,
+		"client_secret": hex.EncodeToString(randomBytes(32)),
+	}}, nil
+}
+
+func (s *Server) deleteApiToken(ctx *operationContext) (map[string]interface{}, error) {
+	id, _ := ctx.variables["api_token_id"].(string)
+	token, ok := s.apiTokens[id]
+	if !ok {
+		return nil, graphqlError{"NotFound", "API token not found"}
+	}
+	token.IsDeleted = true
+	return map[string]interface{}{"delete_api_token": map[string]interface{}{"account": map[string]interface{}{"id": AccountId}}}, nil
+}
+
+func (s *Server) createUmaInvitation(ctx *operationContext) (map[string]interface{}, error) {
+	inviterUma, _ := ctx.variables["inviter_uma"].(string)
+	now := time.Now().UTC()
+	code := hex.EncodeToString(randomBytes(4))
+	invitation := &objects.UmaInvitation{
+		Id:         newId("UmaInvitation"),
+		CreatedAt:  now,
+		UpdatedAt:  now,
+		Code:       code,
+		Url:        "https://uma.me/i/" + code,
+		InviterUma: inviterUma,
+		Typename:   "UmaInvitation",
+	}
+	if ctx.name == "CreateUmaInvitationWithIncentives" {
+		invitation.IncentivesStatus = objects.IncentivesStatusPending
+	}
+	s.umaInvitations[code] = invitation
+	return map[string]interface{}{responseKey(ctx.name): map[string]interface{}{"invitation": invitation}}, nil
+}
+
+func (s *Server) claimUmaInvitation(ctx *operationContext) (map[string]interface{}, error) {
+	code, _ := ctx.variables["invitation_code"].(string)
+	invitation, ok := s.umaInvitations[code]
+	if !ok {
+		return nil, graphqlError{"NotFound", "invitation not found"}
+	}
+	if invitation.InviteeUma != nil {
+		return nil, graphqlError{"InvalidInput", "invitation already claimed"}
+	}
+	inviteeUma, _ := ctx.variables["invitee_uma"].(string)
+	invitation.InviteeUma = &inviteeUma
+	invitation.UpdatedAt = time.Now().UTC()
+	return map[string]interface{}{responseKey(ctx.name): map[string]interface{}{"invitation": invitation}}, nil
+}
+
+func (s *Server) fetchUmaInvitation(ctx *operationContext) (map[string]interface{}, error) {
+	code, _ := ctx.variables["invitation_code"].(string)
+	invitation, ok := s.umaInvitations[code]
+	if !ok {
+		return nil, graphqlError{"NotFound", "invitation not found"}
+	}
+	return map[string]interface{}{"uma_invitation_by_code": invitation}, nil
+}
+
+// newInvoice stores a new open invoice. destination is nil for invoices of nodes outside of the account.
+func (s *Server) newInvoice(network objects.BitcoinNetwork, destination *objects.LightsparkNodeWithRemoteSigning,
+	amountMsats int64, expiry time.Duration, memo string, paymentHash []byte,
+) *objects.Invoice {
+	if paymentHash == nil {
+		paymentHash = randomBytes(32)
+	}
+	now := time.Now().UTC()
+	invoice := &objects.Invoice{
+		Id:        newId("Invoice"),
+		CreatedAt: now,
+		UpdatedAt: now,
+		Data: objects.InvoiceData{
+			EncodedPaymentRequest: encodeInvoice(network, amountMsats, paymentHash),
+			BitcoinNetwork:        network,
+			PaymentHash:           hex.EncodeToString(paymentHash),
+			Amount:                millisatoshis(amountMsats),
+			CreatedAt:             now,
+			ExpiresAt:             now.Add(expiry),
+			Typename:              "InvoiceData",
+		},
+		Status:     objects.PaymentRequestStatusOpen,
+		AmountPaid: &objects.CurrencyAmount{OriginalUnit: objects.CurrencyUnitMillisatoshi},
+		Typename:   "Invoice",
+	}
+	if memo != "" {
+		invoice.Data.Memo = &memo
+	}
+	if destination != nil {
+		invoice.Data.Destination = *destination
+	}
+	s.invoices[invoice.Id] = invoice
+	return invoice
+}
+
+// receivePayment credits a node for the payment of one of its invoices.
+func (s *Server) receivePayment(ctx *operationContext, n *node, invoice *objects.Invoice, amountMsats int64,
+	isInternal bool,
+) *objects.IncomingPayment {
+	now := time.Now().UTC()
+	n.balance += amountMsats
+	invoice.AmountPaid = &objects.CurrencyAmount{
+		OriginalValue: invoice.AmountPaid.OriginalValue + amountMsats,
+		OriginalUnit:  objects.CurrencyUnitMillisatoshi,
+	}
+	invoice.Status = objects.PaymentRequestStatusClosed
+	invoice.UpdatedAt = now
+
+	payment := &objects.IncomingPayment{
+		Id:                newId("IncomingPayment"),
+		CreatedAt:         now,
+		UpdatedAt:         now,
+		Status:            objects.TransactionStatusSuccess,
+		ResolvedAt:        &now,
+		Amount:            millisatoshis(amountMsats),
+		IsUma:             invoice.IsUma != nil && *invoice.IsUma,
+		Destination:       types.EntityWrapper{Id: n.entity.Id},
+		PaymentRequest:    &types.EntityWrapper{Id: invoice.Id},
+		IsInternalPayment: isInternal,
+		Typename:          "IncomingPayment",
+	}
+	s.incomingPayments[payment.Id] = payment
+	ctx.webhooks = append(ctx.webhooks, pendingWebhook{objects.WebhookEventTypePaymentFinished, payment.Id})
+	return payment
+}
+
+func (s *Server) nodeEntity(n *node) objects.LightsparkNodeWithRemoteSigning {
+	entity := n.entity
+	balance := millisatoshis(n.balance)
+	entity.LocalBalance = &balance
+	entity.TotalBalance = &balance
+	entity.TotalLocalBalance = &balance
+	return entity
+}
+
+func (s *Server) nodeIdOf(invoice *objects.Invoice) string {
+	if node, ok := invoice.Data.Destination.(objects.LightsparkNodeWithRemoteSigning); ok {
+		return node.Id
+	}
+	return ""
+}
+
+func (s *Server) variableNode(ctx *operationContext, name string) (*node, error) {
+	id, _ := ctx.variables[name].(string)
+	n, ok := s.nodes[id]
+	if !ok {
+		return nil, graphqlError{"NotFound", fmt.Sprintf("node %q not found", id)}
+	}
+	return n, nil
+}
+
+func (s *Server) variableInvoice(ctx *operationContext, name string) (*objects.Invoice, error) {
+	encoded, _ := ctx.variables[name].(string)
+	for _, invoice := range s.invoices {
+		if invoice.Data.EncodedPaymentRequest == encoded {
+			return invoice, nil
+		}
+	}
+	return nil, graphqlError{"InvalidInput", "unknown payment request"}
+}
+
+func paymentAmount(ctx *operationContext, invoice *objects.Invoice) (int64, error) {
+	amountMsats, hasAmount := int64Variable(ctx.variables, "amount_msats")
+	switch {
+	case invoice.Data.Amount.OriginalValue > 0 && hasAmount:
+		return 0, graphqlError{"InvalidInput", "amount_msats must not be set for invoices with an amount"}
+	case invoice.Data.Amount.OriginalValue > 0:
+		return invoice.Data.Amount.OriginalValue, nil
+	case !hasAmount || amountMsats <= 0:
+		return 0, graphqlError{"InvalidInput", "amount_msats is required for zero-amount invoices"}
+	}
+	return amountMsats, nil
+}
+
+func matchesStatus(variables map[string]interface{}, status objects.TransactionStatus) bool {
+	statuses, ok := variables["statuses"].([]interface{})
+	if !ok || len(statuses) == 0 {
+		return true
+	}
+	for _, value := range statuses {
+		if value == status.StringValue() {
+			return true
+		}
+	}
+	return false
+}
+
+// responseKey returns the field of the response of a mutation, for example create_uma_invoice for
+// CreateUmaInvoice.
+func responseKey(operationName string) string {
+	var key strings.Builder
+	for i, r := range operationName {
+		if unicode.IsUpper(r) {
+			if i > 0 {
+				key.WriteByte('_')
+			}
+			r = unicode.ToLower(r)
+		}
+		key.WriteRune(r)
+	}
+	return key.String()
+}
+
+func int64Variable(variables map[string]interface{}, name string) (int64, bool) {
+	value, ok := variables[name].(float64)
+	return int64(value), ok
+}
+
+func millisatoshis(amount int64) objects.CurrencyAmount {
+	return objects.CurrencyAmount{
+		OriginalValue:                 amount,
+		OriginalUnit:                  objects.CurrencyUnitMillisatoshi,
+		PreferredCurrencyUnit:         objects.CurrencyUnitSatoshi,
+		PreferredCurrencyValueRounded: amount / 1000,
+		PreferredCurrencyValueApprox:  float64(amount) / 1000,
+	}
+}
+
+// encodeInvoice returns a unique payment request for the fake server. It looks like a BOLT11 invoice
+// but cannot be decoded outside of lightsparktest.
+func encodeInvoice(network objects.BitcoinNetwork, amountMsats int64, paymentHash []byte) string {
+	prefix := "lnbcrt"
+	switch network {
+	case objects.BitcoinNetworkMainnet:
+		prefix = "lnbc"
+	case objects.BitcoinNetworkTestnet:
+		prefix = "lntb"
+	case objects.BitcoinNetworkSignet:
+		prefix = "lntbs"
+	}
+	return fmt.Sprintf("%s%dn1lightsparktest%s", prefix, amountMsats, hex.EncodeToString(paymentHash))
+}
@@ -0,0 +1,351 @@
+// Copyright ©, 2023-present, Lightspark Group, Inc. - All Rights Reserved
+
+// Package lightsparktest provides an in-process fake of the Lightspark GraphQL API, so that code using
+// services.LightsparkClient can be tested without credentials or network access.
+//
+// The fake implements the operations of the scripts package on top of an in-memory state of nodes,
+// invoices and payments. It checks the API token, verifies the `X-Lightspark-Signing` header of signed
+// operations, handles zstd bodies, and can send signed webhooks:
+//
+//	server := lightsparktest.NewServer()
+//	defer server.Close()
+//	nodeId := server.AddNode(lightsparktest.NodeConfig{BalanceMsats: 1_000_000})
+//	client := server.Client()
+//	invoice, err := client.CreateInvoice(nodeId, 1000, nil, nil, nil)
+package lightsparktest
+
+import (
+	"crypto"
+	"crypto/rand"
+	"crypto/rsa"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"io"
+	"log/slog"
+	"net/http"
+	"net/http/httptest"
+	"sync"
+	"time"
+
+	"github.com/lightsparkdev/go-sdk/crypto/lightsparkcrypto"
+	"github.com/lightsparkdev/go-sdk/internal/zstd"
+	"github.com/lightsparkdev/go-sdk/objects"
+	"github.com/lightsparkdev/go-sdk/services"
+)
+
+const (
+	// ClientId and ClientSecret are the API token accepted by the fake server.
+	ClientId     = "lightsparktest-client-id"
+	ClientSecret = "lightsparktest-client-secret"
+
+	// AccountId is the id of the account owning every node of the fake server.
+	AccountId = "Account:lightsparktest"
+)
+
+// SignatureVerifier checks the signature of a signed operation. payload is the uncompressed JSON body
+// of the request.
+type SignatureVerifier func(payload []byte, signature []byte) error
+
+// Secp256k1Verifier verifies signatures made by a requester.Secp256k1SigningKey, as used by remote
+// signing nodes.
+func Secp256k1Verifier(publicKey []byte) SignatureVerifier {
+	return func(payload []byte, signature []byte) error {
+		valid, err := lightsparkcrypto.VerifyEcdsa(payload, signature, publicKey)
+		if err != nil {
+			return err
+		}
+		if !valid {
+			return errors.New("invalid signature")
+		}
+		return nil
+	}
+}
+
+// RsaVerifier verifies signatures made by a requester.RsaSigningKey, as used by OSK nodes.
+func RsaVerifier(publicKey *rsa.PublicKey) SignatureVerifier {
+	return func(payload []byte, signature []byte) error {
+		hashed := sha256.Sum256(payload)
+		return rsa.VerifyPSS(publicKey, crypto.SHA256, hashed[:], signature, nil)
+	}
+}
+
+// NodeConfig describes a node added with Server.AddNode.
+type NodeConfig struct {
+	// Network defaults to REGTEST.
+	Network      objects.BitcoinNetwork
+	BalanceMsats int64
+	// Verifier checks the signature of the operations sent on behalf of this node. Signed operations are
+	// rejected when it is nil.
+	Verifier SignatureVerifier
+}
+
+// Option configures a Server.
+type Option func(*Server)
+
+// WithWebhook makes the server send a signed webhook to url whenever a payment or a withdrawal
+// completes. Webhooks are sent in the background once the operation has been answered.
+func WithWebhook(url string, secret string) Option {
+	return func(s *Server) {
+		s.webhookUrl = url
+		s.webhookSecret = secret
+	}
+}
+
+// WithLogf reports the webhooks that could not be delivered with logf, typically t.Logf. They are logged
+// with slog otherwise.
+func WithLogf(logf func(format string, args ...interface{})) Option {
+	return func(s *Server) {
+		s.logf = logf
+	}
+}
+
+// Server is a fake Lightspark GraphQL API. It is safe for concurrent use.
+type Server struct {
+	// URL is the base URL of the fake API, to use as the base URL of a LightsparkClient.
+	URL string
+
+	httpServer    *httptest.Server
+	webhookUrl    string
+	webhookSecret string
+	logf          func(format string, args ...interface{})
+	webhooks      sync.WaitGroup
+
+	mu                 sync.Mutex
+	nodes              map[string]*node
+	invoices           map[string]*objects.Invoice
+	outgoingPayments   map[string]*objects.OutgoingPayment
+	incomingPayments   map[string]*objects.IncomingPayment
+	withdrawalRequests map[string]*objects.WithdrawalRequest
+	umaInvitations     map[string]*objects.UmaInvitation
+	apiTokens          map[string]*objects.ApiToken
+	operations         []string
+}
+
+type node struct {
+	entity   objects.LightsparkNodeWithRemoteSigning
+	balance  int64
+	verifier SignatureVerifier
+}
+
+// NewServer starts a fake Lightspark API. Close must be called once it is not needed anymore.
+func NewServer(opts ...Option) *Server {
+	s := &Server{
+		nodes:              map[string]*node{},
+		invoices:           map[string]*objects.Invoice{},
+		outgoingPayments:   map[string]*objects.OutgoingPayment{},
+		incomingPayments:   map[string]*objects.IncomingPayment{},
+		withdrawalRequests: map[string]*objects.WithdrawalRequest{},
+		umaInvitations:     map[string]*objects.UmaInvitation{},
+		apiTokens:          map[string]*objects.ApiToken{},
+	}
+	for _, opt := range opts {
+		opt(s)
+	}
+	s.httpServer = httptest.NewServer(http.HandlerFunc(s.serveHTTP))
+	s.URL = s.httpServer.URL
+	return s
+}
+
+// Close shuts down the server, once the webhooks being sent are delivered.
+func (s *Server) Close() {
+	s.httpServer.Close()
+	s.webhooks.Wait()
+}
+
+// Client returns a LightsparkClient using the fake API.
+func (s *Server) Client(opts ...services.Option) *services.LightsparkClient {
+	return services.NewLightsparkClient(ClientId, ClientSecret, &s.URL, opts...)
+}
+
+// AddNode adds a node to the account and returns its id.
+func (s *Server) AddNode(config NodeConfig) string {
+	network := config.Network
+	if network == objects.BitcoinNetworkUndefined {
+		network = objects.BitcoinNetworkRegtest
+	}
+	status := objects.LightsparkNodeStatusReady
+	now := time.Now().UTC()
+	publicKey := hex.EncodeToString(randomBytes(33))
+	entity := objects.LightsparkNodeWithRemoteSigning{
+		Id:             newId("LightsparkNodeWithRemoteSigning"),
+		CreatedAt:      now,
+		UpdatedAt:      now,
+		BitcoinNetwork: network,
+		DisplayName:    "lightsparktest",
+		PublicKey:      &publicKey,
+		Status:         &status,
+		Typename:       "LightsparkNodeWithRemoteSigning",
+	}
+	entity.Owner.Id = AccountId
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.nodes[entity.Id] = &node{entity: entity, balance: config.BalanceMsats, verifier: config.Verifier}
+	return entity.Id
+}
+
+// BalanceMsats returns the balance of a node.
+func (s *Server) BalanceMsats(nodeId string) int64 {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if n, ok := s.nodes[nodeId]; ok {
+		return n.balance
+	}
+	return 0
+}
+
+// Operations returns the names of the GraphQL operations received so far, in order.
+func (s *Server) Operations() []string {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return append([]string(nil), s.operations...)
+}
+
+type graphqlRequest struct {
+	OperationName string                 `json:"operationName"`
+	Query         string                 `json:"query"`
+	Variables     map[string]interface{} `json:"variables"`
+}
+
+// graphqlError is returned by operation handlers to answer with a GraphQL user error.
+type graphqlError struct {
+	name    string
+	message string
+}
+
+func (e graphqlError) Error() string {
+	return e.name + ": " + e.message
+}
+
+func (s *Server) serveHTTP(w http.ResponseWriter, r *http.Request) {
+	clientId, clientSecret, ok := r.BasicAuth()
+	if !ok || clientId != ClientId || clientSecret != ClientSecret {
+		http.Error(w, "invalid API token", http.StatusUnauthorized)
+		return
+	}
+	if r.Method != http.MethodPost {
+		http.Error(w, "method not allowed", http.StatusMethodNotAllowed)
+		return
+	}
+
+	body, err := io.ReadAll(r.Body)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+	if r.Header.Get("Content-Encoding") == "zstd" {
+		body, err = zstd.Decompress(nil, body)
+		if err != nil {
+			http.Error(w, err.Error(), http.StatusBadRequest)
+			return
+		}
+	}
+	var request graphqlRequest
+	if err := json.Unmarshal(body, &request); err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+	if request.Variables == nil {
+		request.Variables = map[string]interface{}{}
+	}
+
+	s.mu.Lock()
+	s.operations = append(s.operations, request.OperationName)
+	s.mu.Unlock()
+
+	var response map[string]interface{}
+	data, events, err := s.execute(request, body, r.Header.Get("X-Lightspark-Signing"))
+	if err != nil {
+		var userError graphqlError
+		if errors.As(err, &userError) {
+			response = map[string]interface{}{"errors": []interface{}{map[string]interface{}{
+				"message":    userError.message,
+				"path":       []interface{}{request.OperationName},
+				"extensions": map[string]interface{}{"error_name": userError.name},
+			}}}
+		} else {
+			response = map[string]interface{}{"errors": []interface{}{map[string]interface{}{
+				"message": err.Error(),
+			}}}
+		}
+	} else {
+		response = map[string]interface{}{"data": data}
+	}
+
+	encoded, err := json.Marshal(response)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+		return
+	}
+	w.Header().Set("Content-Type", "application/json")
+	if len(encoded) > 1024 && r.Header.Get("Accept-Encoding") == "zstd" {
+		if compressed, err := zstd.Compress(nil, encoded); err == nil {
+			w.Header().Set("Content-Encoding", "zstd")
+			encoded = compressed
+		}
+	}
+	w.Write(encoded)
+
+	if len(events) > 0 {
+		s.webhooks.Add(1)
+		go s.sendWebhooks(events)
+	}
+}
+
+// sendWebhooks delivers the webhooks of an operation in order, reporting the failures.
+func (s *Server) sendWebhooks(events []pendingWebhook) {
+	defer s.webhooks.Done()
+	for _, event := range events {
+		if err := s.SendWebhook(event.eventType, event.entityId, nil); err != nil {
+			if s.logf != nil {
+				s.logf("lightsparktest: sending %s webhook for %s: %v", event.eventType.StringValue(), event.entityId, err)
+			} else {
+				slog.Warn("lightsparktest: sending webhook failed",
+					slog.String("event_type", event.eventType.StringValue()),
+					slog.String("entity_id", event.entityId),
+					slog.Any("error", err),
+				)
+			}
+		}
+	}
+}
+
+// verifySignature checks the `X-Lightspark-Signing` header against the verifier of the node.
+func (s *Server) verifySignature(n *node, payload []byte, header string) error {
+	if header == "" {
+		return graphqlError{"MissingSignature", "this operation must be signed with the node signing key"}
+	}
+	var signing struct {
+		Version   int    `json:"v"`
+		Signature string `json:"signature"`
+	}
+	if err := json.Unmarshal([]byte(header), &signing); err != nil {
+		return graphqlError{"InvalidSignature", "invalid X-Lightspark-Signing header"}
+	}
+	signature, err := base64.StdEncoding.DecodeString(signing.Signature)
+	if err != nil || signing.Version != 1 {
+		return graphqlError{"InvalidSignature", "invalid X-Lightspark-Signing header"}
+	}
+	if n.verifier == nil {
+		return graphqlError{"InvalidSignature", "no signing key is registered for node " + n.entity.Id}
+	}
+	if err := n.verifier(payload, signature); err != nil {
+		return graphqlError{"InvalidSignature", err.Error()}
+	}
+	return nil
+}
+
+func randomBytes(length int) []byte {
+	result := make([]byte, length)
+	if _, err := rand.Read(result); err != nil {
+		panic(err)
+	}
+	return result
+}
+
+func newId(typename string) string {
+	return typename + ":" + hex.EncodeToString(randomBytes(16))
+}
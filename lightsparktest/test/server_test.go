@@ -0,0 +1,199 @@
+// Copyright ©, 2023-present, Lightspark Group, Inc. - All Rights Reserved
+package lightsparktest_test
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/btcsuite/btcd/btcec/v2"
+	"github.com/lightsparkdev/go-sdk/lightsparktest"
+	"github.com/lightsparkdev/go-sdk/objects"
+	"github.com/lightsparkdev/go-sdk/requester"
+	"github.com/lightsparkdev/go-sdk/services"
+	"github.com/lightsparkdev/go-sdk/webhooks"
+	"github.com/stretchr/testify/require"
+)
+
+func newSigningNode(t *testing.T, server *lightsparktest.Server, client *services.LightsparkClient,
+	balanceMsats int64,
+) string {
+	privateKey, err := btcec.NewPrivateKey()
+	require.NoError(t, err)
+	nodeId := server.AddNode(lightsparktest.NodeConfig{
+		BalanceMsats: balanceMsats,
+		Verifier:     lightsparktest.Secp256k1Verifier(privateKey.PubKey().SerializeCompressed()),
+	})
+	client.SetNodeSigningKey(nodeId, &requester.Secp256k1SigningKey{PrivateKey: privateKey.Serialize()})
+	return nodeId
+}
+
+func TestPayInternalInvoice(t *testing.T) {
+	server := lightsparktest.NewServer()
+	defer server.Close()
+	client := server.Client()
+	payerId := newSigningNode(t, server, client, 1_000_000)
+	payeeId := server.AddNode(lightsparktest.NodeConfig{})
+
+	memo := "coffee"
+	invoice, err := client.CreateInvoice(payeeId, 250_000, &memo, nil, nil)
+	require.NoError(t, err)
+	require.Equal(t, objects.PaymentRequestStatusOpen, invoice.Status)
+	require.True(t, strings.HasPrefix(invoice.Data.EncodedPaymentRequest, "lnbcrt"))
+	require.Equal(t, memo, *invoice.Data.Memo)
+
+	payment, err := client.PayInvoice(payerId, invoice.Data.EncodedPaymentRequest, 60, 1000, nil)
+	require.NoError(t, err)
+	require.Equal(t, objects.TransactionStatusSuccess, payment.Status)
+	require.Equal(t, int64(250_000), payment.Amount.OriginalValue)
+	require.Equal(t, int64(750_000), server.BalanceMsats(payerId))
+	require.Equal(t, int64(250_000), server.BalanceMsats(payeeId))
+
+	paidInvoice, err := client.WaitForInvoicePaid(context.Background(), invoice.Id)
+	require.NoError(t, err)
+	require.Equal(t, objects.PaymentRequestStatusClosed, paidInvoice.Status)
+}
+
+func TestPaymentFailures(t *testing.T) {
+	server := lightsparktest.NewServer()
+	defer server.Close()
+	client := server.Client()
+	payerId := newSigningNode(t, server, client, 1000)
+	payeeId := server.AddNode(lightsparktest.NodeConfig{})
+
+	invoice, err := client.CreateInvoice(payeeId, 5000, nil, nil, nil)
+	require.NoError(t, err)
+	payment, err := client.PayInvoice(payerId, invoice.Data.EncodedPaymentRequest, 60, 1000, nil)
+	require.NoError(t, err)
+	require.Equal(t, objects.TransactionStatusFailed, payment.Status)
+	require.Equal(t, objects.PaymentFailureReasonInsufficientBalance, *payment.FailureReason)
+
+	_, err = client.WaitForOutgoingPayment(context.Background(), payment.Id)
+	require.ErrorIs(t, err, requester.ErrInsufficientBalance)
+	require.Equal(t, int64(1000), server.BalanceMsats(payerId))
+}
+
+func TestSignedOperationsRequireValidSignature(t *testing.T) {
+	server := lightsparktest.NewServer()
+	defer server.Close()
+	client := server.Client()
+	nodeId := newSigningNode(t, server, client, 1_000_000)
+
+	otherKey, err := btcec.NewPrivateKey()
+	require.NoError(t, err)
+	client.SetNodeSigningKey(nodeId, &requester.Secp256k1SigningKey{PrivateKey: otherKey.Serialize()})
+
+	_, err = client.RequestWithdrawal(nodeId, 100, "bcrt1qtest", objects.WithdrawalModeWalletOnly)
+	var graphqlError requester.GraphQLError
+	require.True(t, errors.As(err, &graphqlError))
+	require.Equal(t, "InvalidSignature", graphqlError.Type)
+	require.Equal(t, int64(1_000_000), server.BalanceMsats(nodeId))
+}
+
+func TestWithdrawalMustCoverFee(t *testing.T) {
+	server := lightsparktest.NewServer()
+	defer server.Close()
+	client := server.Client()
+	nodeId := newSigningNode(t, server, client, 1_000_000)
+
+	_, err := client.RequestWithdrawal(nodeId, 500, "bcrt1qtest", objects.WithdrawalModeWalletOnly)
+	var graphqlError requester.GraphQLError
+	require.True(t, errors.As(err, &graphqlError))
+	require.Equal(t, "InvalidInput", graphqlError.Type)
+	require.Equal(t, int64(1_000_000), server.BalanceMsats(nodeId))
+
+	request, err := client.RequestWithdrawal(nodeId, 501, "bcrt1qtest", objects.WithdrawalModeWalletOnly)
+	require.NoError(t, err)
+	require.Equal(t, int64(1000), request.AmountWithdrawn.OriginalValue)
+	require.Equal(t, int64(499_000), server.BalanceMsats(nodeId))
+}
+
+func TestTestModePaymentAndWebhook(t *testing.T) {
+	events := make(chan *webhooks.WebhookEvent, 10)
+	webhookServer := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		body, _ := io.ReadAll(r.Body)
+		event, err := webhooks.VerifyAndParse(body, r.Header.Get(webhooks.SIGNATURE_HEADER), "secret")
+		if err != nil {
+			http.Error(w, err.Error(), http.StatusBadRequest)
+			return
+		}
+		events <- event
+	}))
+	defer webhookServer.Close()
+
+	server := lightsparktest.NewServer(lightsparktest.WithWebhook(webhookServer.URL, "secret"), lightsparktest.WithLogf(t.Errorf))
+	defer server.Close()
+	client := server.Client()
+	nodeId := server.AddNode(lightsparktest.NodeConfig{})
+
+	invoice, err := client.CreateInvoice(nodeId, 0, nil, nil, nil)
+	require.NoError(t, err)
+	amountMsats := int64(42_000)
+	payment, err := client.CreateTestModePayment(nodeId, invoice.Data.EncodedPaymentRequest, &amountMsats)
+	require.NoError(t, err)
+	require.Equal(t, objects.TransactionStatusSuccess, payment.Status)
+	require.Equal(t, amountMsats, server.BalanceMsats(nodeId))
+
+	select {
+	case event := <-events:
+		require.Equal(t, objects.WebhookEventTypePaymentFinished, event.EventType)
+		require.Equal(t, payment.Id, event.EntityId)
+	case <-time.After(5 * time.Second):
+		t.Fatal("no webhook received")
+	}
+}
+
+func TestWebhookFailuresAreReported(t *testing.T) {
+	webhookServer := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		http.Error(w, "unavailable", http.StatusServiceUnavailable)
+	}))
+	defer webhookServer.Close()
+
+	failures := make(chan string, 10)
+	logf := func(format string, args ...interface{}) {
+		failures <- fmt.Sprintf(format, args...)
+	}
+	server := lightsparktest.NewServer(lightsparktest.WithWebhook(webhookServer.URL, "secret"), lightsparktest.WithLogf(logf))
+	client := server.Client()
+	nodeId := server.AddNode(lightsparktest.NodeConfig{})
+
+	invoice, err := client.CreateInvoice(nodeId, 1000, nil, nil, nil)
+	require.NoError(t, err)
+	payment, err := client.CreateTestModePayment(nodeId, invoice.Data.EncodedPaymentRequest, nil)
+	require.NoError(t, err)
+
+	// Close waits for the webhooks, so the failure has been reported once it returns.
+	server.Close()
+	select {
+	case failure := <-failures:
+		require.Contains(t, failure, payment.Id)
+		require.Contains(t, failure, "status 503")
+	default:
+		t.Fatal("webhook failure not reported")
+	}
+}
+
+func TestUnsupportedOperationAndAuthentication(t *testing.T) {
+	server := lightsparktest.NewServer()
+	defer server.Close()
+
+	_, err := server.Client().ExecuteGraphql("query Unknown { unknown }", nil, nil)
+	var graphqlError requester.GraphQLError
+	require.True(t, errors.As(err, &graphqlError))
+	require.Equal(t, "UnsupportedOperation", graphqlError.Type)
+
+	baseUrl := server.URL
+	client := services.NewLightsparkClient("wrong", "token", &baseUrl)
+	_, err = client.GetCurrentAccount()
+	var requestError requester.RequestError
+	require.True(t, errors.As(err, &requestError))
+	require.Equal(t, http.StatusUnauthorized, requestError.StatusCode)
+
+	require.Contains(t, server.Operations(), "Unknown")
+}
@@ -0,0 +1,56 @@
+// Copyright ©, 2023-present, Lightspark Group, Inc. - All Rights Reserved
+package lightsparktest
+
+import (
+	"bytes"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"time"
+
+	"github.com/lightsparkdev/go-sdk/objects"
+	"github.com/lightsparkdev/go-sdk/webhooks"
+)
+
+type pendingWebhook struct {
+	eventType objects.WebhookEventType
+	entityId  string
+}
+
+// SendWebhook sends a webhook event signed with the secret given to WithWebhook. It does nothing when no
+// webhook is configured.
+func (s *Server) SendWebhook(eventType objects.WebhookEventType, entityId string, data map[string]interface{}) error {
+	if s.webhookUrl == "" {
+		return nil
+	}
+	event := map[string]interface{}{
+		"event_type": eventType.StringValue(),
+		"event_id":   hex.EncodeToString(randomBytes(16)),
+		"timestamp":  time.Now().UTC().Format(time.RFC3339),
+		"entity_id":  entityId,
+	}
+	if data != nil {
+		event["data"] = data
+	}
+	body, err := json.Marshal(event)
+	if err != nil {
+		return err
+	}
+
+	request, err := http.NewRequest(http.MethodPost, s.webhookUrl, bytes.NewReader(body))
+	if err != nil {
+		return err
+	}
+	request.Header.Set("Content-Type", "application/json")
+	request.Header.Set(webhooks.SIGNATURE_HEADER, webhooks.Sign(body, s.webhookSecret))
+	response, err := http.DefaultClient.Do(request)
+	if err != nil {
+		return err
+	}
+	defer response.Body.Close()
+	if response.StatusCode < 200 || response.StatusCode > 299 {
+		return fmt.Errorf("webhook rejected with status %d", response.StatusCode)
+	}
+	return nil
+}
@@ -0,0 +1,244 @@
+// Copyright ©, 2023-present, Lightspark Group, Inc. - All Rights Reserved
+package pagination
+
+import (
+	"github.com/lightsparkdev/go-sdk/objects"
+	"github.com/lightsparkdev/go-sdk/requester"
+)
+
+// The constructors below create a Paginator for every connection of the objects package. fetch is
+// usually a closure calling the matching accessor, such as Account.GetTransactions, with the cursor and
+// page size it receives. Connections without page info, such as NodeToAddressesConnection, are returned
+// as a single page. ChannelToTransactionsConnection has no entities and cannot be iterated.
+
+// AccountApiTokens creates a Paginator over an objects.AccountToApiTokensConnection.
+func AccountApiTokens(
+	requester *requester.Requester,
+	fetch func(requester *requester.Requester, first *int64, after *string) (*objects.AccountToApiTokensConnection, error),
+	opts ...Option,
+) *Paginator[objects.ApiToken] {
+	return fromConnection(requester, fetch, func(connection *objects.AccountToApiTokensConnection) Page[objects.ApiToken] {
+		return pageFromConnection(connection.Entities, connection.PageInfo)
+	}, opts...)
+}
+
+// AccountChannels creates a Paginator over an objects.AccountToChannelsConnection.
+func AccountChannels(
+	requester *requester.Requester,
+	fetch func(requester *requester.Requester, first *int64, after *string) (*objects.AccountToChannelsConnection, error),
+	opts ...Option,
+) *Paginator[objects.Channel] {
+	return fromConnection(requester, fetch, func(connection *objects.AccountToChannelsConnection) Page[objects.Channel] {
+		return pageFromConnection(connection.Entities, connection.PageInfo)
+	}, opts...)
+}
+
+// AccountNodes creates a Paginator over an objects.AccountToNodesConnection.
+func AccountNodes(
+	requester *requester.Requester,
+	fetch func(requester *requester.Requester, first *int64, after *string) (*objects.AccountToNodesConnection, error),
+	opts ...Option,
+) *Paginator[objects.LightsparkNode] {
+	return fromConnection(requester, fetch, func(connection *objects.AccountToNodesConnection) Page[objects.LightsparkNode] {
+		return pageFromConnection(connection.Entities, connection.PageInfo)
+	}, opts...)
+}
+
+// AccountPaymentRequests creates a Paginator over an objects.AccountToPaymentRequestsConnection.
+func AccountPaymentRequests(
+	requester *requester.Requester,
+	fetch func(requester *requester.Requester, first *int64, after *string) (*objects.AccountToPaymentRequestsConnection, error),
+	opts ...Option,
+) *Paginator[objects.PaymentRequest] {
+	return fromConnection(requester, fetch, func(connection *objects.AccountToPaymentRequestsConnection) Page[objects.PaymentRequest] {
+		return pageFromConnection(connection.Entities, connection.PageInfo)
+	}, opts...)
+}
+
+// AccountTransactions creates a Paginator over an objects.AccountToTransactionsConnection.
+func AccountTransactions(
+	requester *requester.Requester,
+	fetch func(requester *requester.Requester, first *int64, after *string) (*objects.AccountToTransactionsConnection, error),
+	opts ...Option,
+) *Paginator[objects.Transaction] {
+	return fromConnection(requester, fetch, func(connection *objects.AccountToTransactionsConnection) Page[objects.Transaction] {
+		return pageFromConnection(connection.Entities, connection.PageInfo)
+	}, opts...)
+}
+
+// AccountWallets creates a Paginator over an objects.AccountToWalletsConnection.
+func AccountWallets(
+	requester *requester.Requester,
+	fetch func(requester *requester.Requester, first *int64, after *string) (*objects.AccountToWalletsConnection, error),
+	opts ...Option,
+) *Paginator[objects.Wallet] {
+	return fromConnection(requester, fetch, func(connection *objects.AccountToWalletsConnection) Page[objects.Wallet] {
+		return pageFromConnection(connection.Entities, connection.PageInfo)
+	}, opts...)
+}
+
+// AccountWithdrawalRequests creates a Paginator over an objects.AccountToWithdrawalRequestsConnection.
+func AccountWithdrawalRequests(
+	requester *requester.Requester,
+	fetch func(requester *requester.Requester, first *int64, after *string) (*objects.AccountToWithdrawalRequestsConnection, error),
+	opts ...Option,
+) *Paginator[objects.WithdrawalRequest] {
+	return fromConnection(requester, fetch, func(connection *objects.AccountToWithdrawalRequestsConnection) Page[objects.WithdrawalRequest] {
+		return pageFromConnection(connection.Entities, connection.PageInfo)
+	}, opts...)
+}
+
+// IncomingPaymentAttempts creates a Paginator over an objects.IncomingPaymentToAttemptsConnection.
+func IncomingPaymentAttempts(
+	requester *requester.Requester,
+	fetch func(requester *requester.Requester, first *int64, after *string) (*objects.IncomingPaymentToAttemptsConnection, error),
+	opts ...Option,
+) *Paginator[objects.IncomingPaymentAttempt] {
+	return fromConnection(requester, fetch, func(connection *objects.IncomingPaymentToAttemptsConnection) Page[objects.IncomingPaymentAttempt] {
+		return pageFromConnection(connection.Entities, connection.PageInfo)
+	}, opts...)
+}
+
+// LightsparkNodeChannels creates a Paginator over an objects.LightsparkNodeToChannelsConnection.
+func LightsparkNodeChannels(
+	requester *requester.Requester,
+	fetch func(requester *requester.Requester, first *int64, after *string) (*objects.LightsparkNodeToChannelsConnection, error),
+	opts ...Option,
+) *Paginator[objects.Channel] {
+	return fromConnection(requester, fetch, func(connection *objects.LightsparkNodeToChannelsConnection) Page[objects.Channel] {
+		return pageFromConnection(connection.Entities, connection.PageInfo)
+	}, opts...)
+}
+
+// LightsparkNodeDailyLiquidityForecasts creates a Paginator over an objects.LightsparkNodeToDailyLiquidityForecastsConnection, which is returned as a single page.
+func LightsparkNodeDailyLiquidityForecasts(
+	requester *requester.Requester,
+	fetch func(requester *requester.Requester, first *int64, after *string) (*objects.LightsparkNodeToDailyLiquidityForecastsConnection, error),
+	opts ...Option,
+) *Paginator[objects.DailyLiquidityForecast] {
+	return fromConnection(requester, fetch, func(connection *objects.LightsparkNodeToDailyLiquidityForecastsConnection) Page[objects.DailyLiquidityForecast] {
+		return Page[objects.DailyLiquidityForecast]{Entities: connection.Entities}
+	}, opts...)
+}
+
+// NodeAddresses creates a Paginator over an objects.NodeToAddressesConnection, which is returned as a single page.
+func NodeAddresses(
+	requester *requester.Requester,
+	fetch func(requester *requester.Requester, first *int64, after *string) (*objects.NodeToAddressesConnection, error),
+	opts ...Option,
+) *Paginator[objects.NodeAddress] {
+	return fromConnection(requester, fetch, func(connection *objects.NodeToAddressesConnection) Page[objects.NodeAddress] {
+		return Page[objects.NodeAddress]{Entities: connection.Entities}
+	}, opts...)
+}
+
+// OutgoingPaymentAttemptHops creates a Paginator over an objects.OutgoingPaymentAttemptToHopsConnection.
+func OutgoingPaymentAttemptHops(
+	requester *requester.Requester,
+	fetch func(requester *requester.Requester, first *int64, after *string) (*objects.OutgoingPaymentAttemptToHopsConnection, error),
+	opts ...Option,
+) *Paginator[objects.Hop] {
+	return fromConnection(requester, fetch, func(connection *objects.OutgoingPaymentAttemptToHopsConnection) Page[objects.Hop] {
+		return pageFromConnection(connection.Entities, connection.PageInfo)
+	}, opts...)
+}
+
+// OutgoingPaymentAttempts creates a Paginator over an objects.OutgoingPaymentToAttemptsConnection.
+func OutgoingPaymentAttempts(
+	requester *requester.Requester,
+	fetch func(requester *requester.Requester, first *int64, after *string) (*objects.OutgoingPaymentToAttemptsConnection, error),
+	opts ...Option,
+) *Paginator[objects.OutgoingPaymentAttempt] {
+	return fromConnection(requester, fetch, func(connection *objects.OutgoingPaymentToAttemptsConnection) Page[objects.OutgoingPaymentAttempt] {
+		return pageFromConnection(connection.Entities, connection.PageInfo)
+	}, opts...)
+}
+
+// WalletPaymentRequests creates a Paginator over an objects.WalletToPaymentRequestsConnection.
+func WalletPaymentRequests(
+	requester *requester.Requester,
+	fetch func(requester *requester.Requester, first *int64, after *string) (*objects.WalletToPaymentRequestsConnection, error),
+	opts ...Option,
+) *Paginator[objects.PaymentRequest] {
+	return fromConnection(requester, fetch, func(connection *objects.WalletToPaymentRequestsConnection) Page[objects.PaymentRequest] {
+		return pageFromConnection(connection.Entities, connection.PageInfo)
+	}, opts...)
+}
+
+// WalletTransactions creates a Paginator over an objects.WalletToTransactionsConnection.
+func WalletTransactions(
+	requester *requester.Requester,
+	fetch func(requester *requester.Requester, first *int64, after *string) (*objects.WalletToTransactionsConnection, error),
+	opts ...Option,
+) *Paginator[objects.Transaction] {
+	return fromConnection(requester, fetch, func(connection *objects.WalletToTransactionsConnection) Page[objects.Transaction] {
+		return pageFromConnection(connection.Entities, connection.PageInfo)
+	}, opts...)
+}
+
+// WalletWithdrawalRequests creates a Paginator over an objects.WalletToWithdrawalRequestsConnection.
+func WalletWithdrawalRequests(
+	requester *requester.Requester,
+	fetch func(requester *requester.Requester, first *int64, after *string) (*objects.WalletToWithdrawalRequestsConnection, error),
+	opts ...Option,
+) *Paginator[objects.WithdrawalRequest] {
+	return fromConnection(requester, fetch, func(connection *objects.WalletToWithdrawalRequestsConnection) Page[objects.WithdrawalRequest] {
+		return pageFromConnection(connection.Entities, connection.PageInfo)
+	}, opts...)
+}
+
+// WithdrawalRequestChannelClosingTransactions creates a Paginator over an objects.WithdrawalRequestToChannelClosingTransactionsConnection.
+func WithdrawalRequestChannelClosingTransactions(
+	requester *requester.Requester,
+	fetch func(requester *requester.Requester, first *int64, after *string) (*objects.WithdrawalRequestToChannelClosingTransactionsConnection, error),
+	opts ...Option,
+) *Paginator[objects.ChannelClosingTransaction] {
+	return fromConnection(requester, fetch, func(connection *objects.WithdrawalRequestToChannelClosingTransactionsConnection) Page[objects.ChannelClosingTransaction] {
+		return pageFromConnection(connection.Entities, connection.PageInfo)
+	}, opts...)
+}
+
+// WithdrawalRequestChannelOpeningTransactions creates a Paginator over an objects.WithdrawalRequestToChannelOpeningTransactionsConnection.
+func WithdrawalRequestChannelOpeningTransactions(
+	requester *requester.Requester,
+	fetch func(requester *requester.Requester, first *int64, after *string) (*objects.WithdrawalRequestToChannelOpeningTransactionsConnection, error),
+	opts ...Option,
+) *Paginator[objects.ChannelOpeningTransaction] {
+	return fromConnection(requester, fetch, func(connection *objects.WithdrawalRequestToChannelOpeningTransactionsConnection) Page[objects.ChannelOpeningTransaction] {
+		return pageFromConnection(connection.Entities, connection.PageInfo)
+	}, opts...)
+}
+
+// WithdrawalRequestWithdrawals creates a Paginator over an objects.WithdrawalRequestToWithdrawalsConnection, which is returned as a single page.
+func WithdrawalRequestWithdrawals(
+	requester *requester.Requester,
+	fetch func(requester *requester.Requester, first *int64, after *string) (*objects.WithdrawalRequestToWithdrawalsConnection, error),
+	opts ...Option,
+) *Paginator[objects.Withdrawal] {
+	return fromConnection(requester, fetch, func(connection *objects.WithdrawalRequestToWithdrawalsConnection) Page[objects.Withdrawal] {
+		return Page[objects.Withdrawal]{Entities: connection.Entities}
+	}, opts...)
+}
+
+func fromConnection[C any, T any](
+	req *requester.Requester,
+	fetch func(requester *requester.Requester, first *int64, after *string) (*C, error),
+	toPage func(connection *C) Page[T],
+	opts ...Option,
+) *Paginator[T] {
+	return New(req, func(req *requester.Requester, first *int64, after *string) (Page[T], error) {
+		connection, err := fetch(req, first, after)
+		if err != nil || connection == nil {
+			return Page[T]{}, err
+		}
+		return toPage(connection), nil
+	}, opts...)
+}
+
+func pageFromConnection[T any](entities []T, pageInfo objects.PageInfo) Page[T] {
+	return Page[T]{
+		Entities:    entities,
+		HasNextPage: pageInfo.HasNextPage != nil && *pageInfo.HasNextPage,
+		EndCursor:   pageInfo.EndCursor,
+	}
+}
@@ -0,0 +1,174 @@
+// Copyright ©, 2023-present, Lightspark Group, Inc. - All Rights Reserved
+
+// Package pagination iterates over the connections of the objects package, following their
+// `end_cursor` and `has_next_page` page info automatically.
+//
+// A Paginator is created from the connection accessor to call, for example:
+//
+//	paginator := pagination.AccountTransactions(client.Requester,
+//		func(requester *requester.Requester, first *int64, after *string) (*objects.AccountToTransactionsConnection, error) {
+//			return account.GetTransactions(requester, first, after, nil, nil, nil, &network, nil, nil, nil, nil, nil)
+//		}, pagination.WithPageSize(50))
+//	for transaction, err := range paginator.All(ctx) {
+//		if err != nil {
+//			return err
+//		}
+//		...
+//	}
+package pagination
+
+import (
+	"context"
+	"errors"
+	"iter"
+
+	"github.com/lightsparkdev/go-sdk/requester"
+)
+
+// Page is a single page of entities returned by a connection.
+type Page[T any] struct {
+	Entities []T
+	// HasNextPage reports whether there are more entities after this page.
+	HasNextPage bool
+	// EndCursor is the cursor to use to fetch the next page.
+	EndCursor *string
+}
+
+// FetchFunc fetches the page following the given cursor, which is nil for the first page. first is the
+// requested page size, or nil to use the server default. The requester follows the context given to
+// the Paginator and must be used for the GraphQL call.
+type FetchFunc[T any] func(requester *requester.Requester, first *int64, after *string) (Page[T], error)
+
+// Option configures a Paginator.
+type Option func(*options)
+
+type options struct {
+	pageSize *int64
+	after    *string
+}
+
+// WithPageSize sets the number of entities requested for each page.
+func WithPageSize(pageSize int64) Option {
+	return func(o *options) {
+		if pageSize > 0 {
+			o.pageSize = &pageSize
+		}
+	}
+}
+
+// WithCursor starts the pagination after the given cursor instead of at the beginning of the connection.
+func WithCursor(after string) Option {
+	return func(o *options) {
+		o.after = &after
+	}
+}
+
+// Paginator fetches the pages of a connection one after the other. It is not safe for concurrent use.
+type Paginator[T any] struct {
+	requester *requester.Requester
+	fetch     FetchFunc[T]
+	pageSize  *int64
+	cursor    *string
+	done      bool
+}
+
+// New creates a Paginator calling fetch for every page.
+func New[T any](requester *requester.Requester, fetch FetchFunc[T], opts ...Option) *Paginator[T] {
+	o := options{}
+	for _, opt := range opts {
+		opt(&o)
+	}
+	return &Paginator[T]{
+		requester: requester,
+		fetch:     fetch,
+		pageSize:  o.pageSize,
+		cursor:    o.after,
+	}
+}
+
+// HasNext reports whether Next may return more entities.
+func (p *Paginator[T]) HasNext() bool {
+	return !p.done
+}
+
+// Cursor returns the cursor of the last fetched page, which can be given to WithCursor to resume the
+// pagination later.
+func (p *Paginator[T]) Cursor() *string {
+	return p.cursor
+}
+
+// Next fetches the next page. It returns an empty page without calling the API once every page has been
+// fetched.
+func (p *Paginator[T]) Next(ctx context.Context) ([]T, error) {
+	if p.done {
+		return nil, nil
+	}
+	if err := ctx.Err(); err != nil {
+		return nil, err
+	}
+
+	page, err := p.fetch(p.requester.WithContext(ctx), p.pageSize, p.cursor)
+	if err != nil {
+		return nil, err
+	}
+	if !page.HasNextPage || page.EndCursor == nil {
+		p.done = true
+	} else if p.cursor != nil && *p.cursor == *page.EndCursor {
+		p.done = true
+		return nil, errors.New("pagination cursor did not advance")
+	}
+	if page.EndCursor != nil {
+		p.cursor = page.EndCursor
+	}
+	return page.Entities, nil
+}
+
+// Pages returns an iterator over the remaining pages. Iteration stops after the first error.
+func (p *Paginator[T]) Pages(ctx context.Context) iter.Seq2[[]T, error] {
+	return func(yield func([]T, error) bool) {
+		for p.HasNext() {
+			entities, err := p.Next(ctx)
+			if err != nil {
+				yield(nil, err)
+				return
+			}
+			if len(entities) == 0 {
+				continue
+			}
+			if !yield(entities, nil) {
+				return
+			}
+		}
+	}
+}
+
+// All returns an iterator over the remaining entities of every page. Iteration stops after the first
+// error. Breaking out of the loop stops fetching pages.
+func (p *Paginator[T]) All(ctx context.Context) iter.Seq2[T, error] {
+	return func(yield func(T, error) bool) {
+		for entities, err := range p.Pages(ctx) {
+			if err != nil {
+				var zero T
+				yield(zero, err)
+				return
+			}
+			for _, entity := range entities {
+				if !yield(entity, nil) {
+					return
+				}
+			}
+		}
+	}
+}
+
+// Collect fetches every remaining entity.
+func (p *Paginator[T]) Collect(ctx context.Context) ([]T, error) {
+	var result []T
+	for entity, err := range p.All(ctx) {
+		if err != nil {
+			return nil, err
+		}
+		result = append(result, entity)
+	}
+	return result, nil
+}
@@ -0,0 +1,156 @@
+package pagination_test
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"strconv"
+	"testing"
+
+	"github.com/lightsparkdev/go-sdk/internal/zstd"
+	"github.com/lightsparkdev/go-sdk/objects"
+	"github.com/lightsparkdev/go-sdk/pagination"
+	"github.com/lightsparkdev/go-sdk/requester"
+	"github.com/stretchr/testify/require"
+)
+
+// newApiTokensServer serves `total` api tokens through the api_tokens connection of an account.
+func newApiTokensServer(t *testing.T, total int) (*requester.Requester, *[]map[string]interface{}) {
+	var requests []map[string]interface{}
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		body, err := io.ReadAll(r.Body)
+		require.NoError(t, err)
+		if r.Header.Get("Content-Encoding") == "zstd" {
+			body, err = zstd.Decompress(nil, body)
+			require.NoError(t, err)
+		}
+		var payload map[string]interface{}
+		require.NoError(t, json.Unmarshal(body, &payload))
+		variables := payload["variables"].(map[string]interface{})
+		requests = append(requests, variables)
+
+		start := 0
+		if after, ok := variables["after"].(string); ok {
+			start, _ = strconv.Atoi(after)
+		}
+		end := total
+		if first, ok := variables["first"].(float64); ok && start+int(first) < total {
+			end = start + int(first)
+		}
+		entities := []map[string]interface{}{}
+		for i := start; i < end; i++ {
+			entities = append(entities, map[string]interface{}{
+				"__typename":   "ApiToken",
+				"api_token_id": fmt.Sprintf("ApiToken:%d", i),
+			})
+		}
+		json.NewEncoder(w).Encode(map[string]interface{}{
+			"data": map[string]interface{}{
+				"entity": map[string]interface{}{
+					"api_tokens": map[string]interface{}{
+						"__typename": "AccountToApiTokensConnection",
+						"account_to_api_tokens_connection_page_info": map[string]interface{}{
+							"page_info_has_next_page": end < total,
+							"page_info_end_cursor":    strconv.Itoa(end),
+						},
+						"account_to_api_tokens_connection_entities": entities,
+					},
+				},
+			},
+		})
+	}))
+	t.Cleanup(server.Close)
+	return requester.NewRequesterWithBaseUrl("client_id", "client_secret", &server.URL), &requests
+}
+
+func apiTokens(account objects.Account) func(*requester.Requester, *int64, *string) (*objects.AccountToApiTokensConnection, error) {
+	return func(requester *requester.Requester, first *int64, after *string) (*objects.AccountToApiTokensConnection, error) {
+		return account.GetApiTokens(requester, first, after)
+	}
+}
+
+func TestAllFollowsCursors(t *testing.T) {
+	r, requests := newApiTokensServer(t, 5)
+	paginator := pagination.AccountApiTokens(r, apiTokens(objects.Account{Id: "Account:1"}), pagination.WithPageSize(2))
+
+	var ids []string
+	for token, err := range paginator.All(context.Background()) {
+		require.NoError(t, err)
+		ids = append(ids, token.Id)
+	}
+	require.Equal(t, []string{"ApiToken:0", "ApiToken:1", "ApiToken:2", "ApiToken:3", "ApiToken:4"}, ids)
+	require.Len(t, *requests, 3)
+	require.Nil(t, (*requests)[0]["after"])
+	require.Equal(t, "2", (*requests)[1]["after"])
+	require.Equal(t, float64(2), (*requests)[2]["first"])
+	require.False(t, paginator.HasNext())
+}
+
+func TestAllEarlyStop(t *testing.T) {
+	r, requests := newApiTokensServer(t, 10)
+	paginator := pagination.AccountApiTokens(r, apiTokens(objects.Account{Id: "Account:1"}), pagination.WithPageSize(3))
+
+	count := 0
+	for _, err := range paginator.All(context.Background()) {
+		require.NoError(t, err)
+		count++
+		if count == 4 {
+			break
+		}
+	}
+	require.Len(t, *requests, 2)
+	require.True(t, paginator.HasNext())
+
+	// The paginator resumes at the next page.
+	page, err := paginator.Next(context.Background())
+	require.NoError(t, err)
+	require.Equal(t, "ApiToken:6", page[0].Id)
+}
+
+func TestContextCancellation(t *testing.T) {
+	r, requests := newApiTokensServer(t, 10)
+	paginator := pagination.AccountApiTokens(r, apiTokens(objects.Account{Id: "Account:1"}), pagination.WithPageSize(3))
+
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	var err error
+	for _, err = range paginator.All(ctx) {
+		if err != nil {
+			break
+		}
+		cancel()
+	}
+	require.ErrorIs(t, err, context.Canceled)
+	require.Len(t, *requests, 1)
+}
+
+func TestCollectAndCursor(t *testing.T) {
+	fetch := func(requester *requester.Requester, first *int64, after *string) (pagination.Page[int], error) {
+		if after == nil {
+			cursor := "a"
+			return pagination.Page[int]{Entities: []int{1, 2}, HasNextPage: true, EndCursor: &cursor}, nil
+		}
+		return pagination.Page[int]{Entities: []int{3}}, nil
+	}
+
+	r := requester.NewRequester("client_id", "client_secret")
+	entities, err := pagination.New(r, fetch).Collect(context.Background())
+	require.NoError(t, err)
+	require.Equal(t, []int{1, 2, 3}, entities)
+
+	entities, err = pagination.New(r, fetch, pagination.WithCursor("a")).Collect(context.Background())
+	require.NoError(t, err)
+	require.Equal(t, []int{3}, entities)
+}
+
+func TestStuckCursor(t *testing.T) {
+	cursor := "same"
+	fetch := func(requester *requester.Requester, first *int64, after *string) (pagination.Page[int], error) {
+		return pagination.Page[int]{Entities: []int{1}, HasNextPage: true, EndCursor: &cursor}, nil
+	}
+	_, err := pagination.New(requester.NewRequester("client_id", "client_secret"), fetch).Collect(context.Background())
+	require.Error(t, err)
+}
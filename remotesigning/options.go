@@ -0,0 +1,93 @@
+// Copyright ©, 2023-present, Lightspark Group, Inc. - All Rights Reserved
+package remotesigning
+
+import (
+	"context"
+	"log/slog"
+
+	lightspark "github.com/lightsparkdev/go-sdk"
+	"go.opentelemetry.io/otel"
+	"go.opentelemetry.io/otel/trace"
+)
+
+const tracerName = "github.com/lightsparkdev/go-sdk/remotesigning"
+
+// Option configures how remote signing webhooks are parsed and handled.
+type Option func(*options)
+
+type options struct {
+	ctx        context.Context
+	logger     *slog.Logger
+	tracer     trace.Tracer
+	stateStore SignerStateStore
+	webhook    *signedWebhook
+}
+
+// WithLogger sets the logger used to report remote signing events. slog.Default() is used when
+// no logger is provided.
+func WithLogger(logger *slog.Logger) Option {
+	return func(o *options) {
+		if logger != nil {
+			o.logger = logger
+		}
+	}
+}
+
+// WithContext sets the context used for tracing and for the GraphQL calls answering the webhook.
+func WithContext(ctx context.Context) Option {
+	return func(o *options) {
+		if ctx != nil {
+			o.ctx = ctx
+		}
+	}
+}
+
+// WithTracerProvider sets the OpenTelemetry provider used to create a span for every remote signing
+// webhook and signing job. The global provider is used when no provider is set.
+func WithTracerProvider(tracerProvider trace.TracerProvider) Option {
+	return func(o *options) {
+		if tracerProvider != nil {
+			o.tracer = newTracer(tracerProvider)
+		}
+	}
+}
+
+// WithStateStore sets the store recording the per-commitment state of every channel. With a store,
+// per-commitment secrets are only released once the channel has advanced past their commitment, and
+// never for commitments older than the last revoked one. Channels unknown to the store can't release
+// secrets until they hand out a new per-commitment point.
+func WithStateStore(store SignerStateStore) Option {
+	return func(o *options) {
+		o.stateStore = store
+	}
+}
+
+// WithSignedWebhook sets the body and the signature header of the webhook being handled, as received from
+// Lightspark. A SocketSigner forwards them to its signer process, which verifies them before answering.
+func WithSignedWebhook(body []byte, signature string) Option {
+	return func(o *options) {
+		o.webhook = &signedWebhook{body: body, signature: signature}
+	}
+}
+
+// withOptions forwards already resolved options to another handler.
+func withOptions(resolved *options) Option {
+	return func(o *options) {
+		*o = *resolved
+	}
+}
+
+func newOptions(opts []Option) *options {
+	o := &options{ctx: context.Background(), logger: slog.Default()}
+	for _, opt := range opts {
+		opt(o)
+	}
+	if o.tracer == nil {
+		o.tracer = newTracer(otel.GetTracerProvider())
+	}
+	return o
+}
+
+func newTracer(tracerProvider trace.TracerProvider) trace.Tracer {
+	return tracerProvider.Tracer(tracerName, trace.WithInstrumentationVersion(lightspark.VERSION))
+}
@@ -0,0 +1,446 @@
+// Copyright ©, 2023-present, Lightspark Group, Inc. - All Rights Reserved
+package remotesigning
+
+import (
+	"bytes"
+	"errors"
+	"fmt"
+	"slices"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/btcsuite/btcd/btcutil"
+	"github.com/btcsuite/btcd/chaincfg"
+	"github.com/btcsuite/btcd/txscript"
+	"github.com/btcsuite/btcd/wire"
+	"github.com/lightsparkdev/go-sdk/objects"
+	"github.com/lightsparkdev/go-sdk/webhooks"
+)
+
+// ReasonCode identifies why a Rule allowed or declined a remote signing webhook. ReasonInvalidRequest is
+// returned for webhooks that can't be parsed, and is never inverted by Not.
+type ReasonCode string
+
+const (
+	ReasonAllowed               ReasonCode = "ALLOWED"
+	ReasonInvalidRequest        ReasonCode = "INVALID_REQUEST"
+	ReasonSubEventTypeDenied    ReasonCode = "SUB_EVENT_TYPE_DENIED"
+	ReasonDestinationNotAllowed ReasonCode = "DESTINATION_NOT_ALLOWED"
+	ReasonAmountExceeded        ReasonCode = "AMOUNT_EXCEEDED"
+	ReasonVelocityExceeded      ReasonCode = "VELOCITY_EXCEEDED"
+	ReasonOutsideTimeWindow     ReasonCode = "OUTSIDE_TIME_WINDOW"
+	ReasonInvalidWitnessHash    ReasonCode = "INVALID_WITNESS_HASH"
+	ReasonInvalidChangeOutput   ReasonCode = "INVALID_CHANGE_OUTPUT"
+	ReasonInvalidClaimOutput    ReasonCode = "INVALID_CLAIM_OUTPUT"
+	ReasonInvalidOutput         ReasonCode = "INVALID_OUTPUT"
+	ReasonNegated               ReasonCode = "NEGATED"
+	ReasonNoRuleAllowed         ReasonCode = "NO_RULE_ALLOWED"
+	ReasonValidatorDeclined     ReasonCode = "VALIDATOR_DECLINED"
+)
+
+// Decision is the outcome of a Rule for a remote signing webhook.
+type Decision struct {
+	Allowed bool
+	Reason  ReasonCode
+	// Message describes the decision for logs.
+	Message string
+}
+
+// Allow returns a decision allowing the webhook to be signed.
+func Allow() Decision {
+	return Decision{Allowed: true, Reason: ReasonAllowed}
+}
+
+// Deny returns a decision declining the webhook with a reason.
+func Deny(reason ReasonCode, format string, args ...interface{}) Decision {
+	return Decision{Reason: reason, Message: fmt.Sprintf(format, args...)}
+}
+
+func (d Decision) String() string {
+	if d.Message == "" {
+		return string(d.Reason)
+	}
+	return fmt.Sprintf("%s: %s", d.Reason, d.Message)
+}
+
+// Rule decides whether to sign a remote signing webhook event, with the reason of the decision.
+type Rule interface {
+	Evaluate(webhook webhooks.WebhookEvent) Decision
+}
+
+// RuleFunc adapts a function to a Rule.
+type RuleFunc func(webhook webhooks.WebhookEvent) Decision
+
+// Evaluate implements Rule.
+func (f RuleFunc) Evaluate(webhook webhooks.WebhookEvent) Decision {
+	return f(webhook)
+}
+
+// RuleFromValidator returns the Rule of a Validator. Validators that are not Rules only report
+// ReasonValidatorDeclined when they decline.
+func RuleFromValidator(validator Validator) Rule {
+	if rule, ok := validator.(Rule); ok {
+		return rule
+	}
+	return RuleFunc(func(webhook webhooks.WebhookEvent) Decision {
+		if validator.ShouldSign(webhook) {
+			return Allow()
+		}
+		return Deny(ReasonValidatorDeclined, "%T declined", validator)
+	})
+}
+
+// Policy is a Validator evaluating a Rule, usually built with And, Or and Not or loaded with LoadPolicy.
+type Policy struct {
+	rule Rule
+}
+
+// NewPolicy creates a Policy evaluating rule.
+func NewPolicy(rule Rule) Policy {
+	return Policy{rule: rule}
+}
+
+// Evaluate implements Rule. The zero Policy declines every webhook.
+func (p Policy) Evaluate(webhook webhooks.WebhookEvent) Decision {
+	if p.rule == nil {
+		return Deny(ReasonNoRuleAllowed, "the policy has no rule")
+	}
+	return p.rule.Evaluate(webhook)
+}
+
+// ShouldSign implements Validator.
+func (p Policy) ShouldSign(webhook webhooks.WebhookEvent) bool {
+	return p.Evaluate(webhook).Allowed
+}
+
+// And returns a Rule allowing a webhook when every rule allows it. It returns the decision of the first
+// rule declining it.
+func And(rules ...Rule) Rule {
+	return RuleFunc(func(webhook webhooks.WebhookEvent) Decision {
+		for _, rule := range rules {
+			if decision := rule.Evaluate(webhook); !decision.Allowed {
+				return decision
+			}
+		}
+		return Allow()
+	})
+}
+
+// Or returns a Rule allowing a webhook when any rule allows it. When every rule declines it, the decision
+// lists the reasons of all of them, or is the first ReasonInvalidRequest decision.
+func Or(rules ...Rule) Rule {
+	return RuleFunc(func(webhook webhooks.WebhookEvent) Decision {
+		var reasons []string
+		var invalid *Decision
+		for _, rule := range rules {
+			decision := rule.Evaluate(webhook)
+			if decision.Allowed {
+				return decision
+			}
+			if decision.Reason == ReasonInvalidRequest && invalid == nil {
+				invalid = &decision
+			}
+			reasons = append(reasons, decision.String())
+		}
+		if invalid != nil {
+			return *invalid
+		}
+		return Deny(ReasonNoRuleAllowed, "%s", strings.Join(reasons, "; "))
+	})
+}
+
+// Not returns a Rule allowing a webhook when rule declines it. Webhooks that rule can't evaluate, declined
+// with ReasonInvalidRequest, are still declined.
+func Not(rule Rule) Rule {
+	return RuleFunc(func(webhook webhooks.WebhookEvent) Decision {
+		decision := rule.Evaluate(webhook)
+		if decision.Reason == ReasonInvalidRequest {
+			return decision
+		}
+		if !decision.Allowed {
+			return Allow()
+		}
+		return Deny(ReasonNegated, "negated rule allowed the webhook")
+	})
+}
+
+func subEventType(webhook webhooks.WebhookEvent) objects.RemoteSigningSubEventType {
+	var subEventType objects.RemoteSigningSubEventType
+	if webhook.Data != nil {
+		if value, ok := (*webhook.Data)["sub_event_type"].(string); ok {
+			subEventType.UnmarshalJSON([]byte(`"` + value + `"`))
+		}
+	}
+	return subEventType
+}
+
+// SubEventTypeRule allows or denies webhooks by sub event type. Denied types are declined; when Allowed is
+// not empty, the other types are declined as well.
+type SubEventTypeRule struct {
+	Allowed []objects.RemoteSigningSubEventType
+	Denied  []objects.RemoteSigningSubEventType
+}
+
+// Evaluate implements Rule.
+func (r SubEventTypeRule) Evaluate(webhook webhooks.WebhookEvent) Decision {
+	subEventType := subEventType(webhook)
+	if slices.Contains(r.Denied, subEventType) ||
+		(len(r.Allowed) > 0 && !slices.Contains(r.Allowed, subEventType)) {
+		return Deny(ReasonSubEventTypeDenied, "%s is not allowed", subEventType.StringValue())
+	}
+	return Allow()
+}
+
+// Withdrawal is an L1 wallet transaction signed by a DERIVE_KEY_AND_SIGN webhook. As checked by
+// DestinationValidator, its first output pays the destination and the second one is the change.
+type Withdrawal struct {
+	Transaction       wire.MsgTx
+	DestinationScript []byte
+	AmountSats        int64
+}
+
+// Withdrawals returns the distinct L1 wallet transactions signed by a DERIVE_KEY_AND_SIGN webhook. It
+// returns no withdrawal for the other sub event types.
+func Withdrawals(webhook webhooks.WebhookEvent) ([]Withdrawal, error) {
+	if subEventType(webhook) != objects.RemoteSigningSubEventTypeDeriveKeyAndSign {
+		return nil, nil
+	}
+	request, err := ParseDeriveAndSignRequest(webhook)
+	if err != nil {
+		return nil, err
+	}
+
+	var withdrawals []Withdrawal
+	seen := map[string]bool{}
+	for _, job := range request.SigningJobs {
+		if !isL1WalletSigningJob(job) {
+			continue
+		}
+		if job.Transaction == nil {
+			return nil, fmt.Errorf("missing transaction in signing job %s", job.Id)
+		}
+		// Every input of a transaction is signed by its own job.
+		if seen[*job.Transaction] {
+			continue
+		}
+		seen[*job.Transaction] = true
+		tx, err := job.BitcoinTx()
+		if err != nil {
+			return nil, err
+		}
+		if len(tx.TxOut) == 0 {
+			return nil, fmt.Errorf("no output in the transaction of signing job %s", job.Id)
+		}
+		withdrawals = append(withdrawals, Withdrawal{
+			Transaction:       tx,
+			DestinationScript: tx.TxOut[0].PkScript,
+			AmountSats:        tx.TxOut[0].Value,
+		})
+	}
+	return withdrawals, nil
+}
+
+// evaluateWithdrawals parses the withdrawals of a webhook, declining the webhooks that can't be parsed.
+func evaluateWithdrawals(webhook webhooks.WebhookEvent, evaluate func(withdrawals []Withdrawal) Decision) Decision {
+	withdrawals, err := Withdrawals(webhook)
+	if err != nil {
+		return Deny(ReasonInvalidRequest, "%s", err)
+	}
+	if len(withdrawals) == 0 {
+		return Allow()
+	}
+	return evaluate(withdrawals)
+}
+
+// DestinationAllowlistRule declines L1 withdrawals to addresses outside of an allowlist.
+type DestinationAllowlistRule struct {
+	scripts   [][]byte
+	addresses []string
+}
+
+// NewDestinationAllowlistRule creates a DestinationAllowlistRule allowing the given addresses of network.
+func NewDestinationAllowlistRule(network objects.BitcoinNetwork, addresses ...string) (*DestinationAllowlistRule, error) {
+	params, err := chainParams(network)
+	if err != nil {
+		return nil, err
+	}
+	rule := &DestinationAllowlistRule{addresses: addresses}
+	for _, address := range addresses {
+		decoded, err := btcutil.DecodeAddress(address, params)
+		if err != nil {
+			return nil, fmt.Errorf("invalid address %s: %w", address, err)
+		}
+		if !decoded.IsForNet(params) {
+			return nil, fmt.Errorf("address %s is not a %s address", address, network.StringValue())
+		}
+		script, err := txscript.PayToAddrScript(decoded)
+		if err != nil {
+			return nil, err
+		}
+		rule.scripts = append(rule.scripts, script)
+	}
+	return rule, nil
+}
+
+func chainParams(network objects.BitcoinNetwork) (*chaincfg.Params, error) {
+	switch network {
+	case objects.BitcoinNetworkMainnet:
+		return &chaincfg.MainNetParams, nil
+	case objects.BitcoinNetworkTestnet:
+		return &chaincfg.TestNet3Params, nil
+	case objects.BitcoinNetworkRegtest:
+		return &chaincfg.RegressionNetParams, nil
+	case objects.BitcoinNetworkSignet:
+		return &chaincfg.SigNetParams, nil
+	default:
+		return nil, errors.New("invalid network")
+	}
+}
+
+// Evaluate implements Rule.
+func (r *DestinationAllowlistRule) Evaluate(webhook webhooks.WebhookEvent) Decision {
+	return evaluateWithdrawals(webhook, func(withdrawals []Withdrawal) Decision {
+		for _, withdrawal := range withdrawals {
+			allowed := slices.ContainsFunc(r.scripts, func(script []byte) bool {
+				return bytes.Equal(script, withdrawal.DestinationScript)
+			})
+			if !allowed {
+				return Deny(ReasonDestinationNotAllowed, "destination script %x is not in the allowlist",
+					withdrawal.DestinationScript)
+			}
+		}
+		return Allow()
+	})
+}
+
+// MaxAmountRule declines L1 withdrawals of more than MaxSats.
+type MaxAmountRule struct {
+	MaxSats int64
+}
+
+// Evaluate implements Rule.
+func (r MaxAmountRule) Evaluate(webhook webhooks.WebhookEvent) Decision {
+	return evaluateWithdrawals(webhook, func(withdrawals []Withdrawal) Decision {
+		for _, withdrawal := range withdrawals {
+			if withdrawal.AmountSats > r.MaxSats {
+				return Deny(ReasonAmountExceeded, "withdrawal of %d sats exceeds the maximum of %d sats",
+					withdrawal.AmountSats, r.MaxSats)
+			}
+		}
+		return Allow()
+	})
+}
+
+// VelocityRule limits the L1 withdrawals of every node over a rolling window. A limit of 0 is not
+// enforced. Withdrawals are counted when the rule allows them, even if another rule declines the webhook
+// later. Use a pointer, since the rule keeps the history of the withdrawals.
+type VelocityRule struct {
+	Window   time.Duration
+	MaxSats  int64
+	MaxCount int
+	// Now returns the current time. time.Now is used when nil.
+	Now func() time.Time
+
+	mu      sync.Mutex
+	history map[string][]velocityEntry
+}
+
+type velocityEntry struct {
+	time       time.Time
+	amountSats int64
+}
+
+// Evaluate implements Rule.
+func (r *VelocityRule) Evaluate(webhook webhooks.WebhookEvent) Decision {
+	return evaluateWithdrawals(webhook, func(withdrawals []Withdrawal) Decision {
+		r.mu.Lock()
+		defer r.mu.Unlock()
+
+		now := time.Now()
+		if r.Now != nil {
+			now = r.Now()
+		}
+		if r.history == nil {
+			r.history = map[string][]velocityEntry{}
+		}
+		history := r.history[webhook.EntityId]
+		for len(history) > 0 && !history[0].time.After(now.Add(-r.Window)) {
+			history = history[1:]
+		}
+		r.history[webhook.EntityId] = history
+
+		var totalSats int64
+		for _, entry := range history {
+			totalSats += entry.amountSats
+		}
+		count := len(history)
+		for _, withdrawal := range withdrawals {
+			totalSats += withdrawal.AmountSats
+			count++
+		}
+		if r.MaxSats > 0 && totalSats > r.MaxSats {
+			return Deny(ReasonVelocityExceeded, "withdrawals of %d sats within %s exceed the maximum of %d sats",
+				totalSats, r.Window, r.MaxSats)
+		}
+		if r.MaxCount > 0 && count > r.MaxCount {
+			return Deny(ReasonVelocityExceeded, "%d withdrawals within %s exceed the maximum of %d",
+				count, r.Window, r.MaxCount)
+		}
+
+		for _, withdrawal := range withdrawals {
+			history = append(history, velocityEntry{time: now, amountSats: withdrawal.AmountSats})
+		}
+		r.history[webhook.EntityId] = history
+		return Allow()
+	})
+}
+
+// TimeWindowRule declines webhooks outside of a daily time window. Start and End are offsets from
+// midnight in Location; the window wraps around midnight when End is before Start, and covers the whole
+// day when they are equal. When Days is not empty, the other days are declined entirely. When
+// SubEventTypes is not empty, the other sub event types are always allowed. Restricting channel
+// operations such as GET_PER_COMMITMENT_POINT stops the node from operating outside of the window.
+type TimeWindowRule struct {
+	Start         time.Duration
+	End           time.Duration
+	Location      *time.Location
+	Days          []time.Weekday
+	SubEventTypes []objects.RemoteSigningSubEventType
+	// Now returns the current time. time.Now is used when nil.
+	Now func() time.Time
+}
+
+// Evaluate implements Rule.
+func (r TimeWindowRule) Evaluate(webhook webhooks.WebhookEvent) Decision {
+	if len(r.SubEventTypes) > 0 && !slices.Contains(r.SubEventTypes, subEventType(webhook)) {
+		return Allow()
+	}
+	now := time.Now()
+	if r.Now != nil {
+		now = r.Now()
+	}
+	if r.Location != nil {
+		now = now.In(r.Location)
+	}
+
+	midnight := time.Date(now.Year(), now.Month(), now.Day(), 0, 0, 0, 0, now.Location())
+	offset := now.Sub(midnight)
+	day := now.Weekday()
+	var inWindow bool
+	if r.Start == r.End {
+		inWindow = true
+	} else if r.Start < r.End {
+		inWindow = offset >= r.Start && offset < r.End
+	} else if offset >= r.Start {
+		inWindow = true
+	} else if offset < r.End {
+		// The window started the day before.
+		inWindow = true
+		day = (day + 6) % 7
+	}
+	if !inWindow || (len(r.Days) > 0 && !slices.Contains(r.Days, day)) {
+		return Deny(ReasonOutsideTimeWindow, "%s is outside of the signing window", now.Format(time.RFC3339))
+	}
+	return Allow()
+}
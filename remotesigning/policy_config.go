@@ -0,0 +1,272 @@
+// Copyright ©, 2023-present, Lightspark Group, Inc. - All Rights Reserved
+package remotesigning
+
+import (
+	"bytes"
+	"errors"
+	"fmt"
+	"os"
+	"strings"
+	"time"
+
+	"github.com/lightsparkdev/go-sdk/objects"
+	"gopkg.in/yaml.v3"
+)
+
+// PolicyConfig is a node of a policy file. Exactly one field must be set. Policy files are YAML or JSON:
+//
+//	all:
+//	  - sub_event_types:
+//	      denied: [RELEASE_PAYMENT_PREIMAGE]
+//	  - destination_allowlist:
+//	      network: MAINNET
+//	      addresses: [bc1qwqdg6squsna38e46795at95yu9atm8azzmyvckulcc7kytlcckxswvvzej]
+//	  - max_amount:
+//	      sats: 1000000
+//	  - velocity:
+//	      window: 24h
+//	      max_sats: 5000000
+//	      max_count: 10
+//	  - any:
+//	      - time_window:
+//	          start: "09:00"
+//	          end: "17:00"
+//	          timezone: America/New_York
+//	          days: [MONDAY, TUESDAY, WEDNESDAY, THURSDAY, FRIDAY]
+//	          sub_event_types: [DERIVE_KEY_AND_SIGN]
+//	      - not:
+//	          sub_event_types:
+//	            allowed: [DERIVE_KEY_AND_SIGN]
+type PolicyConfig struct {
+	All                  []PolicyConfig              `yaml:"all" json:"all"`
+	Any                  []PolicyConfig              `yaml:"any" json:"any"`
+	Not                  *PolicyConfig               `yaml:"not" json:"not"`
+	SubEventTypes        *SubEventTypesConfig        `yaml:"sub_event_types" json:"sub_event_types"`
+	DestinationAllowlist *DestinationAllowlistConfig `yaml:"destination_allowlist" json:"destination_allowlist"`
+	MaxAmount            *MaxAmountConfig            `yaml:"max_amount" json:"max_amount"`
+	Velocity             *VelocityConfig             `yaml:"velocity" json:"velocity"`
+	TimeWindow           *TimeWindowConfig           `yaml:"time_window" json:"time_window"`
+}
+
+// SubEventTypesConfig configures a SubEventTypeRule.
+type SubEventTypesConfig struct {
+	Allowed []string `yaml:"allowed" json:"allowed"`
+	Denied  []string `yaml:"denied" json:"denied"`
+}
+
+// DestinationAllowlistConfig configures a DestinationAllowlistRule.
+type DestinationAllowlistConfig struct {
+	Network   string   `yaml:"network" json:"network"`
+	Addresses []string `yaml:"addresses" json:"addresses"`
+}
+
+// MaxAmountConfig configures a MaxAmountRule. Sats is required.
+type MaxAmountConfig struct {
+	Sats *int64 `yaml:"sats" json:"sats"`
+}
+
+// VelocityConfig configures a VelocityRule. Window is a duration such as "24h", and at least one of MaxSats
+// and MaxCount must be set.
+type VelocityConfig struct {
+	Window   string `yaml:"window" json:"window"`
+	MaxSats  int64  `yaml:"max_sats" json:"max_sats"`
+	MaxCount int    `yaml:"max_count" json:"max_count"`
+}
+
+// TimeWindowConfig configures a TimeWindowRule. Start and End are times of the day such as "09:00", and
+// Timezone is an IANA time zone name, UTC by default.
+type TimeWindowConfig struct {
+	Start         string   `yaml:"start" json:"start"`
+	End           string   `yaml:"end" json:"end"`
+	Timezone      string   `yaml:"timezone" json:"timezone"`
+	Days          []string `yaml:"days" json:"days"`
+	SubEventTypes []string `yaml:"sub_event_types" json:"sub_event_types"`
+}
+
+// LoadPolicy reads a YAML or JSON policy file.
+func LoadPolicy(path string) (Policy, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return Policy{}, err
+	}
+	policy, err := ParsePolicy(data)
+	if err != nil {
+		return Policy{}, fmt.Errorf("invalid policy %s: %w", path, err)
+	}
+	return policy, nil
+}
+
+// ParsePolicy parses a YAML or JSON policy. Unknown keys are rejected.
+func ParsePolicy(data []byte) (Policy, error) {
+	var config PolicyConfig
+	// JSON documents are valid YAML documents.
+	decoder := yaml.NewDecoder(bytes.NewReader(data))
+	decoder.KnownFields(true)
+	if err := decoder.Decode(&config); err != nil {
+		return Policy{}, err
+	}
+	rule, err := config.Rule()
+	if err != nil {
+		return Policy{}, err
+	}
+	return NewPolicy(rule), nil
+}
+
+// Rule builds the Rule of the node.
+func (c PolicyConfig) Rule() (Rule, error) {
+	var rules []Rule
+	set := 0
+	if c.All != nil {
+		set++
+		rule, err := buildRules(c.All, And)
+		if err != nil {
+			return nil, fmt.Errorf("all: %w", err)
+		}
+		rules = append(rules, rule)
+	}
+	if c.Any != nil {
+		set++
+		rule, err := buildRules(c.Any, Or)
+		if err != nil {
+			return nil, fmt.Errorf("any: %w", err)
+		}
+		rules = append(rules, rule)
+	}
+	if c.Not != nil {
+		set++
+		rule, err := c.Not.Rule()
+		if err != nil {
+			return nil, fmt.Errorf("not: %w", err)
+		}
+		rules = append(rules, Not(rule))
+	}
+	if c.SubEventTypes != nil {
+		set++
+		allowed, err := parseSubEventTypes(c.SubEventTypes.Allowed)
+		if err != nil {
+			return nil, fmt.Errorf("sub_event_types: %w", err)
+		}
+		denied, err := parseSubEventTypes(c.SubEventTypes.Denied)
+		if err != nil {
+			return nil, fmt.Errorf("sub_event_types: %w", err)
+		}
+		rules = append(rules, SubEventTypeRule{Allowed: allowed, Denied: denied})
+	}
+	if c.DestinationAllowlist != nil {
+		set++
+		var network objects.BitcoinNetwork
+		network.UnmarshalJSON([]byte(`"` + strings.ToUpper(c.DestinationAllowlist.Network) + `"`))
+		rule, err := NewDestinationAllowlistRule(network, c.DestinationAllowlist.Addresses...)
+		if err != nil {
+			return nil, fmt.Errorf("destination_allowlist: %w", err)
+		}
+		rules = append(rules, rule)
+	}
+	if c.MaxAmount != nil {
+		set++
+		if c.MaxAmount.Sats == nil || *c.MaxAmount.Sats < 0 {
+			return nil, errors.New("max_amount: sats must be set and not negative")
+		}
+		rules = append(rules, MaxAmountRule{MaxSats: *c.MaxAmount.Sats})
+	}
+	if c.Velocity != nil {
+		set++
+		window, err := time.ParseDuration(c.Velocity.Window)
+		if err != nil || window <= 0 {
+			return nil, fmt.Errorf("velocity: invalid window %q", c.Velocity.Window)
+		}
+		if c.Velocity.MaxSats < 0 || c.Velocity.MaxCount < 0 || (c.Velocity.MaxSats == 0 && c.Velocity.MaxCount == 0) {
+			return nil, errors.New("velocity: max_sats or max_count must be positive")
+		}
+		rules = append(rules, &VelocityRule{Window: window, MaxSats: c.Velocity.MaxSats, MaxCount: c.Velocity.MaxCount})
+	}
+	if c.TimeWindow != nil {
+		set++
+		rule, err := c.TimeWindow.rule()
+		if err != nil {
+			return nil, fmt.Errorf("time_window: %w", err)
+		}
+		rules = append(rules, rule)
+	}
+
+	if set != 1 {
+		return nil, errors.New("a policy node must have exactly one rule")
+	}
+	return rules[0], nil
+}
+
+func buildRules(configs []PolicyConfig, combine func(rules ...Rule) Rule) (Rule, error) {
+	if len(configs) == 0 {
+		return nil, errors.New("no rule")
+	}
+	rules := make([]Rule, len(configs))
+	for i, config := range configs {
+		rule, err := config.Rule()
+		if err != nil {
+			return nil, err
+		}
+		rules[i] = rule
+	}
+	return combine(rules...), nil
+}
+
+func parseSubEventTypes(names []string) ([]objects.RemoteSigningSubEventType, error) {
+	var subEventTypes []objects.RemoteSigningSubEventType
+	for _, name := range names {
+		var subEventType objects.RemoteSigningSubEventType
+		subEventType.UnmarshalJSON([]byte(`"` + strings.ToUpper(name) + `"`))
+		if subEventType == objects.RemoteSigningSubEventTypeUndefined {
+			return nil, fmt.Errorf("unknown sub event type %s", name)
+		}
+		subEventTypes = append(subEventTypes, subEventType)
+	}
+	return subEventTypes, nil
+}
+
+func (c TimeWindowConfig) rule() (TimeWindowRule, error) {
+	start, err := parseTimeOfDay(c.Start)
+	if err != nil {
+		return TimeWindowRule{}, err
+	}
+	end, err := parseTimeOfDay(c.End)
+	if err != nil {
+		return TimeWindowRule{}, err
+	}
+	location := time.UTC
+	if c.Timezone != "" {
+		location, err = time.LoadLocation(c.Timezone)
+		if err != nil {
+			return TimeWindowRule{}, err
+		}
+	}
+	var days []time.Weekday
+	for _, name := range c.Days {
+		day, err := parseWeekday(name)
+		if err != nil {
+			return TimeWindowRule{}, err
+		}
+		days = append(days, day)
+	}
+	subEventTypes, err := parseSubEventTypes(c.SubEventTypes)
+	if err != nil {
+		return TimeWindowRule{}, err
+	}
+	return TimeWindowRule{Start: start, End: end, Location: location, Days: days, SubEventTypes: subEventTypes}, nil
+}
+
+func parseTimeOfDay(value string) (time.Duration, error) {
+	parsed, err := time.Parse("15:04", value)
+	if err != nil {
+		return 0, fmt.Errorf("invalid time of day %q", value)
+	}
+	return time.Duration(parsed.Hour())*time.Hour + time.Duration(parsed.Minute())*time.Minute, nil
+}
+
+func parseWeekday(name string) (time.Weekday, error) {
+	for day := time.Sunday; day <= time.Saturday; day++ {
+		if strings.EqualFold(day.String(), name) {
+			return day, nil
+		}
+	}
+	return 0, fmt.Errorf("invalid day %q", name)
+}
@@ -2,14 +2,18 @@
 package remotesigning
 
 import (
+	"context"
 	"encoding/hex"
 	"encoding/json"
 	"errors"
-	"log"
+	"fmt"
+	"log/slog"
 
-	"github.com/lightsparkdev/go-sdk/crypto"
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/codes"
+	"go.opentelemetry.io/otel/trace"
 
-	lightspark_crypto "github.com/lightsparkdev/lightspark-crypto-uniffi/lightspark-crypto-go"
+	"github.com/lightsparkdev/go-sdk/crypto/lightsparkcrypto"
 
 	"github.com/lightsparkdev/go-sdk/objects"
 	"github.com/lightsparkdev/go-sdk/scripts"
@@ -28,18 +32,43 @@ import (
 //	    validator: A validator for deciding whether to sign events.
 //		webhook: The webhook event that you want to handle.
 //		seedBytes: The bytes of the master seed that you want to use to sign messages or derive keys.
+//		opts: Options such as WithLogger, WithContext or WithTracerProvider.
 func HandleRemoteSigningWebhook(
 	client *services.LightsparkClient,
 	validator Validator,
 	webhook webhooks.WebhookEvent,
 	seedBytes []byte,
+	opts ...Option,
 ) (string, error) {
-	response, err := GraphQLResponseForRemoteSigningWebhook(validator, webhook, seedBytes)
+	return HandleRemoteSigningWebhookWithSigner(client, validator, webhook, NewMemorySigner(seedBytes), opts...)
+}
+
+// HandleRemoteSigningWebhookWithSigner is HandleRemoteSigningWebhook using a Signer instead of the
+// master seed.
+func HandleRemoteSigningWebhookWithSigner(
+	client *services.LightsparkClient,
+	validator Validator,
+	webhook webhooks.WebhookEvent,
+	signer Signer,
+	opts ...Option,
+) (string, error) {
+	o := newOptions(opts)
+	ctx, span := o.tracer.Start(o.ctx, "HandleRemoteSigningWebhook", trace.WithAttributes(webhookAttributes(webhook)...))
+	defer span.End()
+	o.ctx = ctx
+
+	response, err := GraphQLResponseForRemoteSigningWebhookWithSigner(validator, webhook, signer, withOptions(o))
 
 	if err != nil {
-		if err.Error() == "declined to sign messages" {
-			DeclineToSignMessages(client, webhook)
+		var declined *DeclinedError
+		if errors.As(err, &declined) {
+			o.logger = o.logger.With(
+				slog.String("reason", string(declined.Decision.Reason)),
+				slog.String("reason_message", declined.Decision.Message),
+			)
+			DeclineToSignMessages(client, webhook, withOptions(o))
 		}
+		endSpanWithError(span, err)
 		return "", err
 	}
 
@@ -48,41 +77,90 @@ func HandleRemoteSigningWebhook(
 		return "", nil
 	}
 
-	return HandleSigningResponse(client, response)
+	result, err := HandleSigningResponse(client, response, withOptions(o))
+	if err != nil {
+		endSpanWithError(span, err)
+		return "", err
+	}
+	return result, nil
+}
+
+// ErrDeclinedToSign matches the DeclinedError returned when the validator declines a webhook.
+var ErrDeclinedToSign = errors.New("declined to sign messages")
+
+// DeclinedError is returned when the validator declines a webhook, with the decision of the validator.
+type DeclinedError struct {
+	Decision Decision
+}
+
+func (e *DeclinedError) Error() string {
+	return fmt.Sprintf("%s (%s)", ErrDeclinedToSign, e.Decision)
+}
+
+func (e *DeclinedError) Is(target error) bool {
+	return target == ErrDeclinedToSign
 }
 
 func GraphQLResponseForRemoteSigningWebhook(
 	validator Validator,
 	webhook webhooks.WebhookEvent,
 	seedBytes []byte,
+	opts ...Option,
 ) (SigningResponse, error) {
-	// If derive key and sign, calculate the xpub for each L1 signing job
-	subEventTypeStr, isValidSubEventType := (*webhook.Data)["sub_event_type"].(string)
-	if !isValidSubEventType {
-		return nil, errors.New("sub_event_type not found or invalid type")
-	}
-	if !validator.ShouldSign(webhook) {
-		return nil, errors.New("declined to sign messages")
-	}
+	return GraphQLResponseForRemoteSigningWebhookWithSigner(validator, webhook, NewMemorySigner(seedBytes), opts...)
+}
+
+// GraphQLResponseForRemoteSigningWebhookWithSigner is GraphQLResponseForRemoteSigningWebhook using a
+// Signer instead of the master seed.
+func GraphQLResponseForRemoteSigningWebhookWithSigner(
+	validator Validator,
+	webhook webhooks.WebhookEvent,
+	signer Signer,
+	opts ...Option,
+) (SigningResponse, error) {
+	o := newOptions(opts)
+	logger := o.logger.With(
+		slog.String("event_id", webhook.EventId),
+		slog.String("entity_id", webhook.EntityId),
+	)
 	if webhook.EventType != objects.WebhookEventTypeRemoteSigning {
 		return nil, errors.New("webhook event is not for remote signing")
 	}
 	if webhook.Data == nil {
 		return nil, errors.New("webhook data is missing")
 	}
+	subEventTypeStr, isValidSubEventType := (*webhook.Data)["sub_event_type"].(string)
+	if !isValidSubEventType {
+		return nil, errors.New("sub_event_type not found or invalid type")
+	}
+	logger = logger.With(slog.String("sub_event_type", subEventTypeStr))
+	logger.Info("received remote signing webhook")
+	if decision := evaluate(validator, webhook); !decision.Allowed {
+		logger.Warn("declined to sign messages",
+			slog.String("reason", string(decision.Reason)),
+			slog.String("reason_message", decision.Message),
+		)
+		return nil, &DeclinedError{Decision: decision}
+	}
 	var subtype objects.RemoteSigningSubEventType
-	log.Printf("Received remote signing webhook with sub_event_type %s", subEventTypeStr)
 	err := subtype.UnmarshalJSON([]byte(`"` + subEventTypeStr + `"`))
 	if err != nil {
 		return nil, errors.New("invalid remote signing sub_event_type")
 	}
 
-	request, err := ParseRemoteSigningRequest(webhook)
+	o.logger = logger
+	request, err := ParseRemoteSigningRequest(webhook, withOptions(o))
 	if err != nil {
+		logger.Error("failed to parse remote signing webhook", slog.Any("error", err))
 		return nil, err
 	}
 
-	response, err := HandleSigningRequest(request, seedBytes)
+	// A SocketSigner sends the signed webhook to its signer process, which checks it again.
+	if o.webhook != nil {
+		o.ctx = contextWithSignedWebhook(o.ctx, *o.webhook)
+	}
+
+	response, err := HandleSigningRequestWithSigner(request, signer, withOptions(o))
 
 	if err != nil {
 		return nil, err
@@ -91,42 +169,59 @@ func GraphQLResponseForRemoteSigningWebhook(
 	return response, nil
 }
 
-func HandleSigningRequest(request SigningRequest, seedBytes []byte) (SigningResponse, error) {
+func HandleSigningRequest(request SigningRequest, seedBytes []byte, opts ...Option) (SigningResponse, error) {
+	return HandleSigningRequestWithSigner(request, NewMemorySigner(seedBytes), opts...)
+}
+
+// HandleSigningRequestWithSigner is HandleSigningRequest using a Signer instead of the master seed.
+func HandleSigningRequestWithSigner(request SigningRequest, signer Signer, opts ...Option) (SigningResponse, error) {
+	o := newOptions(opts)
+	subEventType := request.Type().StringValue()
+	ctx, span := o.tracer.Start(o.ctx, subEventType,
+		trace.WithAttributes(attribute.String("lightspark.remote_signing.sub_event_type", subEventType)))
+	defer span.End()
+	logger := o.logger.With(slog.String("sub_event_type", subEventType))
+	o.ctx = ctx
+	o.logger = logger
+
 	var response SigningResponse
 	var err error
 	switch request.Type() {
 	case objects.RemoteSigningSubEventTypeEcdh:
-		response, err = HandleEcdhRequest(request.(*ECDHRequest), seedBytes)
+		response, err = HandleEcdhRequestWithSigner(request.(*ECDHRequest), signer, withOptions(o))
 	case objects.RemoteSigningSubEventTypeGetPerCommitmentPoint:
-		response, err = HandleGetPerCommitmentPointRequest(request.(*GetPerCommitmentPointRequest), seedBytes)
+		response, err = HandleGetPerCommitmentPointRequestWithSigner(request.(*GetPerCommitmentPointRequest), signer, withOptions(o))
 	case objects.RemoteSigningSubEventTypeReleasePerCommitmentSecret:
-		response, err = HandleReleasePerCommitmentSecretRequest(request.(*ReleasePerCommitmentSecretRequest), seedBytes)
+		response, err = HandleReleasePerCommitmentSecretRequestWithSigner(request.(*ReleasePerCommitmentSecretRequest), signer, withOptions(o))
 	case objects.RemoteSigningSubEventTypeDeriveKeyAndSign:
-		response, err = HandleDeriveKeyAndSignRequest(request.(*DeriveKeyAndSignRequest), seedBytes)
+		response, err = HandleDeriveKeyAndSignRequestWithSigner(request.(*DeriveKeyAndSignRequest), signer, withOptions(o))
 	case objects.RemoteSigningSubEventTypeRequestInvoicePaymentHash:
-		response, err = HandleInvoicePaymentHashRequest(request.(*InvoicePaymentHashRequest), seedBytes)
+		response, err = HandleInvoicePaymentHashRequestWithSigner(request.(*InvoicePaymentHashRequest), signer, withOptions(o))
 	case objects.RemoteSigningSubEventTypeSignInvoice:
-		response, err = HandleSignInvoiceRequest(request.(*SignInvoiceRequest), seedBytes)
+		response, err = HandleSignInvoiceRequestWithSigner(request.(*SignInvoiceRequest), signer, withOptions(o))
 	case objects.RemoteSigningSubEventTypeReleasePaymentPreimage:
-		response, err = HandleReleaseInvoicePreimageRequest(request.(*ReleasePaymentPreimageRequest), seedBytes)
+		response, err = HandleReleaseInvoicePreimageRequestWithSigner(request.(*ReleasePaymentPreimageRequest), signer, withOptions(o))
 	case objects.RemoteSigningSubEventTypeRevealCounterpartyPerCommitmentSecret:
 		// No op for this event type.
+		logger.Debug("no response required for remote signing request")
 		return nil, nil
 	default:
 		return nil, errors.New("webhook event is not for remote signing")
 	}
 
 	if err != nil {
+		logger.Error("failed to handle remote signing request", slog.Any("error", err))
+		endSpanWithError(span, err)
 		return nil, err
 	}
 
 	return response, nil
 }
 
-func HandleSigningResponse(client *services.LightsparkClient, response SigningResponse) (string, error) {
+func HandleSigningResponse(client *services.LightsparkClient, response SigningResponse, opts ...Option) (string, error) {
 	graphql := response.GraphqlResponse()
 
-	result, err := client.Requester.ExecuteGraphql(graphql.Query, graphql.Variables, nil)
+	result, err := client.Requester.ExecuteGraphqlWithContext(newOptions(opts).ctx, graphql.Query, graphql.Variables, nil)
 	if err != nil {
 		return "", err
 	}
@@ -147,12 +242,17 @@ func HandleSigningResponse(client *services.LightsparkClient, response SigningRe
 	return string(outputJson), nil
 }
 
-func DeclineToSignMessages(client *services.LightsparkClient, event webhooks.WebhookEvent) (string, error) {
-	signingJobsJson := (*event.Data)["signing_jobs"]
-	if signingJobsJson == nil {
+func DeclineToSignMessages(client *services.LightsparkClient, event webhooks.WebhookEvent, opts ...Option) (string, error) {
+	o := newOptions(opts)
+	logger := o.logger.With(slog.String("event_id", event.EventId))
+	if event.Data == nil {
+		return "", errors.New("webhook data is missing")
+	}
+	signingJobsJson, ok := (*event.Data)["signing_jobs"].([]interface{})
+	if !ok {
 		return "", errors.New("missing signing_jobs in webhook")
 	}
-	signingJobsJsonString, err := json.Marshal(signingJobsJson.([]interface{}))
+	signingJobsJsonString, err := json.Marshal(signingJobsJson)
 	if err != nil {
 		return "", err
 	}
@@ -172,8 +272,10 @@ func DeclineToSignMessages(client *services.LightsparkClient, event webhooks.Web
 		"payload_ids": payloadIds,
 	}
 
-	response, err := client.Requester.ExecuteGraphql(scripts.DECLINE_TO_SIGN_MESSAGES_MUTATION, variables, nil)
+	logger.Info("declining to sign messages", slog.Any("payload_ids", payloadIds))
+	response, err := client.Requester.ExecuteGraphqlWithContext(o.ctx, scripts.DECLINE_TO_SIGN_MESSAGES_MUTATION, variables, nil)
 	if err != nil {
+		logger.Error("failed to decline to sign messages", slog.Any("error", err))
 		return "", err
 	}
 
@@ -193,36 +295,59 @@ func DeclineToSignMessages(client *services.LightsparkClient, event webhooks.Web
 	return "rejected signing", nil
 }
 
-func HandleEcdhRequest(request *ECDHRequest, seedBytes []byte) (*ECDHResponse, error) {
-	log.Println("Handling ECDH webhook")
-	bitcoinNetwork, err := bitcoinNetworkConversion(request.BitcoinNetwork)
+func HandleEcdhRequest(request *ECDHRequest, seedBytes []byte, opts ...Option) (*ECDHResponse, error) {
+	return HandleEcdhRequestWithSigner(request, NewMemorySigner(seedBytes), opts...)
+}
+
+// HandleEcdhRequestWithSigner is HandleEcdhRequest using a Signer instead of the master seed.
+func HandleEcdhRequestWithSigner(request *ECDHRequest, signer Signer, opts ...Option) (*ECDHResponse, error) {
+	o := newOptions(opts)
+	o.logger.Info("handling ECDH request", slog.String("node_id", request.NodeId))
+	peerPubKey, err := hex.DecodeString(request.PeerPubKeyHex)
 	if err != nil {
 		return nil, err
 	}
 
-	sharedSecret, err := crypto.ECDH(seedBytes, bitcoinNetwork, request.PeerPubKeyHex)
+	sharedSecret, err := signer.Ecdh(o.ctx, request.BitcoinNetwork, peerPubKey)
 	if err != nil {
 		return nil, err
 	}
 
 	response := ECDHResponse{
 		NodeId:          request.NodeId,
-		SharedSecretHex: sharedSecret,
+		SharedSecretHex: hex.EncodeToString(sharedSecret),
 	}
 
 	return &response, nil
 }
 
-func HandleGetPerCommitmentPointRequest(request *GetPerCommitmentPointRequest, seedBytes []byte) (*GetPerCommitmentPointResponse, error) {
-	log.Println("Handling GET_PER_COMMITMENT_POINT webhook")
-	bitcoinNetwork, err := bitcoinNetworkConversion(request.BitcoinNetwork)
-	if err != nil {
+func HandleGetPerCommitmentPointRequest(request *GetPerCommitmentPointRequest, seedBytes []byte, opts ...Option) (*GetPerCommitmentPointResponse, error) {
+	return HandleGetPerCommitmentPointRequestWithSigner(request, NewMemorySigner(seedBytes), opts...)
+}
+
+// HandleGetPerCommitmentPointRequestWithSigner is HandleGetPerCommitmentPointRequest using a Signer
+// instead of the master seed.
+func HandleGetPerCommitmentPointRequestWithSigner(request *GetPerCommitmentPointRequest, signer Signer, opts ...Option) (*GetPerCommitmentPointResponse, error) {
+	o := newOptions(opts)
+	o.logger.Info("handling GET_PER_COMMITMENT_POINT request",
+		slog.String("node_id", request.NodeId),
+		slog.String("channel_id", request.ChannelId),
+		slog.Uint64("per_commitment_point_idx", request.PerCommitmentPointIdx),
+	)
+	if _, err := bitcoinNetworkConversion(request.BitcoinNetwork); err != nil {
 		return nil, err
 	}
+	if o.stateStore != nil {
+		err := recordPerCommitmentPoint(o.ctx, o.stateStore, request.ChannelId, request.PerCommitmentPointIdx)
+		if err != nil {
+			o.logger.Error("refused to hand out per-commitment point", slog.Any("error", err))
+			return nil, err
+		}
+	}
 
-	perCommitmentPoint, err := lightspark_crypto.GetPerCommitmentPoint(
-		seedBytes,
-		bitcoinNetwork,
+	perCommitmentPoint, err := signer.GetPerCommitmentPoint(
+		o.ctx,
+		request.BitcoinNetwork,
 		request.DerivationPath,
 		request.PerCommitmentPointIdx)
 	if err != nil {
@@ -238,16 +363,33 @@ func HandleGetPerCommitmentPointRequest(request *GetPerCommitmentPointRequest, s
 	return &response, nil
 }
 
-func HandleReleasePerCommitmentSecretRequest(request *ReleasePerCommitmentSecretRequest, seedBytes []byte) (*ReleasePerCommitmentSecretResponse, error) {
-	log.Println("Handling RELEASE_PER_COMMITMENT_SECRET webhook")
-	bitcoinNetwork, err := bitcoinNetworkConversion(request.BitcoinNetwork)
-	if err != nil {
+func HandleReleasePerCommitmentSecretRequest(request *ReleasePerCommitmentSecretRequest, seedBytes []byte, opts ...Option) (*ReleasePerCommitmentSecretResponse, error) {
+	return HandleReleasePerCommitmentSecretRequestWithSigner(request, NewMemorySigner(seedBytes), opts...)
+}
+
+// HandleReleasePerCommitmentSecretRequestWithSigner is HandleReleasePerCommitmentSecretRequest using a
+// Signer instead of the master seed.
+func HandleReleasePerCommitmentSecretRequestWithSigner(request *ReleasePerCommitmentSecretRequest, signer Signer, opts ...Option) (*ReleasePerCommitmentSecretResponse, error) {
+	o := newOptions(opts)
+	o.logger.Info("handling RELEASE_PER_COMMITMENT_SECRET request",
+		slog.String("node_id", request.NodeId),
+		slog.String("channel_id", request.ChannelId),
+		slog.Uint64("per_commitment_point_idx", request.PerCommitmentPointIdx),
+	)
+	if _, err := bitcoinNetworkConversion(request.BitcoinNetwork); err != nil {
 		return nil, err
 	}
+	if o.stateStore != nil {
+		err := recordPerCommitmentSecret(o.ctx, o.stateStore, request.ChannelId, request.PerCommitmentPointIdx)
+		if err != nil {
+			o.logger.Error("refused to release per-commitment secret", slog.Any("error", err))
+			return nil, err
+		}
+	}
 
-	perCommitmentSecret, err := lightspark_crypto.ReleasePerCommitmentSecret(
-		seedBytes,
-		bitcoinNetwork,
+	perCommitmentSecret, err := signer.ReleasePerCommitmentSecret(
+		o.ctx,
+		request.BitcoinNetwork,
 		request.DerivationPath,
 		request.PerCommitmentPointIdx)
 	if err != nil {
@@ -263,13 +405,20 @@ func HandleReleasePerCommitmentSecretRequest(request *ReleasePerCommitmentSecret
 	return &response, nil
 }
 
-func HandleInvoicePaymentHashRequest(request *InvoicePaymentHashRequest, seedBytes []byte) (*InvoicePaymentHashResponse, error) {
-	log.Println("Handling REQUEST_INVOICE_PAYMENT_HASH webhook")
-	nonce, err := lightspark_crypto.GeneratePreimageNonce(seedBytes)
+func HandleInvoicePaymentHashRequest(request *InvoicePaymentHashRequest, seedBytes []byte, opts ...Option) (*InvoicePaymentHashResponse, error) {
+	return HandleInvoicePaymentHashRequestWithSigner(request, NewMemorySigner(seedBytes), opts...)
+}
+
+// HandleInvoicePaymentHashRequestWithSigner is HandleInvoicePaymentHashRequest using a Signer instead of
+// the master seed.
+func HandleInvoicePaymentHashRequestWithSigner(request *InvoicePaymentHashRequest, signer Signer, opts ...Option) (*InvoicePaymentHashResponse, error) {
+	o := newOptions(opts)
+	o.logger.Info("handling REQUEST_INVOICE_PAYMENT_HASH request", slog.String("invoice_id", request.InvoiceId))
+	nonce, err := signer.GeneratePreimageNonce(o.ctx)
 	if err != nil {
 		return nil, err
 	}
-	paymentHash, err := lightspark_crypto.GeneratePreimageHash(seedBytes, nonce)
+	paymentHash, err := signer.GeneratePreimageHash(o.ctx, nonce)
 	if err != nil {
 		return nil, err
 	}
@@ -285,35 +434,49 @@ func HandleInvoicePaymentHashRequest(request *InvoicePaymentHashRequest, seedByt
 	return &response, nil
 }
 
-func HandleSignInvoiceRequest(request *SignInvoiceRequest, seedBytes []byte) (*SignInvoiceResponse, error) {
-	log.Println("Handling SIGN_INVOICE webhook")
-	bitcoinNetwork, err := bitcoinNetworkConversion(request.BitcoinNetwork)
-	if err != nil {
-		return nil, err
-	}
+func HandleSignInvoiceRequest(request *SignInvoiceRequest, seedBytes []byte, opts ...Option) (*SignInvoiceResponse, error) {
+	return HandleSignInvoiceRequestWithSigner(request, NewMemorySigner(seedBytes), opts...)
+}
 
+// HandleSignInvoiceRequestWithSigner is HandleSignInvoiceRequest using a Signer instead of the master
+// seed.
+func HandleSignInvoiceRequestWithSigner(request *SignInvoiceRequest, signer Signer, opts ...Option) (*SignInvoiceResponse, error) {
+	o := newOptions(opts)
+	logger := o.logger.With(slog.String("invoice_id", request.InvoiceId))
+	logger.Info("handling SIGN_INVOICE request")
 	hash, err := hex.DecodeString(request.PaymentRequestHash)
 	if err != nil {
 		return nil, err
 	}
 
-	signedInvoice, err := lightspark_crypto.SignInvoiceHash(seedBytes, bitcoinNetwork, hash)
+	signature, recoveryId, err := signer.SignInvoiceHash(o.ctx, request.BitcoinNetwork, hash)
 	if err != nil {
-		log.Fatalf("Error signing invoice: %v", err)
-		return nil, err
+		logger.Error("failed to sign invoice", slog.Any("error", err))
+		return nil, fmt.Errorf("error signing invoice: %w", err)
 	}
 
 	response := SignInvoiceResponse{
 		InvoiceId:  request.InvoiceId,
-		Signature:  hex.EncodeToString(signedInvoice.Signature),
-		RecoveryId: signedInvoice.RecoveryId,
+		Signature:  hex.EncodeToString(signature),
+		RecoveryId: recoveryId,
 	}
 
 	return &response, nil
 }
 
-func HandleReleaseInvoicePreimageRequest(request *ReleasePaymentPreimageRequest, seedBytes []byte) (*ReleasePaymentPreimageResponse, error) {
-	log.Println("Handling RELEASE_PAYMENT_PREIMAGE webhook")
+func HandleReleaseInvoicePreimageRequest(request *ReleasePaymentPreimageRequest, seedBytes []byte, opts ...Option) (*ReleasePaymentPreimageResponse, error) {
+	return HandleReleaseInvoicePreimageRequestWithSigner(request, NewMemorySigner(seedBytes), opts...)
+}
+
+// HandleReleaseInvoicePreimageRequestWithSigner is HandleReleaseInvoicePreimageRequest using a Signer
+// instead of the master seed.
+func HandleReleaseInvoicePreimageRequestWithSigner(request *ReleasePaymentPreimageRequest, signer Signer, opts ...Option) (*ReleasePaymentPreimageResponse, error) {
+	o := newOptions(opts)
+	o.logger.Info("handling RELEASE_PAYMENT_PREIMAGE request",
+		slog.String("invoice_id", request.InvoiceId),
+		slog.Bool("is_uma", request.IsUma),
+		slog.Bool("is_lnurl", request.IsLnurl),
+	)
 	nonce := request.Nonce
 	if nonce == nil {
 		return nil, errors.New("missing preimage_nonce in webhook")
@@ -323,7 +486,7 @@ func HandleReleaseInvoicePreimageRequest(request *ReleasePaymentPreimageRequest,
 		return nil, err
 	}
 
-	preimage, err := lightspark_crypto.GeneratePreimage(seedBytes, nonceBytes)
+	preimage, err := signer.GeneratePreimage(o.ctx, nonceBytes)
 	if err != nil {
 		return nil, err
 	}
@@ -336,19 +499,29 @@ func HandleReleaseInvoicePreimageRequest(request *ReleasePaymentPreimageRequest,
 	return &response, nil
 }
 
-func HandleDeriveKeyAndSignRequest(request *DeriveKeyAndSignRequest, seedBytes []byte) (*DeriveKeyAndSignResponse, error) {
-	log.Println("Handling DERIVE_KEY_AND_SIGN webhook")
-	bitcoinNetwork, err := bitcoinNetworkConversion(request.BitcoinNetwork)
-	if err != nil {
-		return nil, err
-	}
+func HandleDeriveKeyAndSignRequest(request *DeriveKeyAndSignRequest, seedBytes []byte, opts ...Option) (*DeriveKeyAndSignResponse, error) {
+	return HandleDeriveKeyAndSignRequestWithSigner(request, NewMemorySigner(seedBytes), opts...)
+}
+
+// HandleDeriveKeyAndSignRequestWithSigner is HandleDeriveKeyAndSignRequest using a Signer instead of the
+// master seed.
+func HandleDeriveKeyAndSignRequestWithSigner(request *DeriveKeyAndSignRequest, signer Signer, opts ...Option) (*DeriveKeyAndSignResponse, error) {
+	o := newOptions(opts)
+	o.logger.Info("handling DERIVE_KEY_AND_SIGN request", slog.Int("signing_jobs", len(request.SigningJobs)))
 
 	var signatures []SignatureResponse
 	for _, signingJob := range request.SigningJobs {
-		signature, err := signSigningJob(signingJob, seedBytes, bitcoinNetwork)
+		ctx, span := o.tracer.Start(o.ctx, "SigningJob", trace.WithAttributes(
+			attribute.String("lightspark.signing_job.id", signingJob.Id),
+			attribute.String("lightspark.signing_job.derivation_path", signingJob.DerivationPath),
+		))
+		signature, err := signSigningJob(ctx, signingJob, signer, request.BitcoinNetwork)
 		if err != nil {
+			endSpanWithError(span, err)
+			span.End()
 			return nil, err
 		}
+		span.End()
 		signatures = append(signatures, SignatureResponse{
 			Id:        signingJob.Id,
 			Signature: signature.Signature,
@@ -362,20 +535,38 @@ func HandleDeriveKeyAndSignRequest(request *DeriveKeyAndSignRequest, seedBytes [
 	return &response, nil
 }
 
-func bitcoinNetworkConversion(network objects.BitcoinNetwork) (lightspark_crypto.BitcoinNetwork, error) {
+func webhookAttributes(webhook webhooks.WebhookEvent) []attribute.KeyValue {
+	attributes := []attribute.KeyValue{
+		attribute.String("lightspark.webhook.event_id", webhook.EventId),
+		attribute.String("lightspark.webhook.entity_id", webhook.EntityId),
+	}
+	if webhook.Data != nil {
+		if subEventType, ok := (*webhook.Data)["sub_event_type"].(string); ok {
+			attributes = append(attributes, attribute.String("lightspark.remote_signing.sub_event_type", subEventType))
+		}
+	}
+	return attributes
+}
+
+func endSpanWithError(span trace.Span, err error) {
+	span.RecordError(err)
+	span.SetStatus(codes.Error, err.Error())
+}
+
+func bitcoinNetworkConversion(network objects.BitcoinNetwork) (lightsparkcrypto.BitcoinNetwork, error) {
 	switch network {
 	case objects.BitcoinNetworkMainnet:
-		return lightspark_crypto.Mainnet, nil
+		return lightsparkcrypto.Mainnet, nil
 	case objects.BitcoinNetworkTestnet:
-		return lightspark_crypto.Testnet, nil
+		return lightsparkcrypto.Testnet, nil
 	case objects.BitcoinNetworkRegtest:
-		return lightspark_crypto.Regtest, nil
+		return lightsparkcrypto.Regtest, nil
 	default:
-		return lightspark_crypto.BitcoinNetwork(0), errors.New("invalid network")
+		return lightsparkcrypto.BitcoinNetwork(0), errors.New("invalid network")
 	}
 }
 
-func signSigningJob(signingJob SigningJob, seedBytes []byte, network lightspark_crypto.BitcoinNetwork) (objects.IdAndSignature, error) {
+func signSigningJob(ctx context.Context, signingJob SigningJob, signer Signer, network objects.BitcoinNetwork) (objects.IdAndSignature, error) {
 	addTweakBytes, err := signingJob.AddTweakBytes()
 	if err != nil {
 		return objects.IdAndSignature{}, err
@@ -389,14 +580,13 @@ func signSigningJob(signingJob SigningJob, seedBytes []byte, network lightspark_
 		return objects.IdAndSignature{}, err
 	}
 
-	signatureBytes, err := lightspark_crypto.DeriveKeyAndSign(
-		seedBytes,
+	signatureBytes, err := signer.DeriveKeyAndSign(
+		ctx,
 		network,
 		messageBytes,
 		signingJob.DerivationPath,
-		true,
-		&addTweakBytes,
-		&mulTweakBytes)
+		addTweakBytes,
+		mulTweakBytes)
 	if err != nil {
 		return objects.IdAndSignature{}, err
 	}
@@ -7,20 +7,26 @@ import (
 	"encoding/json"
 	"errors"
 	"fmt"
-	"log"
+	"log/slog"
 
 	"github.com/btcsuite/btcd/wire"
 	"github.com/lightsparkdev/go-sdk/objects"
 	"github.com/lightsparkdev/go-sdk/webhooks"
 )
 
-func ParseRemoteSigningRequest(webhook webhooks.WebhookEvent) (SigningRequest, error) {
+func ParseRemoteSigningRequest(webhook webhooks.WebhookEvent, opts ...Option) (SigningRequest, error) {
 	if webhook.EventType != objects.WebhookEventTypeRemoteSigning {
 		return nil, errors.New("webhook event is not for remote signing")
 	}
+	if webhook.Data == nil {
+		return nil, errors.New("webhook data is missing")
+	}
 
 	var subtype objects.RemoteSigningSubEventType
-	subEventTypeStr := (*webhook.Data)["sub_event_type"].(string)
+	subEventTypeStr, ok := (*webhook.Data)["sub_event_type"].(string)
+	if !ok {
+		return nil, errors.New("sub_event_type not found or invalid type")
+	}
 	err := subtype.UnmarshalJSON([]byte(`"` + subEventTypeStr + `"`))
 	if err != nil {
 		return nil, errors.New("invalid remote signing sub_event_type")
@@ -28,28 +34,28 @@ func ParseRemoteSigningRequest(webhook webhooks.WebhookEvent) (SigningRequest, e
 
 	switch subtype {
 	case objects.RemoteSigningSubEventTypeEcdh:
-		return ParseECDHRequest(webhook)
+		return ParseECDHRequest(webhook, opts...)
 	case objects.RemoteSigningSubEventTypeGetPerCommitmentPoint:
-		return ParseGetPerCommitmentPointRequest(webhook)
+		return ParseGetPerCommitmentPointRequest(webhook, opts...)
 	case objects.RemoteSigningSubEventTypeReleasePerCommitmentSecret:
-		return ParseReleasePerCommitmentSecretRequest(webhook)
+		return ParseReleasePerCommitmentSecretRequest(webhook, opts...)
 	case objects.RemoteSigningSubEventTypeDeriveKeyAndSign:
-		return ParseDeriveAndSignRequest(webhook)
+		return ParseDeriveAndSignRequest(webhook, opts...)
 	case objects.RemoteSigningSubEventTypeRequestInvoicePaymentHash:
-		return ParseRequestInvoicePaymentHashRequest(webhook)
+		return ParseRequestInvoicePaymentHashRequest(webhook, opts...)
 	case objects.RemoteSigningSubEventTypeSignInvoice:
-		return ParseSignInvoiceRequest(webhook)
+		return ParseSignInvoiceRequest(webhook, opts...)
 	case objects.RemoteSigningSubEventTypeReleasePaymentPreimage:
-		return ParseReleasePaymentPreimageRequest(webhook)
+		return ParseReleasePaymentPreimageRequest(webhook, opts...)
 	case objects.RemoteSigningSubEventTypeRevealCounterpartyPerCommitmentSecret:
-		return ParseReleaseCounterpartyPerCommitmentSecretRequest(webhook)
+		return ParseReleaseCounterpartyPerCommitmentSecretRequest(webhook, opts...)
 	default:
 		return nil, errors.New("invalid remote signing sub_event_type")
 	}
 }
 
-func ParseECDHRequest(webhook webhooks.WebhookEvent) (*ECDHRequest, error) {
-	log.Println("Parsing ECDH webhook")
+func ParseECDHRequest(webhook webhooks.WebhookEvent, opts ...Option) (*ECDHRequest, error) {
+	logParsing(webhook, "ECDH", opts)
 	if webhook.Data == nil {
 		return nil, errors.New("webhook data is missing")
 	}
@@ -77,8 +83,8 @@ func ParseECDHRequest(webhook webhooks.WebhookEvent) (*ECDHRequest, error) {
 	return &request, nil
 }
 
-func ParseGetPerCommitmentPointRequest(webhook webhooks.WebhookEvent) (*GetPerCommitmentPointRequest, error) {
-	log.Println("Parsing GET_PER_COMMITMENT_POINT webhook")
+func ParseGetPerCommitmentPointRequest(webhook webhooks.WebhookEvent, opts ...Option) (*GetPerCommitmentPointRequest, error) {
+	logParsing(webhook, "GET_PER_COMMITMENT_POINT", opts)
 	if webhook.Data == nil {
 		return nil, errors.New("missing data in webhook")
 	}
@@ -124,8 +130,8 @@ func ParseGetPerCommitmentPointRequest(webhook webhooks.WebhookEvent) (*GetPerCo
 	return &request, nil
 }
 
-func ParseReleasePerCommitmentSecretRequest(webhook webhooks.WebhookEvent) (*ReleasePerCommitmentSecretRequest, error) {
-	log.Println("Parsing RELEASE_PER_COMMITMENT_SECRET webhook")
+func ParseReleasePerCommitmentSecretRequest(webhook webhooks.WebhookEvent, opts ...Option) (*ReleasePerCommitmentSecretRequest, error) {
+	logParsing(webhook, "RELEASE_PER_COMMITMENT_SECRET", opts)
 	if webhook.Data == nil {
 		return nil, errors.New("missing data in webhook")
 	}
@@ -171,8 +177,8 @@ func ParseReleasePerCommitmentSecretRequest(webhook webhooks.WebhookEvent) (*Rel
 	return &request, nil
 }
 
-func ParseRequestInvoicePaymentHashRequest(webhook webhooks.WebhookEvent) (*InvoicePaymentHashRequest, error) {
-	log.Println("Parsing REQUEST_INVOICE_PAYMENT_HASH webhook")
+func ParseRequestInvoicePaymentHashRequest(webhook webhooks.WebhookEvent, opts ...Option) (*InvoicePaymentHashRequest, error) {
+	logParsing(webhook, "REQUEST_INVOICE_PAYMENT_HASH", opts)
 	if webhook.Data == nil {
 		return nil, errors.New("missing data in webhook")
 	}
@@ -184,7 +190,6 @@ func ParseRequestInvoicePaymentHashRequest(webhook webhooks.WebhookEvent) (*Invo
 	if invoiceId == nil {
 		return nil, errors.New("missing invoice_id in webhook")
 	}
-	log.Printf("invoiceId: %v", invoiceId)
 
 	network, err := bitcoinNetworkFromWebhookData(*webhook.Data)
 	if err != nil {
@@ -198,8 +203,8 @@ func ParseRequestInvoicePaymentHashRequest(webhook webhooks.WebhookEvent) (*Invo
 	return &request, nil
 }
 
-func ParseDeriveAndSignRequest(webhook webhooks.WebhookEvent) (*DeriveKeyAndSignRequest, error) {
-	log.Println("Parsing DERIVE_KEY_AND_SIGN webhook")
+func ParseDeriveAndSignRequest(webhook webhooks.WebhookEvent, opts ...Option) (*DeriveKeyAndSignRequest, error) {
+	logParsing(webhook, "DERIVE_KEY_AND_SIGN", opts)
 	if webhook.Data == nil {
 		return nil, errors.New("missing data in webhook")
 	}
@@ -235,8 +240,8 @@ func ParseDeriveAndSignRequest(webhook webhooks.WebhookEvent) (*DeriveKeyAndSign
 	return &request, nil
 }
 
-func ParseSignInvoiceRequest(webhook webhooks.WebhookEvent) (*SignInvoiceRequest, error) {
-	log.Println("Parsing SIGN_INVOICE webhook")
+func ParseSignInvoiceRequest(webhook webhooks.WebhookEvent, opts ...Option) (*SignInvoiceRequest, error) {
+	logParsing(webhook, "SIGN_INVOICE", opts)
 	if webhook.Data == nil {
 		return nil, errors.New("missing data in webhook")
 	}
@@ -268,8 +273,8 @@ func ParseSignInvoiceRequest(webhook webhooks.WebhookEvent) (*SignInvoiceRequest
 	return &request, nil
 }
 
-func ParseReleasePaymentPreimageRequest(webhook webhooks.WebhookEvent) (*ReleasePaymentPreimageRequest, error) {
-	log.Println("Parsing RELEASE_PAYMENT_PREIMAGE webhook")
+func ParseReleasePaymentPreimageRequest(webhook webhooks.WebhookEvent, opts ...Option) (*ReleasePaymentPreimageRequest, error) {
+	logParsing(webhook, "RELEASE_PAYMENT_PREIMAGE", opts)
 	if webhook.Data == nil {
 		return nil, errors.New("missing data in webhook")
 	}
@@ -312,8 +317,8 @@ func ParseReleasePaymentPreimageRequest(webhook webhooks.WebhookEvent) (*Release
 	return &request, nil
 }
 
-func ParseReleaseCounterpartyPerCommitmentSecretRequest(webhook webhooks.WebhookEvent) (*ReleaseCounterpartyPerCommitmentSecretRequest, error) {
-	log.Println("Parsing RELEASE_COUNTERPARTY_PER_COMMITMENT_SECRET webhook")
+func ParseReleaseCounterpartyPerCommitmentSecretRequest(webhook webhooks.WebhookEvent, opts ...Option) (*ReleaseCounterpartyPerCommitmentSecretRequest, error) {
+	logParsing(webhook, "RELEASE_COUNTERPARTY_PER_COMMITMENT_SECRET", opts)
 	if webhook.Data == nil {
 		return nil, errors.New("missing data in webhook")
 	}
@@ -353,8 +358,16 @@ func ParseReleaseCounterpartyPerCommitmentSecretRequest(webhook webhooks.Webhook
 	return &request, nil
 }
 
+func logParsing(webhook webhooks.WebhookEvent, subEventType string, opts []Option) {
+	newOptions(opts).logger.Debug("parsing remote signing webhook",
+		slog.String("event_id", webhook.EventId),
+		slog.String("entity_id", webhook.EntityId),
+		slog.String("sub_event_type", subEventType),
+	)
+}
+
 func bitcoinNetworkFromWebhookData(data map[string]interface{}) (objects.BitcoinNetwork, error) {
-	network := data["bitcoin_network"].(string)
+	network, _ := data["bitcoin_network"].(string)
 	switch network {
 	case "MAINNET":
 		return objects.BitcoinNetworkMainnet, nil
@@ -0,0 +1,262 @@
+// Copyright ©, 2023-present, Lightspark Group, Inc. - All Rights Reserved
+package remotesigning
+
+import (
+	"context"
+	"errors"
+	"fmt"
+
+	"github.com/lightsparkdev/go-sdk/crypto"
+	"github.com/lightsparkdev/go-sdk/crypto/lightsparkcrypto"
+	"github.com/lightsparkdev/go-sdk/objects"
+)
+
+// Signer performs the key operations of remote signing. The *WithSigner handlers use it instead of the
+// master seed, so the seed can be kept encrypted or in a separate process.
+type Signer interface {
+	// Ecdh returns the shared secret of the node key and a peer public key.
+	Ecdh(ctx context.Context, network objects.BitcoinNetwork, peerPubKey []byte) ([]byte, error)
+	// GetPerCommitmentPoint returns the per-commitment point of a channel commitment.
+	GetPerCommitmentPoint(ctx context.Context, network objects.BitcoinNetwork, derivationPath string, perCommitmentPointIdx uint64) ([]byte, error)
+	// ReleasePerCommitmentSecret returns the per-commitment secret of a channel commitment.
+	ReleasePerCommitmentSecret(ctx context.Context, network objects.BitcoinNetwork, derivationPath string, perCommitmentPointIdx uint64) ([]byte, error)
+	// DeriveKeyAndSign signs a message hash with the key at derivationPath, tweaked by the optional tweaks.
+	DeriveKeyAndSign(ctx context.Context, network objects.BitcoinNetwork, message []byte, derivationPath string, addTweak []byte, mulTweak []byte) ([]byte, error)
+	// SignInvoiceHash signs the hash of an invoice with the node key.
+	SignInvoiceHash(ctx context.Context, network objects.BitcoinNetwork, hash []byte) (signature []byte, recoveryId int32, err error)
+	// GeneratePreimageNonce returns a new nonce to generate a payment preimage with.
+	GeneratePreimageNonce(ctx context.Context) ([]byte, error)
+	// GeneratePreimageHash returns the payment hash of the preimage of a nonce.
+	GeneratePreimageHash(ctx context.Context, nonce []byte) ([]byte, error)
+	// GeneratePreimage returns the payment preimage of a nonce.
+	GeneratePreimage(ctx context.Context, nonce []byte) ([]byte, error)
+}
+
+// SeedProvider provides the master seed to a SeedSigner.
+type SeedProvider interface {
+	// Seed returns the master seed. The caller owns the returned slice and wipes it after use.
+	Seed(ctx context.Context) ([]byte, error)
+}
+
+// ErrWrongNetwork is returned when a Signer whose seed belongs to a network, such as the seed of a
+// crypto.Keystore, is asked to operate on another network.
+var ErrWrongNetwork = errors.New("the seed is for another network")
+
+// networkSeedProvider is a SeedProvider whose seed belongs to a single network.
+type networkSeedProvider interface {
+	SeedProvider
+	// seedForNetwork returns the master seed, or ErrWrongNetwork when the seed is not for network.
+	seedForNetwork(ctx context.Context, network objects.BitcoinNetwork) ([]byte, error)
+}
+
+// StaticSeed is a SeedProvider keeping the master seed in memory.
+type StaticSeed []byte
+
+// Seed implements SeedProvider.
+func (s StaticSeed) Seed(ctx context.Context) ([]byte, error) {
+	return append([]byte(nil), s...), nil
+}
+
+// SeedSigner is a Signer deriving keys from the master seed of a SeedProvider. The seed is requested for
+// every operation and wiped once the operation is done.
+type SeedSigner struct {
+	provider SeedProvider
+}
+
+// NewSeedSigner creates a SeedSigner using the seed of provider.
+func NewSeedSigner(provider SeedProvider) *SeedSigner {
+	return &SeedSigner{provider: provider}
+}
+
+// NewMemorySigner creates a Signer keeping the master seed in memory.
+func NewMemorySigner(seedBytes []byte) *SeedSigner {
+	return NewSeedSigner(StaticSeed(seedBytes))
+}
+
+// NewMnemonicSigner creates a Signer keeping in memory the master seed of a BIP 39 mnemonic and its
+// optional passphrase.
+func NewMnemonicSigner(mnemonic []string, passphrase string) (*SeedSigner, error) {
+	seed, err := crypto.MnemonicToSeed(mnemonic, passphrase)
+	if err != nil {
+		return nil, err
+	}
+	return NewMemorySigner(seed), nil
+}
+
+// withSeed calls fn with the seed, wiping it afterwards. Operations that don't depend on the network pass
+// objects.BitcoinNetworkUndefined.
+func (s *SeedSigner) withSeed(ctx context.Context, network objects.BitcoinNetwork, fn func(seed []byte) error) error {
+	var seed []byte
+	var err error
+	if provider, ok := s.provider.(networkSeedProvider); ok && network != objects.BitcoinNetworkUndefined {
+		seed, err = provider.seedForNetwork(ctx, network)
+	} else {
+		seed, err = s.provider.Seed(ctx)
+	}
+	if err != nil {
+		return err
+	}
+	defer clear(seed)
+	return fn(seed)
+}
+
+func (s *SeedSigner) Ecdh(ctx context.Context, network objects.BitcoinNetwork, peerPubKey []byte) ([]byte, error) {
+	bitcoinNetwork, err := bitcoinNetworkConversion(network)
+	if err != nil {
+		return nil, err
+	}
+	var sharedSecret []byte
+	err = s.withSeed(ctx, network, func(seed []byte) error {
+		sharedSecret, err = lightsparkcrypto.Ecdh(seed, bitcoinNetwork, peerPubKey)
+		return err
+	})
+	return sharedSecret, err
+}
+
+func (s *SeedSigner) GetPerCommitmentPoint(ctx context.Context, network objects.BitcoinNetwork, derivationPath string, perCommitmentPointIdx uint64) ([]byte, error) {
+	bitcoinNetwork, err := bitcoinNetworkConversion(network)
+	if err != nil {
+		return nil, err
+	}
+	var point []byte
+	err = s.withSeed(ctx, network, func(seed []byte) error {
+		point, err = lightsparkcrypto.GetPerCommitmentPoint(seed, bitcoinNetwork, derivationPath, perCommitmentPointIdx)
+		return err
+	})
+	return point, err
+}
+
+func (s *SeedSigner) ReleasePerCommitmentSecret(ctx context.Context, network objects.BitcoinNetwork, derivationPath string, perCommitmentPointIdx uint64) ([]byte, error) {
+	bitcoinNetwork, err := bitcoinNetworkConversion(network)
+	if err != nil {
+		return nil, err
+	}
+	var secret []byte
+	err = s.withSeed(ctx, network, func(seed []byte) error {
+		secret, err = lightsparkcrypto.ReleasePerCommitmentSecret(seed, bitcoinNetwork, derivationPath, perCommitmentPointIdx)
+		return err
+	})
+	return secret, err
+}
+
+func (s *SeedSigner) DeriveKeyAndSign(ctx context.Context, network objects.BitcoinNetwork, message []byte, derivationPath string, addTweak []byte, mulTweak []byte) ([]byte, error) {
+	bitcoinNetwork, err := bitcoinNetworkConversion(network)
+	if err != nil {
+		return nil, err
+	}
+	var signature []byte
+	err = s.withSeed(ctx, network, func(seed []byte) error {
+		signature, err = lightsparkcrypto.DeriveKeyAndSign(seed, bitcoinNetwork, message, derivationPath, true, &addTweak, &mulTweak)
+		return err
+	})
+	return signature, err
+}
+
+func (s *SeedSigner) SignInvoiceHash(ctx context.Context, network objects.BitcoinNetwork, hash []byte) ([]byte, int32, error) {
+	bitcoinNetwork, err := bitcoinNetworkConversion(network)
+	if err != nil {
+		return nil, 0, err
+	}
+	var signed *lightsparkcrypto.SignedInvoice
+	err = s.withSeed(ctx, network, func(seed []byte) error {
+		signed, err = lightsparkcrypto.SignInvoiceHash(seed, bitcoinNetwork, hash)
+		return err
+	})
+	if err != nil {
+		return nil, 0, err
+	}
+	return signed.Signature, signed.RecoveryId, nil
+}
+
+func (s *SeedSigner) GeneratePreimageNonce(ctx context.Context) ([]byte, error) {
+	var nonce []byte
+	err := s.withSeed(ctx, objects.BitcoinNetworkUndefined, func(seed []byte) (err error) {
+		nonce, err = lightsparkcrypto.GeneratePreimageNonce(seed)
+		return err
+	})
+	return nonce, err
+}
+
+func (s *SeedSigner) GeneratePreimageHash(ctx context.Context, nonce []byte) ([]byte, error) {
+	var paymentHash []byte
+	err := s.withSeed(ctx, objects.BitcoinNetworkUndefined, func(seed []byte) (err error) {
+		paymentHash, err = lightsparkcrypto.GeneratePreimageHash(seed, nonce)
+		return err
+	})
+	return paymentHash, err
+}
+
+func (s *SeedSigner) GeneratePreimage(ctx context.Context, nonce []byte) ([]byte, error) {
+	var preimage []byte
+	err := s.withSeed(ctx, objects.BitcoinNetworkUndefined, func(seed []byte) (err error) {
+		preimage, err = lightsparkcrypto.GeneratePreimage(seed, nonce)
+		return err
+	})
+	return preimage, err
+}
+
+// KeystoreSeed is a SeedProvider reading the master seed from a crypto.Keystore file. The keystore is read
+// and unlocked for every operation, so neither the seed nor the passphrase need to stay in memory when
+// Passphrase fetches the passphrase from an external source. Unlocking runs the memory-hard key
+// derivation function of the keystore; use NewKeystoreSigner to unlock it once. Signers using it refuse
+// operations on another network than the one of the keystore.
+type KeystoreSeed struct {
+	Path string
+	// Passphrase returns the passphrase of the keystore.
+	Passphrase func(ctx context.Context) (string, error)
+}
+
+// Seed implements SeedProvider.
+func (k KeystoreSeed) Seed(ctx context.Context) ([]byte, error) {
+	return k.seedForNetwork(ctx, objects.BitcoinNetworkUndefined)
+}
+
+func (k KeystoreSeed) seedForNetwork(ctx context.Context, network objects.BitcoinNetwork) ([]byte, error) {
+	keystore, err := crypto.ReadKeystore(k.Path)
+	if err != nil {
+		return nil, err
+	}
+	if err := checkNetwork(keystore.Network, network); err != nil {
+		return nil, err
+	}
+	passphrase, err := k.Passphrase(ctx)
+	if err != nil {
+		return nil, err
+	}
+	return keystore.Unlock(passphrase)
+}
+
+// NewKeystoreSigner unlocks a keystore and returns a Signer keeping its seed in memory. The signer refuses
+// operations on another network than the one of the keystore.
+func NewKeystoreSigner(keystore *crypto.Keystore, passphrase string) (*SeedSigner, error) {
+	seed, err := keystore.Unlock(passphrase)
+	if err != nil {
+		return nil, err
+	}
+	return NewSeedSigner(networkSeed{seed: seed, network: keystore.Network}), nil
+}
+
+// networkSeed is a master seed of a network kept in memory.
+type networkSeed struct {
+	seed    StaticSeed
+	network objects.BitcoinNetwork
+}
+
+// Seed implements SeedProvider.
+func (s networkSeed) Seed(ctx context.Context) ([]byte, error) {
+	return s.seed.Seed(ctx)
+}
+
+func (s networkSeed) seedForNetwork(ctx context.Context, network objects.BitcoinNetwork) ([]byte, error) {
+	if err := checkNetwork(s.network, network); err != nil {
+		return nil, err
+	}
+	return s.seed.Seed(ctx)
+}
+
+func checkNetwork(seedNetwork objects.BitcoinNetwork, network objects.BitcoinNetwork) error {
+	if network != objects.BitcoinNetworkUndefined && network != seedNetwork {
+		return fmt.Errorf("%w: %s operation with a %s seed", ErrWrongNetwork, network.StringValue(), seedNetwork.StringValue())
+	}
+	return nil
+}
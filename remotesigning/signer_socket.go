@@ -0,0 +1,513 @@
+// Copyright ©, 2023-present, Lightspark Group, Inc. - All Rights Reserved
+package remotesigning
+
+import (
+	"bufio"
+	"bytes"
+	"context"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"log/slog"
+	"net"
+	"sync"
+	"time"
+
+	"github.com/lightsparkdev/go-sdk/objects"
+	"github.com/lightsparkdev/go-sdk/webhooks"
+)
+
+// The socket signer protocol exchanges one JSON request and one JSON response per line.
+type signerRequest struct {
+	Method                string                 `json:"method"`
+	Network               objects.BitcoinNetwork `json:"network,omitempty"`
+	DerivationPath        string                 `json:"derivation_path,omitempty"`
+	PerCommitmentPointIdx uint64                 `json:"per_commitment_point_idx,omitempty"`
+	Data                  []byte                 `json:"data,omitempty"`
+	AddTweak              []byte                 `json:"add_tweak,omitempty"`
+	MulTweak              []byte                 `json:"mul_tweak,omitempty"`
+	// Webhook is the body of the remote signing webhook requesting the operation, as received from
+	// Lightspark, and Signature is its signature header.
+	Webhook   []byte `json:"webhook,omitempty"`
+	Signature string `json:"signature,omitempty"`
+}
+
+type signerResponse struct {
+	Data       []byte `json:"data,omitempty"`
+	RecoveryId int32  `json:"recovery_id,omitempty"`
+	Error      string `json:"error,omitempty"`
+	// Decision is set when the validator of the signer process declined the webhook.
+	Decision *Decision `json:"decision,omitempty"`
+}
+
+const (
+	// signerIdleTimeout is how long ServeSigner waits for a request or for a response to be written.
+	// SocketSigner reopens connections idle for more than half of it.
+	signerIdleTimeout = time.Minute
+	// maxSignerMessageSize is the maximum size of a line of the protocol.
+	maxSignerMessageSize = 1 << 20
+)
+
+const (
+	methodEcdh                       = "ecdh"
+	methodGetPerCommitmentPoint      = "get_per_commitment_point"
+	methodReleasePerCommitmentSecret = "release_per_commitment_secret"
+	methodDeriveKeyAndSign           = "derive_key_and_sign"
+	methodSignInvoiceHash            = "sign_invoice_hash"
+	methodGeneratePreimageNonce      = "generate_preimage_nonce"
+	methodGeneratePreimageHash       = "generate_preimage_hash"
+	methodGeneratePreimage           = "generate_preimage"
+)
+
+// SocketSigner is a Signer forwarding every operation to a signer process listening on a Unix socket
+// with ServeSigner. Operations are sent one at a time over a single connection, which is reopened after
+// an error. The signer process only answers operations made while handling a webhook with
+// GraphQLResponseForRemoteSigningWebhookWithSigner or HandleRemoteSigningWebhookWithSigner and
+// WithSignedWebhook, and returns a DeclinedError when its validator declines the webhook.
+type SocketSigner struct {
+	path string
+
+	mu       sync.Mutex
+	conn     net.Conn
+	reader   *bufio.Reader
+	lastUsed time.Time
+}
+
+// NewSocketSigner creates a SocketSigner connecting to the Unix socket at path. The connection is opened
+// by the first operation.
+func NewSocketSigner(path string) *SocketSigner {
+	return &SocketSigner{path: path}
+}
+
+// Close closes the connection to the signer process.
+func (s *SocketSigner) Close() error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if s.conn == nil {
+		return nil
+	}
+	err := s.conn.Close()
+	s.conn = nil
+	return err
+}
+
+func (s *SocketSigner) call(ctx context.Context, request signerRequest) (*signerResponse, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if s.conn != nil && time.Since(s.lastUsed) > signerIdleTimeout/2 {
+		// The signer process may be closing the connection.
+		s.conn.Close()
+		s.conn = nil
+	}
+	if s.conn == nil {
+		var dialer net.Dialer
+		conn, err := dialer.DialContext(ctx, "unix", s.path)
+		if err != nil {
+			return nil, err
+		}
+		s.conn = conn
+		s.reader = bufio.NewReader(conn)
+	}
+	if webhook := signedWebhookFromContext(ctx); webhook != nil {
+		request.Webhook = webhook.body
+		request.Signature = webhook.signature
+	}
+	deadline, _ := ctx.Deadline()
+	response, err := s.exchange(deadline, request)
+	if err != nil {
+		s.conn.Close()
+		s.conn = nil
+		if ctxErr := ctx.Err(); ctxErr != nil {
+			return nil, ctxErr
+		}
+		return nil, fmt.Errorf("signer socket: %w", err)
+	}
+	s.lastUsed = time.Now()
+	if response.Decision != nil {
+		return nil, &DeclinedError{Decision: *response.Decision}
+	}
+	if response.Error != "" {
+		return nil, errors.New(response.Error)
+	}
+	return response, nil
+}
+
+func (s *SocketSigner) exchange(deadline time.Time, request signerRequest) (*signerResponse, error) {
+	if err := s.conn.SetDeadline(deadline); err != nil {
+		return nil, err
+	}
+	data, err := json.Marshal(request)
+	if err != nil {
+		return nil, err
+	}
+	if _, err := s.conn.Write(append(data, '\n')); err != nil {
+		return nil, err
+	}
+	line, err := readLine(s.reader)
+	if err != nil {
+		return nil, err
+	}
+	var response signerResponse
+	if err := json.Unmarshal(line, &response); err != nil {
+		return nil, err
+	}
+	return &response, nil
+}
+
+func (s *SocketSigner) Ecdh(ctx context.Context, network objects.BitcoinNetwork, peerPubKey []byte) ([]byte, error) {
+	response, err := s.call(ctx, signerRequest{Method: methodEcdh, Network: network, Data: peerPubKey})
+	if err != nil {
+		return nil, err
+	}
+	return response.Data, nil
+}
+
+func (s *SocketSigner) GetPerCommitmentPoint(ctx context.Context, network objects.BitcoinNetwork, derivationPath string, perCommitmentPointIdx uint64) ([]byte, error) {
+	response, err := s.call(ctx, signerRequest{
+		Method:                methodGetPerCommitmentPoint,
+		Network:               network,
+		DerivationPath:        derivationPath,
+		PerCommitmentPointIdx: perCommitmentPointIdx,
+	})
+	if err != nil {
+		return nil, err
+	}
+	return response.Data, nil
+}
+
+func (s *SocketSigner) ReleasePerCommitmentSecret(ctx context.Context, network objects.BitcoinNetwork, derivationPath string, perCommitmentPointIdx uint64) ([]byte, error) {
+	response, err := s.call(ctx, signerRequest{
+		Method:                methodReleasePerCommitmentSecret,
+		Network:               network,
+		DerivationPath:        derivationPath,
+		PerCommitmentPointIdx: perCommitmentPointIdx,
+	})
+	if err != nil {
+		return nil, err
+	}
+	return response.Data, nil
+}
+
+func (s *SocketSigner) DeriveKeyAndSign(ctx context.Context, network objects.BitcoinNetwork, message []byte, derivationPath string, addTweak []byte, mulTweak []byte) ([]byte, error) {
+	response, err := s.call(ctx, signerRequest{
+		Method:         methodDeriveKeyAndSign,
+		Network:        network,
+		DerivationPath: derivationPath,
+		Data:           message,
+		AddTweak:       addTweak,
+		MulTweak:       mulTweak,
+	})
+	if err != nil {
+		return nil, err
+	}
+	return response.Data, nil
+}
+
+func (s *SocketSigner) SignInvoiceHash(ctx context.Context, network objects.BitcoinNetwork, hash []byte) ([]byte, int32, error) {
+	response, err := s.call(ctx, signerRequest{Method: methodSignInvoiceHash, Network: network, Data: hash})
+	if err != nil {
+		return nil, 0, err
+	}
+	return response.Data, response.RecoveryId, nil
+}
+
+func (s *SocketSigner) GeneratePreimageNonce(ctx context.Context) ([]byte, error) {
+	response, err := s.call(ctx, signerRequest{Method: methodGeneratePreimageNonce})
+	if err != nil {
+		return nil, err
+	}
+	return response.Data, nil
+}
+
+func (s *SocketSigner) GeneratePreimageHash(ctx context.Context, nonce []byte) ([]byte, error) {
+	response, err := s.call(ctx, signerRequest{Method: methodGeneratePreimageHash, Data: nonce})
+	if err != nil {
+		return nil, err
+	}
+	return response.Data, nil
+}
+
+func (s *SocketSigner) GeneratePreimage(ctx context.Context, nonce []byte) ([]byte, error) {
+	response, err := s.call(ctx, signerRequest{Method: methodGeneratePreimage, Data: nonce})
+	if err != nil {
+		return nil, err
+	}
+	return response.Data, nil
+}
+
+// readLine reads a line of at most maxSignerMessageSize bytes.
+func readLine(reader *bufio.Reader) ([]byte, error) {
+	var line []byte
+	for {
+		chunk, err := reader.ReadSlice('\n')
+		if len(line)+len(chunk) > maxSignerMessageSize {
+			return nil, errors.New("signer message too large")
+		}
+		line = append(line, chunk...)
+		if err != bufio.ErrBufferFull {
+			return line, err
+		}
+	}
+}
+
+// signedWebhook is the body of a webhook and its signature header, as received from Lightspark.
+type signedWebhook struct {
+	body      []byte
+	signature string
+}
+
+type signedWebhookContextKey struct{}
+
+// contextWithSignedWebhook returns a context carrying the webhook that the operations of a Signer answer.
+func contextWithSignedWebhook(ctx context.Context, webhook signedWebhook) context.Context {
+	return context.WithValue(ctx, signedWebhookContextKey{}, &webhook)
+}
+
+func signedWebhookFromContext(ctx context.Context) *signedWebhook {
+	webhook, _ := ctx.Value(signedWebhookContextKey{}).(*signedWebhook)
+	return webhook
+}
+
+// ServeSigner answers the operations of SocketSigners connecting to listener with signer, until the
+// listener is closed. It doesn't trust the connecting processes: every operation comes with the body
+// and the signature of the webhook requesting it, the webhook is verified with secrets as by
+// webhooks.VerifyAndParseWithSecrets and evaluated by validator, and operations that the webhook doesn't
+// request are refused. With WithStateStore, the per-commitment state of the channels is recorded and
+// enforced as by HandleGetPerCommitmentPointRequest and HandleReleasePerCommitmentSecretRequest. Restrict
+// access to the socket anyway, for example with its file permissions.
+//
+// Once the listener is closed, the open connections are closed and ServeSigner returns when the
+// operations in progress are done.
+func ServeSigner(listener net.Listener, signer Signer, secrets webhooks.Secrets, validator Validator, opts ...Option) error {
+	server := &signerServer{signer: signer, secrets: secrets, validator: validator, options: newOptions(opts)}
+	var mu sync.Mutex
+	conns := map[net.Conn]bool{}
+	var wg sync.WaitGroup
+	defer func() {
+		mu.Lock()
+		for conn := range conns {
+			conn.Close()
+		}
+		mu.Unlock()
+		wg.Wait()
+	}()
+	for {
+		conn, err := listener.Accept()
+		if err != nil {
+			if errors.Is(err, net.ErrClosed) {
+				return nil
+			}
+			return err
+		}
+		mu.Lock()
+		conns[conn] = true
+		mu.Unlock()
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			server.serveConn(conn)
+			mu.Lock()
+			delete(conns, conn)
+			mu.Unlock()
+		}()
+	}
+}
+
+type signerServer struct {
+	signer    Signer
+	secrets   webhooks.Secrets
+	validator Validator
+	options   *options
+}
+
+// authorizedWebhook is a webhook evaluated by the validator of ServeSigner, with its request when the
+// webhook was allowed.
+type authorizedWebhook struct {
+	body      []byte
+	signature string
+	eventId   string
+	request   SigningRequest
+	decision  *Decision
+	err       error
+}
+
+func (s *signerServer) serveConn(conn net.Conn) {
+	defer conn.Close()
+	reader := bufio.NewReader(conn)
+	// A webhook is evaluated once for all of its operations, which SocketSigner sends over the same
+	// connection.
+	var current *authorizedWebhook
+	for {
+		if err := conn.SetReadDeadline(time.Now().Add(signerIdleTimeout)); err != nil {
+			return
+		}
+		line, err := readLine(reader)
+		if err != nil {
+			return
+		}
+		var request signerRequest
+		var response signerResponse
+		if err := json.Unmarshal(line, &request); err != nil {
+			response.Error = fmt.Sprintf("invalid request: %s", err)
+		} else if request.Webhook == nil {
+			response.Error = "the operation has no webhook"
+		} else if current != nil && bytes.Equal(current.body, request.Webhook) && current.signature == request.Signature {
+			response = s.handle(current, request)
+		} else if webhook, err := webhooks.VerifyAndParseWithSecrets(request.Webhook, request.Signature, s.secrets); err != nil {
+			response.Error = fmt.Sprintf("invalid webhook: %s", err)
+		} else {
+			current = s.authorize(*webhook)
+			current.body = request.Webhook
+			current.signature = request.Signature
+			response = s.handle(current, request)
+		}
+		data, err := json.Marshal(response)
+		if err != nil {
+			return
+		}
+		if err := conn.SetWriteDeadline(time.Now().Add(signerIdleTimeout)); err != nil {
+			return
+		}
+		if _, err := conn.Write(append(data, '\n')); err != nil {
+			return
+		}
+	}
+}
+
+// authorize evaluates a webhook and records the per-commitment state of its request.
+func (s *signerServer) authorize(webhook webhooks.WebhookEvent) (authorized *authorizedWebhook) {
+	authorized = &authorizedWebhook{eventId: webhook.EventId}
+	defer func() {
+		// The webhook parsers panic on fields of the wrong type.
+		if r := recover(); r != nil {
+			authorized.request = nil
+			authorized.err = fmt.Errorf("invalid webhook: %v", r)
+		}
+	}()
+	logger := s.options.logger.With(
+		slog.String("event_id", webhook.EventId),
+		slog.String("entity_id", webhook.EntityId),
+	)
+	if decision := evaluate(s.validator, webhook); !decision.Allowed {
+		logger.Warn("declined to sign messages",
+			slog.String("reason", string(decision.Reason)),
+			slog.String("reason_message", decision.Message),
+		)
+		authorized.decision = &decision
+		return authorized
+	}
+	request, err := ParseRemoteSigningRequest(webhook, WithLogger(logger))
+	if err != nil {
+		authorized.err = err
+		return authorized
+	}
+	if s.options.stateStore != nil {
+		switch request := request.(type) {
+		case *GetPerCommitmentPointRequest:
+			err = recordPerCommitmentPoint(s.options.ctx, s.options.stateStore, request.ChannelId, request.PerCommitmentPointIdx)
+		case *ReleasePerCommitmentSecretRequest:
+			err = recordPerCommitmentSecret(s.options.ctx, s.options.stateStore, request.ChannelId, request.PerCommitmentPointIdx)
+		}
+		if err != nil {
+			logger.Error("refused to answer per-commitment request", slog.Any("error", err))
+			authorized.err = err
+			return authorized
+		}
+	}
+	authorized.request = request
+	return authorized
+}
+
+func (s *signerServer) handle(authorized *authorizedWebhook, request signerRequest) signerResponse {
+	if authorized.decision != nil {
+		return signerResponse{Decision: authorized.decision}
+	}
+	if authorized.err != nil {
+		return signerResponse{Error: authorized.err.Error()}
+	}
+	if !request.requestedBy(authorized.request) {
+		return signerResponse{Error: fmt.Sprintf("%s is not requested by webhook %s", request.Method, authorized.eventId)}
+	}
+	return handleSignerRequest(s.options.ctx, s.signer, request)
+}
+
+// requestedBy reports whether the operation is one of those made to answer request.
+func (r signerRequest) requestedBy(request SigningRequest) bool {
+	switch request := request.(type) {
+	case *ECDHRequest:
+		return r.Method == methodEcdh && r.Network == request.BitcoinNetwork && hexEqual(request.PeerPubKeyHex, r.Data)
+	case *GetPerCommitmentPointRequest:
+		return r.Method == methodGetPerCommitmentPoint && r.Network == request.BitcoinNetwork &&
+			r.DerivationPath == request.DerivationPath && r.PerCommitmentPointIdx == request.PerCommitmentPointIdx
+	case *ReleasePerCommitmentSecretRequest:
+		return r.Method == methodReleasePerCommitmentSecret && r.Network == request.BitcoinNetwork &&
+			r.DerivationPath == request.DerivationPath && r.PerCommitmentPointIdx == request.PerCommitmentPointIdx
+	case *DeriveKeyAndSignRequest:
+		if r.Method != methodDeriveKeyAndSign || r.Network != request.BitcoinNetwork {
+			return false
+		}
+		for _, job := range request.SigningJobs {
+			message, err := job.MessageBytes()
+			if err != nil {
+				continue
+			}
+			addTweak, err := job.AddTweakBytes()
+			if err != nil {
+				continue
+			}
+			mulTweak, err := job.MulTweakBytes()
+			if err != nil {
+				continue
+			}
+			if r.DerivationPath == job.DerivationPath && bytes.Equal(r.Data, message) &&
+				bytes.Equal(r.AddTweak, addTweak) && bytes.Equal(r.MulTweak, mulTweak) {
+				return true
+			}
+		}
+		return false
+	case *SignInvoiceRequest:
+		return r.Method == methodSignInvoiceHash && r.Network == request.BitcoinNetwork &&
+			hexEqual(request.PaymentRequestHash, r.Data)
+	case *InvoicePaymentHashRequest:
+		return r.Method == methodGeneratePreimageNonce || r.Method == methodGeneratePreimageHash
+	case *ReleasePaymentPreimageRequest:
+		return r.Method == methodGeneratePreimage && request.Nonce != nil && hexEqual(*request.Nonce, r.Data)
+	default:
+		return false
+	}
+}
+
+func hexEqual(hexValue string, value []byte) bool {
+	decoded, err := hex.DecodeString(hexValue)
+	return err == nil && bytes.Equal(decoded, value)
+}
+
+func handleSignerRequest(ctx context.Context, signer Signer, request signerRequest) signerResponse {
+	var response signerResponse
+	var err error
+	switch request.Method {
+	case methodEcdh:
+		response.Data, err = signer.Ecdh(ctx, request.Network, request.Data)
+	case methodGetPerCommitmentPoint:
+		response.Data, err = signer.GetPerCommitmentPoint(ctx, request.Network, request.DerivationPath, request.PerCommitmentPointIdx)
+	case methodReleasePerCommitmentSecret:
+		response.Data, err = signer.ReleasePerCommitmentSecret(ctx, request.Network, request.DerivationPath, request.PerCommitmentPointIdx)
+	case methodDeriveKeyAndSign:
+		response.Data, err = signer.DeriveKeyAndSign(ctx, request.Network, request.Data, request.DerivationPath, request.AddTweak, request.MulTweak)
+	case methodSignInvoiceHash:
+		response.Data, response.RecoveryId, err = signer.SignInvoiceHash(ctx, request.Network, request.Data)
+	case methodGeneratePreimageNonce:
+		response.Data, err = signer.GeneratePreimageNonce(ctx)
+	case methodGeneratePreimageHash:
+		response.Data, err = signer.GeneratePreimageHash(ctx, request.Data)
+	case methodGeneratePreimage:
+		response.Data, err = signer.GeneratePreimage(ctx, request.Data)
+	default:
+		err = fmt.Errorf("unknown method %q", request.Method)
+	}
+	if err != nil {
+		return signerResponse{Error: err.Error()}
+	}
+	return response
+}
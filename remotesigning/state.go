@@ -0,0 +1,198 @@
+// Copyright ©, 2023-present, Lightspark Group, Inc. - All Rights Reserved
+package remotesigning
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"sync"
+)
+
+// maxPerCommitmentIdx is the index of the first commitment of a channel. Per-commitment indexes count down
+// from it, as defined by BOLT 3.
+const maxPerCommitmentIdx = 1<<48 - 1
+
+var (
+	// ErrCommitmentNotAdvanced is returned when a per-commitment secret is requested before the point of
+	// the next commitment was handed out, which would revoke the current commitment of the channel.
+	ErrCommitmentNotAdvanced = errors.New("channel has not advanced past the commitment")
+	// ErrCommitmentRevoked is returned when a per-commitment point or secret is requested for a commitment
+	// older than the last revoked one.
+	ErrCommitmentRevoked = errors.New("commitment is older than the last revoked commitment")
+)
+
+// ChannelState is the per-commitment state of a channel recorded by a SignerStateStore. Commitment numbers
+// count up from 0 for the first commitment; the per-commitment index of commitment n is 2^48 - 1 - n.
+type ChannelState struct {
+	// HighestPointCommitment is the highest commitment number whose per-commitment point was handed out.
+	HighestPointCommitment *uint64 `json:"highest_point_commitment,omitempty"`
+	// RevokedCommitment is the highest commitment number whose per-commitment secret was released.
+	RevokedCommitment *uint64 `json:"revoked_commitment,omitempty"`
+}
+
+// SignerStateStore persists the ChannelState of every channel, so that the remote signer refuses to release
+// the secret of a commitment still in use, even across restarts. Implementations must be safe for
+// concurrent use.
+type SignerStateStore interface {
+	// Update loads the state of a channel, zero if unknown, and passes it to update. When update returns
+	// no error, the modified state must be stored durably before Update returns. Updates of a channel
+	// must not run concurrently.
+	Update(ctx context.Context, channelId string, update func(state *ChannelState) error) error
+}
+
+func commitmentNumber(perCommitmentIdx uint64) (uint64, error) {
+	if perCommitmentIdx > maxPerCommitmentIdx {
+		return 0, fmt.Errorf("invalid per-commitment index %d", perCommitmentIdx)
+	}
+	return maxPerCommitmentIdx - perCommitmentIdx, nil
+}
+
+// recordPerCommitmentPoint checks that the point of a commitment may be handed out and records it.
+func recordPerCommitmentPoint(ctx context.Context, store SignerStateStore, channelId string, perCommitmentIdx uint64) error {
+	number, err := commitmentNumber(perCommitmentIdx)
+	if err != nil {
+		return err
+	}
+	return store.Update(ctx, channelId, func(state *ChannelState) error {
+		if state.RevokedCommitment != nil && number <= *state.RevokedCommitment {
+			return fmt.Errorf("%w: point of commitment %d requested, commitment %d revoked",
+				ErrCommitmentRevoked, number, *state.RevokedCommitment)
+		}
+		if state.HighestPointCommitment == nil || number > *state.HighestPointCommitment {
+			state.HighestPointCommitment = &number
+		}
+		return nil
+	})
+}
+
+// recordPerCommitmentSecret checks that the secret of a commitment may be released and records it.
+// Releasing the last revoked secret again is allowed, so that failed webhooks can be retried.
+func recordPerCommitmentSecret(ctx context.Context, store SignerStateStore, channelId string, perCommitmentIdx uint64) error {
+	number, err := commitmentNumber(perCommitmentIdx)
+	if err != nil {
+		return err
+	}
+	return store.Update(ctx, channelId, func(state *ChannelState) error {
+		if state.RevokedCommitment != nil && number < *state.RevokedCommitment {
+			return fmt.Errorf("%w: secret of commitment %d requested, commitment %d revoked",
+				ErrCommitmentRevoked, number, *state.RevokedCommitment)
+		}
+		if state.HighestPointCommitment == nil || number >= *state.HighestPointCommitment {
+			return fmt.Errorf("%w: secret of commitment %d requested before the point of commitment %d",
+				ErrCommitmentNotAdvanced, number, number+1)
+		}
+		state.RevokedCommitment = &number
+		return nil
+	})
+}
+
+// MemorySignerStateStore is a SignerStateStore keeping the states in memory. The states are lost when the
+// process exits, so it is only suited to tests.
+type MemorySignerStateStore struct {
+	mu     sync.Mutex
+	states map[string]ChannelState
+}
+
+// NewMemorySignerStateStore creates an empty MemorySignerStateStore.
+func NewMemorySignerStateStore() *MemorySignerStateStore {
+	return &MemorySignerStateStore{states: map[string]ChannelState{}}
+}
+
+// Update implements SignerStateStore.
+func (s *MemorySignerStateStore) Update(ctx context.Context, channelId string, update func(state *ChannelState) error) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	state := s.states[channelId]
+	if err := update(&state); err != nil {
+		return err
+	}
+	s.states[channelId] = state
+	return nil
+}
+
+// State returns the state of a channel.
+func (s *MemorySignerStateStore) State(channelId string) ChannelState {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return s.states[channelId]
+}
+
+// FileSignerStateStore is a SignerStateStore keeping the states in a JSON file. Every update rewrites the
+// file atomically and syncs it before returning.
+type FileSignerStateStore struct {
+	path string
+
+	mu     sync.Mutex
+	states map[string]ChannelState
+}
+
+// OpenFileSignerStateStore opens the FileSignerStateStore stored at path, creating it on the first update.
+func OpenFileSignerStateStore(path string) (*FileSignerStateStore, error) {
+	s := &FileSignerStateStore{path: path, states: map[string]ChannelState{}}
+	data, err := os.ReadFile(path)
+	if errors.Is(err, os.ErrNotExist) {
+		return s, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+	if err := json.Unmarshal(data, &s.states); err != nil {
+		return nil, fmt.Errorf("invalid signer state file %s: %w", path, err)
+	}
+	return s, nil
+}
+
+// Update implements SignerStateStore.
+func (s *FileSignerStateStore) Update(ctx context.Context, channelId string, update func(state *ChannelState) error) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	previous, existed := s.states[channelId]
+	state := previous
+	if err := update(&state); err != nil {
+		return err
+	}
+	s.states[channelId] = state
+	if err := s.save(); err != nil {
+		if existed {
+			s.states[channelId] = previous
+		} else {
+			delete(s.states, channelId)
+		}
+		return err
+	}
+	return nil
+}
+
+// State returns the state of a channel.
+func (s *FileSignerStateStore) State(channelId string) ChannelState {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return s.states[channelId]
+}
+
+func (s *FileSignerStateStore) save() error {
+	data, err := json.Marshal(s.states)
+	if err != nil {
+		return err
+	}
+	temp, err := os.CreateTemp(filepath.Dir(s.path), filepath.Base(s.path)+".*.tmp")
+	if err != nil {
+		return err
+	}
+	defer os.Remove(temp.Name())
+	if _, err := temp.Write(data); err != nil {
+		temp.Close()
+		return err
+	}
+	if err := temp.Sync(); err != nil {
+		temp.Close()
+		return err
+	}
+	if err := temp.Close(); err != nil {
+		return err
+	}
+	return os.Rename(temp.Name(), s.path)
+}
@@ -0,0 +1,110 @@
+package remotesigning_test
+
+import (
+	"bytes"
+	"encoding/hex"
+	"testing"
+	"time"
+
+	"github.com/btcsuite/btcd/wire"
+	"github.com/lightsparkdev/go-sdk/objects"
+	"github.com/lightsparkdev/go-sdk/remotesigning"
+	"github.com/lightsparkdev/go-sdk/webhooks"
+	"github.com/stretchr/testify/require"
+)
+
+// The change of withdrawalTx is sent to the P2WPKH of m/1/77 of this seed.
+const destinationSeed = "370eb72fc3dd38c74f933b477378d51c3b5e6db126ad64fa3244b16e2b8a1bd37f6454dbdb2d58b98f3f6fa2d1c9232216b67616eb2c61bf8c2abe8f67edf252"
+
+func withdrawalWebhook(network objects.BitcoinNetwork, transaction string) webhooks.WebhookEvent {
+	data := map[string]interface{}{
+		"sub_event_type":  objects.RemoteSigningSubEventTypeDeriveKeyAndSign.StringValue(),
+		"bitcoin_network": network.StringValue(),
+		"signing_jobs": []interface{}{
+			map[string]interface{}{
+				"id":                          "job-1",
+				"derivation_path":             "m/84'/1'/0'/0/0",
+				"destination_derivation_path": "m/1/77",
+				"message":                     "00",
+				"transaction":                 transaction,
+			},
+		},
+	}
+	return webhooks.WebhookEvent{
+		EventType: objects.WebhookEventTypeRemoteSigning,
+		EventId:   "event-id",
+		Timestamp: time.Now(),
+		EntityId:  "node:1",
+		Data:      &data,
+	}
+}
+
+func withExtraOutput(t *testing.T, transaction string, output *wire.TxOut) string {
+	raw, err := hex.DecodeString(transaction)
+	require.NoError(t, err)
+	var tx wire.MsgTx
+	require.NoError(t, tx.Deserialize(bytes.NewReader(raw)))
+	tx.AddTxOut(output)
+	var buf bytes.Buffer
+	require.NoError(t, tx.Serialize(&buf))
+	return hex.EncodeToString(buf.Bytes())
+}
+
+func TestDestinationValidatorUsesRequestNetwork(t *testing.T) {
+	seed, err := hex.DecodeString(destinationSeed)
+	require.NoError(t, err)
+	validator := remotesigning.NewDestinationValidator(seed, false)
+	for _, network := range []objects.BitcoinNetwork{
+		objects.BitcoinNetworkMainnet,
+		objects.BitcoinNetworkTestnet,
+		objects.BitcoinNetworkRegtest,
+		objects.BitcoinNetworkSignet,
+	} {
+		require.True(t, validator.ShouldSign(withdrawalWebhook(network, withdrawalTx)), network.StringValue())
+	}
+
+	validator = remotesigning.NewDestinationValidator(seed, false,
+		remotesigning.WithDestinationScriptTypes(remotesigning.ScriptTypeP2TR, remotesigning.ScriptTypeP2WSH))
+	require.Equal(t, remotesigning.ReasonInvalidChangeOutput,
+		validator.Evaluate(withdrawalWebhook(objects.BitcoinNetworkRegtest, withdrawalTx)).Reason)
+}
+
+func TestDestinationValidatorStrictOutputs(t *testing.T) {
+	seed, err := hex.DecodeString(destinationSeed)
+	require.NoError(t, err)
+	validator := remotesigning.NewDestinationValidator(seed, false, remotesigning.WithStrictOutputs())
+	require.True(t, validator.ShouldSign(withdrawalWebhook(objects.BitcoinNetworkRegtest, withdrawalTx)))
+
+	// A second change output to the wallet is allowed, a second destination isn't.
+	change, err := hex.DecodeString("0014dd71b57f94e6876380850d0fbbaedb52d698b9e0")
+	require.NoError(t, err)
+	transaction := withExtraOutput(t, withdrawalTx, wire.NewTxOut(1000, change))
+	require.True(t, validator.ShouldSign(withdrawalWebhook(objects.BitcoinNetworkRegtest, transaction)))
+
+	destination, err := hex.DecodeString("0014aff40d81f6ffd5a98e358af465b1e1bf3fe9c012")
+	require.NoError(t, err)
+	transaction = withExtraOutput(t, withdrawalTx, wire.NewTxOut(1000, destination))
+	require.Equal(t, remotesigning.ReasonInvalidOutput,
+		validator.Evaluate(withdrawalWebhook(objects.BitcoinNetworkRegtest, transaction)).Reason)
+
+	transaction = withExtraOutput(t, withdrawalTx, wire.NewTxOut(0, change))
+	require.Equal(t, remotesigning.ReasonInvalidOutput,
+		validator.Evaluate(withdrawalWebhook(objects.BitcoinNetworkRegtest, transaction)).Reason)
+}
+
+func TestGenerateScriptFromPubkey(t *testing.T) {
+	// Test vector of BIP 86 for m/86'/0'/0'/0/0.
+	internalKey, err := hex.DecodeString("02cc8a4bc64d897bddc5fbc2f670f7a8ba0b386779106cf1223c6fc5d7cd6fc115")
+	require.NoError(t, err)
+	script, err := remotesigning.GenerateScriptFromPubkey(remotesigning.ScriptTypeP2TR, internalKey)
+	require.NoError(t, err)
+	require.Equal(t, "5120a60869f0dbcf1dc659c9cecbaf8050135ea9e8cdc487053f1dc6880949dc684c", hex.EncodeToString(script))
+
+	script, err = remotesigning.GenerateScriptFromPubkey(remotesigning.ScriptTypeP2WSH, internalKey)
+	require.NoError(t, err)
+	require.Len(t, script, 34)
+	require.Equal(t, []byte{0x00, 0x20}, script[:2])
+
+	_, err = remotesigning.GenerateScriptFromPubkey(remotesigning.ScriptType(42), internalKey)
+	require.Error(t, err)
+}
@@ -0,0 +1,66 @@
+package remotesigning_test
+
+import (
+	"bytes"
+	"encoding/json"
+	"log/slog"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/lightsparkdev/go-sdk/objects"
+	"github.com/lightsparkdev/go-sdk/remotesigning"
+	"github.com/lightsparkdev/go-sdk/webhooks"
+	"github.com/stretchr/testify/require"
+)
+
+func TestRemoteSigningStructuredLogs(t *testing.T) {
+	var buffer bytes.Buffer
+	logger := slog.New(slog.NewJSONHandler(&buffer, &slog.HandlerOptions{Level: slog.LevelDebug}))
+
+	webhookEvent := webhooks.WebhookEvent{
+		EventType: objects.WebhookEventTypeRemoteSigning,
+		EventId:   "event-id",
+		Timestamp: time.Now(),
+		EntityId:  "Node:node-id",
+		Data: &map[string]interface{}{
+			"sub_event_type":  objects.RemoteSigningSubEventTypeReleasePaymentPreimage.StringValue(),
+			"invoice_id":      "invoice-id",
+			"bitcoin_network": "REGTEST",
+			"preimage_nonce":  strings.Repeat("00", 32),
+		},
+	}
+
+	_, err := remotesigning.GraphQLResponseForRemoteSigningWebhook(
+		remotesigning.PositiveValidator{}, webhookEvent, bytes.Repeat([]byte{1}, 32), remotesigning.WithLogger(logger))
+	require.NoError(t, err)
+
+	var records []map[string]interface{}
+	for _, line := range strings.Split(strings.TrimSpace(buffer.String()), "\n") {
+		var record map[string]interface{}
+		require.NoError(t, json.Unmarshal([]byte(line), &record))
+		records = append(records, record)
+	}
+	require.NotEmpty(t, records)
+	for _, record := range records {
+		require.Equal(t, "event-id", record["event_id"])
+		require.Equal(t, "RELEASE_PAYMENT_PREIMAGE", record["sub_event_type"])
+	}
+	handled := records[len(records)-1]
+	require.Equal(t, "INFO", handled["level"])
+	require.Equal(t, "invoice-id", handled["invoice_id"])
+}
+
+func TestSignInvoiceFailureReturnsError(t *testing.T) {
+	var buffer bytes.Buffer
+	logger := slog.New(slog.NewJSONHandler(&buffer, nil))
+
+	request := &remotesigning.SignInvoiceRequest{
+		InvoiceId:          "invoice-id",
+		PaymentRequestHash: "abcd",
+		BitcoinNetwork:     objects.BitcoinNetworkRegtest,
+	}
+	_, err := remotesigning.HandleSignInvoiceRequest(request, []byte{}, remotesigning.WithLogger(logger))
+	require.Error(t, err)
+	require.Contains(t, buffer.String(), `"level":"ERROR"`)
+}
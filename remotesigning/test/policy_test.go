@@ -0,0 +1,234 @@
+package remotesigning_test
+
+import (
+	"encoding/hex"
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/btcsuite/btcd/btcutil"
+	"github.com/btcsuite/btcd/chaincfg"
+	"github.com/lightsparkdev/go-sdk/objects"
+	"github.com/lightsparkdev/go-sdk/remotesigning"
+	"github.com/lightsparkdev/go-sdk/webhooks"
+	"github.com/stretchr/testify/require"
+)
+
+// Sends 100000 sats to 0014aff40d81f6ffd5a98e358af465b1e1bf3fe9c012, with the change in the second output.
+const withdrawalTx = "02000000017ab44ffadf03b57ce0eb63074c541b3aea0b57497764a6790611332c441b989d0100000000ffffffff02a086010000000000160014aff40d81f6ffd5a98e358af465b1e1bf3fe9c012a086010000000000160014dd71b57f94e6876380850d0fbbaedb52d698b9e000000000"
+
+func policyWebhook(subEventType objects.RemoteSigningSubEventType, nodeId string) webhooks.WebhookEvent {
+	data := map[string]interface{}{
+		"sub_event_type":  subEventType.StringValue(),
+		"bitcoin_network": "MAINNET",
+	}
+	if subEventType == objects.RemoteSigningSubEventTypeDeriveKeyAndSign {
+		// Both inputs of the transaction are signed by their own job.
+		data["signing_jobs"] = []interface{}{
+			map[string]interface{}{"id": "job-1", "derivation_path": "m/84'/0'/0'/0/0", "message": "00", "transaction": withdrawalTx},
+			map[string]interface{}{"id": "job-2", "derivation_path": "m/84'/0'/0'/0/1", "message": "00", "transaction": withdrawalTx},
+			map[string]interface{}{"id": "job-3", "derivation_path": "m/3/2104864975", "message": "00"},
+		}
+	}
+	return webhooks.WebhookEvent{
+		EventType: objects.WebhookEventTypeRemoteSigning,
+		EventId:   "event-id",
+		Timestamp: time.Now(),
+		EntityId:  nodeId,
+		Data:      &data,
+	}
+}
+
+func destinationAddress(t *testing.T) string {
+	hash, err := hex.DecodeString("aff40d81f6ffd5a98e358af465b1e1bf3fe9c012")
+	require.NoError(t, err)
+	address, err := btcutil.NewAddressWitnessPubKeyHash(hash, &chaincfg.MainNetParams)
+	require.NoError(t, err)
+	return address.EncodeAddress()
+}
+
+func TestWithdrawals(t *testing.T) {
+	withdrawals, err := remotesigning.Withdrawals(policyWebhook(objects.RemoteSigningSubEventTypeDeriveKeyAndSign, "node:1"))
+	require.NoError(t, err)
+	require.Len(t, withdrawals, 1)
+	require.Equal(t, int64(100_000), withdrawals[0].AmountSats)
+
+	withdrawals, err = remotesigning.Withdrawals(policyWebhook(objects.RemoteSigningSubEventTypeEcdh, "node:1"))
+	require.NoError(t, err)
+	require.Empty(t, withdrawals)
+}
+
+func TestPolicyRules(t *testing.T) {
+	withdrawal := policyWebhook(objects.RemoteSigningSubEventTypeDeriveKeyAndSign, "node:1")
+	ecdh := policyWebhook(objects.RemoteSigningSubEventTypeEcdh, "node:1")
+
+	subEventTypes := remotesigning.SubEventTypeRule{Denied: []objects.RemoteSigningSubEventType{objects.RemoteSigningSubEventTypeEcdh}}
+	require.True(t, subEventTypes.Evaluate(withdrawal).Allowed)
+	require.Equal(t, remotesigning.ReasonSubEventTypeDenied, subEventTypes.Evaluate(ecdh).Reason)
+
+	allowlist, err := remotesigning.NewDestinationAllowlistRule(objects.BitcoinNetworkMainnet, destinationAddress(t))
+	require.NoError(t, err)
+	require.True(t, allowlist.Evaluate(withdrawal).Allowed)
+	require.True(t, allowlist.Evaluate(ecdh).Allowed)
+	other, err := remotesigning.NewDestinationAllowlistRule(objects.BitcoinNetworkMainnet,
+		"bc1qwqdg6squsna38e46795at95yu9atm8azzmyvckulcc7kytlcckxswvvzej")
+	require.NoError(t, err)
+	require.Equal(t, remotesigning.ReasonDestinationNotAllowed, other.Evaluate(withdrawal).Reason)
+	_, err = remotesigning.NewDestinationAllowlistRule(objects.BitcoinNetworkRegtest, destinationAddress(t))
+	require.Error(t, err)
+
+	require.True(t, remotesigning.MaxAmountRule{MaxSats: 100_000}.Evaluate(withdrawal).Allowed)
+	require.Equal(t, remotesigning.ReasonAmountExceeded, remotesigning.MaxAmountRule{MaxSats: 99_999}.Evaluate(withdrawal).Reason)
+
+	combined := remotesigning.Or(remotesigning.Not(subEventTypes), remotesigning.MaxAmountRule{MaxSats: 1})
+	require.True(t, combined.Evaluate(ecdh).Allowed)
+	decision := combined.Evaluate(withdrawal)
+	require.Equal(t, remotesigning.ReasonNoRuleAllowed, decision.Reason)
+	require.Contains(t, decision.Message, string(remotesigning.ReasonNegated))
+	require.Contains(t, decision.Message, string(remotesigning.ReasonAmountExceeded))
+}
+
+func TestNotKeepsInvalidRequests(t *testing.T) {
+	malformed := policyWebhook(objects.RemoteSigningSubEventTypeDeriveKeyAndSign, "node:1")
+	(*malformed.Data)["signing_jobs"] = []interface{}{
+		map[string]interface{}{"id": "job-1", "derivation_path": "m/84'/0'/0'/0/0", "message": "00", "transaction": "not a transaction"},
+	}
+	allowlist, err := remotesigning.NewDestinationAllowlistRule(objects.BitcoinNetworkMainnet, destinationAddress(t))
+	require.NoError(t, err)
+	require.Equal(t, remotesigning.ReasonInvalidRequest, allowlist.Evaluate(malformed).Reason)
+
+	for _, rule := range []remotesigning.Rule{
+		remotesigning.Not(allowlist),
+		remotesigning.Not(remotesigning.Or(allowlist, remotesigning.MaxAmountRule{})),
+		remotesigning.Not(remotesigning.Not(allowlist)),
+	} {
+		decision := rule.Evaluate(malformed)
+		require.False(t, decision.Allowed)
+		require.Equal(t, remotesigning.ReasonInvalidRequest, decision.Reason)
+	}
+}
+
+func TestVelocityRule(t *testing.T) {
+	now := time.Date(2024, 1, 1, 12, 0, 0, 0, time.UTC)
+	rule := &remotesigning.VelocityRule{Window: time.Hour, MaxSats: 250_000, Now: func() time.Time { return now }}
+	withdrawal := policyWebhook(objects.RemoteSigningSubEventTypeDeriveKeyAndSign, "node:1")
+
+	require.True(t, rule.Evaluate(withdrawal).Allowed)
+	now = now.Add(10 * time.Minute)
+	require.True(t, rule.Evaluate(withdrawal).Allowed)
+	require.Equal(t, remotesigning.ReasonVelocityExceeded, rule.Evaluate(withdrawal).Reason)
+	require.True(t, rule.Evaluate(policyWebhook(objects.RemoteSigningSubEventTypeDeriveKeyAndSign, "node:2")).Allowed)
+
+	// The first withdrawal leaves the window.
+	now = now.Add(50 * time.Minute)
+	require.True(t, rule.Evaluate(withdrawal).Allowed)
+
+	count := &remotesigning.VelocityRule{Window: time.Hour, MaxCount: 1, Now: func() time.Time { return now }}
+	require.True(t, count.Evaluate(withdrawal).Allowed)
+	require.Equal(t, remotesigning.ReasonVelocityExceeded, count.Evaluate(withdrawal).Reason)
+}
+
+func TestTimeWindowRule(t *testing.T) {
+	var now time.Time
+	rule := remotesigning.TimeWindowRule{
+		Start:         22 * time.Hour,
+		End:           6 * time.Hour,
+		Days:          []time.Weekday{time.Friday},
+		SubEventTypes: []objects.RemoteSigningSubEventType{objects.RemoteSigningSubEventTypeDeriveKeyAndSign},
+		Now:           func() time.Time { return now },
+	}
+	withdrawal := policyWebhook(objects.RemoteSigningSubEventTypeDeriveKeyAndSign, "node:1")
+
+	// Friday 2024-01-05 at 23:00 and Saturday at 05:00 are in the window started on Friday.
+	now = time.Date(2024, 1, 5, 23, 0, 0, 0, time.UTC)
+	require.True(t, rule.Evaluate(withdrawal).Allowed)
+	now = time.Date(2024, 1, 6, 5, 0, 0, 0, time.UTC)
+	require.True(t, rule.Evaluate(withdrawal).Allowed)
+	now = time.Date(2024, 1, 6, 23, 0, 0, 0, time.UTC)
+	require.Equal(t, remotesigning.ReasonOutsideTimeWindow, rule.Evaluate(withdrawal).Reason)
+	now = time.Date(2024, 1, 5, 12, 0, 0, 0, time.UTC)
+	require.Equal(t, remotesigning.ReasonOutsideTimeWindow, rule.Evaluate(withdrawal).Reason)
+	require.True(t, rule.Evaluate(policyWebhook(objects.RemoteSigningSubEventTypeEcdh, "node:1")).Allowed)
+}
+
+func TestParsePolicy(t *testing.T) {
+	policy, err := remotesigning.ParsePolicy([]byte(`
+all:
+  - sub_event_types:
+      denied: [RELEASE_PAYMENT_PREIMAGE]
+  - destination_allowlist:
+      network: MAINNET
+      addresses: [` + destinationAddress(t) + `]
+  - max_amount:
+      sats: 150000
+  - velocity:
+      window: 24h
+      max_count: 1
+  - any:
+      - not:
+          sub_event_types:
+            allowed: [DERIVE_KEY_AND_SIGN]
+      - time_window:
+          start: "08:00"
+          end: "08:00"
+          timezone: America/New_York
+`))
+	require.NoError(t, err)
+	withdrawal := policyWebhook(objects.RemoteSigningSubEventTypeDeriveKeyAndSign, "node:1")
+	require.True(t, policy.ShouldSign(withdrawal))
+	require.Equal(t, remotesigning.ReasonVelocityExceeded, policy.Evaluate(withdrawal).Reason)
+	require.Equal(t, remotesigning.ReasonSubEventTypeDenied,
+		policy.Evaluate(policyWebhook(objects.RemoteSigningSubEventTypeReleasePaymentPreimage, "node:1")).Reason)
+
+	policy, err = remotesigning.ParsePolicy([]byte(`{"max_amount": {"sats": 1}}`))
+	require.NoError(t, err)
+	require.False(t, policy.ShouldSign(withdrawal))
+
+	for _, invalid := range []string{
+		`{}`,
+		`{"max_amount": {"sats": 1}, "velocity": {"window": "1h"}}`,
+		`{"all": []}`,
+		`{"sub_event_types": {"denied": ["UNKNOWN"]}}`,
+		`{"velocity": {"window": "a day"}}`,
+		`{"time_window": {"start": "9am", "end": "17:00"}}`,
+		`{"destination_allowlist": {"network": "MAINNET", "addresses": ["not an address"]}}`,
+		`{"max_amount": {"sat": 1}}`,
+		`{"max_amount": {}}`,
+		`{"velocity": {"window": "1h"}}`,
+		`{"velocity": {"window": "1h", "max_sat": 1}}`,
+		`{"all": [{"max_amount": {"sats": 1}}], "unknown": {}}`,
+	} {
+		_, err := remotesigning.ParsePolicy([]byte(invalid))
+		require.Error(t, err, invalid)
+	}
+}
+
+type negativeValidator struct{}
+
+func (v negativeValidator) ShouldSign(webhook webhooks.WebhookEvent) bool {
+	return false
+}
+
+func TestZeroPolicies(t *testing.T) {
+	withdrawal := policyWebhook(objects.RemoteSigningSubEventTypeDeriveKeyAndSign, "node:1")
+	require.False(t, remotesigning.Policy{}.ShouldSign(withdrawal))
+	require.Equal(t, remotesigning.ReasonNoRuleAllowed, remotesigning.Policy{}.Evaluate(withdrawal).Reason)
+	require.True(t, remotesigning.MultiValidator{}.ShouldSign(withdrawal))
+	require.True(t, remotesigning.NewMultiValidator().ShouldSign(withdrawal))
+}
+
+func TestMultiValidatorReportsReason(t *testing.T) {
+	withdrawal := policyWebhook(objects.RemoteSigningSubEventTypeDeriveKeyAndSign, "node:1")
+	validator := remotesigning.NewMultiValidator(remotesigning.PositiveValidator{}, remotesigning.NewPolicy(remotesigning.MaxAmountRule{}))
+	require.False(t, validator.ShouldSign(withdrawal))
+	require.Equal(t, remotesigning.ReasonAmountExceeded, validator.Evaluate(withdrawal).Reason)
+
+	validator = remotesigning.NewMultiValidator(remotesigning.PositiveValidator{}, negativeValidator{})
+	require.Equal(t, remotesigning.ReasonValidatorDeclined, validator.Evaluate(withdrawal).Reason)
+
+	_, err := remotesigning.GraphQLResponseForRemoteSigningWebhook(validator, withdrawal, make([]byte, 32))
+	require.ErrorIs(t, err, remotesigning.ErrDeclinedToSign)
+	var declined *remotesigning.DeclinedError
+	require.True(t, errors.As(err, &declined))
+	require.Equal(t, remotesigning.ReasonValidatorDeclined, declined.Decision.Reason)
+}
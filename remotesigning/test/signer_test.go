@@ -0,0 +1,366 @@
+package remotesigning_test
+
+import (
+	"bufio"
+	"bytes"
+	"context"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"net"
+	"path/filepath"
+	"testing"
+
+	"github.com/lightsparkdev/go-sdk/crypto"
+	"github.com/lightsparkdev/go-sdk/objects"
+	"github.com/lightsparkdev/go-sdk/remotesigning"
+	"github.com/lightsparkdev/go-sdk/webhooks"
+	"github.com/stretchr/testify/require"
+)
+
+// signerRequests covers every operation of a Signer with deterministic results.
+var signerRequests = []remotesigning.SigningRequest{
+	&remotesigning.ECDHRequest{
+		NodeId:         "node:1",
+		PeerPubKeyHex:  "0279be667ef9dcbbac55a06295ce870b07029bfcdb2dce28d959f2815b16f81798",
+		BitcoinNetwork: objects.BitcoinNetworkRegtest,
+	},
+	&remotesigning.GetPerCommitmentPointRequest{
+		ChannelId:             "channel:1",
+		DerivationPath:        "m/3/2104864975",
+		PerCommitmentPointIdx: 1<<48 - 1,
+		NodeId:                "node:1",
+		BitcoinNetwork:        objects.BitcoinNetworkRegtest,
+	},
+	&remotesigning.ReleasePerCommitmentSecretRequest{
+		ChannelId:             "channel:1",
+		DerivationPath:        "m/3/2104864975",
+		PerCommitmentPointIdx: 1<<48 - 1,
+		NodeId:                "node:1",
+		BitcoinNetwork:        objects.BitcoinNetworkRegtest,
+	},
+	&remotesigning.SignInvoiceRequest{
+		InvoiceId:          "invoice:1",
+		PaymentRequestHash: "0b3ff4b4bc6be1c5a7a5e5f0bcac1e1b9d0d3e0c6c8e2f7b0f1a6a4c2d5b8e9f",
+		BitcoinNetwork:     objects.BitcoinNetworkRegtest,
+	},
+	&remotesigning.ReleasePaymentPreimageRequest{
+		InvoiceId: "invoice:1",
+		Nonce:     ptr("5c3c1200b86db0eacbf1cbdd40b86ee5d66482b1f214ef26624aa407829a1a5b"),
+	},
+	&remotesigning.DeriveKeyAndSignRequest{
+		SigningJobs: []remotesigning.SigningJob{{
+			Id:             "job-1",
+			DerivationPath: "m/3/2104864975/0",
+			Message:        "0b3ff4b4bc6be1c5a7a5e5f0bcac1e1b9d0d3e0c6c8e2f7b0f1a6a4c2d5b8e9f",
+			AddTweak:       ptr("5c3c1200b86db0eacbf1cbdd40b86ee5d66482b1f214ef26624aa407829a1a5b"),
+		}},
+		BitcoinNetwork: objects.BitcoinNetworkRegtest,
+	},
+}
+
+func requireSameResponses(t *testing.T, seed []byte, signer remotesigning.Signer) {
+	for _, request := range signerRequests {
+		expected, err := remotesigning.HandleSigningRequest(request, seed)
+		require.NoError(t, err, request.Type().StringValue())
+		response, err := remotesigning.HandleSigningRequestWithSigner(request, signer)
+		require.NoError(t, err, request.Type().StringValue())
+		require.Equal(t, expected, response, request.Type().StringValue())
+	}
+
+	response, err := remotesigning.HandleSigningRequestWithSigner(&remotesigning.InvoicePaymentHashRequest{InvoiceId: "invoice:1"}, signer)
+	require.NoError(t, err)
+	paymentHash := response.(*remotesigning.InvoicePaymentHashResponse)
+	preimage, err := remotesigning.HandleSigningRequest(&remotesigning.ReleasePaymentPreimageRequest{
+		InvoiceId: "invoice:1",
+		Nonce:     paymentHash.Nonce,
+	}, seed)
+	require.NoError(t, err)
+	require.NotEmpty(t, preimage.(*remotesigning.ReleasePaymentPreimageResponse).PaymentPreimage)
+}
+
+// signerWebhookSecret is the webhook secret of the signer process.
+const signerWebhookSecret = "webhook-secret"
+
+// signedWebhook is a webhook with its body and signature, as received from Lightspark.
+type signedWebhook struct {
+	event     webhooks.WebhookEvent
+	body      []byte
+	signature string
+}
+
+func signerWebhook(eventId string, entityId string, data map[string]interface{}) signedWebhook {
+	data["bitcoin_network"] = "REGTEST"
+	body, err := json.Marshal(map[string]interface{}{
+		"event_type": "REMOTE_SIGNING",
+		"event_id":   eventId,
+		"timestamp":  "2024-01-01T00:00:00Z",
+		"entity_id":  entityId,
+		"data":       data,
+	})
+	if err != nil {
+		panic(err)
+	}
+	event, err := webhooks.Parse(body)
+	if err != nil {
+		panic(err)
+	}
+	return signedWebhook{event: *event, body: body, signature: webhooks.Sign(body, signerWebhookSecret)}
+}
+
+func (w signedWebhook) option() remotesigning.Option {
+	return remotesigning.WithSignedWebhook(w.body, w.signature)
+}
+
+// signerWebhooks covers every operation of a Signer, in an order accepted by a SignerStateStore.
+var signerWebhooks = []signedWebhook{
+	signerWebhook("ecdh", "node:1", map[string]interface{}{
+		"sub_event_type":  "ECDH",
+		"peer_public_key": "0279be667ef9dcbbac55a06295ce870b07029bfcdb2dce28d959f2815b16f81798",
+	}),
+	signerWebhook("point-0", "channel:1", map[string]interface{}{
+		"sub_event_type":           "GET_PER_COMMITMENT_POINT",
+		"derivation_path":          "m/3/2104864975",
+		"per_commitment_point_idx": json.Number("281474976710655"),
+		"node_id":                  "node:1",
+	}),
+	signerWebhook("point-1", "channel:1", map[string]interface{}{
+		"sub_event_type":           "GET_PER_COMMITMENT_POINT",
+		"derivation_path":          "m/3/2104864975",
+		"per_commitment_point_idx": json.Number("281474976710654"),
+		"node_id":                  "node:1",
+	}),
+	signerWebhook("secret-0", "channel:1", map[string]interface{}{
+		"sub_event_type":           "RELEASE_PER_COMMITMENT_SECRET",
+		"derivation_path":          "m/3/2104864975",
+		"per_commitment_point_idx": json.Number("281474976710655"),
+		"node_id":                  "node:1",
+	}),
+	signerWebhook("sign-invoice", "invoice:1", map[string]interface{}{
+		"sub_event_type": "SIGN_INVOICE",
+		"invoice_id":     "invoice:1",
+		"payreq_hash":    "0b3ff4b4bc6be1c5a7a5e5f0bcac1e1b9d0d3e0c6c8e2f7b0f1a6a4c2d5b8e9f",
+	}),
+	signerWebhook("preimage", "invoice:1", map[string]interface{}{
+		"sub_event_type": "RELEASE_PAYMENT_PREIMAGE",
+		"invoice_id":     "invoice:1",
+		"preimage_nonce": "5c3c1200b86db0eacbf1cbdd40b86ee5d66482b1f214ef26624aa407829a1a5b",
+	}),
+	signerWebhook("derive-and-sign", "node:1", map[string]interface{}{
+		"sub_event_type": "DERIVE_KEY_AND_SIGN",
+		"signing_jobs": []interface{}{
+			map[string]interface{}{
+				"id":              "job-1",
+				"derivation_path": "m/3/2104864975/0",
+				"message":         "0b3ff4b4bc6be1c5a7a5e5f0bcac1e1b9d0d3e0c6c8e2f7b0f1a6a4c2d5b8e9f",
+				"add_tweak":       "5c3c1200b86db0eacbf1cbdd40b86ee5d66482b1f214ef26624aa407829a1a5b",
+			},
+			map[string]interface{}{
+				"id":              "job-2",
+				"derivation_path": "m/3/2104864975/1",
+				"message":         "0b3ff4b4bc6be1c5a7a5e5f0bcac1e1b9d0d3e0c6c8e2f7b0f1a6a4c2d5b8e9f",
+			},
+		},
+	}),
+}
+
+func serveSigner(t *testing.T, seed []byte, validator remotesigning.Validator, opts ...remotesigning.Option) (net.Listener, <-chan error) {
+	listener, err := net.Listen("unix", filepath.Join(t.TempDir(), "signer.sock"))
+	require.NoError(t, err)
+	served := make(chan error, 1)
+	go func() {
+		served <- remotesigning.ServeSigner(listener, remotesigning.NewMemorySigner(seed),
+			webhooks.Secrets{Primary: signerWebhookSecret}, validator, opts...)
+	}()
+	return listener, served
+}
+
+func TestSocketSigner(t *testing.T) {
+	seed := bytes.Repeat([]byte{1}, 32)
+	store := remotesigning.NewMemorySignerStateStore()
+	listener, served := serveSigner(t, seed, remotesigning.PositiveValidator{}, remotesigning.WithStateStore(store))
+	signer := remotesigning.NewSocketSigner(listener.Addr().String())
+	defer signer.Close()
+
+	for _, webhook := range signerWebhooks {
+		expected, err := remotesigning.GraphQLResponseForRemoteSigningWebhook(remotesigning.PositiveValidator{}, webhook.event, seed)
+		require.NoError(t, err, webhook.event.EventId)
+		response, err := remotesigning.GraphQLResponseForRemoteSigningWebhookWithSigner(remotesigning.PositiveValidator{},
+			webhook.event, signer, webhook.option())
+		require.NoError(t, err, webhook.event.EventId)
+		require.Equal(t, expected, response, webhook.event.EventId)
+	}
+	paymentHash := signerWebhook("payment-hash", "invoice:1", map[string]interface{}{
+		"sub_event_type": "REQUEST_INVOICE_PAYMENT_HASH",
+		"invoice_id":     "invoice:1",
+	})
+	response, err := remotesigning.GraphQLResponseForRemoteSigningWebhookWithSigner(remotesigning.PositiveValidator{},
+		paymentHash.event, signer, paymentHash.option())
+	require.NoError(t, err)
+	require.NotNil(t, response.(*remotesigning.InvoicePaymentHashResponse).Nonce)
+
+	// The state store of the signer process refuses to release the secret of the current commitment.
+	secret := signerWebhook("secret-1", "channel:1", map[string]interface{}{
+		"sub_event_type":           "RELEASE_PER_COMMITMENT_SECRET",
+		"derivation_path":          "m/3/2104864975",
+		"per_commitment_point_idx": json.Number("281474976710654"),
+		"node_id":                  "node:1",
+	})
+	_, err = remotesigning.GraphQLResponseForRemoteSigningWebhookWithSigner(remotesigning.PositiveValidator{},
+		secret.event, signer, secret.option())
+	require.ErrorContains(t, err, remotesigning.ErrCommitmentNotAdvanced.Error())
+
+	// Operations that no webhook requests are refused.
+	_, err = signer.GeneratePreimage(context.Background(), []byte{1})
+	require.ErrorContains(t, err, "no webhook")
+	_, err = remotesigning.GraphQLResponseForRemoteSigningWebhookWithSigner(remotesigning.PositiveValidator{},
+		secret.event, signer)
+	require.ErrorContains(t, err, "no webhook")
+
+	// Webhooks not signed with the webhook secret of the signer process are refused.
+	forged := signerWebhooks[0]
+	forged.signature = webhooks.Sign(forged.body, "another-secret")
+	_, err = remotesigning.GraphQLResponseForRemoteSigningWebhookWithSigner(remotesigning.PositiveValidator{},
+		forged.event, signer, forged.option())
+	require.ErrorContains(t, err, webhooks.ErrInvalidSignature.Error())
+	forged = signerWebhooks[0]
+	forged.body = bytes.Replace(forged.body, []byte("node:1"), []byte("node:2"), 1)
+	_, err = remotesigning.GraphQLResponseForRemoteSigningWebhookWithSigner(remotesigning.PositiveValidator{},
+		forged.event, signer, forged.option())
+	require.ErrorContains(t, err, webhooks.ErrInvalidSignature.Error())
+
+	// The listener is closed while the signer keeps its connection open.
+	require.NoError(t, listener.Close())
+	require.NoError(t, <-served)
+	_, err = signer.GeneratePreimageNonce(context.Background())
+	require.Error(t, err)
+}
+
+func TestSocketSignerValidatesWebhooks(t *testing.T) {
+	seed := bytes.Repeat([]byte{1}, 32)
+	listener, served := serveSigner(t, seed, negativeValidator{})
+	defer func() {
+		require.NoError(t, listener.Close())
+		require.NoError(t, <-served)
+	}()
+	signer := remotesigning.NewSocketSigner(listener.Addr().String())
+	defer signer.Close()
+
+	// The validator of the webhook process allows the webhook, the one of the signer process declines it.
+	_, err := remotesigning.GraphQLResponseForRemoteSigningWebhookWithSigner(remotesigning.PositiveValidator{},
+		signerWebhooks[0].event, signer, signerWebhooks[0].option())
+	require.ErrorIs(t, err, remotesigning.ErrDeclinedToSign)
+	var declined *remotesigning.DeclinedError
+	require.True(t, errors.As(err, &declined))
+	require.Equal(t, remotesigning.ReasonValidatorDeclined, declined.Decision.Reason)
+}
+
+func TestServeSignerRefusesOperationsNotRequested(t *testing.T) {
+	seed := bytes.Repeat([]byte{1}, 32)
+	listener, served := serveSigner(t, seed, remotesigning.PositiveValidator{})
+	defer func() {
+		require.NoError(t, listener.Close())
+		require.NoError(t, <-served)
+	}()
+	conn, err := net.Dial("unix", listener.Addr().String())
+	require.NoError(t, err)
+	defer conn.Close()
+	reader := bufio.NewReader(conn)
+
+	exchange := func(request map[string]interface{}) map[string]interface{} {
+		data, err := json.Marshal(request)
+		require.NoError(t, err)
+		_, err = conn.Write(append(data, '\n'))
+		require.NoError(t, err)
+		line, err := reader.ReadBytes('\n')
+		require.NoError(t, err)
+		var response map[string]interface{}
+		require.NoError(t, json.Unmarshal(line, &response))
+		return response
+	}
+
+	// A key at a path that the webhook doesn't sign with.
+	message, err := hex.DecodeString("0b3ff4b4bc6be1c5a7a5e5f0bcac1e1b9d0d3e0c6c8e2f7b0f1a6a4c2d5b8e9f")
+	require.NoError(t, err)
+	response := exchange(map[string]interface{}{
+		"method":          "derive_key_and_sign",
+		"network":         "REGTEST",
+		"derivation_path": "m/3/2104864975/1",
+		"data":            message,
+		"add_tweak":       bytes.Repeat([]byte{1}, 32),
+		"webhook":         signerWebhooks[6].body,
+		"signature":       signerWebhooks[6].signature,
+	})
+	require.Contains(t, response["error"], "not requested")
+	// A secret that the webhook doesn't release.
+	response = exchange(map[string]interface{}{
+		"method":                   "release_per_commitment_secret",
+		"network":                  "REGTEST",
+		"derivation_path":          "m/3/2104864975",
+		"per_commitment_point_idx": 1,
+		"webhook":                  signerWebhooks[3].body,
+		"signature":                signerWebhooks[3].signature,
+	})
+	require.Contains(t, response["error"], "not requested")
+	response = exchange(map[string]interface{}{
+		"method":                   "release_per_commitment_secret",
+		"network":                  "REGTEST",
+		"derivation_path":          "m/3/2104864975",
+		"per_commitment_point_idx": uint64(1<<48 - 1),
+		"webhook":                  signerWebhooks[3].body,
+		"signature":                signerWebhooks[3].signature,
+	})
+	require.Empty(t, response["error"])
+	require.NotEmpty(t, response["data"])
+
+	// Lines longer than the limit close the connection.
+	_, err = conn.Write(bytes.Repeat([]byte{' '}, 2<<20))
+	if err == nil {
+		_, err = reader.ReadBytes('\n')
+	}
+	require.Error(t, err)
+}
+
+func TestKeystoreSigner(t *testing.T) {
+	seed := bytes.Repeat([]byte{3}, 32)
+	keystore, err := crypto.NewKeystore(seed, objects.BitcoinNetworkRegtest, "passphrase", crypto.WithScrypt(1<<10, 8, 1))
+	require.NoError(t, err)
+	path := filepath.Join(t.TempDir(), "keystore.json")
+	require.NoError(t, keystore.Write(path))
+
+	fileSigner := remotesigning.NewSeedSigner(remotesigning.KeystoreSeed{
+		Path:       path,
+		Passphrase: func(ctx context.Context) (string, error) { return "passphrase", nil },
+	})
+	requireSameResponses(t, seed, fileSigner)
+
+	signer, err := remotesigning.NewKeystoreSigner(keystore, "passphrase")
+	require.NoError(t, err)
+	requireSameResponses(t, seed, signer)
+
+	// The keystore is for REGTEST.
+	for _, signer := range []remotesigning.Signer{fileSigner, signer} {
+		_, err = signer.Ecdh(context.Background(), objects.BitcoinNetworkMainnet, bytes.Repeat([]byte{2}, 33))
+		require.ErrorIs(t, err, remotesigning.ErrWrongNetwork)
+		_, err = signer.DeriveKeyAndSign(context.Background(), objects.BitcoinNetworkTestnet,
+			bytes.Repeat([]byte{1}, 32), "m/3/2104864975/0", nil, nil)
+		require.ErrorIs(t, err, remotesigning.ErrWrongNetwork)
+		_, _, err = signer.SignInvoiceHash(context.Background(), objects.BitcoinNetworkMainnet, bytes.Repeat([]byte{1}, 32))
+		require.ErrorIs(t, err, remotesigning.ErrWrongNetwork)
+	}
+	_, err = remotesigning.NewKeystoreSigner(keystore, "wrong")
+	require.ErrorIs(t, err, crypto.ErrWrongPassphrase)
+}
+
+func TestMnemonicSigner(t *testing.T) {
+	mnemonic, err := crypto.GenerateMnemonic(24)
+	require.NoError(t, err)
+	seed, err := crypto.MnemonicToSeed(mnemonic, "passphrase")
+	require.NoError(t, err)
+	signer, err := remotesigning.NewMnemonicSigner(mnemonic, "passphrase")
+	require.NoError(t, err)
+	requireSameResponses(t, seed, signer)
+
+	_, err = remotesigning.NewMnemonicSigner(mnemonic[:23], "passphrase")
+	require.ErrorIs(t, err, crypto.ErrInvalidMnemonicLength)
+}
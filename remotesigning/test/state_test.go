@@ -0,0 +1,83 @@
+package remotesigning_test
+
+import (
+	"bytes"
+	"path/filepath"
+	"testing"
+
+	"github.com/lightsparkdev/go-sdk/objects"
+	"github.com/lightsparkdev/go-sdk/remotesigning"
+	"github.com/stretchr/testify/require"
+)
+
+// perCommitmentIdx returns the per-commitment index of a commitment number.
+func perCommitmentIdx(commitment uint64) uint64 {
+	return 1<<48 - 1 - commitment
+}
+
+func getPoint(store remotesigning.SignerStateStore, commitment uint64) error {
+	_, err := remotesigning.HandleGetPerCommitmentPointRequest(&remotesigning.GetPerCommitmentPointRequest{
+		ChannelId:             "channel:1",
+		DerivationPath:        "m/3/2104864975",
+		PerCommitmentPointIdx: perCommitmentIdx(commitment),
+		NodeId:                "node:1",
+		BitcoinNetwork:        objects.BitcoinNetworkRegtest,
+	}, bytes.Repeat([]byte{1}, 32), remotesigning.WithStateStore(store))
+	return err
+}
+
+func releaseSecret(store remotesigning.SignerStateStore, commitment uint64) error {
+	_, err := remotesigning.HandleReleasePerCommitmentSecretRequest(&remotesigning.ReleasePerCommitmentSecretRequest{
+		ChannelId:             "channel:1",
+		DerivationPath:        "m/3/2104864975",
+		PerCommitmentPointIdx: perCommitmentIdx(commitment),
+		NodeId:                "node:1",
+		BitcoinNetwork:        objects.BitcoinNetworkRegtest,
+	}, bytes.Repeat([]byte{1}, 32), remotesigning.WithStateStore(store))
+	return err
+}
+
+func TestStateStoreRefusesUnsafeSecretRelease(t *testing.T) {
+	store := remotesigning.NewMemorySignerStateStore()
+
+	// Unknown channels can't release any secret.
+	require.ErrorIs(t, releaseSecret(store, 0), remotesigning.ErrCommitmentNotAdvanced)
+
+	require.NoError(t, getPoint(store, 0))
+	require.NoError(t, getPoint(store, 1))
+	// Commitment 1 is the current commitment.
+	require.ErrorIs(t, releaseSecret(store, 1), remotesigning.ErrCommitmentNotAdvanced)
+	require.NoError(t, releaseSecret(store, 0))
+	// Retries of the last release are allowed.
+	require.NoError(t, releaseSecret(store, 0))
+
+	require.NoError(t, getPoint(store, 2))
+	require.NoError(t, releaseSecret(store, 1))
+	require.ErrorIs(t, releaseSecret(store, 0), remotesigning.ErrCommitmentRevoked)
+	require.ErrorIs(t, getPoint(store, 1), remotesigning.ErrCommitmentRevoked)
+	// Points of commitments still in use may be requested again.
+	require.NoError(t, getPoint(store, 2))
+
+	state := store.State("channel:1")
+	require.Equal(t, uint64(2), *state.HighestPointCommitment)
+	require.Equal(t, uint64(1), *state.RevokedCommitment)
+}
+
+func TestFileStateStoreSurvivesRestart(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "signer_state.json")
+	store, err := remotesigning.OpenFileSignerStateStore(path)
+	require.NoError(t, err)
+	require.NoError(t, getPoint(store, 5))
+	require.NoError(t, releaseSecret(store, 4))
+	require.ErrorIs(t, releaseSecret(store, 5), remotesigning.ErrCommitmentNotAdvanced)
+
+	store, err = remotesigning.OpenFileSignerStateStore(path)
+	require.NoError(t, err)
+	state := store.State("channel:1")
+	require.Equal(t, uint64(5), *state.HighestPointCommitment)
+	require.Equal(t, uint64(4), *state.RevokedCommitment)
+	require.ErrorIs(t, releaseSecret(store, 3), remotesigning.ErrCommitmentRevoked)
+	require.ErrorIs(t, getPoint(store, 4), remotesigning.ErrCommitmentRevoked)
+	require.NoError(t, getPoint(store, 6))
+	require.NoError(t, releaseSecret(store, 5))
+}
@@ -0,0 +1,55 @@
+package remotesigning_test
+
+import (
+	"bytes"
+	"context"
+	"strings"
+	"testing"
+
+	"github.com/lightsparkdev/go-sdk/objects"
+	"github.com/lightsparkdev/go-sdk/remotesigning"
+	"github.com/stretchr/testify/require"
+	"go.opentelemetry.io/otel/attribute"
+	sdktrace "go.opentelemetry.io/otel/sdk/trace"
+	"go.opentelemetry.io/otel/sdk/trace/tracetest"
+)
+
+func TestDeriveKeyAndSignSpans(t *testing.T) {
+	exporter := tracetest.NewInMemoryExporter()
+	tracerProvider := sdktrace.NewTracerProvider(sdktrace.WithSyncer(exporter))
+	ctx, parent := tracerProvider.Tracer("test").Start(context.Background(), "webhook")
+
+	request := &remotesigning.DeriveKeyAndSignRequest{
+		BitcoinNetwork: objects.BitcoinNetworkRegtest,
+		SigningJobs: []remotesigning.SigningJob{
+			{Id: "job-1", DerivationPath: "m/3/2104864975", Message: strings.Repeat("01", 32)},
+			{Id: "job-2", DerivationPath: "m/3/2104864975/0", Message: strings.Repeat("02", 32)},
+		},
+	}
+	_, err := remotesigning.HandleSigningRequest(request, bytes.Repeat([]byte{1}, 32),
+		remotesigning.WithContext(ctx), remotesigning.WithTracerProvider(tracerProvider))
+	require.NoError(t, err)
+	parent.End()
+
+	spans := exporter.GetSpans()
+	require.Len(t, spans, 4)
+	byName := map[string][]tracetest.SpanStub{}
+	for _, span := range spans {
+		byName[span.Name] = append(byName[span.Name], span)
+		require.Equal(t, parent.SpanContext().TraceID(), span.SpanContext.TraceID())
+	}
+
+	require.Len(t, byName["DERIVE_KEY_AND_SIGN"], 1)
+	requestSpan := byName["DERIVE_KEY_AND_SIGN"][0]
+	require.Equal(t, parent.SpanContext().SpanID(), requestSpan.Parent.SpanID())
+
+	jobSpans := byName["SigningJob"]
+	require.Len(t, jobSpans, 2)
+	for i, span := range jobSpans {
+		require.Equal(t, requestSpan.SpanContext.SpanID(), span.Parent.SpanID())
+		require.Contains(t, span.Attributes,
+			attribute.String("lightspark.signing_job.id", request.SigningJobs[i].Id))
+		require.Contains(t, span.Attributes,
+			attribute.String("lightspark.signing_job.derivation_path", request.SigningJobs[i].DerivationPath))
+	}
+}
@@ -2,6 +2,7 @@ package remotesigning
 
 import (
 	"bytes"
+	"crypto/sha256"
 	"encoding/hex"
 	"errors"
 	"fmt"
@@ -191,6 +192,70 @@ func GenerateP2WPKHFromPubkey(child_pubkey []byte) ([]byte, error) {
 		Script()
 }
 
+// GenerateP2WSHFromPubkey returns the P2WSH script paying to the single key witness script
+// <pubkey> OP_CHECKSIG.
+func GenerateP2WSHFromPubkey(child_pubkey []byte) ([]byte, error) {
+	witnessScript, err := txscript.NewScriptBuilder().
+		AddData(child_pubkey).
+		AddOp(txscript.OP_CHECKSIG).
+		Script()
+	if err != nil {
+		return nil, err
+	}
+	scriptHash := sha256.Sum256(witnessScript)
+	// Create P2WSH script: OP_0 <32-byte-script-hash>
+	return txscript.NewScriptBuilder().
+		AddOp(txscript.OP_0).
+		AddData(scriptHash[:]).
+		Script()
+}
+
+// GenerateP2TRFromPubkey returns the P2TR script of a key path only output using the key as internal
+// key, as defined by BIP 86.
+func GenerateP2TRFromPubkey(child_pubkey []byte) ([]byte, error) {
+	internalKey, err := secp256k1.ParsePubKey(child_pubkey)
+	if err != nil {
+		return nil, err
+	}
+	return txscript.PayToTaprootScript(txscript.ComputeTaprootKeyNoScript(internalKey))
+}
+
+// ScriptType is a kind of output script paying to a single key.
+type ScriptType int
+
+const (
+	ScriptTypeP2WPKH ScriptType = iota
+	ScriptTypeP2WSH
+	ScriptTypeP2TR
+)
+
+func (t ScriptType) String() string {
+	switch t {
+	case ScriptTypeP2WPKH:
+		return "P2WPKH"
+	case ScriptTypeP2WSH:
+		return "P2WSH"
+	case ScriptTypeP2TR:
+		return "P2TR"
+	default:
+		return fmt.Sprintf("ScriptType(%d)", int(t))
+	}
+}
+
+// GenerateScriptFromPubkey returns the output script of the given type paying to a compressed public key.
+func GenerateScriptFromPubkey(scriptType ScriptType, child_pubkey []byte) ([]byte, error) {
+	switch scriptType {
+	case ScriptTypeP2WPKH:
+		return GenerateP2WPKHFromPubkey(child_pubkey)
+	case ScriptTypeP2WSH:
+		return GenerateP2WSHFromPubkey(child_pubkey)
+	case ScriptTypeP2TR:
+		return GenerateP2TRFromPubkey(child_pubkey)
+	default:
+		return nil, fmt.Errorf("unsupported script type %s", scriptType)
+	}
+}
+
 func L1WalletDerivationPrefix(networkParams *chaincfg.Params) (string, error) {
 	var network uint32
 	switch networkParams.Name {
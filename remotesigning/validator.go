@@ -2,13 +2,18 @@
 package remotesigning
 
 import (
+	"bytes"
+	"slices"
 	"strings"
 
 	"github.com/btcsuite/btcd/chaincfg"
+	"github.com/btcsuite/btcd/txscript"
+	"github.com/btcsuite/btcd/wire"
 	"github.com/lightsparkdev/go-sdk/webhooks"
 )
 
 // Validator an interface which decides whether to sign or reject a remote signing webhook event.
+// Validators that also implement Rule report why they decline an event.
 type Validator interface {
 	ShouldSign(webhook webhooks.WebhookEvent) bool
 }
@@ -19,21 +24,40 @@ func (v PositiveValidator) ShouldSign(webhook webhooks.WebhookEvent) bool {
 	return true
 }
 
+func (v PositiveValidator) Evaluate(webhook webhooks.WebhookEvent) Decision {
+	return Allow()
+}
+
+// MultiValidator signs an event only when all of its validators do. It is a Policy combining them with
+// And. The zero MultiValidator has no validator and signs every event.
 type MultiValidator struct {
-	validators []Validator
+	Policy
 }
 
-func NewMultiValidator(validators ...Validator) MultiValidator {
-	return MultiValidator{validators: validators}
+// Evaluate implements Rule.
+func (v MultiValidator) Evaluate(webhook webhooks.WebhookEvent) Decision {
+	if v.rule == nil {
+		return Allow()
+	}
+	return v.Policy.Evaluate(webhook)
 }
 
-func (v MultiValidator) ShouldSign(webhookEvent webhooks.WebhookEvent) bool {
-	for _, validator := range v.validators {
-		if !validator.ShouldSign(webhookEvent) {
-			return false
-		}
+// ShouldSign implements Validator.
+func (v MultiValidator) ShouldSign(webhook webhooks.WebhookEvent) bool {
+	return v.Evaluate(webhook).Allowed
+}
+
+func NewMultiValidator(validators ...Validator) MultiValidator {
+	rules := make([]Rule, len(validators))
+	for i, validator := range validators {
+		rules[i] = RuleFromValidator(validator)
 	}
-	return true
+	return MultiValidator{NewPolicy(And(rules...))}
+}
+
+// evaluate returns the decision of a validator.
+func evaluate(validator Validator, webhook webhooks.WebhookEvent) Decision {
+	return RuleFromValidator(validator).Evaluate(webhook)
 }
 
 func isL1WalletSigningJob(job SigningJob) bool {
@@ -49,17 +73,21 @@ func isForceClosureClaimSigningJob(job SigningJob) bool {
 type HashValidator struct{}
 
 func (v HashValidator) ShouldSign(webhookEvent webhooks.WebhookEvent) bool {
+	return v.Evaluate(webhookEvent).Allowed
+}
+
+func (v HashValidator) Evaluate(webhookEvent webhooks.WebhookEvent) Decision {
 	request, err := ParseDeriveAndSignRequest(webhookEvent)
 	if err != nil {
 		// Only validate DeriveAndSignRequest events
-		return true
+		return Allow()
 	}
 	for _, signing := range request.SigningJobs {
 		if !ValidateWitnessHash(&signing) {
-			return false
+			return Deny(ReasonInvalidWitnessHash, "message of signing job %s doesn't match its transaction", signing.Id)
 		}
 	}
-	return true
+	return Allow()
 }
 
 func ValidateWitnessHash(signing *SigningJob) bool {
@@ -87,20 +115,62 @@ func ValidateWitnessHash(signing *SigningJob) bool {
 // for where we allow sending funds. This is used to ensure that when signing
 // transactions spending L1 wallet funds, we are only sending funds to certain
 // addresses.
+//
+// Keys are derived with the network of each request. By default the change is expected to be a P2WPKH
+// output; WithDestinationScriptTypes accepts other script types, and WithStrictOutputs validates every
+// output of the transactions.
 type DestinationValidator struct {
 	masterSeed                 []byte
 	validateForceClosureClaims bool
+	scriptTypes                []ScriptType
+	strictOutputs              bool
+}
+
+// DestinationValidatorOption configures a DestinationValidator.
+type DestinationValidatorOption func(*DestinationValidator)
+
+// WithDestinationScriptTypes sets the script types the wallet outputs may use. The default is P2WPKH.
+func WithDestinationScriptTypes(scriptTypes ...ScriptType) DestinationValidatorOption {
+	return func(v *DestinationValidator) {
+		v.scriptTypes = scriptTypes
+	}
+}
+
+// WithStrictOutputs validates every output of the transactions: all outputs must be standard scripts
+// with a positive amount, the destination of a withdrawal must pay to a single standard address,
+// every other output of a withdrawal must pay to the wallet, and every output of a force closure claim
+// must pay to the wallet.
+func WithStrictOutputs() DestinationValidatorOption {
+	return func(v *DestinationValidator) {
+		v.strictOutputs = true
+	}
 }
 
-func NewDestinationValidator(masterSeed []byte, validateForceClosureClaims bool) DestinationValidator {
-	return DestinationValidator{masterSeed, validateForceClosureClaims}
+func NewDestinationValidator(masterSeed []byte, validateForceClosureClaims bool, opts ...DestinationValidatorOption) DestinationValidator {
+	v := DestinationValidator{
+		masterSeed:                 masterSeed,
+		validateForceClosureClaims: validateForceClosureClaims,
+		scriptTypes:                []ScriptType{ScriptTypeP2WPKH},
+	}
+	for _, opt := range opts {
+		opt(&v)
+	}
+	return v
 }
 
 func (v DestinationValidator) ShouldSign(webhookEvent webhooks.WebhookEvent) bool {
+	return v.Evaluate(webhookEvent).Allowed
+}
+
+func (v DestinationValidator) Evaluate(webhookEvent webhooks.WebhookEvent) Decision {
 	request, err := ParseDeriveAndSignRequest(webhookEvent)
 	if err != nil {
 		// Only validate DeriveAndSignRequest events
-		return true
+		return Allow()
+	}
+	params, err := chainParams(request.BitcoinNetwork)
+	if err != nil {
+		return Deny(ReasonInvalidRequest, "%s", err)
 	}
 	for _, signing := range request.SigningJobs {
 		l1SigningJob := isL1WalletSigningJob(signing)
@@ -112,29 +182,71 @@ func (v DestinationValidator) ShouldSign(webhookEvent webhooks.WebhookEvent) boo
 
 		tx, err := signing.BitcoinTx()
 		if err != nil {
-			return false
+			return Deny(ReasonInvalidRequest, "signing job %s: %s", signing.Id, err)
 		}
-		publicKey, err := DerivePublicKey(v.masterSeed, signing.DestinationDerivationPath, &chaincfg.MainNetParams)
+		scripts, err := v.walletScripts(signing.DestinationDerivationPath, params)
 		if err != nil {
-			return false
-		}
-		script, err := GenerateP2WPKHFromPubkey(publicKey.SerializeCompressed())
-		if err != nil {
-			return false
+			return Deny(ReasonInvalidRequest, "signing job %s: %s", signing.Id, err)
 		}
 
-		if isL1WalletSigningJob(signing) {
-			validScript, err := ValidateChangeScript(tx, script)
-			if err != nil || !validScript {
-				return false
+		if l1SigningJob {
+			if len(tx.TxOut) < 2 || !slices.ContainsFunc(scripts, scriptMatcher(tx.TxOut[1].PkScript)) {
+				return Deny(ReasonInvalidChangeOutput, "change of signing job %s is not sent to the wallet", signing.Id)
 			}
 		}
 		if forceClosureClaim {
-			validScript, err := ValidateOutputScript(tx, script)
-			if err != nil || !validScript {
-				return false
+			if len(tx.TxOut) < 1 || !slices.ContainsFunc(scripts, scriptMatcher(tx.TxOut[0].PkScript)) {
+				return Deny(ReasonInvalidClaimOutput, "claim of signing job %s is not sent to the wallet", signing.Id)
+			}
+		}
+		if v.strictOutputs {
+			if decision := validateOutputs(signing, tx, scripts, l1SigningJob, params); !decision.Allowed {
+				return decision
 			}
 		}
 	}
-	return true
+	return Allow()
+}
+
+// walletScripts returns the scripts of every accepted type paying to the key at derivationPath.
+func (v DestinationValidator) walletScripts(derivationPath string, params *chaincfg.Params) ([][]byte, error) {
+	publicKey, err := DerivePublicKey(v.masterSeed, derivationPath, params)
+	if err != nil {
+		return nil, err
+	}
+	scripts := make([][]byte, len(v.scriptTypes))
+	for i, scriptType := range v.scriptTypes {
+		scripts[i], err = GenerateScriptFromPubkey(scriptType, publicKey.SerializeCompressed())
+		if err != nil {
+			return nil, err
+		}
+	}
+	return scripts, nil
+}
+
+func scriptMatcher(script []byte) func([]byte) bool {
+	return func(walletScript []byte) bool {
+		return bytes.Equal(walletScript, script)
+	}
+}
+
+// validateOutputs checks every output of a transaction. The first output of a withdrawal is its
+// destination; every other output must pay to the wallet.
+func validateOutputs(signing SigningJob, tx wire.MsgTx, walletScripts [][]byte, withdrawal bool, params *chaincfg.Params) Decision {
+	for i, output := range tx.TxOut {
+		if output.Value <= 0 {
+			return Deny(ReasonInvalidOutput, "output %d of signing job %s has no amount", i, signing.Id)
+		}
+		if withdrawal && i == 0 {
+			class, addresses, _, err := txscript.ExtractPkScriptAddrs(output.PkScript, params)
+			if err != nil || class == txscript.NonStandardTy || class == txscript.NullDataTy || len(addresses) != 1 {
+				return Deny(ReasonInvalidOutput, "destination of signing job %s is not a standard address", signing.Id)
+			}
+			continue
+		}
+		if !slices.ContainsFunc(walletScripts, scriptMatcher(output.PkScript)) {
+			return Deny(ReasonInvalidOutput, "output %d of signing job %s is not sent to the wallet", i, signing.Id)
+		}
+	}
+	return Allow()
 }
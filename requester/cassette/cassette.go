@@ -0,0 +1,85 @@
+// Copyright ©, 2023-present, Lightspark Group, Inc. - All Rights Reserved
+
+// Package cassette records the GraphQL interactions of a Requester with the Lightspark API to a file,
+// and replays them later without network access.
+//
+// A Recorder is an http.RoundTripper, so it plugs into the HTTP client of the Requester:
+//
+//	recorder, err := cassette.New("testdata/create_invoice.json")
+//	if err != nil {
+//		t.Fatal(err)
+//	}
+//	defer recorder.Stop()
+//	client := services.NewLightsparkClient(clientId, clientSecret, nil,
+//		services.WithHTTPClient(recorder.HTTPClient()))
+//
+// Requests are matched on their operation name and normalized variables. Nonces, expiration dates and
+// signatures are scrubbed so that recordings are stable, and secrets such as the API token and the
+// `X-Lightspark-Signing` header are redacted before anything is written to disk.
+package cassette
+
+import (
+	"encoding/json"
+	"errors"
+	"os"
+	"path/filepath"
+)
+
+// Version is the format version of the cassette files written by this package.
+const Version = 1
+
+// ErrInteractionNotFound is returned in replay mode when no recorded interaction matches a request.
+var ErrInteractionNotFound = errors.New("cassette: no recorded interaction matches the request")
+
+// Cassette is the content of a cassette file.
+type Cassette struct {
+	Version      int            `json:"version"`
+	Interactions []*Interaction `json:"interactions"`
+}
+
+// Interaction is a recorded request and the response it received.
+type Interaction struct {
+	Request  RecordedRequest  `json:"request"`
+	Response RecordedResponse `json:"response"`
+}
+
+// RecordedRequest is a GraphQL request, scrubbed and redacted.
+type RecordedRequest struct {
+	OperationName string                 `json:"operation_name"`
+	Variables     map[string]interface{} `json:"variables,omitempty"`
+	Headers       map[string]string      `json:"headers,omitempty"`
+}
+
+// RecordedResponse is the uncompressed response of the Lightspark API, scrubbed and redacted.
+type RecordedResponse struct {
+	StatusCode int             `json:"status_code"`
+	Body       json.RawMessage `json:"body,omitempty"`
+}
+
+// Load reads a cassette file.
+func Load(path string) (*Cassette, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, err
+	}
+	var cassette Cassette
+	if err := json.Unmarshal(data, &cassette); err != nil {
+		return nil, err
+	}
+	if cassette.Version != Version {
+		return nil, errors.New("cassette: unsupported version")
+	}
+	return &cassette, nil
+}
+
+// Save writes the cassette to path, creating its directory if needed.
+func (c *Cassette) Save(path string) error {
+	data, err := json.MarshalIndent(c, "", "  ")
+	if err != nil {
+		return err
+	}
+	if err := os.MkdirAll(filepath.Dir(path), 0o755); err != nil {
+		return err
+	}
+	return os.WriteFile(path, append(data, '\n'), 0o644)
+}
@@ -0,0 +1,368 @@
+// Copyright ©, 2023-present, Lightspark Group, Inc. - All Rights Reserved
+package cassette
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"os"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/lightsparkdev/go-sdk/internal/zstd"
+)
+
+// Mode selects whether a Recorder talks to the Lightspark API or replays a cassette.
+type Mode int
+
+const (
+	// ModeAuto replays the cassette when the file exists and records it otherwise.
+	ModeAuto Mode = iota
+	// ModeRecord sends every request to the Lightspark API and overwrites the cassette on Stop.
+	ModeRecord
+	// ModeReplay answers every request from the cassette and never touches the network.
+	ModeReplay
+)
+
+const (
+	// Scrubbed replaces the values of scrubbed fields.
+	Scrubbed = "SCRUBBED"
+	// Redacted replaces the values of redacted fields and headers.
+	Redacted = "REDACTED"
+)
+
+// ScrubbedTime replaces scrubbed timestamps. It is far in the future, so that replayed invoices and
+// signing requests never look expired.
+var ScrubbedTime = time.Date(2100, time.January, 1, 0, 0, 0, 0, time.UTC)
+
+var (
+	defaultScrubbedFields = []string{"nonce", "expires_at", "signature"}
+	defaultRedactedFields = []string{"client_secret", "secret", "private_key", "password"}
+	redactedHeaders       = []string{"Authorization", "X-Lightspark-Signing"}
+)
+
+// Option configures a Recorder.
+type Option func(*Recorder)
+
+// WithMode sets the mode of the recorder. The default is ModeAuto.
+func WithMode(mode Mode) Option {
+	return func(r *Recorder) {
+		r.mode = mode
+	}
+}
+
+// WithTransport sets the transport used to reach the Lightspark API while recording. The default is
+// http.DefaultTransport.
+func WithTransport(transport http.RoundTripper) Option {
+	return func(r *Recorder) {
+		r.transport = transport
+	}
+}
+
+// WithScrubbedFields scrubs additional fields, on top of nonces, `expires_at` and signatures. A field
+// matches every JSON key equal to it or ending with `_<field>`, so `nonce` also scrubs
+// `preimage_nonce`. Scrubbed variables are ignored when matching requests.
+func WithScrubbedFields(fields ...string) Option {
+	return func(r *Recorder) {
+		r.scrubbedFields = append(r.scrubbedFields, fields...)
+	}
+}
+
+// WithRedactedFields redacts additional fields, on top of client secrets, private keys and passwords.
+// Fields are matched like in WithScrubbedFields.
+func WithRedactedFields(fields ...string) Option {
+	return func(r *Recorder) {
+		r.redactedFields = append(r.redactedFields, fields...)
+	}
+}
+
+// Recorder is an http.RoundTripper recording or replaying the GraphQL requests of a Requester. It is safe
+// for concurrent use.
+type Recorder struct {
+	path           string
+	mode           Mode
+	transport      http.RoundTripper
+	scrubbedFields []string
+	redactedFields []string
+
+	mu       sync.Mutex
+	cassette *Cassette
+	replayed map[*Interaction]bool
+}
+
+// New creates a Recorder for the cassette at path. In replay mode, the cassette must exist.
+func New(path string, opts ...Option) (*Recorder, error) {
+	r := &Recorder{
+		path:           path,
+		transport:      http.DefaultTransport,
+		scrubbedFields: append([]string(nil), defaultScrubbedFields...),
+		redactedFields: append([]string(nil), defaultRedactedFields...),
+		replayed:       map[*Interaction]bool{},
+	}
+	for _, opt := range opts {
+		opt(r)
+	}
+
+	if r.mode == ModeAuto {
+		r.mode = ModeRecord
+		if _, err := os.Stat(path); err == nil {
+			r.mode = ModeReplay
+		}
+	}
+	if r.mode == ModeReplay {
+		cassette, err := Load(path)
+		if err != nil {
+			return nil, err
+		}
+		r.cassette = cassette
+	} else {
+		r.cassette = &Cassette{Version: Version}
+	}
+	return r, nil
+}
+
+// Mode returns the mode of the recorder, ModeRecord or ModeReplay.
+func (r *Recorder) Mode() Mode {
+	return r.mode
+}
+
+// HTTPClient returns an HTTP client using the recorder as its transport.
+func (r *Recorder) HTTPClient() *http.Client {
+	return &http.Client{Transport: r}
+}
+
+// Stop writes the recorded interactions to the cassette file. It does nothing in replay mode.
+func (r *Recorder) Stop() error {
+	if r.mode != ModeRecord {
+		return nil
+	}
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	return r.cassette.Save(r.path)
+}
+
+// RoundTrip implements http.RoundTripper.
+func (r *Recorder) RoundTrip(request *http.Request) (*http.Response, error) {
+	var body []byte
+	if request.Body != nil {
+		var err error
+		body, err = io.ReadAll(request.Body)
+		request.Body.Close()
+		if err != nil {
+			return nil, err
+		}
+	}
+	payload := body
+	if request.Header.Get("Content-Encoding") == "zstd" {
+		var err error
+		if payload, err = zstd.Decompress(nil, body); err != nil {
+			return nil, err
+		}
+	}
+	recordedRequest, err := r.recordRequest(request.Header, payload)
+	if err != nil {
+		return nil, err
+	}
+
+	if r.mode == ModeReplay {
+		return r.replay(request, recordedRequest)
+	}
+	return r.record(request, body, recordedRequest)
+}
+
+func (r *Recorder) replay(request *http.Request, recordedRequest RecordedRequest) (*http.Response, error) {
+	key, err := matchKey(recordedRequest)
+	if err != nil {
+		return nil, err
+	}
+
+	r.mu.Lock()
+	var match *Interaction
+	for _, interaction := range r.cassette.Interactions {
+		interactionKey, err := matchKey(interaction.Request)
+		if err != nil || interactionKey != key {
+			continue
+		}
+		// Interactions are replayed in order. Once they have all been replayed, the last one is
+		// repeated, so that polling loops don't depend on the number of polls made while recording.
+		match = interaction
+		if !r.replayed[interaction] {
+			break
+		}
+	}
+	if match != nil {
+		r.replayed[match] = true
+	}
+	r.mu.Unlock()
+
+	if match == nil {
+		return nil, fmt.Errorf("%w: %s", ErrInteractionNotFound, recordedRequest.OperationName)
+	}
+	return newResponse(request, match.Response.StatusCode, replayedBody(match.Response.Body)), nil
+}
+
+func (r *Recorder) record(request *http.Request, body []byte, recordedRequest RecordedRequest) (*http.Response, error) {
+	forwarded := request.Clone(request.Context())
+	forwarded.Body = io.NopCloser(bytes.NewReader(body))
+	forwarded.ContentLength = int64(len(body))
+	response, err := r.transport.RoundTrip(forwarded)
+	if err != nil {
+		return nil, err
+	}
+	defer response.Body.Close()
+
+	responseBody, err := io.ReadAll(response.Body)
+	if err != nil {
+		return nil, err
+	}
+	if response.Header.Get("Content-Encoding") == "zstd" {
+		if responseBody, err = zstd.Decompress(nil, responseBody); err != nil {
+			return nil, err
+		}
+	}
+
+	recordedBody, err := r.recordBody(responseBody)
+	if err != nil {
+		return nil, err
+	}
+	r.mu.Lock()
+	r.cassette.Interactions = append(r.cassette.Interactions, &Interaction{
+		Request:  recordedRequest,
+		Response: RecordedResponse{StatusCode: response.StatusCode, Body: recordedBody},
+	})
+	r.mu.Unlock()
+
+	replayed := newResponse(request, response.StatusCode, responseBody)
+	for key, values := range response.Header {
+		if key != "Content-Encoding" && key != "Content-Length" {
+			replayed.Header[key] = values
+		}
+	}
+	return replayed, nil
+}
+
+func (r *Recorder) recordRequest(header http.Header, payload []byte) (RecordedRequest, error) {
+	var graphqlRequest struct {
+		OperationName string      `json:"operationName"`
+		Variables     interface{} `json:"variables"`
+	}
+	decoder := json.NewDecoder(bytes.NewReader(payload))
+	decoder.UseNumber()
+	if err := decoder.Decode(&graphqlRequest); err != nil {
+		return RecordedRequest{}, fmt.Errorf("cassette: invalid GraphQL request: %w", err)
+	}
+
+	recorded := RecordedRequest{OperationName: graphqlRequest.OperationName}
+	if variables, ok := r.clean(graphqlRequest.Variables).(map[string]interface{}); ok && len(variables) > 0 {
+		recorded.Variables = variables
+	}
+	for _, name := range redactedHeaders {
+		if header.Get(name) != "" {
+			if recorded.Headers == nil {
+				recorded.Headers = map[string]string{}
+			}
+			recorded.Headers[name] = Redacted
+		}
+	}
+	return recorded, nil
+}
+
+func (r *Recorder) recordBody(body []byte) (json.RawMessage, error) {
+	if len(body) == 0 {
+		return nil, nil
+	}
+	decoder := json.NewDecoder(bytes.NewReader(body))
+	decoder.UseNumber()
+	var value interface{}
+	if err := decoder.Decode(&value); err != nil {
+		// Error pages are not JSON. They are kept as a JSON string.
+		return json.Marshal(string(body))
+	}
+	return json.Marshal(r.clean(value))
+}
+
+// clean normalizes a decoded JSON value: it drops null object members, scrubs and redacts fields.
+func (r *Recorder) clean(value interface{}) interface{} {
+	switch value := value.(type) {
+	case map[string]interface{}:
+		result := make(map[string]interface{}, len(value))
+		for key, member := range value {
+			switch {
+			case member == nil:
+			case matchesField(key, r.redactedFields):
+				result[key] = Redacted
+			case matchesField(key, r.scrubbedFields):
+				result[key] = scrub(member)
+			default:
+				result[key] = r.clean(member)
+			}
+		}
+		return result
+	case []interface{}:
+		result := make([]interface{}, len(value))
+		for i, element := range value {
+			result[i] = r.clean(element)
+		}
+		return result
+	}
+	return value
+}
+
+func scrub(value interface{}) interface{} {
+	switch value := value.(type) {
+	case json.Number:
+		return json.Number("0")
+	case string:
+		if _, err := time.Parse(time.RFC3339, value); err == nil {
+			return ScrubbedTime.Format(time.RFC3339)
+		}
+	}
+	return Scrubbed
+}
+
+func matchesField(key string, fields []string) bool {
+	for _, field := range fields {
+		if key == field || strings.HasSuffix(key, "_"+field) {
+			return true
+		}
+	}
+	return false
+}
+
+// matchKey identifies the requests that can be answered by the same interaction. encoding/json sorts
+// map keys, so equal variables give equal keys.
+func matchKey(request RecordedRequest) (string, error) {
+	variables, err := json.Marshal(request.Variables)
+	if err != nil {
+		return "", err
+	}
+	return request.OperationName + " " + string(variables), nil
+}
+
+func replayedBody(body json.RawMessage) []byte {
+	var text string
+	if err := json.Unmarshal(body, &text); err == nil {
+		return []byte(text)
+	}
+	return body
+}
+
+func newResponse(request *http.Request, statusCode int, body []byte) *http.Response {
+	return &http.Response{
+		Status:        strconv.Itoa(statusCode) + " " + http.StatusText(statusCode),
+		StatusCode:    statusCode,
+		Proto:         "HTTP/1.1",
+		ProtoMajor:    1,
+		ProtoMinor:    1,
+		Header:        http.Header{"Content-Type": []string{"application/json"}},
+		Body:          io.NopCloser(bytes.NewReader(body)),
+		ContentLength: int64(len(body)),
+		Request:       request,
+	}
+}
+
+var _ http.RoundTripper = (*Recorder)(nil)
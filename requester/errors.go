@@ -0,0 +1,162 @@
+// Copyright ©, 2023-present, Lightspark Group, Inc. - All Rights Reserved
+package requester
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"strconv"
+	"strings"
+)
+
+// Sentinel errors matching well known Lightspark API error codes. They can be checked with errors.Is on
+// any error returned by the Requester:
+//
+//	if errors.Is(err, requester.ErrInsufficientBalance) { ... }
+var (
+	ErrInsufficientBalance  = errors.New("insufficient balance")
+	ErrInvoiceExpired       = errors.New("invoice expired")
+	ErrPaymentAlreadyExists = errors.New("payment already exists")
+	ErrNodeLocked           = errors.New("node locked")
+
+	// ErrMalformedResponse is returned when the Lightspark API answers with a response that is not a
+	// valid GraphQL response.
+	ErrMalformedResponse = errors.New("malformed graphql response")
+)
+
+// errorCodes maps normalized `error_name` extensions to their sentinel errors. See normalizeErrorName.
+var errorCodes = map[string]error{
+	"insufficientbalance":   ErrInsufficientBalance,
+	"insufficientfunds":     ErrInsufficientBalance,
+	"invoiceexpired":        ErrInvoiceExpired,
+	"paymentrequestexpired": ErrInvoiceExpired,
+	"paymentalreadyexists":  ErrPaymentAlreadyExists,
+	"duplicatepayment":      ErrPaymentAlreadyExists,
+	"invoicealreadypaid":    ErrPaymentAlreadyExists,
+	"nodelocked":            ErrNodeLocked,
+	"walletlocked":          ErrNodeLocked,
+}
+
+// GraphQLErrorLocation is the position in the query that caused a GraphQL error.
+type GraphQLErrorLocation struct {
+	Line   int `json:"line"`
+	Column int `json:"column"`
+}
+
+// GraphQLInternalError indicates there's a failure in the Lightspark API.
+// It could be due to a bug on Ligthspark's side.
+// The request can be retried, because the error might be transient.
+type GraphQLInternalError struct {
+	Message    string
+	Path       []interface{}
+	Locations  []GraphQLErrorLocation
+	Extensions map[string]interface{}
+}
+
+func (e GraphQLInternalError) Error() string {
+	return "lightspark request failed: " + e.Message
+}
+
+// GraphQLError indicates the GraphQL request succeeded, but there's a user error.
+// The request should not be retried, because the error is due to the user's input.
+type GraphQLError struct {
+	Message string
+	// Type is the `error_name` extension set by the Lightspark API.
+	Type       string
+	Path       []interface{}
+	Locations  []GraphQLErrorLocation
+	Extensions map[string]interface{}
+}
+
+func (e GraphQLError) Error() string {
+	return e.Type + ": " + e.Message
+}
+
+// Is reports whether the error matches one of the sentinel errors of this package, such as
+// ErrInsufficientBalance.
+func (e GraphQLError) Is(target error) bool {
+	code, ok := errorCodes[normalizeErrorName(e.Type)]
+	return ok && code == target
+}
+
+// GraphQLErrors holds every error returned by the Lightspark API for a single operation. Each element is
+// either a GraphQLError or a GraphQLInternalError, and can be matched with errors.Is and errors.As.
+type GraphQLErrors []error
+
+func (e GraphQLErrors) Error() string {
+	if len(e) == 1 {
+		return e[0].Error()
+	}
+	messages := make([]string, len(e))
+	for i, err := range e {
+		messages[i] = err.Error()
+	}
+	return strconv.Itoa(len(e)) + " graphql errors: " + strings.Join(messages, "; ")
+}
+
+func (e GraphQLErrors) Unwrap() []error {
+	return e
+}
+
+type rawGraphQLError struct {
+	Message    string                 `json:"message"`
+	Path       []interface{}          `json:"path"`
+	Locations  []GraphQLErrorLocation `json:"locations"`
+	Extensions map[string]interface{} `json:"extensions"`
+}
+
+// parseGraphQLErrors converts the `errors` field of a GraphQL response into GraphQLErrors. It returns nil
+// when there are no errors.
+func parseGraphQLErrors(errs interface{}) error {
+	if errs == nil {
+		return nil
+	}
+	encoded, err := json.Marshal(errs)
+	if err != nil {
+		return malformedResponseError(err.Error())
+	}
+	var rawErrors []rawGraphQLError
+	if err := json.Unmarshal(encoded, &rawErrors); err != nil {
+		return malformedResponseError("invalid errors: " + err.Error())
+	}
+	if len(rawErrors) == 0 {
+		return nil
+	}
+
+	result := make(GraphQLErrors, len(rawErrors))
+	for i, rawError := range rawErrors {
+		errorName, _ := rawError.Extensions["error_name"].(string)
+		if errorName == "" {
+			result[i] = GraphQLInternalError{
+				Message:    rawError.Message,
+				Path:       rawError.Path,
+				Locations:  rawError.Locations,
+				Extensions: rawError.Extensions,
+			}
+			continue
+		}
+		result[i] = GraphQLError{
+			Message:    rawError.Message,
+			Type:       errorName,
+			Path:       rawError.Path,
+			Locations:  rawError.Locations,
+			Extensions: rawError.Extensions,
+		}
+	}
+	return result
+}
+
+// normalizeErrorName turns names such as `INSUFFICIENT_BALANCE`, `InsufficientBalanceError` or
+// `lightspark.InsufficientBalance` into `insufficientbalance`.
+func normalizeErrorName(name string) string {
+	if index := strings.LastIndex(name, "."); index >= 0 {
+		name = name[index+1:]
+	}
+	name = strings.ToLower(name)
+	name = strings.NewReplacer("_", "", "-", "", " ", "").Replace(name)
+	return strings.TrimSuffix(name, "error")
+}
+
+func malformedResponseError(reason string) error {
+	return fmt.Errorf("%w: %s", ErrMalformedResponse, reason)
+}
@@ -0,0 +1,72 @@
+// Copyright ©, 2023-present, Lightspark Group, Inc. - All Rights Reserved
+package requester
+
+import (
+	"context"
+	"net/http"
+)
+
+// Request is a GraphQL operation on its way to the Lightspark API, as seen by a Middleware.
+//
+// Middlewares are allowed to modify the request (for example to rewrite variables or add headers)
+// before handing it to the next Handler.
+type Request struct {
+	// OperationName The name of the GraphQL operation, e.g. `CreateInvoice`.
+	OperationName string
+
+	// Query The GraphQL document.
+	Query string
+
+	// Variables The variables of the GraphQL operation.
+	Variables map[string]interface{}
+
+	// SigningKey The key used to sign the request, or nil if the request is not signed.
+	SigningKey SigningKey
+
+	// Header Additional HTTP headers sent along with the request.
+	Header http.Header
+}
+
+// IsSigned returns whether the request will be signed with a node signing key.
+func (r *Request) IsSigned() bool {
+	return r.SigningKey != nil
+}
+
+// Response is the result of a GraphQL operation, as seen by a Middleware.
+type Response struct {
+	// Data The `data` field of the GraphQL response.
+	Data map[string]interface{}
+
+	// StatusCode The HTTP status code of the last attempt.
+	StatusCode int
+
+	// Compressed Whether the request body was compressed with zstd.
+	Compressed bool
+
+	// Size The size in bytes of the response body, as received on the wire.
+	Size int
+
+	// Attempts The number of attempts that were made, including retries.
+	Attempts int
+}
+
+// Handler executes a GraphQL Request.
+type Handler func(ctx context.Context, request *Request) (*Response, error)
+
+// Middleware wraps a Handler to add behavior around GraphQL operations, such as logging, metrics,
+// auditing or caching. A middleware may short-circuit the chain by not calling next.
+type Middleware func(next Handler) Handler
+
+// Chain wraps handler with the given middlewares. The first middleware is the outermost one, so it
+// is the first to see the request and the last to see the response.
+func Chain(handler Handler, middlewares ...Middleware) Handler {
+	for i := len(middlewares) - 1; i >= 0; i-- {
+		handler = middlewares[i](handler)
+	}
+	return handler
+}
+
+// Use appends middlewares to the chain of the Requester.
+func (r *Requester) Use(middlewares ...Middleware) {
+	r.Middlewares = append(r.Middlewares, middlewares...)
+}
@@ -9,6 +9,7 @@ import (
 	"encoding/json"
 	"errors"
 	"io"
+	"log/slog"
 	"math/big"
 	"net/http"
 	"net/url"
@@ -18,7 +19,7 @@ import (
 	"strings"
 	"time"
 
-	"github.com/DataDog/zstd"
+	"github.com/lightsparkdev/go-sdk/internal/zstd"
 
 	lightspark "github.com/lightsparkdev/go-sdk"
 )
@@ -29,34 +30,14 @@ import (
 type RequestError struct {
 	Message    string
 	StatusCode int
+	// RetryAfter is the delay requested by the server through the `Retry-After` header, if any.
+	RetryAfter time.Duration
 }
 
 func (e RequestError) Error() string {
 	return "lightspark request failed: " + strconv.Itoa(e.StatusCode) + ": " + e.Message
 }
 
-// GraphQLInternalError indicates there's a failure in the Lightspark API.
-// It could be due to a bug on Ligthspark's side.
-// The request can be retried, because the error might be transient.
-type GraphQLInternalError struct {
-	Message string
-}
-
-func (e GraphQLInternalError) Error() string {
-	return "lightspark request failed: " + e.Message
-}
-
-// GraphQLError indicates the GraphQL request succeeded, but there's a user error.
-// The request should not be retried, because the error is due to the user's input.
-type GraphQLError struct {
-	Message string
-	Type    string
-}
-
-func (e GraphQLError) Error() string {
-	return e.Type + ": " + e.Message
-}
-
 type Requester struct {
 	ApiTokenClientId string
 
@@ -65,6 +46,17 @@ type Requester struct {
 	BaseUrl *string
 
 	HTTPClient *http.Client
+
+	// RetryPolicy controls how failed requests are retried. Requests are not retried when it is nil.
+	RetryPolicy *RetryPolicy
+
+	// Middlewares wrap every GraphQL operation executed by this Requester. See Use.
+	Middlewares []Middleware
+
+	// Logger receives structured logs about GraphQL operations. slog.Default() is used when nil.
+	Logger *slog.Logger
+
+	ctx context.Context
 }
 
 func NewRequester(apiTokenClientId string, apiTokenClientSecret string) *Requester {
@@ -107,22 +99,119 @@ func ValidateBaseUrl(baseUrl string) error {
 
 const DEFAULT_BASE_URL = "https://api.lightspark.com/graphql/server/2023-09-13"
 
+// WithContext returns a shallow copy of the Requester whose ExecuteGraphql calls follow ctx. This is useful
+// to pass a per-request context to the accessors of the objects package, for example:
+//
+//	account.GetTransactions(requester.WithContext(ctx), ...)
+func (r *Requester) WithContext(ctx context.Context) *Requester {
+	if ctx == nil {
+		panic("nil context")
+	}
+	requesterCopy := *r
+	requesterCopy.ctx = ctx
+	return &requesterCopy
+}
+
+// Context returns the context set with WithContext, or context.Background() when there is none.
+func (r *Requester) Context() context.Context {
+	if r.ctx == nil {
+		return context.Background()
+	}
+	return r.ctx
+}
+
 func (r *Requester) ExecuteGraphql(query string, variables map[string]interface{},
 	signingKey SigningKey,
 ) (map[string]interface{}, error) {
-	return r.ExecuteGraphqlWithContext(context.Background(), query, variables, signingKey)
+	return r.ExecuteGraphqlWithContext(r.Context(), query, variables, signingKey)
 }
 
+var operationRegex = regexp.MustCompile(`(?i)\s*(?P<OperationType>query|mutation)\s+(?P<OperationName>\w+)`)
+
 func (r *Requester) ExecuteGraphqlWithContext(ctx context.Context, query string, variables map[string]interface{},
 	signingKey SigningKey,
 ) (map[string]interface{}, error) {
-	re := regexp.MustCompile(`(?i)\s*(?:query|mutation)\s+(?P<OperationName>\w+)`)
-	matches := re.FindStringSubmatch(query)
-	index := re.SubexpIndex("OperationName")
+	matches := operationRegex.FindStringSubmatch(query)
+	index := operationRegex.SubexpIndex("OperationName")
 	if len(matches) <= index {
 		return nil, errors.New("invalid query payload")
 	}
-	operationName := matches[index]
+
+	request := &Request{
+		OperationName: matches[index],
+		Query:         query,
+		Variables:     variables,
+		SigningKey:    signingKey,
+		Header:        http.Header{},
+	}
+	logger := r.logger().With(slog.String("operation", request.OperationName))
+	if nodeId, ok := variables["node_id"].(string); ok {
+		logger = logger.With(slog.String("node_id", nodeId))
+	}
+
+	start := time.Now()
+	response, err := Chain(r.execute, r.Middlewares...)(ctx, request)
+	if err == nil && response == nil {
+		err = errors.New("middleware returned no response")
+	}
+	if err != nil {
+		logger.WarnContext(ctx, "graphql operation failed",
+			slog.Bool("signed", request.IsSigned()),
+			slog.Duration("duration", time.Since(start)),
+			slog.Any("error", err),
+		)
+		return nil, err
+	}
+	logger.DebugContext(ctx, "graphql operation succeeded",
+		slog.Bool("signed", request.IsSigned()),
+		slog.Duration("duration", time.Since(start)),
+		slog.Int("attempts", response.Attempts),
+		slog.Int("response_size", response.Size),
+	)
+	return response.Data, nil
+}
+
+func (r *Requester) logger() *slog.Logger {
+	if r.Logger == nil {
+		return slog.Default()
+	}
+	return r.Logger
+}
+
+// execute sends the request to the Lightspark API, retrying it according to the RetryPolicy.
+func (r *Requester) execute(ctx context.Context, request *Request) (*Response, error) {
+	matches := operationRegex.FindStringSubmatch(request.Query)
+	isMutation := len(matches) > 0 && strings.EqualFold(matches[operationRegex.SubexpIndex("OperationType")], "mutation")
+
+	policy := r.RetryPolicy
+	if policy == nil || (isMutation && !hasIdempotencyKey(request.Variables)) {
+		return r.executeOnce(ctx, request)
+	}
+
+	for attempt := 1; ; attempt++ {
+		response, err := r.executeOnce(ctx, request)
+		if err == nil {
+			response.Attempts = attempt
+			return response, nil
+		}
+		if attempt >= policy.maxAttempts() || !policy.isRetryable(err) {
+			return nil, err
+		}
+		backoff := policy.backoff(attempt, err)
+		r.logger().InfoContext(ctx, "retrying graphql operation",
+			slog.String("operation", request.OperationName),
+			slog.Int("attempt", attempt),
+			slog.Duration("backoff", backoff),
+			slog.Any("error", err),
+		)
+		if waitErr := sleepWithContext(ctx, backoff); waitErr != nil {
+			return nil, err
+		}
+	}
+}
+
+func (r *Requester) executeOnce(ctx context.Context, request *Request) (*Response, error) {
+	signingKey := request.SigningKey
 
 	var nonce uint64
 	if signingKey != nil {
@@ -139,9 +228,9 @@ func (r *Requester) ExecuteGraphqlWithContext(ctx context.Context, query string,
 	}
 
 	payload := map[string]interface{}{
-		"operationName": operationName,
-		"query":         query,
-		"variables":     variables,
+		"operationName": request.OperationName,
+		"query":         request.Query,
+		"variables":     request.Variables,
 		"nonce":         nonce,
 		"expires_at":    expiresAt,
 	}
@@ -171,20 +260,25 @@ func (r *Requester) ExecuteGraphqlWithContext(ctx context.Context, query string,
 		return nil, err
 	}
 
-	request, err := http.NewRequestWithContext(ctx, http.MethodPost, serverUrl, bytes.NewBuffer(body))
+	httpRequest, err := http.NewRequestWithContext(ctx, http.MethodPost, serverUrl, bytes.NewBuffer(body))
 	if err != nil {
 		return nil, err
 	}
 
-	request.SetBasicAuth(r.ApiTokenClientId, r.ApiTokenClientSecret)
-	request.Header.Add("Content-Type", "application/json")
+	for key, values := range request.Header {
+		for _, value := range values {
+			httpRequest.Header.Add(key, value)
+		}
+	}
+	httpRequest.SetBasicAuth(r.ApiTokenClientId, r.ApiTokenClientSecret)
+	httpRequest.Header.Add("Content-Type", "application/json")
 	if compressed {
-		request.Header.Add("Content-Encoding", "zstd")
+		httpRequest.Header.Add("Content-Encoding", "zstd")
 	}
-	request.Header.Add("Accept-Encoding", "zstd")
-	request.Header.Add("X-GraphQL-Operation", operationName)
-	request.Header.Add("User-Agent", r.getUserAgent())
-	request.Header.Add("X-Lightspark-SDK", r.getUserAgent())
+	httpRequest.Header.Add("Accept-Encoding", "zstd")
+	httpRequest.Header.Add("X-GraphQL-Operation", request.OperationName)
+	httpRequest.Header.Add("User-Agent", r.getUserAgent())
+	httpRequest.Header.Add("X-Lightspark-SDK", r.getUserAgent())
 
 	if signingKey != nil {
 		signature, err := signingKey.Sign(encodedPayload)
@@ -198,26 +292,31 @@ func (r *Requester) ExecuteGraphqlWithContext(ctx context.Context, query string,
 		if err != nil {
 			return nil, err
 		}
-		request.Header.Add("X-Lightspark-Signing", bytes.NewBuffer(signaturePayloadBytes).String())
+		httpRequest.Header.Add("X-Lightspark-Signing", bytes.NewBuffer(signaturePayloadBytes).String())
 	}
 
 	httpClient := r.HTTPClient
 	if httpClient == nil {
 		httpClient = &http.Client{}
 	}
-	response, err := httpClient.Do(request)
+	response, err := httpClient.Do(httpRequest)
 	if err != nil {
 		return nil, err
 	}
 	defer response.Body.Close()
 	if response.StatusCode < 200 || response.StatusCode > 299 {
-		return nil, RequestError{Message: response.Status, StatusCode: response.StatusCode}
+		return nil, RequestError{
+			Message:    response.Status,
+			StatusCode: response.StatusCode,
+			RetryAfter: parseRetryAfter(response.Header.Get("Retry-After")),
+		}
 	}
 
 	data, err := io.ReadAll(response.Body)
 	if err != nil {
 		return nil, err
 	}
+	size := len(data)
 
 	if response.Header.Get("Content-Encoding") == "zstd" {
 		data, err = zstd.Decompress(nil, data)
@@ -232,22 +331,24 @@ func (r *Requester) ExecuteGraphqlWithContext(ctx context.Context, query string,
 		return nil, err
 	}
 
-	if errs, ok := result["errors"]; ok {
-		err := errs.([]interface{})[0]
-		errMap := err.(map[string]interface{})
-		errorMessage := errMap["message"].(string)
-		if errMap["extensions"] == nil {
-			return nil, GraphQLInternalError{Message: errorMessage}
-		}
-		extensions := errMap["extensions"].(map[string]interface{})
-		if extensions["error_name"] == nil {
-			return nil, GraphQLInternalError{Message: errorMessage}
-		}
-		errorName := extensions["error_name"].(string)
-		return nil, GraphQLError{Message: errorMessage, Type: errorName}
+	if err := parseGraphQLErrors(result["errors"]); err != nil {
+		return nil, err
 	}
 
-	return result["data"].(map[string]interface{}), nil
+	if result["data"] == nil {
+		return nil, malformedResponseError("missing data")
+	}
+	resultData, ok := result["data"].(map[string]interface{})
+	if !ok {
+		return nil, malformedResponseError("data is not an object")
+	}
+	return &Response{
+		Data:       resultData,
+		StatusCode: response.StatusCode,
+		Compressed: compressed,
+		Size:       size,
+		Attempts:   1,
+	}, nil
 }
 
 func (r *Requester) getUserAgent() string {
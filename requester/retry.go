@@ -0,0 +1,160 @@
+// Copyright ©, 2023-present, Lightspark Group, Inc. - All Rights Reserved
+package requester
+
+import (
+	"context"
+	"errors"
+	"math"
+	"math/rand"
+	"net"
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// RetryPolicy describes how a Requester retries requests that failed with a retryable error.
+//
+// Queries are always eligible for retries. Mutations are only retried when they carry an
+// `idempotency_key` variable (for example `PayInvoiceWithIdempotencyKey` or
+// `RequestWithdrawalWithIdempotencyKey`), since retrying them otherwise could perform the
+// operation twice.
+type RetryPolicy struct {
+	// MaxAttempts is the maximum number of attempts, including the first one.
+	MaxAttempts int
+
+	// InitialBackoff is the delay before the first retry.
+	InitialBackoff time.Duration
+
+	// MaxBackoff caps the delay between two attempts. A `Retry-After` header sent by the server
+	// takes precedence over it.
+	MaxBackoff time.Duration
+
+	// Multiplier is the factor applied to the backoff after every attempt.
+	Multiplier float64
+
+	// Jitter is the fraction of the backoff, between 0 and 1, that is randomized to avoid
+	// synchronized retries across clients.
+	Jitter float64
+
+	// IsRetryable decides whether an error should be retried. IsRetryableError is used when nil.
+	IsRetryable func(err error) bool
+}
+
+// DefaultRetryPolicy returns a RetryPolicy making up to 3 attempts with an exponential backoff
+// starting at 250ms.
+func DefaultRetryPolicy() *RetryPolicy {
+	return &RetryPolicy{
+		MaxAttempts:    3,
+		InitialBackoff: 250 * time.Millisecond,
+		MaxBackoff:     5 * time.Second,
+		Multiplier:     2,
+		Jitter:         0.2,
+	}
+}
+
+// IsRetryableError reports whether err is a transient failure that can safely be retried:
+// server errors (500-599), rate limiting (429), GraphQLInternalError and network errors.
+// User errors (GraphQLError) and context cancellations are never retryable.
+func IsRetryableError(err error) bool {
+	if err == nil {
+		return false
+	}
+	if errors.Is(err, context.Canceled) || errors.Is(err, context.DeadlineExceeded) {
+		return false
+	}
+
+	var requestError RequestError
+	if errors.As(err, &requestError) {
+		return requestError.StatusCode >= 500 || requestError.StatusCode == http.StatusTooManyRequests
+	}
+	var userError GraphQLError
+	if errors.As(err, &userError) {
+		return false
+	}
+	var internalError GraphQLInternalError
+	if errors.As(err, &internalError) {
+		return true
+	}
+	var netError net.Error
+	return errors.As(err, &netError)
+}
+
+func (p *RetryPolicy) maxAttempts() int {
+	if p.MaxAttempts < 1 {
+		return 1
+	}
+	return p.MaxAttempts
+}
+
+func (p *RetryPolicy) isRetryable(err error) bool {
+	if p.IsRetryable != nil {
+		return p.IsRetryable(err)
+	}
+	return IsRetryableError(err)
+}
+
+// backoff returns the delay to wait after the given (1-based) attempt failed with err.
+func (p *RetryPolicy) backoff(attempt int, err error) time.Duration {
+	var requestError RequestError
+	if errors.As(err, &requestError) && requestError.RetryAfter > 0 {
+		return requestError.RetryAfter
+	}
+
+	multiplier := p.Multiplier
+	if multiplier < 1 {
+		multiplier = 1
+	}
+	backoff := float64(p.InitialBackoff) * math.Pow(multiplier, float64(attempt-1))
+	if p.MaxBackoff > 0 && backoff > float64(p.MaxBackoff) {
+		backoff = float64(p.MaxBackoff)
+	}
+	if p.Jitter > 0 {
+		jitter := math.Min(p.Jitter, 1)
+		backoff = backoff * (1 - jitter + 2*jitter*rand.Float64())
+	}
+	return time.Duration(backoff)
+}
+
+func hasIdempotencyKey(variables map[string]interface{}) bool {
+	switch key := variables["idempotency_key"].(type) {
+	case string:
+		return key != ""
+	case *string:
+		return key != nil && *key != ""
+	default:
+		return false
+	}
+}
+
+// parseRetryAfter parses the value of a `Retry-After` header, which is either a number of seconds
+// or an HTTP date. It returns 0 when the header is missing or invalid.
+func parseRetryAfter(value string) time.Duration {
+	value = strings.TrimSpace(value)
+	if value == "" {
+		return 0
+	}
+	if seconds, err := strconv.Atoi(value); err == nil {
+		if seconds < 0 {
+			return 0
+		}
+		return time.Duration(seconds) * time.Second
+	}
+	if date, err := http.ParseTime(value); err == nil {
+		if delay := time.Until(date); delay > 0 {
+			return delay
+		}
+	}
+	return 0
+}
+
+func sleepWithContext(ctx context.Context, delay time.Duration) error {
+	timer := time.NewTimer(delay)
+	defer timer.Stop()
+	select {
+	case <-ctx.Done():
+		return ctx.Err()
+	case <-timer.C:
+		return nil
+	}
+}
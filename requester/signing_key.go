@@ -8,7 +8,7 @@ import (
 	"crypto/x509"
 	"errors"
 
-	lightspark_crypto "github.com/lightsparkdev/lightspark-crypto-uniffi/lightspark-crypto-go"
+	"github.com/lightsparkdev/go-sdk/crypto/lightsparkcrypto"
 )
 
 type SigningKey interface {
@@ -20,7 +20,7 @@ type Secp256k1SigningKey struct {
 }
 
 func (s *Secp256k1SigningKey) Sign(payload []byte) ([]byte, error) {
-	return lightspark_crypto.SignEcdsa(payload, s.PrivateKey)
+	return lightsparkcrypto.SignEcdsa(payload, s.PrivateKey)
 }
 
 type RsaSigningKey struct {
@@ -0,0 +1,81 @@
+// Copyright ©, 2023-present, Lightspark Group, Inc. - All Rights Reserved
+package requester_test
+
+import (
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/btcsuite/btcd/btcec/v2"
+	"github.com/lightsparkdev/go-sdk/lightsparktest"
+	"github.com/lightsparkdev/go-sdk/objects"
+	"github.com/lightsparkdev/go-sdk/requester"
+	"github.com/lightsparkdev/go-sdk/requester/cassette"
+	"github.com/lightsparkdev/go-sdk/services"
+	"github.com/stretchr/testify/require"
+)
+
+func TestCassetteRecordAndReplay(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "cassette.json")
+	server := lightsparktest.NewServer()
+	privateKey, err := btcec.NewPrivateKey()
+	require.NoError(t, err)
+	nodeId := server.AddNode(lightsparktest.NodeConfig{
+		BalanceMsats: 1_000_000,
+		Verifier:     lightsparktest.Secp256k1Verifier(privateKey.PubKey().SerializeCompressed()),
+	})
+	payeeId := server.AddNode(lightsparktest.NodeConfig{})
+	signingKey := &requester.Secp256k1SigningKey{PrivateKey: privateKey.Serialize()}
+
+	recorder, err := cassette.New(path)
+	require.NoError(t, err)
+	require.Equal(t, cassette.ModeRecord, recorder.Mode())
+	client := server.Client(services.WithHTTPClient(recorder.HTTPClient()))
+	client.SetNodeSigningKey(nodeId, signingKey)
+
+	invoice, err := client.CreateInvoice(payeeId, 1000, nil, nil, nil)
+	require.NoError(t, err)
+	payment, err := client.PayInvoice(nodeId, invoice.Data.EncodedPaymentRequest, 60, 1000, nil)
+	require.NoError(t, err)
+	token, err := client.CreateApiToken("recorded", true, true)
+	require.NoError(t, err)
+	require.NoError(t, recorder.Stop())
+	server.Close()
+
+	data, err := os.ReadFile(path)
+	require.NoError(t, err)
+	content := string(data)
+	require.NotContains(t, content, lightsparktest.ClientSecret)
+	require.NotContains(t, content, token.ClientSecret)
+	require.Contains(t, content, `"X-Lightspark-Signing": "REDACTED"`)
+	require.Contains(t, content, `"client_secret": "REDACTED"`)
+	require.Contains(t, content, "2100-01-01T00:00:00Z")
+	require.False(t, strings.Contains(content, invoice.Data.ExpiresAt.Format("2006-01-02T15:04:05")))
+
+	recorder, err = cassette.New(path)
+	require.NoError(t, err)
+	require.Equal(t, cassette.ModeReplay, recorder.Mode())
+	client = server.Client(services.WithHTTPClient(recorder.HTTPClient()))
+	client.SetNodeSigningKey(nodeId, signingKey)
+
+	replayedInvoice, err := client.CreateInvoice(payeeId, 1000, nil, nil, nil)
+	require.NoError(t, err)
+	require.Equal(t, invoice.Id, replayedInvoice.Id)
+	require.Equal(t, invoice.Data.EncodedPaymentRequest, replayedInvoice.Data.EncodedPaymentRequest)
+	require.Equal(t, cassette.ScrubbedTime, replayedInvoice.Data.ExpiresAt)
+
+	// The signature of the replayed request differs from the recorded one.
+	replayedPayment, err := client.PayInvoice(nodeId, invoice.Data.EncodedPaymentRequest, 60, 1000, nil)
+	require.NoError(t, err)
+	require.Equal(t, payment.Id, replayedPayment.Id)
+	require.Equal(t, objects.TransactionStatusSuccess, replayedPayment.Status)
+
+	_, err = client.CreateInvoice(payeeId, 2000, nil, nil, nil)
+	require.ErrorIs(t, err, cassette.ErrInteractionNotFound)
+}
+
+func TestCassetteReplayMissingFile(t *testing.T) {
+	_, err := cassette.New(filepath.Join(t.TempDir(), "missing.json"), cassette.WithMode(cassette.ModeReplay))
+	require.ErrorIs(t, err, os.ErrNotExist)
+}
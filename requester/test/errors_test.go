@@ -0,0 +1,91 @@
+package requester_test
+
+import (
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/lightsparkdev/go-sdk/requester"
+	"github.com/stretchr/testify/require"
+)
+
+func newRawServer(t *testing.T, body string) *requester.Requester {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Write([]byte(body))
+	}))
+	t.Cleanup(server.Close)
+	return requester.NewRequesterWithBaseUrl("client_id", "client_secret", &server.URL)
+}
+
+func TestMultipleGraphQLErrors(t *testing.T) {
+	r := newRawServer(t, `{"errors": [
+		{"message": "not enough funds", "path": ["pay_invoice"], "locations": [{"line": 2, "column": 3}],
+			"extensions": {"error_name": "InsufficientBalanceError", "available": 10}},
+		{"message": "something broke", "path": ["pay_invoice", "payment"]}
+	]}`)
+
+	_, err := r.ExecuteGraphql(testQuery, nil, nil)
+	require.Error(t, err)
+
+	var graphQLErrors requester.GraphQLErrors
+	require.True(t, errors.As(err, &graphQLErrors))
+	require.Len(t, graphQLErrors, 2)
+
+	var userError requester.GraphQLError
+	require.True(t, errors.As(err, &userError))
+	require.Equal(t, "InsufficientBalanceError", userError.Type)
+	require.Equal(t, []interface{}{"pay_invoice"}, userError.Path)
+	require.Equal(t, []requester.GraphQLErrorLocation{{Line: 2, Column: 3}}, userError.Locations)
+	require.Equal(t, float64(10), userError.Extensions["available"])
+
+	var internalError requester.GraphQLInternalError
+	require.True(t, errors.As(err, &internalError))
+	require.Equal(t, "something broke", internalError.Message)
+
+	require.ErrorIs(t, err, requester.ErrInsufficientBalance)
+	require.NotErrorIs(t, err, requester.ErrInvoiceExpired)
+	require.False(t, requester.IsRetryableError(err))
+}
+
+func TestGraphQLErrorCodes(t *testing.T) {
+	tests := map[string]error{
+		"INSUFFICIENT_BALANCE":            requester.ErrInsufficientBalance,
+		"InvoiceExpiredError":             requester.ErrInvoiceExpired,
+		"lightspark.PaymentAlreadyExists": requester.ErrPaymentAlreadyExists,
+		"NodeLocked":                      requester.ErrNodeLocked,
+	}
+	for errorName, expected := range tests {
+		err := requester.GraphQLError{Message: "message", Type: errorName}
+		require.ErrorIs(t, err, expected, errorName)
+	}
+	require.NotErrorIs(t, requester.GraphQLError{Type: "InvalidInput"}, requester.ErrNodeLocked)
+}
+
+func TestMalformedResponses(t *testing.T) {
+	for _, body := range []string{
+		`{"errors": "oops"}`,
+		`{"errors": [{"message": 42}]}`,
+		`{"errors": [{"message": "bad", "extensions": "oops"}]}`,
+		`{"data": "oops"}`,
+		`{}`,
+		`{"data": null}`,
+		`null`,
+		`[1, 2]`,
+	} {
+		r := newRawServer(t, body)
+		require.NotPanics(t, func() {
+			_, err := r.ExecuteGraphql(testQuery, nil, nil)
+			require.Error(t, err, body)
+		})
+	}
+
+	r := newRawServer(t, `{}`)
+	_, err := r.ExecuteGraphql(testQuery, nil, nil)
+	require.ErrorIs(t, err, requester.ErrMalformedResponse)
+
+	r = newRawServer(t, `{"errors": [{"message": "bad", "extensions": {"error_name": 42}}]}`)
+	_, err = r.ExecuteGraphql(testQuery, nil, nil)
+	var internalError requester.GraphQLInternalError
+	require.True(t, errors.As(err, &internalError))
+}
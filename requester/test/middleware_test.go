@@ -0,0 +1,96 @@
+package requester_test
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/lightsparkdev/go-sdk/requester"
+	"github.com/stretchr/testify/require"
+)
+
+func TestMiddlewareChain(t *testing.T) {
+	var receivedHeader string
+	var receivedVariables map[string]interface{}
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		receivedHeader = r.Header.Get("X-Audit-Id")
+		var payload map[string]interface{}
+		json.NewDecoder(r.Body).Decode(&payload)
+		receivedVariables = payload["variables"].(map[string]interface{})
+		json.NewEncoder(w).Encode(map[string]interface{}{
+			"data": map[string]interface{}{"current_account": map[string]interface{}{"id": "Account:1"}},
+		})
+	}))
+	defer server.Close()
+
+	var calls []string
+	recorder := func(name string) requester.Middleware {
+		return func(next requester.Handler) requester.Handler {
+			return func(ctx context.Context, request *requester.Request) (*requester.Response, error) {
+				calls = append(calls, name+":before")
+				response, err := next(ctx, request)
+				calls = append(calls, name+":after")
+				return response, err
+			}
+		}
+	}
+	var seen *requester.Response
+	mutate := func(next requester.Handler) requester.Handler {
+		return func(ctx context.Context, request *requester.Request) (*requester.Response, error) {
+			require.Equal(t, "CurrentAccount", request.OperationName)
+			require.False(t, request.IsSigned())
+			request.Header.Set("X-Audit-Id", "audit")
+			request.Variables["injected"] = true
+			response, err := next(ctx, request)
+			seen = response
+			return response, err
+		}
+	}
+
+	r := requester.NewRequesterWithBaseUrl("client_id", "client_secret", &server.URL)
+	r.Use(recorder("outer"), recorder("inner"), mutate)
+
+	data, err := r.ExecuteGraphql(testQuery, map[string]interface{}{}, nil)
+	require.NoError(t, err)
+	require.NotNil(t, data["current_account"])
+	require.Equal(t, []string{"outer:before", "inner:before", "inner:after", "outer:after"}, calls)
+	require.Equal(t, "audit", receivedHeader)
+	require.Equal(t, true, receivedVariables["injected"])
+	require.Equal(t, http.StatusOK, seen.StatusCode)
+	require.Equal(t, 1, seen.Attempts)
+	require.Positive(t, seen.Size)
+}
+
+func TestMiddlewareShortCircuit(t *testing.T) {
+	cached := map[string]interface{}{"current_account": map[string]interface{}{"id": "Account:cached"}}
+	cache := func(next requester.Handler) requester.Handler {
+		return func(ctx context.Context, request *requester.Request) (*requester.Response, error) {
+			return &requester.Response{Data: cached}, nil
+		}
+	}
+
+	url := "https://127.0.0.1:1"
+	r := requester.NewRequesterWithBaseUrl("client_id", "client_secret", &url)
+	r.Use(cache)
+
+	data, err := r.ExecuteGraphql(testQuery, nil, nil)
+	require.NoError(t, err)
+	require.Equal(t, cached, data)
+}
+
+func TestMiddlewareWithoutResponse(t *testing.T) {
+	empty := func(next requester.Handler) requester.Handler {
+		return func(ctx context.Context, request *requester.Request) (*requester.Response, error) {
+			return nil, nil
+		}
+	}
+
+	url := "https://127.0.0.1:1"
+	r := requester.NewRequesterWithBaseUrl("client_id", "client_secret", &url)
+	r.Use(empty)
+
+	_, err := r.ExecuteGraphql(testQuery, nil, nil)
+	require.ErrorContains(t, err, "middleware returned no response")
+}
@@ -0,0 +1,123 @@
+package requester_test
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/lightsparkdev/go-sdk/requester"
+	"github.com/stretchr/testify/require"
+)
+
+const testQuery = `query CurrentAccount { current_account { id } }`
+
+const testMutation = `mutation PayInvoice($idempotency_key: String) { pay_invoice(input: { idempotency_key: $idempotency_key }) { payment { id } } }`
+
+func newFlakyServer(t *testing.T, failures int32, status int, header http.Header) (*httptest.Server, *int32) {
+	var calls int32
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		call := atomic.AddInt32(&calls, 1)
+		if call <= failures {
+			for key, values := range header {
+				w.Header()[key] = values
+			}
+			w.WriteHeader(status)
+			return
+		}
+		w.Header().Set("Content-Type", "application/json")
+		json.NewEncoder(w).Encode(map[string]interface{}{
+			"data": map[string]interface{}{"current_account": map[string]interface{}{"id": "Account:1"}},
+		})
+	}))
+	t.Cleanup(server.Close)
+	return server, &calls
+}
+
+func newRetryingRequester(url string) *requester.Requester {
+	r := requester.NewRequesterWithBaseUrl("client_id", "client_secret", &url)
+	r.RetryPolicy = &requester.RetryPolicy{
+		MaxAttempts:    3,
+		InitialBackoff: time.Millisecond,
+		MaxBackoff:     10 * time.Millisecond,
+		Multiplier:     2,
+	}
+	return r
+}
+
+func TestRetryOnServerError(t *testing.T) {
+	server, calls := newFlakyServer(t, 2, http.StatusServiceUnavailable, nil)
+	r := newRetryingRequester(server.URL)
+
+	response, err := r.ExecuteGraphql(testQuery, nil, nil)
+	require.NoError(t, err)
+	require.NotNil(t, response["current_account"])
+	require.Equal(t, int32(3), atomic.LoadInt32(calls))
+}
+
+func TestRetryGivesUpAfterMaxAttempts(t *testing.T) {
+	server, calls := newFlakyServer(t, 10, http.StatusBadGateway, nil)
+	r := newRetryingRequester(server.URL)
+
+	_, err := r.ExecuteGraphql(testQuery, nil, nil)
+	require.ErrorAs(t, err, &requester.RequestError{})
+	require.Equal(t, int32(3), atomic.LoadInt32(calls))
+}
+
+func TestNoRetryOnClientError(t *testing.T) {
+	server, calls := newFlakyServer(t, 1, http.StatusBadRequest, nil)
+	r := newRetryingRequester(server.URL)
+
+	_, err := r.ExecuteGraphql(testQuery, nil, nil)
+	require.Error(t, err)
+	require.Equal(t, int32(1), atomic.LoadInt32(calls))
+}
+
+func TestRetryHonorsRetryAfter(t *testing.T) {
+	server, calls := newFlakyServer(t, 1, http.StatusTooManyRequests, http.Header{"Retry-After": []string{"1"}})
+	r := newRetryingRequester(server.URL)
+
+	start := time.Now()
+	_, err := r.ExecuteGraphql(testQuery, nil, nil)
+	require.NoError(t, err)
+	require.Equal(t, int32(2), atomic.LoadInt32(calls))
+	require.GreaterOrEqual(t, time.Since(start), time.Second)
+}
+
+func TestMutationRetriedOnlyWithIdempotencyKey(t *testing.T) {
+	server, calls := newFlakyServer(t, 1, http.StatusInternalServerError, nil)
+	r := newRetryingRequester(server.URL)
+
+	_, err := r.ExecuteGraphql(testMutation, map[string]interface{}{}, nil)
+	require.Error(t, err)
+	require.Equal(t, int32(1), atomic.LoadInt32(calls))
+
+	atomic.StoreInt32(calls, 0)
+	_, err = r.ExecuteGraphql(testMutation, map[string]interface{}{"idempotency_key": "key"}, nil)
+	require.NoError(t, err)
+	require.Equal(t, int32(2), atomic.LoadInt32(calls))
+}
+
+func TestRetryStopsWhenContextIsDone(t *testing.T) {
+	server, calls := newFlakyServer(t, 10, http.StatusServiceUnavailable, nil)
+	r := newRetryingRequester(server.URL)
+	r.RetryPolicy.InitialBackoff = time.Hour
+	r.RetryPolicy.MaxBackoff = time.Hour
+
+	ctx, cancel := context.WithTimeout(context.Background(), 50*time.Millisecond)
+	defer cancel()
+	_, err := r.ExecuteGraphqlWithContext(ctx, testQuery, nil, nil)
+	require.ErrorAs(t, err, &requester.RequestError{})
+	require.Equal(t, int32(1), atomic.LoadInt32(calls))
+}
+
+func TestIsRetryableError(t *testing.T) {
+	require.True(t, requester.IsRetryableError(requester.RequestError{StatusCode: 500}))
+	require.True(t, requester.IsRetryableError(requester.GraphQLInternalError{Message: "oops"}))
+	require.False(t, requester.IsRetryableError(requester.GraphQLError{Message: "bad", Type: "InvalidInput"}))
+	require.False(t, requester.IsRetryableError(requester.RequestError{StatusCode: 401}))
+	require.False(t, requester.IsRetryableError(context.Canceled))
+}
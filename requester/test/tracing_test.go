@@ -0,0 +1,68 @@
+package requester_test
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/lightsparkdev/go-sdk/requester"
+	"github.com/stretchr/testify/require"
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/codes"
+	"go.opentelemetry.io/otel/propagation"
+	sdktrace "go.opentelemetry.io/otel/sdk/trace"
+	"go.opentelemetry.io/otel/sdk/trace/tracetest"
+)
+
+func TestTracingMiddleware(t *testing.T) {
+	var traceparent string
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		traceparent = r.Header.Get("traceparent")
+		json.NewEncoder(w).Encode(map[string]interface{}{
+			"data": map[string]interface{}{"current_account": map[string]interface{}{"id": "Account:1"}},
+		})
+	}))
+	defer server.Close()
+
+	exporter := tracetest.NewInMemoryExporter()
+	tracerProvider := sdktrace.NewTracerProvider(sdktrace.WithSyncer(exporter))
+
+	r := requester.NewRequesterWithBaseUrl("client_id", "client_secret", &server.URL)
+	r.Use(requester.NewTracingMiddleware(tracerProvider, propagation.TraceContext{}))
+
+	_, err := r.ExecuteGraphql(testQuery, nil, nil)
+	require.NoError(t, err)
+
+	spans := exporter.GetSpans()
+	require.Len(t, spans, 1)
+	span := spans[0]
+	require.Equal(t, "CurrentAccount", span.Name)
+	require.Equal(t, codes.Ok, span.Status.Code)
+	require.Contains(t, span.Attributes, attribute.String("graphql.operation.name", "CurrentAccount"))
+	require.Contains(t, span.Attributes, attribute.Bool("lightspark.request.signed", false))
+	require.Contains(t, span.Attributes, attribute.Int("http.response.status_code", http.StatusOK))
+	require.Contains(t, span.Attributes, attribute.Int("lightspark.request.attempts", 1))
+	require.Contains(t, traceparent, span.SpanContext.TraceID().String())
+}
+
+func TestTracingMiddlewareRecordsErrors(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusBadRequest)
+	}))
+	defer server.Close()
+
+	exporter := tracetest.NewInMemoryExporter()
+	tracerProvider := sdktrace.NewTracerProvider(sdktrace.WithSyncer(exporter))
+
+	r := requester.NewRequesterWithBaseUrl("client_id", "client_secret", &server.URL)
+	r.Use(requester.NewTracingMiddleware(tracerProvider, nil))
+
+	_, err := r.ExecuteGraphql(testQuery, nil, nil)
+	require.Error(t, err)
+
+	spans := exporter.GetSpans()
+	require.Len(t, spans, 1)
+	require.Equal(t, codes.Error, spans[0].Status.Code)
+	require.Contains(t, spans[0].Attributes, attribute.Int("http.response.status_code", http.StatusBadRequest))
+}
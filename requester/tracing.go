@@ -0,0 +1,68 @@
+// Copyright ©, 2023-present, Lightspark Group, Inc. - All Rights Reserved
+package requester
+
+import (
+	"context"
+	"errors"
+
+	lightspark "github.com/lightsparkdev/go-sdk"
+	"go.opentelemetry.io/otel"
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/codes"
+	"go.opentelemetry.io/otel/propagation"
+	"go.opentelemetry.io/otel/trace"
+)
+
+const tracerName = "github.com/lightsparkdev/go-sdk/requester"
+
+// NewTracingMiddleware returns a Middleware that creates an OpenTelemetry span for every GraphQL
+// operation and propagates the trace context to the Lightspark API through the request headers.
+//
+// Args:
+//
+//	tracerProvider: the provider used to create spans. The global provider is used when nil.
+//	propagator: the propagator used to inject the trace context. The global propagator is used when nil.
+func NewTracingMiddleware(tracerProvider trace.TracerProvider, propagator propagation.TextMapPropagator) Middleware {
+	if tracerProvider == nil {
+		tracerProvider = otel.GetTracerProvider()
+	}
+	if propagator == nil {
+		propagator = otel.GetTextMapPropagator()
+	}
+	tracer := tracerProvider.Tracer(tracerName, trace.WithInstrumentationVersion(lightspark.VERSION))
+
+	return func(next Handler) Handler {
+		return func(ctx context.Context, request *Request) (*Response, error) {
+			ctx, span := tracer.Start(ctx, request.OperationName,
+				trace.WithSpanKind(trace.SpanKindClient),
+				trace.WithAttributes(
+					attribute.String("graphql.operation.name", request.OperationName),
+					attribute.Bool("lightspark.request.signed", request.IsSigned()),
+				),
+			)
+			defer span.End()
+
+			propagator.Inject(ctx, propagation.HeaderCarrier(request.Header))
+
+			response, err := next(ctx, request)
+			if err != nil {
+				var requestError RequestError
+				if errors.As(err, &requestError) {
+					span.SetAttributes(attribute.Int("http.response.status_code", requestError.StatusCode))
+				}
+				span.RecordError(err)
+				span.SetStatus(codes.Error, err.Error())
+				return nil, err
+			}
+
+			span.SetAttributes(
+				attribute.Int("http.response.status_code", response.StatusCode),
+				attribute.Bool("lightspark.request.compressed", response.Compressed),
+				attribute.Int("lightspark.response.size", response.Size),
+				attribute.Int("lightspark.request.attempts", response.Attempts),
+			)
+			span.SetStatus(codes.Ok, "")
+			return response, nil
+		}
+	}
+}
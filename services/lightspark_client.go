@@ -8,6 +8,7 @@ import (
 	"encoding/json"
 	"errors"
 	"fmt"
+	"log/slog"
 	"net/http"
 	"regexp"
 	"time"
@@ -16,6 +17,7 @@ import (
 	"github.com/lightsparkdev/go-sdk/objects"
 	"github.com/lightsparkdev/go-sdk/requester"
 	"github.com/lightsparkdev/go-sdk/scripts"
+	"go.opentelemetry.io/otel/trace"
 )
 
 type Option func(*LightsparkClient)
@@ -27,22 +29,43 @@ func WithHTTPClient(c *http.Client) Option {
 	}
 }
 
-// graphqlRequesterWithContext performs GraphQL operations following a given context.
-type graphqlRequesterWithContext struct {
-	ctx context.Context
-	*requester.Requester
+// WithRetryPolicy sets the RetryPolicy of the LightsparkClient requester, so that transient failures
+// are retried automatically. Use requester.DefaultRetryPolicy() for sensible defaults.
+func WithRetryPolicy(policy *requester.RetryPolicy) Option {
+	return func(client *LightsparkClient) {
+		client.Requester.RetryPolicy = policy
+	}
 }
 
-func (client *graphqlRequesterWithContext) ExecuteGraphql(query string, variables map[string]interface{},
-	signingKey requester.SigningKey,
-) (map[string]interface{}, error) {
-	return client.ExecuteGraphqlWithContext(client.ctx, query, variables, signingKey)
+// WithLogger sets the structured logger used by the LightsparkClient requester.
+func WithLogger(logger *slog.Logger) Option {
+	return func(client *LightsparkClient) {
+		client.Requester.Logger = logger
+	}
+}
+
+// WithMiddleware adds middlewares around every GraphQL operation performed by the LightsparkClient.
+// Middlewares are called in the order they are provided.
+func WithMiddleware(middlewares ...requester.Middleware) Option {
+	return func(client *LightsparkClient) {
+		client.Requester.Use(middlewares...)
+	}
 }
 
-// WithContext allows using a context along the Lightspark client lifecycle.
+// WithTracerProvider enables OpenTelemetry tracing: a span is created for every GraphQL operation and
+// the trace context is propagated to the Lightspark API using the global propagator.
+func WithTracerProvider(tracerProvider trace.TracerProvider) Option {
+	return func(client *LightsparkClient) {
+		client.Requester.Use(requester.NewTracingMiddleware(tracerProvider, nil))
+	}
+}
+
+// WithContext allows using a context along the Lightspark client lifecycle. Use
+// LightsparkClient.WithContext instead to use a different context for each call.
 func WithContext(ctx context.Context) Option {
 	return func(client *LightsparkClient) {
-		client.graphqlRequester = &graphqlRequesterWithContext{ctx, client.Requester}
+		client.Requester = client.Requester.WithContext(ctx)
+		client.graphqlRequester = client.Requester
 	}
 }
 
@@ -77,6 +100,20 @@ func NewLightsparkClient(apiTokenClientId string, apiTokenClientSecret string,
 	return client
 }
 
+// WithContext returns a shallow copy of the client whose operations follow ctx. The copy shares the
+// signing keys loaded in the original client. This is useful to propagate the deadline of an incoming
+// HTTP request:
+//
+//	invoice, err := client.WithContext(r.Context()).CreateInvoice(nodeId, amountMsats, nil, nil, nil)
+//
+// The Requester of the copy also follows ctx, so it can be passed to the accessors of the objects package.
+func (client *LightsparkClient) WithContext(ctx context.Context) *LightsparkClient {
+	clientCopy := *client
+	clientCopy.Requester = client.Requester.WithContext(ctx)
+	clientCopy.graphqlRequester = clientCopy.Requester
+	return &clientCopy
+}
+
 // CreateApiToken creates a new API token that can be used to authenticate requests
 // for this account when using the Lightspark APIs and SDKs.
 //
@@ -460,7 +497,8 @@ func (client *LightsparkClient) CreateTestModePayment(localNodeId string,
 }
 
 // DecodePaymentRequest decodes the content of an encoded payment request into
-// structured data that can be used by the client.
+// structured data that can be used by the client. The bolt11 package decodes BOLT11
+// invoices to the same shape without calling the API.
 //
 // Args:
 //
@@ -782,6 +820,8 @@ func (client *LightsparkClient) PayUmaInvoiceWithSenderIdentifier(nodeId string,
 	return &payment, nil
 }
 
+// PayOffer sends a payment to a BOLT12 offer. The bolt12 package decodes the offer locally, so that its
+// amount and expiry can be checked with Offer.CheckPayment before paying.
 func (client *LightsparkClient) PayOffer(nodeId string, encodedOffer string, timeoutSecs int, maximumFeesMsats int64, amountMsats *int64, idempotencyKey *string) (*objects.OutgoingPayment, error) {
 	variables := map[string]interface{}{
 		"node_id":            nodeId,
@@ -1443,7 +1483,7 @@ func (client *LightsparkClient) FailHtlc(invoiceId string, cancelInvoice bool) (
 		"cancel_invoice": cancelInvoice,
 	}
 
-	response, err := client.Requester.ExecuteGraphql(scripts.FAIL_HTLCS_MUTATION, variables, nil)
+	response, err := client.ExecuteGraphql(scripts.FAIL_HTLCS_MUTATION, variables, nil)
 	if err != nil {
 		return nil, err
 	}
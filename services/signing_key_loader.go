@@ -5,16 +5,17 @@ import (
 	"errors"
 
 	"github.com/lightsparkdev/go-sdk/crypto"
+	"github.com/lightsparkdev/go-sdk/crypto/lightsparkcrypto"
 	"github.com/lightsparkdev/go-sdk/objects"
 	"github.com/lightsparkdev/go-sdk/requester"
 	"github.com/lightsparkdev/go-sdk/scripts"
-	lightspark_crypto "github.com/lightsparkdev/lightspark-crypto-uniffi/lightspark-crypto-go"
 )
 
 type SigningKeyLoader struct {
-	cachedSigningKey     requester.SigningKey
-	masterSeedAndNetwork *masterSeedAndNetwork
-	idPasswordPair       *idPasswordPair
+	cachedSigningKey      requester.SigningKey
+	masterSeedAndNetwork  *masterSeedAndNetwork
+	idPasswordPair        *idPasswordPair
+	keystoreAndPassphrase *keystoreAndPassphrase
 }
 
 // NewSigningKeyLoaderFromNodeIdAndPassword creates a new SigningKeyLoader from a node ID and password.
@@ -37,11 +38,43 @@ func NewSigningKeyLoaderFromSignerMasterSeed(masterSeedBytes []byte, network obj
 	}
 }
 
+// NewSigningKeyLoaderFromMnemonic creates a new SigningKeyLoader from the BIP 39 mnemonic of a master seed,
+// with its optional passphrase, and the network. Like NewSigningKeyLoaderFromSignerMasterSeed, this should
+// be used if you are using remote signing.
+func NewSigningKeyLoaderFromMnemonic(mnemonic []string, passphrase string, network objects.BitcoinNetwork) (*SigningKeyLoader, error) {
+	masterSeed, err := crypto.MnemonicToSeed(mnemonic, passphrase)
+	if err != nil {
+		return nil, err
+	}
+	return NewSigningKeyLoaderFromSignerMasterSeed(masterSeed, network), nil
+}
+
+// NewSigningKeyLoaderFromKeystore creates a new SigningKeyLoader from the master seed of a keystore, which
+// is unlocked with the passphrase when the key is first loaded. Like
+// NewSigningKeyLoaderFromSignerMasterSeed, this should be used if you are using remote signing.
+func NewSigningKeyLoaderFromKeystore(keystore *crypto.Keystore, passphrase string) *SigningKeyLoader {
+	return &SigningKeyLoader{
+		keystoreAndPassphrase: &keystoreAndPassphrase{keystore: keystore, passphrase: passphrase},
+	}
+}
+
 func (s *SigningKeyLoader) LoadSigningKey(req requester.Requester) (requester.SigningKey, error) {
 	if s.cachedSigningKey != nil {
 		return s.cachedSigningKey, nil
 	}
 
+	if s.keystoreAndPassphrase != nil {
+		masterSeed, err := s.keystoreAndPassphrase.keystore.Unlock(s.keystoreAndPassphrase.passphrase)
+		if err != nil {
+			return nil, err
+		}
+		s.masterSeedAndNetwork = &masterSeedAndNetwork{
+			masterSeed: masterSeed,
+			network:    s.keystoreAndPassphrase.keystore.Network,
+		}
+		s.keystoreAndPassphrase = nil
+	}
+
 	if s.masterSeedAndNetwork != nil {
 		key, err := s.loadSigningKeyFromMasterSeed()
 		if err != nil {
@@ -67,19 +100,19 @@ func (s *SigningKeyLoader) loadSigningKeyFromMasterSeed() (requester.SigningKey,
 	if s.masterSeedAndNetwork == nil {
 		return nil, errors.New("invalid signing key loader")
 	}
-	var network lightspark_crypto.BitcoinNetwork
+	var network lightsparkcrypto.BitcoinNetwork
 	if s.masterSeedAndNetwork.network == objects.BitcoinNetworkMainnet {
-		network = lightspark_crypto.Mainnet
+		network = lightsparkcrypto.Mainnet
 	} else if s.masterSeedAndNetwork.network == objects.BitcoinNetworkTestnet {
-		network = lightspark_crypto.Testnet
+		network = lightsparkcrypto.Testnet
 	} else if s.masterSeedAndNetwork.network == objects.BitcoinNetworkRegtest {
-		network = lightspark_crypto.Regtest
+		network = lightsparkcrypto.Regtest
 	} else {
 		return nil, errors.New("invalid network")
 	}
 
 	derivationPath := "m/5"
-	key, error := lightspark_crypto.DerivePrivateKey(s.masterSeedAndNetwork.masterSeed, network, derivationPath)
+	key, error := lightsparkcrypto.DerivePrivateKey(s.masterSeedAndNetwork.masterSeed, network, derivationPath)
 	if error != nil {
 		return nil, error
 	}
@@ -120,6 +153,11 @@ type idPasswordPair struct {
 	password string
 }
 
+type keystoreAndPassphrase struct {
+	keystore   *crypto.Keystore
+	passphrase string
+}
+
 type masterSeedAndNetwork struct {
 	masterSeed []byte
 	network    objects.BitcoinNetwork
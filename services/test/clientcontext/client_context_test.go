@@ -0,0 +1,60 @@
+package clientcontext
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/lightsparkdev/go-sdk/objects"
+	"github.com/lightsparkdev/go-sdk/services"
+	"github.com/stretchr/testify/require"
+)
+
+func newSlowServer(t *testing.T) *httptest.Server {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		select {
+		case <-r.Context().Done():
+			return
+		case <-time.After(100 * time.Millisecond):
+		}
+		json.NewEncoder(w).Encode(map[string]interface{}{
+			"data": map[string]interface{}{
+				"current_account": map[string]interface{}{
+					"__typename":         "Account",
+					"account_id":         "Account:1",
+					"account_created_at": "2023-01-01T00:00:00Z",
+					"account_updated_at": "2023-01-01T00:00:00Z",
+				},
+			},
+		})
+	}))
+	t.Cleanup(server.Close)
+	return server
+}
+
+func TestClientWithContext(t *testing.T) {
+	server := newSlowServer(t)
+	client := services.NewLightsparkClient("client_id", "client_secret", &server.URL)
+
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Millisecond)
+	defer cancel()
+	_, err := client.WithContext(ctx).GetCurrentAccount()
+	require.ErrorIs(t, err, context.DeadlineExceeded)
+
+	account, err := client.GetCurrentAccount()
+	require.NoError(t, err)
+	require.Equal(t, "Account:1", account.Id)
+}
+
+func TestObjectAccessorWithContext(t *testing.T) {
+	server := newSlowServer(t)
+	client := services.NewLightsparkClient("client_id", "client_secret", &server.URL)
+
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+	_, err := objects.Account{Id: "Account:1"}.GetNodes(client.WithContext(ctx).Requester, nil, nil, nil, nil)
+	require.ErrorIs(t, err, context.Canceled)
+}
@@ -8,6 +8,7 @@ import (
 	"testing"
 
 	"github.com/lightsparkdev/go-sdk/crypto"
+	"github.com/lightsparkdev/go-sdk/crypto/lightsparkcrypto"
 	"github.com/lightsparkdev/go-sdk/services"
 	servicestest "github.com/lightsparkdev/go-sdk/services/test"
 	"github.com/lightsparkdev/go-sdk/utils"
@@ -23,7 +24,7 @@ func TestGetNodeWalletAddressWithKeys(t *testing.T) {
 
 	// Get the master xpub
 	seed := env.MasterSeedHex
-	ourMasterPubkey, err := crypto.DerivePublicKey(seed, lightspark_crypto.Regtest, "m")
+	ourMasterPubkey, err := crypto.DerivePublicKey(seed, lightsparkcrypto.Regtest, "m")
 	require.NoError(t, err)
 
 	pubkey1, err := hex.DecodeString(address.MultisigWalletAddressValidationParameters.CounterpartyFundingPubkey)
@@ -33,7 +34,7 @@ func TestGetNodeWalletAddressWithKeys(t *testing.T) {
 	pubkey2, err := lightspark_crypto.DeriveAndTweakPubkey(ourMasterPubkey, address.MultisigWalletAddressValidationParameters.FundingPubkeyDerivationPath, nil, nil)
 	require.NoError(t, err)
 
-	generatedAddress, err := utils.GenerateMultiSigAddress(lightspark_crypto.Regtest, pubkey1, pubkey2)
+	generatedAddress, err := utils.GenerateMultiSigAddress(lightsparkcrypto.Regtest, pubkey1, pubkey2)
 	require.NoError(t, err)
 	require.Equal(t, address.WalletAddress, generatedAddress)
 }
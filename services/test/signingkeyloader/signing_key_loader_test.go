@@ -0,0 +1,28 @@
+package signingkeyloader
+
+import (
+	"bytes"
+	"testing"
+
+	"github.com/lightsparkdev/go-sdk/crypto"
+	"github.com/lightsparkdev/go-sdk/objects"
+	"github.com/lightsparkdev/go-sdk/requester"
+	"github.com/lightsparkdev/go-sdk/services"
+	"github.com/stretchr/testify/require"
+)
+
+func TestSigningKeyLoaderFromKeystore(t *testing.T) {
+	seed := bytes.Repeat([]byte{4}, 32)
+	keystore, err := crypto.NewKeystore(seed, objects.BitcoinNetworkRegtest, "passphrase", crypto.WithScrypt(1<<10, 8, 1))
+	require.NoError(t, err)
+
+	expected, err := services.NewSigningKeyLoaderFromSignerMasterSeed(seed, objects.BitcoinNetworkRegtest).
+		LoadSigningKey(requester.Requester{})
+	require.NoError(t, err)
+	key, err := services.NewSigningKeyLoaderFromKeystore(keystore, "passphrase").LoadSigningKey(requester.Requester{})
+	require.NoError(t, err)
+	require.Equal(t, expected, key)
+
+	_, err = services.NewSigningKeyLoaderFromKeystore(keystore, "wrong").LoadSigningKey(requester.Requester{})
+	require.ErrorIs(t, err, crypto.ErrWrongPassphrase)
+}
@@ -0,0 +1,160 @@
+package wait
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/lightsparkdev/go-sdk/objects"
+	"github.com/lightsparkdev/go-sdk/requester"
+	"github.com/lightsparkdev/go-sdk/services"
+	"github.com/lightsparkdev/go-sdk/webhooks"
+	"github.com/stretchr/testify/require"
+)
+
+// newEntityServer answers GetEntity queries with the entity returned by entityForPoll, called with the
+// 1-based number of the poll.
+func newEntityServer(t *testing.T, entityForPoll func(poll int32) map[string]interface{}) (*services.LightsparkClient, *atomic.Int32) {
+	var polls atomic.Int32
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		json.NewEncoder(w).Encode(map[string]interface{}{
+			"data": map[string]interface{}{"entity": entityForPoll(polls.Add(1))},
+		})
+	}))
+	t.Cleanup(server.Close)
+	return services.NewLightsparkClient("client_id", "client_secret", &server.URL), &polls
+}
+
+func outgoingPayment(status string, extra map[string]interface{}) map[string]interface{} {
+	payment := map[string]interface{}{
+		"__typename":              "OutgoingPayment",
+		"outgoing_payment_id":     "OutgoingPayment:1",
+		"outgoing_payment_status": status,
+	}
+	for key, value := range extra {
+		payment[key] = value
+	}
+	return payment
+}
+
+func TestWaitForOutgoingPaymentSuccess(t *testing.T) {
+	client, polls := newEntityServer(t, func(poll int32) map[string]interface{} {
+		if poll < 3 {
+			return outgoingPayment("PENDING", nil)
+		}
+		return outgoingPayment("SUCCESS", nil)
+	})
+
+	payment, err := client.WaitForOutgoingPayment(context.Background(), "OutgoingPayment:1",
+		services.WithPollInterval(time.Millisecond, 5*time.Millisecond))
+	require.NoError(t, err)
+	require.Equal(t, objects.TransactionStatusSuccess, payment.Status)
+	require.Equal(t, int32(3), polls.Load())
+}
+
+func TestWaitForOutgoingPaymentFailure(t *testing.T) {
+	client, _ := newEntityServer(t, func(poll int32) map[string]interface{} {
+		return outgoingPayment("FAILED", map[string]interface{}{
+			"outgoing_payment_failure_reason":  "INSUFFICIENT_BALANCE",
+			"outgoing_payment_failure_message": map[string]interface{}{"rich_text_text": "Not enough funds"},
+		})
+	})
+
+	payment, err := client.WaitForOutgoingPayment(context.Background(), "OutgoingPayment:1")
+	require.NotNil(t, payment)
+	var failure *services.PaymentFailedError
+	require.True(t, errors.As(err, &failure))
+	require.Equal(t, objects.PaymentFailureReasonInsufficientBalance, *failure.FailureReason)
+	require.Equal(t, "Not enough funds", failure.FailureMessage.Text)
+	require.ErrorIs(t, err, requester.ErrInsufficientBalance)
+}
+
+func TestWaitForOutgoingPaymentDeadline(t *testing.T) {
+	client, _ := newEntityServer(t, func(poll int32) map[string]interface{} {
+		return outgoingPayment("PENDING", nil)
+	})
+
+	ctx, cancel := context.WithTimeout(context.Background(), 20*time.Millisecond)
+	defer cancel()
+	_, err := client.WaitForOutgoingPayment(ctx, "OutgoingPayment:1",
+		services.WithPollInterval(time.Millisecond, time.Millisecond))
+	require.ErrorIs(t, err, context.DeadlineExceeded)
+}
+
+func TestWaitWakesUpOnWebhook(t *testing.T) {
+	client, polls := newEntityServer(t, func(poll int32) map[string]interface{} {
+		if poll == 1 {
+			return outgoingPayment("PENDING", nil)
+		}
+		return outgoingPayment("SUCCESS", nil)
+	})
+
+	notifier := services.NewEventNotifier()
+	go func() {
+		for polls.Load() < 1 {
+			time.Sleep(time.Millisecond)
+		}
+		time.Sleep(10 * time.Millisecond)
+		notifier.Notify(webhooks.WebhookEvent{
+			EventType: objects.WebhookEventTypePaymentFinished,
+			EntityId:  "OutgoingPayment:1",
+		})
+	}()
+
+	start := time.Now()
+	payment, err := client.WaitForOutgoingPayment(context.Background(), "OutgoingPayment:1",
+		services.WithPollInterval(time.Hour, time.Hour), services.WithEventSource(notifier))
+	require.NoError(t, err)
+	require.Equal(t, objects.TransactionStatusSuccess, payment.Status)
+	require.Less(t, time.Since(start), time.Minute)
+}
+
+func TestWaitForWithdrawalRequestFailure(t *testing.T) {
+	client, _ := newEntityServer(t, func(poll int32) map[string]interface{} {
+		return map[string]interface{}{
+			"__typename":                "WithdrawalRequest",
+			"withdrawal_request_id":     "WithdrawalRequest:1",
+			"withdrawal_request_status": "FAILED",
+		}
+	})
+
+	_, err := client.WaitForWithdrawalRequest(context.Background(), "WithdrawalRequest:1")
+	var failure *services.WithdrawalFailedError
+	require.True(t, errors.As(err, &failure))
+	require.Equal(t, "WithdrawalRequest:1", failure.WithdrawalRequestId)
+}
+
+func TestWaitForInvoicePaid(t *testing.T) {
+	amount := func(value int64) map[string]interface{} {
+		return map[string]interface{}{
+			"currency_amount_original_value": value,
+			"currency_amount_original_unit":  "MILLISATOSHI",
+		}
+	}
+	client, _ := newEntityServer(t, func(poll int32) map[string]interface{} {
+		paid := int64(0)
+		if poll > 1 {
+			paid = 1000
+		}
+		return map[string]interface{}{
+			"__typename":          "Invoice",
+			"invoice_id":          "Invoice:1",
+			"invoice_status":      "OPEN",
+			"invoice_amount_paid": amount(paid),
+			"invoice_data": map[string]interface{}{
+				"invoice_data_amount":     amount(1000),
+				"invoice_data_expires_at": time.Now().Add(time.Hour).Format(time.RFC3339),
+			},
+		}
+	})
+
+	invoice, err := client.WaitForInvoicePaid(context.Background(), "Invoice:1",
+		services.WithPollInterval(time.Millisecond, time.Millisecond))
+	require.NoError(t, err)
+	require.Equal(t, int64(1000), invoice.AmountPaid.OriginalValue)
+}
@@ -0,0 +1,295 @@
+// Copyright ©, 2023-present, Lightspark Group, Inc. - All Rights Reserved
+package services
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"sync"
+	"time"
+
+	"github.com/lightsparkdev/go-sdk/objects"
+	"github.com/lightsparkdev/go-sdk/requester"
+	"github.com/lightsparkdev/go-sdk/utils"
+	"github.com/lightsparkdev/go-sdk/webhooks"
+)
+
+// PaymentFailedError is returned by WaitForOutgoingPayment and WaitForIncomingPayment when the payment
+// reaches the FAILED status. It matches the sentinel errors of the requester package with errors.Is, for
+// example requester.ErrInsufficientBalance.
+type PaymentFailedError struct {
+	PaymentId string
+	// FailureReason is only set for outgoing payments.
+	FailureReason *objects.PaymentFailureReason
+	// FailureMessage is only set for outgoing payments.
+	FailureMessage *objects.RichText
+}
+
+func (e *PaymentFailedError) Error() string {
+	message := "payment " + e.PaymentId + " failed"
+	if e.FailureReason != nil {
+		message += ": " + e.FailureReason.StringValue()
+	}
+	if e.FailureMessage != nil && e.FailureMessage.Text != "" {
+		message += ": " + e.FailureMessage.Text
+	}
+	return message
+}
+
+func (e *PaymentFailedError) Is(target error) bool {
+	if e.FailureReason == nil {
+		return false
+	}
+	switch *e.FailureReason {
+	case objects.PaymentFailureReasonInsufficientBalance,
+		objects.PaymentFailureReasonInsufficientBalanceOnSinglePathInvoice:
+		return target == requester.ErrInsufficientBalance
+	case objects.PaymentFailureReasonInvoiceExpired:
+		return target == requester.ErrInvoiceExpired
+	case objects.PaymentFailureReasonInvoiceAlreadyPaid:
+		return target == requester.ErrPaymentAlreadyExists
+	}
+	return false
+}
+
+// WithdrawalFailedError is returned by WaitForWithdrawalRequest when the withdrawal request fails.
+type WithdrawalFailedError struct {
+	WithdrawalRequestId string
+	Status              objects.WithdrawalRequestStatus
+}
+
+func (e *WithdrawalFailedError) Error() string {
+	return "withdrawal request " + e.WithdrawalRequestId + " failed: " + e.Status.StringValue()
+}
+
+// ErrInvoiceClosed is returned by WaitForInvoicePaid when the invoice is closed without being paid.
+var ErrInvoiceClosed = errors.New("invoice closed without being paid")
+
+// EventSource wakes up the Wait* helpers when something happened to an entity, so that they don't have to
+// wait for the next poll.
+type EventSource interface {
+	// Subscribe returns a channel receiving a value every time the entity may have changed, and a function
+	// to call once the subscription is not needed anymore.
+	Subscribe(entityId string) (<-chan struct{}, func())
+}
+
+// EventNotifier is an EventSource fed by webhook events. Call Notify from your webhook handler and pass the
+// notifier to the Wait* helpers with WithEventSource.
+type EventNotifier struct {
+	mu          sync.Mutex
+	subscribers map[string]map[chan struct{}]struct{}
+}
+
+func NewEventNotifier() *EventNotifier {
+	return &EventNotifier{subscribers: map[string]map[chan struct{}]struct{}{}}
+}
+
+// Subscribe implements EventSource.
+func (n *EventNotifier) Subscribe(entityId string) (<-chan struct{}, func()) {
+	channel := make(chan struct{}, 1)
+	n.mu.Lock()
+	if n.subscribers[entityId] == nil {
+		n.subscribers[entityId] = map[chan struct{}]struct{}{}
+	}
+	n.subscribers[entityId][channel] = struct{}{}
+	n.mu.Unlock()
+
+	return channel, func() {
+		n.mu.Lock()
+		defer n.mu.Unlock()
+		delete(n.subscribers[entityId], channel)
+		if len(n.subscribers[entityId]) == 0 {
+			delete(n.subscribers, entityId)
+		}
+	}
+}
+
+// Notify wakes up the helpers waiting on the entity of the webhook event.
+func (n *EventNotifier) Notify(event webhooks.WebhookEvent) {
+	n.NotifyEntity(event.EntityId)
+}
+
+// NotifyEntity wakes up the helpers waiting on the given entity.
+func (n *EventNotifier) NotifyEntity(entityId string) {
+	n.mu.Lock()
+	defer n.mu.Unlock()
+	for channel := range n.subscribers[entityId] {
+		select {
+		case channel <- struct{}{}:
+		default:
+			// A wake up is already pending.
+		}
+	}
+}
+
+// WaitOption configures how the Wait* helpers poll the Lightspark API.
+type WaitOption func(*waitOptions)
+
+type waitOptions struct {
+	initialInterval time.Duration
+	maxInterval     time.Duration
+	multiplier      float64
+	events          EventSource
+}
+
+// WithPollInterval sets the delay before the first poll and the maximum delay between two polls. The
+// defaults are 100ms and 5s.
+func WithPollInterval(initialInterval time.Duration, maxInterval time.Duration) WaitOption {
+	return func(o *waitOptions) {
+		o.initialInterval = initialInterval
+		o.maxInterval = maxInterval
+	}
+}
+
+// WithPollMultiplier sets the factor applied to the poll interval after each poll. The default is 1.5.
+// Use 1 to poll at a constant interval.
+func WithPollMultiplier(multiplier float64) WaitOption {
+	return func(o *waitOptions) {
+		o.multiplier = multiplier
+	}
+}
+
+// WithEventSource polls the entity as soon as the EventSource reports a change, usually because a webhook
+// has been received.
+func WithEventSource(events EventSource) WaitOption {
+	return func(o *waitOptions) {
+		o.events = events
+	}
+}
+
+// WaitForOutgoingPayment waits until the outgoing payment succeeds or fails. A *PaymentFailedError is
+// returned along with the payment when it fails. Use the context to set a deadline.
+func (client *LightsparkClient) WaitForOutgoingPayment(ctx context.Context, paymentId string,
+	opts ...WaitOption,
+) (*objects.OutgoingPayment, error) {
+	return waitForEntity(ctx, client, paymentId, opts, func(payment objects.OutgoingPayment) (bool, error) {
+		switch payment.Status {
+		case objects.TransactionStatusSuccess:
+			return true, nil
+		case objects.TransactionStatusFailed:
+			return true, &PaymentFailedError{
+				PaymentId:      payment.Id,
+				FailureReason:  payment.FailureReason,
+				FailureMessage: payment.FailureMessage,
+			}
+		}
+		return false, nil
+	})
+}
+
+// WaitForIncomingPayment waits until the incoming payment succeeds or fails. A *PaymentFailedError is
+// returned along with the payment when it fails. Use the context to set a deadline.
+func (client *LightsparkClient) WaitForIncomingPayment(ctx context.Context, paymentId string,
+	opts ...WaitOption,
+) (*objects.IncomingPayment, error) {
+	return waitForEntity(ctx, client, paymentId, opts, func(payment objects.IncomingPayment) (bool, error) {
+		switch payment.Status {
+		case objects.TransactionStatusSuccess:
+			return true, nil
+		case objects.TransactionStatusFailed:
+			return true, &PaymentFailedError{PaymentId: payment.Id}
+		}
+		return false, nil
+	})
+}
+
+// WaitForInvoicePaid waits until the full amount of the invoice has been paid. An error matching
+// requester.ErrInvoiceExpired is returned along with the invoice when it expires before being paid, and
+// ErrInvoiceClosed when it is closed without being paid. Use the context to set a deadline.
+func (client *LightsparkClient) WaitForInvoicePaid(ctx context.Context, invoiceId string,
+	opts ...WaitOption,
+) (*objects.Invoice, error) {
+	return waitForEntity(ctx, client, invoiceId, opts, func(invoice objects.Invoice) (bool, error) {
+		paid, err := isInvoicePaid(invoice)
+		if err != nil || paid {
+			return true, err
+		}
+		if invoice.Status == objects.PaymentRequestStatusClosed {
+			return true, ErrInvoiceClosed
+		}
+		if !invoice.Data.ExpiresAt.IsZero() && time.Now().After(invoice.Data.ExpiresAt) {
+			return true, fmt.Errorf("invoice %s: %w", invoice.Id, requester.ErrInvoiceExpired)
+		}
+		return false, nil
+	})
+}
+
+// WaitForWithdrawalRequest waits until the withdrawal request completes. A *WithdrawalFailedError is
+// returned along with the request when it fails. Use the context to set a deadline.
+func (client *LightsparkClient) WaitForWithdrawalRequest(ctx context.Context, withdrawalRequestId string,
+	opts ...WaitOption,
+) (*objects.WithdrawalRequest, error) {
+	return waitForEntity(ctx, client, withdrawalRequestId, opts, func(request objects.WithdrawalRequest) (bool, error) {
+		switch request.Status {
+		case objects.WithdrawalRequestStatusSuccessful, objects.WithdrawalRequestStatusPartiallySuccessful:
+			return true, nil
+		case objects.WithdrawalRequestStatusFailed:
+			return true, &WithdrawalFailedError{WithdrawalRequestId: request.Id, Status: request.Status}
+		}
+		return false, nil
+	})
+}
+
+func isInvoicePaid(invoice objects.Invoice) (bool, error) {
+	if invoice.AmountPaid == nil {
+		return false, nil
+	}
+	paid, err := utils.ValueMilliSatoshi(*invoice.AmountPaid)
+	if err != nil {
+		return false, err
+	}
+	requested, err := utils.ValueMilliSatoshi(invoice.Data.Amount)
+	if err != nil {
+		return false, err
+	}
+	return paid > 0 && paid >= requested, nil
+}
+
+// waitForEntity polls the entity until isTerminal reports it reached a terminal state, the context is done,
+// or an event wakes it up earlier.
+func waitForEntity[T objects.Entity](ctx context.Context, client *LightsparkClient, entityId string,
+	opts []WaitOption, isTerminal func(entity T) (bool, error),
+) (*T, error) {
+	o := waitOptions{initialInterval: 100 * time.Millisecond, maxInterval: 5 * time.Second, multiplier: 1.5}
+	for _, opt := range opts {
+		opt(&o)
+	}
+
+	var wakeUp <-chan struct{}
+	if o.events != nil {
+		var unsubscribe func()
+		wakeUp, unsubscribe = o.events.Subscribe(entityId)
+		defer unsubscribe()
+	}
+
+	ctxClient := client.WithContext(ctx)
+	interval := o.initialInterval
+	for {
+		entity, err := ctxClient.GetEntity(entityId)
+		if err != nil {
+			return nil, err
+		}
+		typedEntity, ok := (*entity).(T)
+		if !ok {
+			return nil, fmt.Errorf("entity %s is a %T", entityId, *entity)
+		}
+		terminal, err := isTerminal(typedEntity)
+		if terminal {
+			return &typedEntity, err
+		}
+
+		timer := time.NewTimer(interval)
+		select {
+		case <-ctx.Done():
+			timer.Stop()
+			return nil, ctx.Err()
+		case <-wakeUp:
+			timer.Stop()
+		case <-timer.C:
+		}
+		interval = time.Duration(float64(interval) * o.multiplier)
+		if interval > o.maxInterval {
+			interval = o.maxInterval
+		}
+	}
+}
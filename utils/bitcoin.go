@@ -1,9 +1,9 @@
 package utils
 
 import (
-	lightspark_crypto "github.com/lightsparkdev/lightspark-crypto-uniffi/lightspark-crypto-go"
+	"github.com/lightsparkdev/go-sdk/crypto/lightsparkcrypto"
 )
 
-func GenerateMultiSigAddress(network lightspark_crypto.BitcoinNetwork, publicKey1 []byte, publicKey2 []byte) (string, error) {
-	return lightspark_crypto.GenerateMultiSigAddress(network, publicKey1, publicKey2)
+func GenerateMultiSigAddress(network lightsparkcrypto.BitcoinNetwork, publicKey1 []byte, publicKey2 []byte) (string, error) {
+	return lightsparkcrypto.GenerateMultiSigAddress(network, publicKey1, publicKey2)
 }
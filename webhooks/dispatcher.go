@@ -0,0 +1,284 @@
+// Copyright ©, 2023-present, Lightspark Group, Inc. - All Rights Reserved
+package webhooks
+
+import (
+	"context"
+	"errors"
+	"hash/fnv"
+	"log/slog"
+	"sync"
+	"time"
+)
+
+const (
+	// DefaultDispatcherConcurrency is the number of events a Dispatcher processes at the same time.
+	DefaultDispatcherConcurrency = 4
+	// DefaultMaxAttempts is the number of times a Dispatcher calls its HandlerFunc for an event before
+	// giving up.
+	DefaultMaxAttempts = 5
+	// DefaultInitialBackoff is the delay before the first retry of a failed event. It doubles after every
+	// attempt, up to DefaultMaxBackoff.
+	DefaultInitialBackoff = time.Second
+	// DefaultMaxBackoff is the maximum delay between two attempts.
+	DefaultMaxBackoff = time.Minute
+
+	laneBufferSize = 64
+)
+
+// DeadLetterSink receives the events a Dispatcher gave up on, with the error of the last attempt.
+type DeadLetterSink interface {
+	DeadLetter(ctx context.Context, event *WebhookEvent, err error) error
+}
+
+// DeadLetterFunc adapts a function to a DeadLetterSink.
+type DeadLetterFunc func(ctx context.Context, event *WebhookEvent, err error) error
+
+// DeadLetter implements DeadLetterSink.
+func (f DeadLetterFunc) DeadLetter(ctx context.Context, event *WebhookEvent, err error) error {
+	return f(ctx, event, err)
+}
+
+// DispatcherOption configures a Dispatcher.
+type DispatcherOption func(*Dispatcher)
+
+// WithQueue sets the queue storing the events until they are processed. A MemoryQueue is used otherwise;
+// use a FileQueue for the events to survive a restart.
+func WithQueue(queue Queue) DispatcherOption {
+	return func(d *Dispatcher) {
+		d.queue = queue
+	}
+}
+
+// WithConcurrency sets the number of events processed at the same time.
+func WithConcurrency(concurrency int) DispatcherOption {
+	return func(d *Dispatcher) {
+		d.concurrency = concurrency
+	}
+}
+
+// WithMaxAttempts sets the number of times the HandlerFunc is called for an event before it is sent to
+// the DeadLetterSink.
+func WithMaxAttempts(maxAttempts int) DispatcherOption {
+	return func(d *Dispatcher) {
+		d.maxAttempts = maxAttempts
+	}
+}
+
+// WithBackoff sets the delay before the first retry of a failed event, doubled after every attempt up to
+// maxBackoff.
+func WithBackoff(initialBackoff time.Duration, maxBackoff time.Duration) DispatcherOption {
+	return func(d *Dispatcher) {
+		d.initialBackoff = initialBackoff
+		d.maxBackoff = maxBackoff
+	}
+}
+
+// WithDeadLetterSink sets the sink receiving the events that failed every attempt. They are only logged
+// otherwise. When the sink fails, a MemoryQueue or FileQueue returns the event to be attempted again.
+func WithDeadLetterSink(sink DeadLetterSink) DispatcherOption {
+	return func(d *Dispatcher) {
+		d.deadLetters = sink
+	}
+}
+
+// WithPartitionKey sets the function returning the key of an event. Events with the same key are
+// processed one at a time, in the order they were enqueued. By default, the key is the node_id of the
+// data of the event if present, or its EntityId.
+func WithPartitionKey(partitionKey func(event *WebhookEvent) string) DispatcherOption {
+	return func(d *Dispatcher) {
+		d.partitionKey = partitionKey
+	}
+}
+
+// WithDispatcherLogger sets the logger receiving the failed events. slog.Default() is used otherwise.
+func WithDispatcherLogger(logger *slog.Logger) DispatcherOption {
+	return func(d *Dispatcher) {
+		d.logger = logger
+	}
+}
+
+// Dispatcher processes webhook events in the background, so that webhooks are acknowledged as soon as
+// the events are queued, before slow work such as remote signing is done:
+//
+//	dispatcher := webhooks.NewDispatcher(processEvent, webhooks.WithQueue(fileQueue))
+//	go dispatcher.Run(ctx)
+//	handler := webhooks.NewHandler(webhookSecret)
+//	handler.HandleDefault(dispatcher.Enqueue)
+//
+// Events of the same node are processed in order, one at a time. A failed event is retried with an
+// exponential backoff, which delays the following events of its node, and sent to the DeadLetterSink
+// after the last attempt.
+type Dispatcher struct {
+	handler        HandlerFunc
+	queue          Queue
+	concurrency    int
+	maxAttempts    int
+	initialBackoff time.Duration
+	maxBackoff     time.Duration
+	deadLetters    DeadLetterSink
+	partitionKey   func(event *WebhookEvent) string
+	logger         *slog.Logger
+}
+
+// NewDispatcher creates a Dispatcher calling handler for every event. It processes nothing until Run is
+// called.
+func NewDispatcher(handler HandlerFunc, opts ...DispatcherOption) *Dispatcher {
+	d := &Dispatcher{
+		handler:        handler,
+		queue:          NewMemoryQueue(),
+		concurrency:    DefaultDispatcherConcurrency,
+		maxAttempts:    DefaultMaxAttempts,
+		initialBackoff: DefaultInitialBackoff,
+		maxBackoff:     DefaultMaxBackoff,
+		partitionKey:   defaultPartitionKey,
+		logger:         slog.Default(),
+	}
+	for _, opt := range opts {
+		opt(d)
+	}
+	if d.concurrency < 1 {
+		d.concurrency = 1
+	}
+	if d.maxAttempts < 1 {
+		d.maxAttempts = 1
+	}
+	return d
+}
+
+func defaultPartitionKey(event *WebhookEvent) string {
+	if event.Data != nil {
+		if nodeId, ok := (*event.Data)["node_id"].(string); ok && nodeId != "" {
+			return nodeId
+		}
+	}
+	return event.EntityId
+}
+
+// Enqueue adds an event to the queue. Its signature matches HandlerFunc so that it can be registered on a
+// Handler.
+func (d *Dispatcher) Enqueue(ctx context.Context, event Event) error {
+	return d.queue.Push(ctx, event.Envelope())
+}
+
+// requeuer is implemented by the queues of this package, to take back the entries a Dispatcher popped
+// but didn't process.
+type requeuer interface {
+	requeue(entries []*QueueEntry)
+}
+
+// Run processes the queued events until ctx is done, then waits for the events being processed. Events
+// interrupted by ctx are not acked. A MemoryQueue or a FileQueue takes them back, with the events popped
+// but not processed yet, so that a later Run processes them; a FileQueue also returns them again after a
+// restart. Other Queue implementations get them back only if they return the entries never acked.
+func (d *Dispatcher) Run(ctx context.Context) error {
+	lanes := make([]chan *QueueEntry, d.concurrency)
+	unprocessed := make([][]*QueueEntry, d.concurrency)
+	var wg sync.WaitGroup
+	for i := range lanes {
+		lanes[i] = make(chan *QueueEntry, laneBufferSize)
+		wg.Add(1)
+		go func(i int) {
+			defer wg.Done()
+			for entry := range lanes[i] {
+				if !d.process(ctx, entry) {
+					unprocessed[i] = append(unprocessed[i], entry)
+				}
+			}
+		}(i)
+	}
+	var interrupted *QueueEntry
+	defer func() {
+		for _, lane := range lanes {
+			close(lane)
+		}
+		wg.Wait()
+		if requeuer, ok := d.queue.(requeuer); ok {
+			var entries []*QueueEntry
+			for _, lane := range unprocessed {
+				entries = append(entries, lane...)
+			}
+			if interrupted != nil {
+				entries = append(entries, interrupted)
+			}
+			requeuer.requeue(entries)
+		}
+	}()
+
+	for {
+		entry, err := d.queue.Pop(ctx)
+		if err != nil {
+			if ctx.Err() != nil {
+				return nil
+			}
+			return err
+		}
+		hash := fnv.New32a()
+		hash.Write([]byte(d.partitionKey(entry.Event)))
+		select {
+		case lanes[hash.Sum32()%uint32(len(lanes))] <- entry:
+		case <-ctx.Done():
+			interrupted = entry
+			return nil
+		}
+	}
+}
+
+// process handles an entry and acks it. It returns false when ctx was done before the entry was processed.
+func (d *Dispatcher) process(ctx context.Context, entry *QueueEntry) bool {
+	if ctx.Err() != nil {
+		return false
+	}
+	logger := d.logger.With(
+		slog.String("event_id", entry.Event.EventId),
+		slog.String("event_type", entry.Event.EventType.StringValue()),
+	)
+
+	err := d.attempt(ctx, entry, logger)
+	if ctx.Err() != nil {
+		return false
+	}
+	if err != nil {
+		logger.ErrorContext(ctx, "webhook event failed every attempt", slog.Any("error", err))
+		if d.deadLetters != nil {
+			if deadLetterErr := d.deadLetters.DeadLetter(ctx, entry.Event, err); deadLetterErr != nil {
+				// The event is returned to the queues of this package to be attempted again. It is left in
+				// other queues without being acked.
+				logger.ErrorContext(ctx, "webhook dead letter sink failed", slog.Any("error", deadLetterErr))
+				if requeuer, ok := d.queue.(requeuer); ok {
+					requeuer.requeue([]*QueueEntry{entry})
+				}
+				return true
+			}
+		}
+	}
+	if err := d.queue.Ack(ctx, entry.Id); err != nil {
+		logger.ErrorContext(ctx, "webhook queue ack failed", slog.Any("error", err))
+	}
+	return true
+}
+
+func (d *Dispatcher) attempt(ctx context.Context, entry *QueueEntry, logger *slog.Logger) error {
+	event, err := entry.Event.Decode()
+	if err != nil {
+		return err
+	}
+
+	backoff := d.initialBackoff
+	for attempt := 1; ; attempt++ {
+		err = d.handler(ctx, event)
+		if err == nil || attempt >= d.maxAttempts {
+			return err
+		}
+		logger.WarnContext(ctx, "webhook event failed, retrying",
+			slog.Int("attempt", attempt), slog.Duration("backoff", backoff), slog.Any("error", err))
+
+		timer := time.NewTimer(backoff)
+		select {
+		case <-timer.C:
+		case <-ctx.Done():
+			timer.Stop()
+			return errors.Join(err, ctx.Err())
+		}
+		backoff = min(2*backoff, d.maxBackoff)
+	}
+}
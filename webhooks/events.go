@@ -0,0 +1,298 @@
+// Copyright ©, 2023-present, Lightspark Group, Inc. - All Rights Reserved
+package webhooks
+
+import (
+	"encoding/json"
+	"reflect"
+	"strings"
+
+	"github.com/lightsparkdev/go-sdk/objects"
+)
+
+// Event is a typed webhook event returned by WebhookEvent.Decode. Use a type switch to handle the events
+// you are interested in:
+//
+//	event, err := webhookEvent.Decode()
+//	if err != nil {
+//		return err
+//	}
+//	switch event := event.(type) {
+//	case *webhooks.PaymentFinishedEvent:
+//		...
+//	case *webhooks.RemoteSigningEvent:
+//		...
+//	}
+type Event interface {
+	// Envelope returns the fields shared by every event.
+	Envelope() *WebhookEvent
+}
+
+// Envelope implements Event.
+func (e *WebhookEvent) Envelope() *WebhookEvent {
+	return e
+}
+
+// PaymentFinishedEvent is sent when an outgoing or incoming payment of a node completes. EntityId is the
+// id of the OutgoingPayment or IncomingPayment.
+type PaymentFinishedEvent struct {
+	WebhookEvent `json:"-"`
+	// Extra holds the fields of Data that are not modeled by this type.
+	Extra map[string]interface{} `json:"-"`
+}
+
+// ForceClosureEvent is sent when a channel of a node is force closed. EntityId is the id of the Channel.
+type ForceClosureEvent struct {
+	WebhookEvent `json:"-"`
+	// Extra holds the fields of Data that are not modeled by this type.
+	Extra map[string]interface{} `json:"-"`
+}
+
+// WithdrawalFinishedEvent is sent when a withdrawal request of a node completes. EntityId is the id of the
+// WithdrawalRequest.
+type WithdrawalFinishedEvent struct {
+	WebhookEvent `json:"-"`
+	// Extra holds the fields of Data that are not modeled by this type.
+	Extra map[string]interface{} `json:"-"`
+}
+
+// FundsReceivedEvent is sent when a node receives an on-chain deposit. EntityId is the id of the Deposit.
+type FundsReceivedEvent struct {
+	WebhookEvent `json:"-"`
+	// Extra holds the fields of Data that are not modeled by this type.
+	Extra map[string]interface{} `json:"-"`
+}
+
+// NodeStatusEvent is sent when the status of a node changes. EntityId is the id of the node.
+type NodeStatusEvent struct {
+	WebhookEvent `json:"-"`
+	// Extra holds the fields of Data that are not modeled by this type.
+	Extra map[string]interface{} `json:"-"`
+}
+
+// UmaInvitationClaimedEvent is sent when an UMA invitation is claimed. EntityId is the id of the
+// UmaInvitation.
+type UmaInvitationClaimedEvent struct {
+	WebhookEvent `json:"-"`
+	// Extra holds the fields of Data that are not modeled by this type.
+	Extra map[string]interface{} `json:"-"`
+}
+
+// WalletStatusEvent is sent when the status of a wallet changes. EntityId and WalletId are the id of the
+// Wallet.
+type WalletStatusEvent struct {
+	WebhookEvent `json:"-"`
+	// Extra holds the fields of Data that are not modeled by this type.
+	Extra map[string]interface{} `json:"-"`
+}
+
+// WalletOutgoingPaymentFinishedEvent is sent when an outgoing payment of a wallet completes. EntityId is
+// the id of the OutgoingPayment.
+type WalletOutgoingPaymentFinishedEvent struct {
+	WebhookEvent `json:"-"`
+	// Extra holds the fields of Data that are not modeled by this type.
+	Extra map[string]interface{} `json:"-"`
+}
+
+// WalletIncomingPaymentFinishedEvent is sent when an incoming payment of a wallet completes. EntityId is
+// the id of the IncomingPayment.
+type WalletIncomingPaymentFinishedEvent struct {
+	WebhookEvent `json:"-"`
+	// Extra holds the fields of Data that are not modeled by this type.
+	Extra map[string]interface{} `json:"-"`
+}
+
+// WalletWithdrawalFinishedEvent is sent when a withdrawal of a wallet completes. EntityId is the id of
+// the WithdrawalRequest.
+type WalletWithdrawalFinishedEvent struct {
+	WebhookEvent `json:"-"`
+	// Extra holds the fields of Data that are not modeled by this type.
+	Extra map[string]interface{} `json:"-"`
+}
+
+// WalletFundsReceivedEvent is sent when a wallet receives an on-chain deposit. EntityId is the id of the
+// Deposit.
+type WalletFundsReceivedEvent struct {
+	WebhookEvent `json:"-"`
+	// Extra holds the fields of Data that are not modeled by this type.
+	Extra map[string]interface{} `json:"-"`
+}
+
+// RemoteSigningEvent asks a remote signing node to sign or derive something. EntityId is the id of the
+// node. Only the fields relevant to SubEventType are set. The remotesigning package handles these events.
+type RemoteSigningEvent struct {
+	WebhookEvent `json:"-"`
+
+	SubEventType           objects.RemoteSigningSubEventType `json:"sub_event_type"`
+	BitcoinNetwork         objects.BitcoinNetwork            `json:"bitcoin_network"`
+	NodeId                 *string                           `json:"node_id"`
+	DerivationPath         *string                           `json:"derivation_path"`
+	PeerPublicKey          *string                           `json:"peer_public_key"`
+	PerCommitmentPointIdx  *uint64                           `json:"per_commitment_point_idx"`
+	PerCommitmentSecret    *string                           `json:"per_commitment_secret"`
+	PerCommitmentSecretIdx *uint64                           `json:"per_commitment_secret_idx"`
+	PaymentHash            *string                           `json:"payment_hash"`
+	PayreqHash             *string                           `json:"payreq_hash"`
+	PreimageNonce          *string                           `json:"preimage_nonce"`
+	InvoiceId              *string                           `json:"invoice_id"`
+	IsUma                  *bool                             `json:"is_uma"`
+	IsLnurl                *bool                             `json:"is_lnurl"`
+	// SigningJobs is left encoded, to be parsed by the remotesigning package.
+	SigningJobs json.RawMessage `json:"signing_jobs"`
+
+	// Extra holds the fields of Data that are not modeled by this type.
+	Extra map[string]interface{} `json:"-"`
+}
+
+// LowBalanceEvent is sent when the balance of a node goes below the configured threshold. EntityId is the
+// id of the node.
+type LowBalanceEvent struct {
+	WebhookEvent `json:"-"`
+
+	// Balance is the balance of the node.
+	Balance *objects.CurrencyAmount `json:"balance"`
+
+	// Extra holds the fields of Data that are not modeled by this type.
+	Extra map[string]interface{} `json:"-"`
+}
+
+// HighBalanceEvent is sent when the balance of a node goes above the configured threshold. EntityId is
+// the id of the node.
+type HighBalanceEvent struct {
+	WebhookEvent `json:"-"`
+
+	// Balance is the balance of the node.
+	Balance *objects.CurrencyAmount `json:"balance"`
+
+	// Extra holds the fields of Data that are not modeled by this type.
+	Extra map[string]interface{} `json:"-"`
+}
+
+// ChannelOpeningFeesEvent is sent when fees are charged for opening a channel to a node. EntityId is the
+// id of the entity the fees were charged for.
+type ChannelOpeningFeesEvent struct {
+	WebhookEvent `json:"-"`
+
+	// Fees is the amount charged for opening the channel.
+	Fees *objects.CurrencyAmount `json:"fees"`
+
+	// Extra holds the fields of Data that are not modeled by this type.
+	Extra map[string]interface{} `json:"-"`
+}
+
+// UnknownEvent is returned by Decode for event types unknown to this version of the SDK.
+type UnknownEvent struct {
+	WebhookEvent `json:"-"`
+	// Extra holds every field of Data.
+	Extra map[string]interface{} `json:"-"`
+}
+
+// Decode returns the typed event matching EventType. Fields of Data that the typed event doesn't model are
+// kept in its Extra field, so that new fields sent by the server don't break parsing.
+func (e *WebhookEvent) Decode() (Event, error) {
+	var event Event
+	var extra *map[string]interface{}
+	switch e.EventType {
+	case objects.WebhookEventTypePaymentFinished:
+		typed := &PaymentFinishedEvent{WebhookEvent: *e}
+		event, extra = typed, &typed.Extra
+	case objects.WebhookEventTypeForceClosure:
+		typed := &ForceClosureEvent{WebhookEvent: *e}
+		event, extra = typed, &typed.Extra
+	case objects.WebhookEventTypeWithdrawalFinished:
+		typed := &WithdrawalFinishedEvent{WebhookEvent: *e}
+		event, extra = typed, &typed.Extra
+	case objects.WebhookEventTypeFundsReceived:
+		typed := &FundsReceivedEvent{WebhookEvent: *e}
+		event, extra = typed, &typed.Extra
+	case objects.WebhookEventTypeNodeStatus:
+		typed := &NodeStatusEvent{WebhookEvent: *e}
+		event, extra = typed, &typed.Extra
+	case objects.WebhookEventTypeUmaInvitationClaimed:
+		typed := &UmaInvitationClaimedEvent{WebhookEvent: *e}
+		event, extra = typed, &typed.Extra
+	case objects.WebhookEventTypeWalletStatus:
+		typed := &WalletStatusEvent{WebhookEvent: *e}
+		event, extra = typed, &typed.Extra
+	case objects.WebhookEventTypeWalletOutgoingPaymentFinished:
+		typed := &WalletOutgoingPaymentFinishedEvent{WebhookEvent: *e}
+		event, extra = typed, &typed.Extra
+	case objects.WebhookEventTypeWalletIncomingPaymentFinished:
+		typed := &WalletIncomingPaymentFinishedEvent{WebhookEvent: *e}
+		event, extra = typed, &typed.Extra
+	case objects.WebhookEventTypeWalletWithdrawalFinished:
+		typed := &WalletWithdrawalFinishedEvent{WebhookEvent: *e}
+		event, extra = typed, &typed.Extra
+	case objects.WebhookEventTypeWalletFundsReceived:
+		typed := &WalletFundsReceivedEvent{WebhookEvent: *e}
+		event, extra = typed, &typed.Extra
+	case objects.WebhookEventTypeRemoteSigning:
+		typed := &RemoteSigningEvent{WebhookEvent: *e}
+		event, extra = typed, &typed.Extra
+	case objects.WebhookEventTypeLowBalance:
+		typed := &LowBalanceEvent{WebhookEvent: *e}
+		event, extra = typed, &typed.Extra
+	case objects.WebhookEventTypeHighBalance:
+		typed := &HighBalanceEvent{WebhookEvent: *e}
+		event, extra = typed, &typed.Extra
+	case objects.WebhookEventTypeChannelOpeningFees:
+		typed := &ChannelOpeningFeesEvent{WebhookEvent: *e}
+		event, extra = typed, &typed.Extra
+	default:
+		typed := &UnknownEvent{WebhookEvent: *e}
+		event, extra = typed, &typed.Extra
+	}
+
+	if e.Data == nil {
+		return event, nil
+	}
+	encoded, err := json.Marshal(withCurrencyAmountAliases(*e.Data))
+	if err != nil {
+		return nil, err
+	}
+	if err := json.Unmarshal(encoded, event); err != nil {
+		return nil, err
+	}
+	*extra = unknownFields(*e.Data, reflect.TypeOf(event).Elem())
+	return event, nil
+}
+
+// withCurrencyAmountAliases returns a copy of data where the members shaped like a CurrencyAmount, such as
+// `{"original_value": 1000, "original_unit": "SATOSHI"}`, use the field names of objects.CurrencyAmount.
+func withCurrencyAmountAliases(data map[string]interface{}) map[string]interface{} {
+	aliased := make(map[string]interface{}, len(data))
+	for key, value := range data {
+		aliased[key] = value
+		amount, ok := value.(map[string]interface{})
+		if !ok || amount["original_value"] == nil || amount["original_unit"] == nil {
+			continue
+		}
+		fields := make(map[string]interface{}, len(amount))
+		for name, field := range amount {
+			fields["currency_amount_"+name] = field
+		}
+		aliased[key] = fields
+	}
+	return aliased
+}
+
+// unknownFields returns the members of data that don't match a JSON field of the struct type.
+func unknownFields(data map[string]interface{}, structType reflect.Type) map[string]interface{} {
+	known := map[string]bool{}
+	for i := 0; i < structType.NumField(); i++ {
+		name, _, _ := strings.Cut(structType.Field(i).Tag.Get("json"), ",")
+		if name != "" && name != "-" {
+			known[name] = true
+		}
+	}
+
+	var extra map[string]interface{}
+	for key, value := range data {
+		if !known[key] {
+			if extra == nil {
+				extra = map[string]interface{}{}
+			}
+			extra[key] = value
+		}
+	}
+	return extra
+}
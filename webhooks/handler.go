@@ -0,0 +1,289 @@
+// Copyright ©, 2023-present, Lightspark Group, Inc. - All Rights Reserved
+package webhooks
+
+import (
+	"container/list"
+	"context"
+	"io"
+	"log/slog"
+	"net/http"
+	"sync"
+	"time"
+
+	"github.com/lightsparkdev/go-sdk/objects"
+)
+
+const (
+	// DefaultTimestampTolerance is the maximum difference between the timestamp of an event and the
+	// current time accepted by a Handler.
+	DefaultTimestampTolerance = 5 * time.Minute
+
+	// DefaultDedupCapacity is the number of event ids remembered by the default dedup store of a Handler.
+	DefaultDedupCapacity = 10_000
+
+	maxBodySize = 1 << 20
+)
+
+// HandlerFunc handles a verified webhook event. Returning an error makes the Handler answer with a 500
+// status code, so that Lightspark delivers the event again later.
+type HandlerFunc func(ctx context.Context, event Event) error
+
+// DedupStore remembers the ids of the events a Handler has processed, so that redeliveries are only
+// handled once. Implementations must be safe for concurrent use. Use a shared store, such as a database
+// table, when several instances of the webhook server run behind a load balancer.
+type DedupStore interface {
+	// Add records eventId. It returns false if eventId was already recorded.
+	Add(ctx context.Context, eventId string) (bool, error)
+	// Remove forgets eventId. It is called when handling the event failed, so that the redelivery is
+	// handled again.
+	Remove(ctx context.Context, eventId string) error
+}
+
+// MemoryDedupStore is an in-memory DedupStore that remembers the most recent event ids.
+type MemoryDedupStore struct {
+	capacity int
+
+	mu      sync.Mutex
+	order   *list.List
+	entries map[string]*list.Element
+}
+
+// NewMemoryDedupStore creates a store remembering up to capacity event ids, evicting the least recently
+// added ones first.
+func NewMemoryDedupStore(capacity int) *MemoryDedupStore {
+	if capacity <= 0 {
+		capacity = DefaultDedupCapacity
+	}
+	return &MemoryDedupStore{capacity: capacity, order: list.New(), entries: map[string]*list.Element{}}
+}
+
+// Add implements DedupStore.
+func (s *MemoryDedupStore) Add(ctx context.Context, eventId string) (bool, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if element, ok := s.entries[eventId]; ok {
+		s.order.MoveToFront(element)
+		return false, nil
+	}
+	s.entries[eventId] = s.order.PushFront(eventId)
+	if s.order.Len() > s.capacity {
+		oldest := s.order.Back()
+		s.order.Remove(oldest)
+		delete(s.entries, oldest.Value.(string))
+	}
+	return true, nil
+}
+
+// Remove implements DedupStore.
+func (s *MemoryDedupStore) Remove(ctx context.Context, eventId string) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if element, ok := s.entries[eventId]; ok {
+		s.order.Remove(element)
+		delete(s.entries, eventId)
+	}
+	return nil
+}
+
+// HandlerOption configures a Handler.
+type HandlerOption func(*Handler)
+
+// WithTimestampTolerance sets the maximum difference between the timestamp of an event and the current
+// time. Older or newer events are rejected to prevent replays. Use 0 to disable the check.
+func WithTimestampTolerance(tolerance time.Duration) HandlerOption {
+	return func(h *Handler) {
+		h.tolerance = tolerance
+	}
+}
+
+// WithDedupStore sets the store used to deduplicate events on their id. Use nil to disable
+// deduplication.
+func WithDedupStore(store DedupStore) HandlerOption {
+	return func(h *Handler) {
+		h.store = store
+	}
+}
+
+// WithHandlerLogger sets the logger receiving the rejected and failed events. slog.Default() is used
+// otherwise.
+func WithHandlerLogger(logger *slog.Logger) HandlerOption {
+	return func(h *Handler) {
+		h.logger = logger
+	}
+}
+
+// WithSecondarySecret sets the previous webhook secret, accepted until expiresAt while the secret is
+// rotated. A zero expiresAt keeps it accepted until the option is removed.
+func WithSecondarySecret(secret string, expiresAt time.Time) HandlerOption {
+	return func(h *Handler) {
+		h.secrets.Secondary = secret
+		h.secrets.SecondaryExpiresAt = expiresAt
+	}
+}
+
+// WithClock sets the function returning the current time, for tests.
+func WithClock(now func() time.Time) HandlerOption {
+	return func(h *Handler) {
+		h.now = now
+	}
+}
+
+// Handler is an http.Handler receiving Lightspark webhooks. It verifies the signature and the timestamp of
+// every event, drops the events it has already handled, and routes the others to the HandlerFunc
+// registered for their type:
+//
+//	handler := webhooks.NewHandler(webhookSecret)
+//	handler.Handle(objects.WebhookEventTypePaymentFinished, func(ctx context.Context, event webhooks.Event) error {
+//		payment := event.(*webhooks.PaymentFinishedEvent)
+//		...
+//	})
+//	http.Handle("/webhooks", handler)
+//
+// It answers with:
+//   - 204 when the event was handled, was a duplicate, or has no registered HandlerFunc,
+//   - 400 when the body is not a valid event or its timestamp is outside of the tolerance,
+//   - 401 when the signature is missing or invalid,
+//   - 405 for other methods than POST,
+//   - 500 when the HandlerFunc or the DedupStore fails, so that Lightspark retries the delivery.
+type Handler struct {
+	secrets   Secrets
+	tolerance time.Duration
+	store     DedupStore
+	logger    *slog.Logger
+	now       func() time.Time
+
+	mu       sync.RWMutex
+	handlers map[objects.WebhookEventType]HandlerFunc
+	fallback HandlerFunc
+}
+
+// NewHandler creates a Handler verifying events with the webhook secret of the Lightspark API
+// configuration.
+func NewHandler(secret string, opts ...HandlerOption) *Handler {
+	h := &Handler{
+		secrets:   Secrets{Primary: secret},
+		tolerance: DefaultTimestampTolerance,
+		store:     NewMemoryDedupStore(DefaultDedupCapacity),
+		now:       time.Now,
+		handlers:  map[objects.WebhookEventType]HandlerFunc{},
+	}
+	for _, opt := range opts {
+		opt(h)
+	}
+	return h
+}
+
+// Handle registers the HandlerFunc of an event type, replacing the previous one.
+func (h *Handler) Handle(eventType objects.WebhookEventType, handler HandlerFunc) {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+	h.handlers[eventType] = handler
+}
+
+// HandleDefault registers the HandlerFunc of the event types without a HandlerFunc of their own.
+func (h *Handler) HandleDefault(handler HandlerFunc) {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+	h.fallback = handler
+}
+
+func (h *Handler) handlerFor(eventType objects.WebhookEventType) HandlerFunc {
+	h.mu.RLock()
+	defer h.mu.RUnlock()
+	if handler, ok := h.handlers[eventType]; ok {
+		return handler
+	}
+	return h.fallback
+}
+
+func (h *Handler) log() *slog.Logger {
+	if h.logger == nil {
+		return slog.Default()
+	}
+	return h.logger
+}
+
+// ServeHTTP implements http.Handler.
+func (h *Handler) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	ctx := r.Context()
+	if r.Method != http.MethodPost {
+		w.Header().Set("Allow", http.MethodPost)
+		http.Error(w, "method not allowed", http.StatusMethodNotAllowed)
+		return
+	}
+
+	signature := r.Header.Get(SIGNATURE_HEADER)
+	if signature == "" {
+		h.log().WarnContext(ctx, "webhook rejected: missing signature")
+		http.Error(w, "missing signature", http.StatusUnauthorized)
+		return
+	}
+	body, err := io.ReadAll(http.MaxBytesReader(w, r.Body, maxBodySize))
+	if err != nil {
+		http.Error(w, "invalid body", http.StatusBadRequest)
+		return
+	}
+	if !h.secrets.verify(body, signature, h.now()) {
+		h.log().WarnContext(ctx, "webhook rejected: invalid signature")
+		http.Error(w, "invalid signature", http.StatusUnauthorized)
+		return
+	}
+	webhookEvent, err := Parse(body)
+	if err != nil {
+		h.log().WarnContext(ctx, "webhook rejected: invalid event", slog.Any("error", err))
+		http.Error(w, "invalid event", http.StatusBadRequest)
+		return
+	}
+
+	logger := h.log().With(
+		slog.String("event_id", webhookEvent.EventId),
+		slog.String("event_type", webhookEvent.EventType.StringValue()),
+	)
+	if h.tolerance > 0 {
+		age := h.now().Sub(webhookEvent.Timestamp)
+		if age > h.tolerance || age < -h.tolerance {
+			logger.WarnContext(ctx, "webhook rejected: timestamp outside of tolerance",
+				slog.Time("timestamp", webhookEvent.Timestamp))
+			http.Error(w, "timestamp outside of tolerance", http.StatusBadRequest)
+			return
+		}
+	}
+
+	handler := h.handlerFor(webhookEvent.EventType)
+	if handler == nil {
+		w.WriteHeader(http.StatusNoContent)
+		return
+	}
+	event, err := webhookEvent.Decode()
+	if err != nil {
+		logger.WarnContext(ctx, "webhook rejected: invalid event data", slog.Any("error", err))
+		http.Error(w, "invalid event data", http.StatusBadRequest)
+		return
+	}
+
+	if h.store != nil {
+		added, err := h.store.Add(ctx, webhookEvent.EventId)
+		if err != nil {
+			logger.ErrorContext(ctx, "webhook dedup store failed", slog.Any("error", err))
+			http.Error(w, "internal error", http.StatusInternalServerError)
+			return
+		}
+		if !added {
+			logger.InfoContext(ctx, "webhook ignored: duplicate event")
+			w.WriteHeader(http.StatusNoContent)
+			return
+		}
+	}
+
+	if err := handler(ctx, event); err != nil {
+		logger.ErrorContext(ctx, "webhook handler failed", slog.Any("error", err))
+		if h.store != nil {
+			if removeErr := h.store.Remove(ctx, webhookEvent.EventId); removeErr != nil {
+				logger.ErrorContext(ctx, "webhook dedup store failed", slog.Any("error", removeErr))
+			}
+		}
+		http.Error(w, "handler failed", http.StatusInternalServerError)
+		return
+	}
+	w.WriteHeader(http.StatusNoContent)
+}
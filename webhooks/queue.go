@@ -0,0 +1,457 @@
+// Copyright ©, 2023-present, Lightspark Group, Inc. - All Rights Reserved
+package webhooks
+
+import (
+	"bufio"
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"sort"
+	"strconv"
+	"sync"
+	"time"
+)
+
+// ErrQueueClosed is returned by the operations of a closed Queue.
+var ErrQueueClosed = errors.New("webhook queue is closed")
+
+// QueueEntry is an event stored in a Queue.
+type QueueEntry struct {
+	// Id identifies the entry in the queue. It is unique even when Lightspark delivers an event twice.
+	Id    string
+	Event *WebhookEvent
+}
+
+// Queue stores the events accepted by a Dispatcher until they are processed. Implementations must be safe
+// for concurrent use.
+type Queue interface {
+	// Push appends an event to the queue. The event must be stored durably when Push returns, since the
+	// webhook is acknowledged right after.
+	Push(ctx context.Context, event *WebhookEvent) error
+	// Pop returns the oldest entry not returned yet, waiting until there is one or ctx is done.
+	Pop(ctx context.Context) (*QueueEntry, error)
+	// Ack removes a processed entry from the queue.
+	Ack(ctx context.Context, id string) error
+}
+
+// MemoryQueue is a Queue keeping the events in memory. Queued events are lost when the process exits.
+type MemoryQueue struct {
+	mu      sync.Mutex
+	nextId  uint64
+	pending []*QueueEntry
+	notify  chan struct{}
+	closed  bool
+	// inflight holds the entries popped but not acked yet, when they are tracked by a FileQueue.
+	inflight map[string]*QueueEntry
+}
+
+// NewMemoryQueue creates an empty MemoryQueue.
+func NewMemoryQueue() *MemoryQueue {
+	return &MemoryQueue{notify: make(chan struct{}, 1)}
+}
+
+// Push implements Queue.
+func (q *MemoryQueue) Push(ctx context.Context, event *WebhookEvent) error {
+	q.mu.Lock()
+	defer q.mu.Unlock()
+	if q.closed {
+		return ErrQueueClosed
+	}
+	q.append(&QueueEntry{Id: strconv.FormatUint(q.nextId, 10), Event: event})
+	return nil
+}
+
+func (q *MemoryQueue) append(entry *QueueEntry) {
+	q.nextId++
+	q.pending = append(q.pending, entry)
+	select {
+	case q.notify <- struct{}{}:
+	default:
+	}
+}
+
+// Pop implements Queue.
+func (q *MemoryQueue) Pop(ctx context.Context) (*QueueEntry, error) {
+	for {
+		q.mu.Lock()
+		if q.closed {
+			q.mu.Unlock()
+			return nil, ErrQueueClosed
+		}
+		if len(q.pending) > 0 {
+			entry := q.pending[0]
+			q.pending[0] = nil
+			q.pending = q.pending[1:]
+			if q.inflight != nil {
+				q.inflight[entry.Id] = entry
+			}
+			if len(q.pending) > 0 {
+				// Wake up the next waiting Pop.
+				select {
+				case q.notify <- struct{}{}:
+				default:
+				}
+			}
+			q.mu.Unlock()
+			return entry, nil
+		}
+		q.mu.Unlock()
+
+		select {
+		case <-q.notify:
+		case <-ctx.Done():
+			return nil, ctx.Err()
+		}
+	}
+}
+
+// Ack implements Queue. Entries are removed from a MemoryQueue when they are popped.
+func (q *MemoryQueue) Ack(ctx context.Context, id string) error {
+	return nil
+}
+
+// requeue returns entries popped but not processed to the front of the queue, in order.
+func (q *MemoryQueue) requeue(entries []*QueueEntry) {
+	q.mu.Lock()
+	defer q.mu.Unlock()
+	if q.closed || len(entries) == 0 {
+		return
+	}
+	for _, entry := range entries {
+		delete(q.inflight, entry.Id)
+	}
+	q.pending = append(append([]*QueueEntry(nil), entries...), q.pending...)
+	select {
+	case q.notify <- struct{}{}:
+	default:
+	}
+}
+
+// Len returns the number of entries not popped yet.
+func (q *MemoryQueue) Len() int {
+	q.mu.Lock()
+	defer q.mu.Unlock()
+	return len(q.pending)
+}
+
+// Close makes the pending and future operations fail with ErrQueueClosed.
+func (q *MemoryQueue) Close() error {
+	q.mu.Lock()
+	defer q.mu.Unlock()
+	if !q.closed {
+		q.closed = true
+		close(q.notify)
+	}
+	return nil
+}
+
+// FileQueue is a Queue backed by an append-only file, so that the events accepted but not processed yet
+// survive a restart. Every push and ack is appended to the file as a JSON line. Opening the queue replays
+// the file and returns the entries never acked again. The file is compacted when the queue is opened and
+// every DefaultCompactThreshold acks.
+type FileQueue struct {
+	memory           MemoryQueue
+	path             string
+	compactThreshold int
+	// acked is the number of ack records written since the file was last compacted.
+	acked int
+
+	fileMu sync.Mutex
+	file   *os.File
+	// size is the length of the file up to its last complete record.
+	size int64
+}
+
+// DefaultCompactThreshold is the number of acks after which a FileQueue rewrites its file with the
+// entries not acked yet.
+const DefaultCompactThreshold = 1000
+
+// FileQueueOption configures a FileQueue.
+type FileQueueOption func(*FileQueue)
+
+// WithCompactThreshold sets the number of acks after which the file of a FileQueue is compacted.
+func WithCompactThreshold(threshold int) FileQueueOption {
+	return func(q *FileQueue) {
+		q.compactThreshold = threshold
+	}
+}
+
+type fileQueueRecord struct {
+	Op    string          `json:"op"`
+	Id    string          `json:"id"`
+	Event json.RawMessage `json:"event,omitempty"`
+}
+
+const (
+	fileQueuePush = "push"
+	fileQueueAck  = "ack"
+)
+
+// OpenFileQueue opens the FileQueue stored at path, creating it if needed.
+func OpenFileQueue(path string, opts ...FileQueueOption) (*FileQueue, error) {
+	q := &FileQueue{
+		memory: MemoryQueue{
+			notify:   make(chan struct{}, 1),
+			inflight: map[string]*QueueEntry{},
+		},
+		path:             path,
+		compactThreshold: DefaultCompactThreshold,
+	}
+	for _, opt := range opts {
+		opt(q)
+	}
+	if err := q.replay(path); err != nil {
+		return nil, err
+	}
+	if err := q.compact(); err != nil {
+		return nil, err
+	}
+	return q, nil
+}
+
+func (q *FileQueue) replay(path string) error {
+	data, err := os.ReadFile(path)
+	if errors.Is(err, os.ErrNotExist) {
+		return nil
+	}
+	if err != nil {
+		return err
+	}
+
+	var pending []*QueueEntry
+	var maxId uint64
+	lines := bytes.Split(data, []byte("\n"))
+	for i, line := range lines {
+		if len(bytes.TrimSpace(line)) == 0 {
+			continue
+		}
+		var record fileQueueRecord
+		if err := json.Unmarshal(line, &record); err != nil {
+			if i == len(lines)-1 {
+				// The process stopped in the middle of the last write, which was never acknowledged.
+				break
+			}
+			return fmt.Errorf("invalid webhook queue record on line %d: %w", i+1, err)
+		}
+		if id, err := strconv.ParseUint(record.Id, 10, 64); err == nil && id >= maxId {
+			maxId = id + 1
+		}
+		switch record.Op {
+		case fileQueuePush:
+			event, err := Parse(record.Event)
+			if err != nil {
+				return fmt.Errorf("invalid webhook queue event on line %d: %w", i+1, err)
+			}
+			pending = append(pending, &QueueEntry{Id: record.Id, Event: event})
+		case fileQueueAck:
+			for j, entry := range pending {
+				if entry.Id == record.Id {
+					pending = append(pending[:j], pending[j+1:]...)
+					break
+				}
+			}
+		default:
+			return fmt.Errorf("invalid webhook queue operation %q on line %d", record.Op, i+1)
+		}
+	}
+	q.memory.nextId = maxId
+	for _, entry := range pending {
+		q.memory.append(entry)
+	}
+	return nil
+}
+
+// compact rewrites the file with the entries not acked yet, and reopens it. The lock of the memory queue
+// must be held, or the queue not shared yet.
+func (q *FileQueue) compact() error {
+	temp, err := os.CreateTemp(filepath.Dir(q.path), filepath.Base(q.path)+".*.tmp")
+	if err != nil {
+		return err
+	}
+	defer os.Remove(temp.Name())
+
+	// Entries popped but not acked are older than the pending ones.
+	entries := make([]*QueueEntry, 0, len(q.memory.inflight)+len(q.memory.pending))
+	for _, entry := range q.memory.inflight {
+		entries = append(entries, entry)
+	}
+	sort.Slice(entries, func(i, j int) bool {
+		return entryOrder(entries[i]) < entryOrder(entries[j])
+	})
+	entries = append(entries, q.memory.pending...)
+
+	writer := bufio.NewWriter(temp)
+	var size int64
+	for _, entry := range entries {
+		line, err := pushRecord(entry)
+		if err != nil {
+			temp.Close()
+			return err
+		}
+		writer.Write(line)
+		size += int64(len(line))
+	}
+	if err := writer.Flush(); err != nil {
+		temp.Close()
+		return err
+	}
+	if err := temp.Sync(); err != nil {
+		temp.Close()
+		return err
+	}
+	if err := temp.Close(); err != nil {
+		return err
+	}
+	if err := os.Rename(temp.Name(), q.path); err != nil {
+		return err
+	}
+
+	file, err := os.OpenFile(q.path, os.O_WRONLY|os.O_APPEND, 0o600)
+	if err != nil {
+		return err
+	}
+	q.fileMu.Lock()
+	defer q.fileMu.Unlock()
+	if q.file != nil {
+		q.file.Close()
+	}
+	q.file = file
+	q.size = size
+	q.acked = 0
+	return nil
+}
+
+// entryOrder returns the position of an entry in the queue, from its id.
+func entryOrder(entry *QueueEntry) uint64 {
+	order, _ := strconv.ParseUint(entry.Id, 10, 64)
+	return order
+}
+
+func pushRecord(entry *QueueEntry) ([]byte, error) {
+	event, err := marshalEvent(entry.Event)
+	if err != nil {
+		return nil, err
+	}
+	line, err := json.Marshal(fileQueueRecord{Op: fileQueuePush, Id: entry.Id, Event: event})
+	if err != nil {
+		return nil, err
+	}
+	return append(line, '\n'), nil
+}
+
+// write appends a record to the file. A record that is not completely written is truncated, so that the
+// next record doesn't continue its line.
+func (q *FileQueue) write(line []byte) error {
+	q.fileMu.Lock()
+	defer q.fileMu.Unlock()
+	if q.file == nil {
+		return ErrQueueClosed
+	}
+	_, err := q.file.Write(line)
+	if err == nil {
+		err = q.file.Sync()
+	}
+	if err != nil {
+		if truncateErr := q.file.Truncate(q.size); truncateErr != nil {
+			// Appending after a partial record would corrupt the file, so stop writing to it.
+			q.file.Close()
+			q.file = nil
+			return errors.Join(err, truncateErr)
+		}
+		return err
+	}
+	q.size += int64(len(line))
+	return nil
+}
+
+// Push implements Queue. It returns once the event is synced to disk.
+func (q *FileQueue) Push(ctx context.Context, event *WebhookEvent) error {
+	// The lock of the memory queue is held while writing, so that the ids are written in order.
+	q.memory.mu.Lock()
+	defer q.memory.mu.Unlock()
+	if q.memory.closed {
+		return ErrQueueClosed
+	}
+	entry := &QueueEntry{Id: strconv.FormatUint(q.memory.nextId, 10), Event: event}
+	line, err := pushRecord(entry)
+	if err != nil {
+		return err
+	}
+	if err := q.write(line); err != nil {
+		return err
+	}
+	q.memory.append(entry)
+	return nil
+}
+
+// Pop implements Queue.
+func (q *FileQueue) Pop(ctx context.Context) (*QueueEntry, error) {
+	return q.memory.Pop(ctx)
+}
+
+// Ack implements Queue. Entries popped but not acked are returned again when the queue is reopened.
+func (q *FileQueue) Ack(ctx context.Context, id string) error {
+	line, err := json.Marshal(fileQueueRecord{Op: fileQueueAck, Id: id})
+	if err != nil {
+		return err
+	}
+	q.memory.mu.Lock()
+	defer q.memory.mu.Unlock()
+	if q.memory.closed {
+		return ErrQueueClosed
+	}
+	if err := q.write(append(line, '\n')); err != nil {
+		return err
+	}
+	delete(q.memory.inflight, id)
+	q.acked++
+	if q.compactThreshold > 0 && q.acked >= q.compactThreshold {
+		if err := q.compact(); err != nil {
+			return fmt.Errorf("compacting webhook queue: %w", err)
+		}
+	}
+	return nil
+}
+
+func (q *FileQueue) requeue(entries []*QueueEntry) {
+	q.memory.requeue(entries)
+}
+
+// Len returns the number of entries not popped yet.
+func (q *FileQueue) Len() int {
+	return q.memory.Len()
+}
+
+// Close closes the file. Pending and future operations fail with ErrQueueClosed.
+func (q *FileQueue) Close() error {
+	q.memory.Close()
+	q.fileMu.Lock()
+	defer q.fileMu.Unlock()
+	if q.file == nil {
+		return nil
+	}
+	err := q.file.Close()
+	q.file = nil
+	return err
+}
+
+// marshalEvent encodes an event as it is sent by Lightspark, so that Parse can decode it.
+func marshalEvent(event *WebhookEvent) ([]byte, error) {
+	encoded := map[string]interface{}{
+		"event_type": event.EventType.StringValue(),
+		"event_id":   event.EventId,
+		"timestamp":  event.Timestamp.Format(time.RFC3339Nano),
+		"entity_id":  event.EntityId,
+	}
+	if event.WalletId != nil {
+		encoded["wallet_id"] = *event.WalletId
+	}
+	if event.Data != nil {
+		encoded["data"] = *event.Data
+	}
+	return json.Marshal(encoded)
+}
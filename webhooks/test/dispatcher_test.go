@@ -0,0 +1,258 @@
+package webhooks_test
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+	"sync"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/lightsparkdev/go-sdk/objects"
+	"github.com/lightsparkdev/go-sdk/webhooks"
+	"github.com/stretchr/testify/require"
+)
+
+func queuedEvent(eventId string, nodeId string) *webhooks.WebhookEvent {
+	data := map[string]interface{}{"sub_event_type": "ECDH", "node_id": nodeId}
+	return &webhooks.WebhookEvent{
+		EventType: objects.WebhookEventTypeRemoteSigning,
+		EventId:   eventId,
+		Timestamp: time.Date(2023, 5, 17, 23, 56, 47, 874449000, time.UTC),
+		EntityId:  "channel:" + eventId,
+		Data:      &data,
+	}
+}
+
+func runDispatcher(t *testing.T, dispatcher *webhooks.Dispatcher) func() {
+	ctx, cancel := context.WithCancel(context.Background())
+	done := make(chan error)
+	go func() {
+		done <- dispatcher.Run(ctx)
+	}()
+	return func() {
+		cancel()
+		require.NoError(t, <-done)
+	}
+}
+
+func TestDispatcherOrdersEventsPerNode(t *testing.T) {
+	var mu sync.Mutex
+	processed := map[string][]string{}
+	var running, maxRunning atomic.Int32
+	var wg sync.WaitGroup
+	dispatcher := webhooks.NewDispatcher(func(ctx context.Context, event webhooks.Event) error {
+		defer wg.Done()
+		current := running.Add(1)
+		defer running.Add(-1)
+		for {
+			observed := maxRunning.Load()
+			if current <= observed || maxRunning.CompareAndSwap(observed, current) {
+				break
+			}
+		}
+		time.Sleep(time.Millisecond)
+
+		remoteSigning := event.(*webhooks.RemoteSigningEvent)
+		mu.Lock()
+		defer mu.Unlock()
+		processed[*remoteSigning.NodeId] = append(processed[*remoteSigning.NodeId], remoteSigning.EventId)
+		return nil
+	}, webhooks.WithConcurrency(2))
+	stop := runDispatcher(t, dispatcher)
+	defer stop()
+
+	nodes := []string{"node:1", "node:2", "node:3", "node:4"}
+	expected := map[string][]string{}
+	for i := 0; i < 10; i++ {
+		for _, node := range nodes {
+			eventId := fmt.Sprintf("%s-%d", node, i)
+			expected[node] = append(expected[node], eventId)
+			wg.Add(1)
+			require.NoError(t, dispatcher.Enqueue(context.Background(), queuedEvent(eventId, node)))
+		}
+	}
+	wg.Wait()
+
+	mu.Lock()
+	defer mu.Unlock()
+	require.Equal(t, expected, processed)
+	require.LessOrEqual(t, maxRunning.Load(), int32(2))
+}
+
+func TestDispatcherRetriesAndDeadLetters(t *testing.T) {
+	attempts := map[string]int{}
+	var mu sync.Mutex
+	type deadLetter struct {
+		eventId string
+		err     error
+	}
+	deadLetters := make(chan deadLetter, 1)
+	succeeded := make(chan string, 1)
+	dispatcher := webhooks.NewDispatcher(func(ctx context.Context, event webhooks.Event) error {
+		eventId := event.Envelope().EventId
+		mu.Lock()
+		attempts[eventId]++
+		attempt := attempts[eventId]
+		mu.Unlock()
+		if eventId == "flaky" && attempt == 2 {
+			succeeded <- eventId
+			return nil
+		}
+		return errors.New("signer unavailable")
+	},
+		webhooks.WithMaxAttempts(3),
+		webhooks.WithBackoff(time.Millisecond, 2*time.Millisecond),
+		webhooks.WithDeadLetterSink(webhooks.DeadLetterFunc(func(ctx context.Context, event *webhooks.WebhookEvent, err error) error {
+			deadLetters <- deadLetter{event.EventId, err}
+			return nil
+		})),
+	)
+	stop := runDispatcher(t, dispatcher)
+	defer stop()
+
+	require.NoError(t, dispatcher.Enqueue(context.Background(), queuedEvent("flaky", "node:1")))
+	require.NoError(t, dispatcher.Enqueue(context.Background(), queuedEvent("broken", "node:2")))
+	require.Equal(t, "flaky", <-succeeded)
+	failed := <-deadLetters
+	require.Equal(t, "broken", failed.eventId)
+	require.ErrorContains(t, failed.err, "signer unavailable")
+
+	mu.Lock()
+	defer mu.Unlock()
+	require.Equal(t, map[string]int{"flaky": 2, "broken": 3}, attempts)
+}
+
+func TestDispatcherRequeuesWhenDeadLetterSinkFails(t *testing.T) {
+	var attempts atomic.Int32
+	var sinkCalls atomic.Int32
+	deadLettered := make(chan string, 1)
+	dispatcher := webhooks.NewDispatcher(func(ctx context.Context, event webhooks.Event) error {
+		attempts.Add(1)
+		return errors.New("signer unavailable")
+	},
+		webhooks.WithMaxAttempts(2),
+		webhooks.WithBackoff(time.Millisecond, time.Millisecond),
+		webhooks.WithDeadLetterSink(webhooks.DeadLetterFunc(func(ctx context.Context, event *webhooks.WebhookEvent, err error) error {
+			if sinkCalls.Add(1) == 1 {
+				return errors.New("sink unavailable")
+			}
+			deadLettered <- event.EventId
+			return nil
+		})),
+	)
+	stop := runDispatcher(t, dispatcher)
+	defer stop()
+
+	require.NoError(t, dispatcher.Enqueue(context.Background(), queuedEvent("broken", "node:1")))
+	require.Equal(t, "broken", <-deadLettered)
+	require.Equal(t, int32(4), attempts.Load())
+	require.Equal(t, int32(2), sinkCalls.Load())
+}
+
+func TestFileQueueSurvivesRestart(t *testing.T) {
+	ctx := context.Background()
+	path := filepath.Join(t.TempDir(), "webhooks.queue")
+	queue, err := webhooks.OpenFileQueue(path)
+	require.NoError(t, err)
+	for _, eventId := range []string{"1", "2", "3"} {
+		require.NoError(t, queue.Push(ctx, queuedEvent(eventId, "node:1")))
+	}
+	first, err := queue.Pop(ctx)
+	require.NoError(t, err)
+	require.Equal(t, "1", first.Event.EventId)
+	require.NoError(t, queue.Ack(ctx, first.Id))
+	// Popped but not acked, so it is returned again after a restart.
+	second, err := queue.Pop(ctx)
+	require.NoError(t, err)
+	require.NoError(t, queue.Close())
+	require.ErrorIs(t, queue.Push(ctx, queuedEvent("4", "node:1")), webhooks.ErrQueueClosed)
+
+	// Simulate a crash in the middle of a write.
+	file, err := os.OpenFile(path, os.O_WRONLY|os.O_APPEND, 0o600)
+	require.NoError(t, err)
+	_, err = file.WriteString(`{"op":"push","id":"9","ev`)
+	require.NoError(t, err)
+	require.NoError(t, file.Close())
+
+	queue, err = webhooks.OpenFileQueue(path)
+	require.NoError(t, err)
+	defer queue.Close()
+	require.Equal(t, 2, queue.Len())
+	replayed, err := queue.Pop(ctx)
+	require.NoError(t, err)
+	require.Equal(t, second.Id, replayed.Id)
+	require.Equal(t, second.Event, replayed.Event)
+
+	require.NoError(t, queue.Push(ctx, queuedEvent("4", "node:1")))
+	third, err := queue.Pop(ctx)
+	require.NoError(t, err)
+	require.Equal(t, "3", third.Event.EventId)
+	fourth, err := queue.Pop(ctx)
+	require.NoError(t, err)
+	require.Equal(t, "4", fourth.Event.EventId)
+	require.NotEqual(t, third.Id, fourth.Id)
+}
+
+func TestDispatcherReturnsUnprocessedEventsToTheQueue(t *testing.T) {
+	queue := webhooks.NewMemoryQueue()
+	started := make(chan struct{}, 1)
+	dispatcher := webhooks.NewDispatcher(func(ctx context.Context, event webhooks.Event) error {
+		started <- struct{}{}
+		<-ctx.Done()
+		return ctx.Err()
+	}, webhooks.WithQueue(queue))
+	stop := runDispatcher(t, dispatcher)
+
+	for i := 0; i < 5; i++ {
+		require.NoError(t, dispatcher.Enqueue(context.Background(), queuedEvent(fmt.Sprint(i), "node:1")))
+	}
+	<-started
+	stop()
+
+	require.Equal(t, 5, queue.Len())
+	for i := 0; i < 5; i++ {
+		entry, err := queue.Pop(context.Background())
+		require.NoError(t, err)
+		require.Equal(t, fmt.Sprint(i), entry.Event.EventId)
+	}
+}
+
+func TestFileQueueCompactsAckedEntries(t *testing.T) {
+	ctx := context.Background()
+	path := filepath.Join(t.TempDir(), "webhooks.queue")
+	queue, err := webhooks.OpenFileQueue(path, webhooks.WithCompactThreshold(2))
+	require.NoError(t, err)
+	for _, eventId := range []string{"1", "2", "3", "4"} {
+		require.NoError(t, queue.Push(ctx, queuedEvent(eventId, "node:1")))
+	}
+	// The first entry is being processed while the next ones are acked.
+	first, err := queue.Pop(ctx)
+	require.NoError(t, err)
+	for i := 0; i < 2; i++ {
+		entry, err := queue.Pop(ctx)
+		require.NoError(t, err)
+		require.NoError(t, queue.Ack(ctx, entry.Id))
+	}
+
+	data, err := os.ReadFile(path)
+	require.NoError(t, err)
+	require.Equal(t, 2, strings.Count(string(data), "\n"), "only the entries not acked are left")
+
+	require.NoError(t, queue.Push(ctx, queuedEvent("5", "node:1")))
+	require.NoError(t, queue.Close())
+	queue, err = webhooks.OpenFileQueue(path)
+	require.NoError(t, err)
+	defer queue.Close()
+	var eventIds []string
+	for queue.Len() > 0 {
+		entry, err := queue.Pop(ctx)
+		require.NoError(t, err)
+		eventIds = append(eventIds, entry.Event.EventId)
+	}
+	require.Equal(t, []string{first.Event.EventId, "4", "5"}, eventIds)
+}
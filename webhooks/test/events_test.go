@@ -0,0 +1,102 @@
+package webhooks_test
+
+import (
+	"encoding/json"
+	"testing"
+
+	"github.com/lightsparkdev/go-sdk/objects"
+	"github.com/lightsparkdev/go-sdk/webhooks"
+	"github.com/stretchr/testify/require"
+)
+
+func TestDecodeRemoteSigningEvent(t *testing.T) {
+	data := `{"event_type": "REMOTE_SIGNING", "event_id": "8be9c360a68e420b9126b43ff6007a32", "timestamp": "2023-08-10T02:14:27.559234+00:00", "entity_id": "node_with_server_signing:0189d6bc-558d-88df-0000-502f04e71816", "data": {"sub_event_type": "GET_PER_COMMITMENT_POINT", "bitcoin_network": "TESTNET", "derivation_path": "m/3/2104864975", "per_commitment_point_idx": 281474976710654, "new_server_field": "value"}}`
+	parsed, err := webhooks.Parse([]byte(data))
+	require.NoError(t, err)
+
+	event, err := parsed.Decode()
+	require.NoError(t, err)
+	remoteSigning, ok := event.(*webhooks.RemoteSigningEvent)
+	require.True(t, ok)
+	require.Equal(t, objects.RemoteSigningSubEventTypeGetPerCommitmentPoint, remoteSigning.SubEventType)
+	require.Equal(t, objects.BitcoinNetworkTestnet, remoteSigning.BitcoinNetwork)
+	require.Equal(t, "m/3/2104864975", *remoteSigning.DerivationPath)
+	require.Equal(t, uint64(281474976710654), *remoteSigning.PerCommitmentPointIdx)
+	require.Nil(t, remoteSigning.PerCommitmentSecret)
+	require.Equal(t, map[string]interface{}{"new_server_field": "value"}, remoteSigning.Extra)
+	require.Equal(t, "node_with_server_signing:0189d6bc-558d-88df-0000-502f04e71816", remoteSigning.EntityId)
+	require.Same(t, &remoteSigning.WebhookEvent, event.Envelope())
+}
+
+func TestDecodeEventTypes(t *testing.T) {
+	events := map[string]webhooks.Event{
+		"PAYMENT_FINISHED":                 &webhooks.PaymentFinishedEvent{},
+		"FORCE_CLOSURE":                    &webhooks.ForceClosureEvent{},
+		"WITHDRAWAL_FINISHED":              &webhooks.WithdrawalFinishedEvent{},
+		"FUNDS_RECEIVED":                   &webhooks.FundsReceivedEvent{},
+		"NODE_STATUS":                      &webhooks.NodeStatusEvent{},
+		"UMA_INVITATION_CLAIMED":           &webhooks.UmaInvitationClaimedEvent{},
+		"WALLET_STATUS":                    &webhooks.WalletStatusEvent{},
+		"WALLET_OUTGOING_PAYMENT_FINISHED": &webhooks.WalletOutgoingPaymentFinishedEvent{},
+		"WALLET_INCOMING_PAYMENT_FINISHED": &webhooks.WalletIncomingPaymentFinishedEvent{},
+		"WALLET_WITHDRAWAL_FINISHED":       &webhooks.WalletWithdrawalFinishedEvent{},
+		"WALLET_FUNDS_RECEIVED":            &webhooks.WalletFundsReceivedEvent{},
+		"LOW_BALANCE":                      &webhooks.LowBalanceEvent{},
+		"HIGH_BALANCE":                     &webhooks.HighBalanceEvent{},
+		"CHANNEL_OPENING_FEES":             &webhooks.ChannelOpeningFeesEvent{},
+		"SOMETHING_NEW":                    &webhooks.UnknownEvent{},
+	}
+	for eventType, expected := range events {
+		body, err := json.Marshal(map[string]interface{}{
+			"event_type": eventType,
+			"event_id":   "1615c8be5aa44e429eba700db2ed8ca5",
+			"timestamp":  "2023-05-17T23:56:47.874449+00:00",
+			"entity_id":  "entity:1",
+		})
+		require.NoError(t, err)
+		parsed, err := webhooks.Parse(body)
+		require.NoError(t, err)
+		event, err := parsed.Decode()
+		require.NoError(t, err)
+		require.IsType(t, expected, event, eventType)
+		require.Equal(t, "entity:1", event.Envelope().EntityId)
+	}
+}
+
+func TestDecodeKeepsUnknownFields(t *testing.T) {
+	data := `{"event_type": "LOW_BALANCE", "event_id": "1", "timestamp": "2023-05-17T23:56:47.874449+00:00", "entity_id": "node:1", "data": {"balance": {"original_value": 1000, "original_unit": "SATOSHI"}, "threshold": {"value": 5000, "unit": "SATOSHI"}}}`
+	parsed, err := webhooks.Parse([]byte(data))
+	require.NoError(t, err)
+	event, err := parsed.Decode()
+	require.NoError(t, err)
+
+	lowBalance := event.(*webhooks.LowBalanceEvent)
+	require.Len(t, lowBalance.Extra, 1)
+	threshold := lowBalance.Extra["threshold"].(map[string]interface{})
+	require.Equal(t, json.Number("5000"), threshold["value"])
+}
+
+func TestDecodeCurrencyAmounts(t *testing.T) {
+	data := `{"event_type": "HIGH_BALANCE", "event_id": "1", "timestamp": "2023-05-17T23:56:47.874449+00:00", "entity_id": "node:1", "data": {"balance": {"original_value": 100000000, "original_unit": "MILLISATOSHI", "preferred_currency_unit": "SATOSHI", "preferred_currency_value_rounded": 100000, "preferred_currency_value_approx": 100000.0}}}`
+	parsed, err := webhooks.Parse([]byte(data))
+	require.NoError(t, err)
+	event, err := parsed.Decode()
+	require.NoError(t, err)
+	require.Equal(t, &objects.CurrencyAmount{
+		OriginalValue:                 100_000_000,
+		OriginalUnit:                  objects.CurrencyUnitMillisatoshi,
+		PreferredCurrencyUnit:         objects.CurrencyUnitSatoshi,
+		PreferredCurrencyValueRounded: 100_000,
+		PreferredCurrencyValueApprox:  100_000,
+	}, event.(*webhooks.HighBalanceEvent).Balance)
+	require.Empty(t, event.(*webhooks.HighBalanceEvent).Extra)
+
+	data = `{"event_type": "CHANNEL_OPENING_FEES", "event_id": "2", "timestamp": "2023-05-17T23:56:47.874449+00:00", "entity_id": "channel:1", "data": {"fees": {"currency_amount_original_value": 5000, "currency_amount_original_unit": "SATOSHI"}}}`
+	parsed, err = webhooks.Parse([]byte(data))
+	require.NoError(t, err)
+	event, err = parsed.Decode()
+	require.NoError(t, err)
+	fees := event.(*webhooks.ChannelOpeningFeesEvent).Fees
+	require.Equal(t, int64(5000), fees.OriginalValue)
+	require.Equal(t, objects.CurrencyUnitSatoshi, fees.OriginalUnit)
+}
@@ -0,0 +1,148 @@
+package webhooks_test
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/lightsparkdev/go-sdk/objects"
+	"github.com/lightsparkdev/go-sdk/webhooks"
+	"github.com/stretchr/testify/require"
+)
+
+const handlerSecret = "webhook_secret"
+
+var handlerNow = time.Date(2023, 5, 17, 23, 57, 0, 0, time.UTC)
+
+func handlerEvent(eventId string, timestamp time.Time) string {
+	return `{"event_type": "PAYMENT_FINISHED", "event_id": "` + eventId + `", "timestamp": "` +
+		timestamp.Format(time.RFC3339Nano) + `", "entity_id": "outgoing_payment:1", "data": {"amount": 1}}`
+}
+
+func postWebhook(handler http.Handler, body string, secret string) *httptest.ResponseRecorder {
+	request := httptest.NewRequest(http.MethodPost, "/webhooks", strings.NewReader(body))
+	if secret != "" {
+		request.Header.Set(webhooks.SIGNATURE_HEADER, webhooks.Sign([]byte(body), secret))
+	}
+	recorder := httptest.NewRecorder()
+	handler.ServeHTTP(recorder, request)
+	return recorder
+}
+
+func TestHandlerRoutesEvents(t *testing.T) {
+	handler := webhooks.NewHandler(handlerSecret, webhooks.WithClock(func() time.Time { return handlerNow }))
+	var received []webhooks.Event
+	handler.Handle(objects.WebhookEventTypePaymentFinished, func(ctx context.Context, event webhooks.Event) error {
+		received = append(received, event)
+		return nil
+	})
+
+	response := postWebhook(handler, handlerEvent("1", handlerNow), handlerSecret)
+	require.Equal(t, http.StatusNoContent, response.Code)
+	require.Len(t, received, 1)
+	payment, ok := received[0].(*webhooks.PaymentFinishedEvent)
+	require.True(t, ok)
+	require.Equal(t, "outgoing_payment:1", payment.EntityId)
+
+	// Redeliveries of the same event are acknowledged without calling the handler again.
+	response = postWebhook(handler, handlerEvent("1", handlerNow), handlerSecret)
+	require.Equal(t, http.StatusNoContent, response.Code)
+	require.Len(t, received, 1)
+
+	// Events without a registered handler are acknowledged.
+	body := `{"event_type": "NODE_STATUS", "event_id": "2", "timestamp": "2023-05-17T23:56:47Z", "entity_id": "node:1"}`
+	response = postWebhook(handler, body, handlerSecret)
+	require.Equal(t, http.StatusNoContent, response.Code)
+
+	var fallback []webhooks.Event
+	handler.HandleDefault(func(ctx context.Context, event webhooks.Event) error {
+		fallback = append(fallback, event)
+		return nil
+	})
+	response = postWebhook(handler, body, handlerSecret)
+	require.Equal(t, http.StatusNoContent, response.Code)
+	require.Len(t, fallback, 1)
+	require.IsType(t, &webhooks.NodeStatusEvent{}, fallback[0])
+}
+
+func TestHandlerRejectsInvalidRequests(t *testing.T) {
+	handler := webhooks.NewHandler(handlerSecret, webhooks.WithClock(func() time.Time { return handlerNow }))
+	handler.HandleDefault(func(ctx context.Context, event webhooks.Event) error {
+		t.Fatal("handler must not be called")
+		return nil
+	})
+
+	request := httptest.NewRequest(http.MethodGet, "/webhooks", nil)
+	recorder := httptest.NewRecorder()
+	handler.ServeHTTP(recorder, request)
+	require.Equal(t, http.StatusMethodNotAllowed, recorder.Code)
+
+	require.Equal(t, http.StatusUnauthorized, postWebhook(handler, handlerEvent("1", handlerNow), "").Code)
+	require.Equal(t, http.StatusUnauthorized, postWebhook(handler, handlerEvent("1", handlerNow), "other_secret").Code)
+	require.Equal(t, http.StatusBadRequest, postWebhook(handler, `{"event_type": 1}`, handlerSecret).Code)
+	require.Equal(t, http.StatusBadRequest,
+		postWebhook(handler, handlerEvent("1", handlerNow.Add(-6*time.Minute)), handlerSecret).Code)
+	require.Equal(t, http.StatusBadRequest,
+		postWebhook(handler, handlerEvent("1", handlerNow.Add(6*time.Minute)), handlerSecret).Code)
+
+	lenient := webhooks.NewHandler(handlerSecret,
+		webhooks.WithClock(func() time.Time { return handlerNow }),
+		webhooks.WithTimestampTolerance(0),
+	)
+	require.Equal(t, http.StatusNoContent,
+		postWebhook(lenient, handlerEvent("1", handlerNow.Add(-time.Hour)), handlerSecret).Code)
+}
+
+func TestHandlerAcceptsSecondarySecret(t *testing.T) {
+	handler := webhooks.NewHandler("new_secret",
+		webhooks.WithClock(func() time.Time { return handlerNow }),
+		webhooks.WithSecondarySecret(handlerSecret, handlerNow.Add(time.Hour)),
+	)
+	require.Equal(t, http.StatusNoContent, postWebhook(handler, handlerEvent("1", handlerNow), handlerSecret).Code)
+	require.Equal(t, http.StatusNoContent, postWebhook(handler, handlerEvent("2", handlerNow), "new_secret").Code)
+
+	expired := webhooks.NewHandler("new_secret",
+		webhooks.WithClock(func() time.Time { return handlerNow }),
+		webhooks.WithSecondarySecret(handlerSecret, handlerNow),
+	)
+	require.Equal(t, http.StatusUnauthorized, postWebhook(expired, handlerEvent("1", handlerNow), handlerSecret).Code)
+}
+
+func TestHandlerRetriesFailedEvents(t *testing.T) {
+	handler := webhooks.NewHandler(handlerSecret, webhooks.WithClock(func() time.Time { return handlerNow }))
+	calls := 0
+	handler.HandleDefault(func(ctx context.Context, event webhooks.Event) error {
+		calls++
+		if calls == 1 {
+			return errors.New("database unavailable")
+		}
+		return nil
+	})
+
+	require.Equal(t, http.StatusInternalServerError, postWebhook(handler, handlerEvent("1", handlerNow), handlerSecret).Code)
+	require.Equal(t, http.StatusNoContent, postWebhook(handler, handlerEvent("1", handlerNow), handlerSecret).Code)
+	require.Equal(t, http.StatusNoContent, postWebhook(handler, handlerEvent("1", handlerNow), handlerSecret).Code)
+	require.Equal(t, 2, calls)
+}
+
+func TestMemoryDedupStoreEvictsOldestEvents(t *testing.T) {
+	ctx := context.Background()
+	store := webhooks.NewMemoryDedupStore(2)
+	for _, eventId := range []string{"1", "2", "3"} {
+		added, err := store.Add(ctx, eventId)
+		require.NoError(t, err)
+		require.True(t, added)
+	}
+	added, _ := store.Add(ctx, "3")
+	require.False(t, added)
+	added, _ = store.Add(ctx, "1")
+	require.True(t, added, "1 was evicted")
+
+	require.NoError(t, store.Remove(ctx, "1"))
+	added, _ = store.Add(ctx, "1")
+	require.True(t, added)
+}
@@ -2,6 +2,7 @@ package webhooks_test
 
 import (
 	"encoding/json"
+	"strings"
 	"testing"
 	"time"
 
@@ -80,3 +81,35 @@ func TestWebhooks_ParseNumber(t *testing.T) {
 	require.Equal(t, int64(281474976710654), perCommitmentPointIdx)
 
 }
+
+func TestWebhooks_Sign(t *testing.T) {
+	data := `{"event_type": "NODE_STATUS", "event_id": "1615c8be5aa44e429eba700db2ed8ca5", "timestamp": "2023-05-17T23:56:47.874449+00:00", "entity_id": "lightning_node:01882c25-157a-f96b-0000-362d42b64397"}`
+	webhookSecret := "3gZ5oQQUASYmqQNuEk0KambNMVkOADDItIJjzUlAWjX"
+	hexdigest := webhooks.Sign([]byte(data), webhookSecret)
+	require.Equal(t, "62a8829aeb48b4142533520b1f7f86cdb1ee7d718bf3ea15bc1c662d4c453b74", hexdigest)
+
+	_, err := webhooks.VerifyAndParse([]byte(data), strings.ToUpper(hexdigest), webhookSecret)
+	require.NoError(t, err)
+	_, err = webhooks.VerifyAndParse([]byte(data), hexdigest[:62], webhookSecret)
+	require.ErrorIs(t, err, webhooks.ErrInvalidSignature)
+	_, err = webhooks.VerifyAndParse([]byte(data), "not hex", webhookSecret)
+	require.ErrorIs(t, err, webhooks.ErrInvalidSignature)
+}
+
+func TestWebhooks_VerifyAndParseWithSecrets(t *testing.T) {
+	data := []byte(`{"event_type": "NODE_STATUS", "event_id": "1", "timestamp": "2023-05-17T23:56:47.874449+00:00", "entity_id": "node:1"}`)
+	secrets := webhooks.Secrets{Primary: "new_secret", Secondary: "old_secret"}
+
+	_, err := webhooks.VerifyAndParseWithSecrets(data, webhooks.Sign(data, "new_secret"), secrets)
+	require.NoError(t, err)
+	_, err = webhooks.VerifyAndParseWithSecrets(data, webhooks.Sign(data, "old_secret"), secrets)
+	require.NoError(t, err)
+	_, err = webhooks.VerifyAndParseWithSecrets(data, webhooks.Sign(data, "other_secret"), secrets)
+	require.ErrorIs(t, err, webhooks.ErrInvalidSignature)
+
+	secrets.SecondaryExpiresAt = time.Now().Add(-time.Minute)
+	_, err = webhooks.VerifyAndParseWithSecrets(data, webhooks.Sign(data, "old_secret"), secrets)
+	require.ErrorIs(t, err, webhooks.ErrInvalidSignature)
+	_, err = webhooks.VerifyAndParseWithSecrets(data, webhooks.Sign(data, "new_secret"), secrets)
+	require.NoError(t, err)
+}
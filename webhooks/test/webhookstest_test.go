@@ -0,0 +1,76 @@
+package webhooks_test
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/lightsparkdev/go-sdk/objects"
+	"github.com/lightsparkdev/go-sdk/remotesigning"
+	"github.com/lightsparkdev/go-sdk/webhooks"
+	"github.com/lightsparkdev/go-sdk/webhooks/webhookstest"
+	"github.com/stretchr/testify/require"
+)
+
+func TestSimulatorSendsEveryEventType(t *testing.T) {
+	handler := webhooks.NewHandler(handlerSecret)
+	var received []webhooks.Event
+	handler.HandleDefault(func(ctx context.Context, event webhooks.Event) error {
+		received = append(received, event)
+		return nil
+	})
+	server := httptest.NewServer(handler)
+	defer server.Close()
+
+	simulator := webhookstest.NewSimulator(server.URL, handlerSecret, webhookstest.WithHTTPClient(server.Client()))
+	for _, eventType := range webhookstest.EventTypes {
+		response, err := simulator.Send(context.Background(), webhookstest.NewEvent(eventType))
+		require.NoError(t, err)
+		response.Body.Close()
+		require.Equal(t, http.StatusNoContent, response.StatusCode, eventType.StringValue())
+	}
+	require.Len(t, received, len(webhookstest.EventTypes))
+	for i, event := range received {
+		require.Equal(t, webhookstest.EventTypes[i], event.Envelope().EventType)
+		_, unknown := event.(*webhooks.UnknownEvent)
+		require.False(t, unknown)
+		switch event := event.(type) {
+		case *webhooks.LowBalanceEvent:
+			require.NotNil(t, event.Balance)
+			require.Empty(t, event.Extra)
+		case *webhooks.HighBalanceEvent:
+			require.NotNil(t, event.Balance)
+			require.Empty(t, event.Extra)
+		case *webhooks.ChannelOpeningFeesEvent:
+			require.Equal(t, objects.CurrencyUnitSatoshi, event.Fees.OriginalUnit)
+			require.Empty(t, event.Extra)
+		}
+	}
+	require.NotNil(t, received[6].Envelope().WalletId)
+
+	wrongSecret := webhookstest.NewSimulator(server.URL, "other_secret", webhookstest.WithHTTPClient(server.Client()))
+	response, err := wrongSecret.Send(context.Background(), webhookstest.NewEvent(objects.WebhookEventTypeNodeStatus))
+	require.NoError(t, err)
+	response.Body.Close()
+	require.Equal(t, http.StatusUnauthorized, response.StatusCode)
+}
+
+func TestRemoteSigningTemplatesAreHandled(t *testing.T) {
+	seed := make([]byte, 32)
+	for _, subEventType := range webhookstest.RemoteSigningSubEventTypes {
+		if subEventType == objects.RemoteSigningSubEventTypeVlsMessage {
+			continue
+		}
+		body, err := json.Marshal(webhookstest.NewRemoteSigningEvent(subEventType, objects.BitcoinNetworkRegtest))
+		require.NoError(t, err)
+		event, err := webhooks.Parse(body)
+		require.NoError(t, err)
+
+		request, err := remotesigning.ParseRemoteSigningRequest(*event)
+		require.NoError(t, err, subEventType.StringValue())
+		_, err = remotesigning.HandleSigningRequest(request, seed)
+		require.NoError(t, err, subEventType.StringValue())
+	}
+}
@@ -8,7 +8,6 @@ import (
 	"encoding/hex"
 	"encoding/json"
 	"errors"
-	"strings"
 	"time"
 
 	"github.com/lightsparkdev/go-sdk/objects"
@@ -16,6 +15,9 @@ import (
 
 const SIGNATURE_HEADER = "lightspark-signature"
 
+// ErrInvalidSignature is returned when the signature of a webhook message doesn't match any secret.
+var ErrInvalidSignature = errors.New("Webhook message hash does not match signature")
+
 type WebhookEvent struct {
 	EventType objects.WebhookEventType
 	EventId   string
@@ -33,13 +35,69 @@ type WebhookEvent struct {
 //	hexdigest: the message signature sent in the `lightspark-signature` header.
 //	webhookSecret: the webhook secret configured at the Lightspark API configuration.
 func VerifyAndParse(data []byte, hexdigest string, webhookSecret string) (*WebhookEvent, error) {
+	if !signatureMatches(data, hexdigest, webhookSecret) {
+		return nil, ErrInvalidSignature
+	}
+	return Parse(data)
+}
+
+// VerifyAndParseWithSecrets Verifies the signature against the active secrets and parses the message into a
+// WebhookEvent object. Use it while rotating the webhook secret.
+//
+// Args:
+//
+//	data: the POST message body received by the webhook.
+//	hexdigest: the message signature sent in the `lightspark-signature` header.
+//	secrets: the webhook secrets currently accepted.
+func VerifyAndParseWithSecrets(data []byte, hexdigest string, secrets Secrets) (*WebhookEvent, error) {
+	if !secrets.verify(data, hexdigest, time.Now()) {
+		return nil, ErrInvalidSignature
+	}
+	return Parse(data)
+}
+
+// Sign returns the value of the `lightspark-signature` header for a webhook message, for tests and fixtures.
+//
+// Args:
+//
+//	data: the POST message body of the webhook.
+//	webhookSecret: the webhook secret configured at the Lightspark API configuration.
+func Sign(data []byte, webhookSecret string) string {
 	hash := hmac.New(sha256.New, []byte(webhookSecret))
 	hash.Write(data)
-	result := hash.Sum(nil)
-	if strings.ToLower(hex.EncodeToString(result)) != strings.ToLower(hexdigest) {
-		return nil, errors.New("Webhook message hash does not match signature")
+	return hex.EncodeToString(hash.Sum(nil))
+}
+
+// Secrets is the set of webhook secrets accepted while the secret is rotated. Once the new secret is
+// configured at the Lightspark API configuration, set it as Primary and move the previous one to Secondary
+// until SecondaryExpiresAt, so that the messages signed before the rotation and their retries are still
+// accepted.
+type Secrets struct {
+	// Primary is the current webhook secret.
+	Primary string
+	// Secondary is the previous webhook secret. It is ignored when empty.
+	Secondary string
+	// SecondaryExpiresAt is the end of the grace period of Secondary. Secondary never expires when zero.
+	SecondaryExpiresAt time.Time
+}
+
+func (s Secrets) verify(data []byte, hexdigest string, now time.Time) bool {
+	valid := signatureMatches(data, hexdigest, s.Primary)
+	if s.Secondary != "" && (s.SecondaryExpiresAt.IsZero() || now.Before(s.SecondaryExpiresAt)) {
+		// Both secrets are always checked so the timing doesn't reveal which one matched.
+		valid = signatureMatches(data, hexdigest, s.Secondary) || valid
 	}
-	return Parse(data)
+	return valid
+}
+
+func signatureMatches(data []byte, hexdigest string, webhookSecret string) bool {
+	signature, err := hex.DecodeString(hexdigest)
+	if err != nil {
+		return false
+	}
+	hash := hmac.New(sha256.New, []byte(webhookSecret))
+	hash.Write(data)
+	return hmac.Equal(hash.Sum(nil), signature)
 }
 
 // Parse Parses the message into a WebhookEvent object.
@@ -56,6 +114,15 @@ func Parse(data []byte) (*WebhookEvent, error) {
 		return nil, err
 	}
 
+	for _, field := range []string{"event_type", "event_id", "timestamp", "entity_id"} {
+		if _, ok := eventJSON[field].(string); !ok {
+			return nil, errors.New("webhook message is missing " + field)
+		}
+	}
+	if _, ok := eventJSON["data"].(map[string]interface{}); eventJSON["data"] != nil && !ok {
+		return nil, errors.New("webhook message data is not an object")
+	}
+
 	eventBytes, err := json.Marshal(eventJSON["event_type"].(string))
 	if err != nil {
 		return nil, err
@@ -68,9 +135,8 @@ func Parse(data []byte) (*WebhookEvent, error) {
 		return nil, err
 	}
 	var walletId *string = nil
-	if eventJSON["wallet_id"] != nil {
-		walletId = new(string)
-		*walletId = eventJSON["wallet_id"].(string)
+	if id, ok := eventJSON["wallet_id"].(string); ok {
+		walletId = &id
 	}
 
 	var additionalData *map[string]interface{} = nil
@@ -0,0 +1,267 @@
+// Copyright ©, 2023-present, Lightspark Group, Inc. - All Rights Reserved
+
+// Package webhookstest builds realistic, correctly signed webhook events and sends them to a local URL, so
+// that webhook handlers and remote signing servers can be exercised without a Lightspark account:
+//
+//	simulator := webhookstest.NewSimulator("http://127.0.0.1:8080/ln/webhooks", webhookSecret)
+//	response, err := simulator.Send(ctx, webhookstest.NewRemoteSigningEvent(
+//		objects.RemoteSigningSubEventTypeGetPerCommitmentPoint, objects.BitcoinNetworkRegtest))
+package webhookstest
+
+import (
+	"bytes"
+	"context"
+	"crypto/rand"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"time"
+
+	"github.com/btcsuite/btcd/btcec/v2"
+	"github.com/lightsparkdev/go-sdk/objects"
+	"github.com/lightsparkdev/go-sdk/webhooks"
+)
+
+// EventTypes lists the event types NewEvent has a template for.
+var EventTypes = []objects.WebhookEventType{
+	objects.WebhookEventTypePaymentFinished,
+	objects.WebhookEventTypeForceClosure,
+	objects.WebhookEventTypeWithdrawalFinished,
+	objects.WebhookEventTypeFundsReceived,
+	objects.WebhookEventTypeNodeStatus,
+	objects.WebhookEventTypeUmaInvitationClaimed,
+	objects.WebhookEventTypeWalletStatus,
+	objects.WebhookEventTypeWalletOutgoingPaymentFinished,
+	objects.WebhookEventTypeWalletIncomingPaymentFinished,
+	objects.WebhookEventTypeWalletWithdrawalFinished,
+	objects.WebhookEventTypeWalletFundsReceived,
+	objects.WebhookEventTypeRemoteSigning,
+	objects.WebhookEventTypeLowBalance,
+	objects.WebhookEventTypeHighBalance,
+	objects.WebhookEventTypeChannelOpeningFees,
+}
+
+// RemoteSigningSubEventTypes lists the sub event types NewRemoteSigningEvent has a template for.
+var RemoteSigningSubEventTypes = []objects.RemoteSigningSubEventType{
+	objects.RemoteSigningSubEventTypeEcdh,
+	objects.RemoteSigningSubEventTypeGetPerCommitmentPoint,
+	objects.RemoteSigningSubEventTypeReleasePerCommitmentSecret,
+	objects.RemoteSigningSubEventTypeSignInvoice,
+	objects.RemoteSigningSubEventTypeDeriveKeyAndSign,
+	objects.RemoteSigningSubEventTypeReleasePaymentPreimage,
+	objects.RemoteSigningSubEventTypeRequestInvoicePaymentHash,
+	objects.RemoteSigningSubEventTypeRevealCounterpartyPerCommitmentSecret,
+	objects.RemoteSigningSubEventTypeVlsMessage,
+}
+
+// Event is a webhook event to send. The templates fill every field with random ids and values of the
+// right shape; change them to match the entities known to the handler under test.
+type Event struct {
+	EventType objects.WebhookEventType
+	EventId   string
+	Timestamp time.Time
+	EntityId  string
+	WalletId  *string
+	Data      map[string]interface{}
+}
+
+// MarshalJSON encodes the event as it is sent by Lightspark.
+func (e *Event) MarshalJSON() ([]byte, error) {
+	event := map[string]interface{}{
+		"event_type": e.EventType.StringValue(),
+		"event_id":   e.EventId,
+		"timestamp":  e.Timestamp.UTC().Format(time.RFC3339Nano),
+		"entity_id":  e.EntityId,
+	}
+	if e.WalletId != nil {
+		event["wallet_id"] = *e.WalletId
+	}
+	if e.Data != nil {
+		event["data"] = e.Data
+	}
+	return json.Marshal(event)
+}
+
+// NewEvent returns an event of the given type built from its template. Remote signing events are built
+// for the GET_PER_COMMITMENT_POINT sub event type on REGTEST; use NewRemoteSigningEvent for the others.
+func NewEvent(eventType objects.WebhookEventType) *Event {
+	event := &Event{EventType: eventType, EventId: randomHex(16), Timestamp: time.Now()}
+	switch eventType {
+	case objects.WebhookEventTypePaymentFinished:
+		event.EntityId = entityId("OutgoingPayment")
+	case objects.WebhookEventTypeForceClosure:
+		event.EntityId = entityId("Channel")
+	case objects.WebhookEventTypeWithdrawalFinished:
+		event.EntityId = entityId("WithdrawalRequest")
+	case objects.WebhookEventTypeFundsReceived:
+		event.EntityId = entityId("Deposit")
+	case objects.WebhookEventTypeNodeStatus:
+		event.EntityId = entityId("LightsparkNodeWithRemoteSigning")
+	case objects.WebhookEventTypeUmaInvitationClaimed:
+		event.EntityId = entityId("UmaInvitation")
+	case objects.WebhookEventTypeWalletStatus:
+		event.EntityId = entityId("Wallet")
+		event.WalletId = &event.EntityId
+	case objects.WebhookEventTypeWalletOutgoingPaymentFinished:
+		event.EntityId = entityId("OutgoingPayment")
+		event.WalletId = ptr(entityId("Wallet"))
+	case objects.WebhookEventTypeWalletIncomingPaymentFinished:
+		event.EntityId = entityId("IncomingPayment")
+		event.WalletId = ptr(entityId("Wallet"))
+	case objects.WebhookEventTypeWalletWithdrawalFinished:
+		event.EntityId = entityId("WithdrawalRequest")
+		event.WalletId = ptr(entityId("Wallet"))
+	case objects.WebhookEventTypeWalletFundsReceived:
+		event.EntityId = entityId("Deposit")
+		event.WalletId = ptr(entityId("Wallet"))
+	case objects.WebhookEventTypeRemoteSigning:
+		return NewRemoteSigningEvent(objects.RemoteSigningSubEventTypeGetPerCommitmentPoint, objects.BitcoinNetworkRegtest)
+	case objects.WebhookEventTypeLowBalance, objects.WebhookEventTypeHighBalance:
+		event.EntityId = entityId("LightsparkNodeWithRemoteSigning")
+		event.Data = map[string]interface{}{
+			"balance": map[string]interface{}{"original_value": 100_000_000, "original_unit": "MILLISATOSHI"},
+		}
+	case objects.WebhookEventTypeChannelOpeningFees:
+		event.EntityId = entityId("Channel")
+		event.Data = map[string]interface{}{
+			"fees": map[string]interface{}{"original_value": 5_000, "original_unit": "SATOSHI"},
+		}
+	default:
+		event.EntityId = entityId("Entity")
+	}
+	return event
+}
+
+// NewRemoteSigningEvent returns a REMOTE_SIGNING event of the given sub event type built from its
+// template, with every field read by the remotesigning package.
+func NewRemoteSigningEvent(subEventType objects.RemoteSigningSubEventType, network objects.BitcoinNetwork) *Event {
+	nodeId := entityId("LightsparkNodeWithRemoteSigning")
+	data := map[string]interface{}{
+		"sub_event_type":  subEventType.StringValue(),
+		"bitcoin_network": network.StringValue(),
+	}
+	event := &Event{
+		EventType: objects.WebhookEventTypeRemoteSigning,
+		EventId:   randomHex(16),
+		Timestamp: time.Now(),
+		EntityId:  nodeId,
+		Data:      data,
+	}
+
+	switch subEventType {
+	case objects.RemoteSigningSubEventTypeEcdh:
+		data["peer_public_key"] = randomPublicKey()
+	case objects.RemoteSigningSubEventTypeGetPerCommitmentPoint,
+		objects.RemoteSigningSubEventTypeReleasePerCommitmentSecret:
+		event.EntityId = entityId("Channel")
+		data["node_id"] = nodeId
+		data["derivation_path"] = "m/3/2104864975"
+		data["per_commitment_point_idx"] = uint64(281474976710654)
+	case objects.RemoteSigningSubEventTypeSignInvoice:
+		data["invoice_id"] = entityId("Invoice")
+		data["payreq_hash"] = randomHex(32)
+	case objects.RemoteSigningSubEventTypeDeriveKeyAndSign:
+		data["signing_jobs"] = []interface{}{
+			map[string]interface{}{
+				"id":              randomHex(16),
+				"derivation_path": "m/3/2104864975/0",
+				"message":         randomHex(32),
+				"add_tweak":       randomHex(32),
+			},
+			map[string]interface{}{
+				"id":              randomHex(16),
+				"derivation_path": "m/3/2104864975/1",
+				"message":         randomHex(32),
+			},
+		}
+	case objects.RemoteSigningSubEventTypeReleasePaymentPreimage:
+		data["invoice_id"] = entityId("Invoice")
+		data["preimage_nonce"] = randomHex(32)
+		data["is_uma"] = false
+		data["is_lnurl"] = false
+	case objects.RemoteSigningSubEventTypeRequestInvoicePaymentHash:
+		data["invoice_id"] = entityId("Invoice")
+	case objects.RemoteSigningSubEventTypeRevealCounterpartyPerCommitmentSecret:
+		event.EntityId = entityId("Channel")
+		data["node_id"] = nodeId
+		data["per_commitment_secret_idx"] = uint64(281474976710654)
+		data["per_commitment_secret"] = randomHex(32)
+	case objects.RemoteSigningSubEventTypeVlsMessage:
+		data["node_id"] = nodeId
+	}
+	return event
+}
+
+// Option configures a Simulator.
+type Option func(*Simulator)
+
+// WithHTTPClient sets the client used to send events. http.DefaultClient is used otherwise.
+func WithHTTPClient(client *http.Client) Option {
+	return func(s *Simulator) {
+		s.client = client
+	}
+}
+
+// Simulator sends signed webhook events to a URL.
+type Simulator struct {
+	url    string
+	secret string
+	client *http.Client
+}
+
+// NewSimulator creates a Simulator sending events to url, signed with the webhook secret the receiver
+// verifies them with.
+func NewSimulator(url string, secret string, opts ...Option) *Simulator {
+	s := &Simulator{url: url, secret: secret, client: http.DefaultClient}
+	for _, opt := range opts {
+		opt(s)
+	}
+	return s
+}
+
+// Send signs and POSTs the event. The caller must close the body of the response.
+func (s *Simulator) Send(ctx context.Context, event *Event) (*http.Response, error) {
+	body, err := json.Marshal(event)
+	if err != nil {
+		return nil, err
+	}
+	return s.SendRaw(ctx, body)
+}
+
+// SendRaw signs and POSTs a body as is, to replay a captured event. The caller must close the body of the
+// response.
+func (s *Simulator) SendRaw(ctx context.Context, body []byte) (*http.Response, error) {
+	request, err := http.NewRequestWithContext(ctx, http.MethodPost, s.url, bytes.NewReader(body))
+	if err != nil {
+		return nil, err
+	}
+	request.Header.Set("Content-Type", "application/json")
+	request.Header.Set(webhooks.SIGNATURE_HEADER, webhooks.Sign(body, s.secret))
+	return s.client.Do(request)
+}
+
+func entityId(typename string) string {
+	id := randomHex(16)
+	return fmt.Sprintf("%s:%s-%s-%s-%s-%s", typename, id[:8], id[8:12], id[12:16], id[16:20], id[20:])
+}
+
+func randomHex(length int) string {
+	bytes := make([]byte, length)
+	if _, err := rand.Read(bytes); err != nil {
+		panic(err)
+	}
+	return hex.EncodeToString(bytes)
+}
+
+func randomPublicKey() string {
+	key, err := btcec.NewPrivateKey()
+	if err != nil {
+		panic(err)
+	}
+	return hex.EncodeToString(key.PubKey().SerializeCompressed())
+}
+
+func ptr[T any](value T) *T {
+	return &value
+}